@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// a11yTitles gives the message panels labels that say what the text is, instead of how it was made
+var a11yTitles = map[string]string{
+	"Generated commit message":          "Suggested commit message",
+	"Generated detailed commit message": "Suggested detailed commit message",
+	"Regenerated commit message":        "New suggested commit message",
+	"Summarized commit message":         "Shorter suggested commit message",
+	"Feedback-based commit message":     "Suggested commit message based on your feedback",
+}
+
+// accessibleUI writes output for screen readers: no box-drawing, emoji or cursor movement, every
+// block is labeled where it starts and ends, and state changes are announced as sentences
+type accessibleUI struct {
+	out io.Writer
+	in  *bufio.Reader
+}
+
+// newAccessibleUI creates a UI for people using a screen reader
+func newAccessibleUI(out io.Writer, in io.Reader) *accessibleUI {
+	return &accessibleUI{out: out, in: bufio.NewReader(in)}
+}
+
+// a11yText drops the emoji and symbols decorating the start of a message
+func a11yText(text string) string {
+	return strings.TrimLeftFunc(strings.TrimSpace(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '(' && r != '~'
+	})
+}
+
+// a11yLabel turns a panel title like "✨ GENERATED COMMIT MESSAGE:" into "Suggested commit message".
+// Words in all capitals are lowercased so they aren't spelled out letter by letter.
+func a11yLabel(title string) string {
+	words := strings.Fields(strings.TrimSuffix(a11yText(title), ":"))
+	for i, word := range words {
+		if word == strings.ToUpper(word) && strings.IndexFunc(word, unicode.IsLetter) >= 0 && len(word) > 1 {
+			words[i] = strings.ToLower(word)
+		}
+	}
+	label := strings.Join(words, " ")
+	if label != "" {
+		label = strings.ToUpper(label[:1]) + label[1:]
+	}
+	if renamed, ok := a11yTitles[label]; ok {
+		return renamed
+	}
+	return label
+}
+
+// sentence ends a message with a period unless it already ends in punctuation
+func sentence(text string) string {
+	if text == "" || strings.ContainsRune(".!?:)", rune(text[len(text)-1])) {
+		return text
+	}
+	return text + "."
+}
+
+// announce writes a message as a sentence, with an optional label like "Warning:" in front
+func (a *accessibleUI) announce(label, message string) {
+	text := sentence(a11yText(message))
+	if label != "" {
+		text = label + " " + text
+	}
+	fmt.Fprintln(a.out, text)
+}
+
+func (a *accessibleUI) Info(message string)    { a.announce("", message) }
+func (a *accessibleUI) Success(message string) { a.announce("", message) }
+func (a *accessibleUI) Warn(message string)    { a.announce("Warning:", message) }
+func (a *accessibleUI) Error(message string)   { a.announce("Error:", message) }
+
+// Panel labels the block and says where it ends, so the body can't run into the next message
+func (a *accessibleUI) Panel(title, body string) {
+	label := a11yLabel(title)
+	switch {
+	case body == "":
+		fmt.Fprintln(a.out, sentence(label))
+		return
+	case label == "":
+		fmt.Fprintln(a.out, strings.Trim(body, "\n"))
+		return
+	}
+	fmt.Fprintf(a.out, "%s:\n%s\nEnd of %s.\n", label, strings.Trim(body, "\n"), strings.ToLower(label[:1])+label[1:])
+}
+
+func (a *accessibleUI) Prompt(question string) (string, error) {
+	fmt.Fprint(a.out, a11yText(question))
+	if !strings.HasSuffix(question, " ") {
+		fmt.Fprint(a.out, " ")
+	}
+	return readAnswer(a.in)
+}
+
+// Stream shows nothing, screen readers would read out every fragment; the finished message is
+// announced as a whole
+func (a *accessibleUI) Stream(text string) {}
+
+// Progress shows nothing either, a screen reader can't keep up with a countdown
+func (a *accessibleUI) Progress(status string) {}
+
+// Choose reads each option out with its position and key, then reads a line like the other modes
+func (a *accessibleUI) Choose(question string, options []MenuOption) (string, error) {
+	fmt.Fprintln(a.out, sentence(strings.TrimSuffix(a11yText(question), ":")))
+	for i, option := range options {
+		fmt.Fprintf(a.out, "Option %d of %d: %s, press %s.\n", i+1, len(options), option.Label, option.Key)
+	}
+	return chooseByLine(func(string) (string, error) {
+		return a.Prompt("Type a key and press Enter, or just Enter for " + options[0].Key + ":")
+	}, question, options)
+}
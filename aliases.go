@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Key prefixes for aliases and flag defaults, e.g. rmit set alias.qc "--subject-only --commit"
+// and rmit set defaults.model openai/gpt-4o
+const (
+	aliasKeyPrefix   = "alias."
+	defaultKeyPrefix = "defaults."
+)
+
+// noFlagDefaultAnnotation marks flags that configured defaults don't apply to, e.g. filters that
+// share a name with a generation flag
+const noFlagDefaultAnnotation = "rmit_no_default"
+
+// maxAliasDepth stops aliases that expand to each other
+const maxAliasDepth = 10
+
+// aliasNamePattern matches valid alias and flag names
+var aliasNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// setAlias defines an alias, or removes it when the value is empty
+func setAlias(config *Config, name, value string) error {
+	if !aliasNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid alias name %q: use lowercase letters, digits and dashes", name)
+	}
+	if strings.TrimSpace(value) == "" {
+		delete(config.Aliases, name)
+		return nil
+	}
+	if _, err := splitCommandLine(value); err != nil {
+		return fmt.Errorf("invalid alias %s: %w", name, err)
+	}
+	if config.Aliases == nil {
+		config.Aliases = make(map[string]string)
+	}
+	config.Aliases[name] = value
+	return nil
+}
+
+// setFlagDefault sets the default value of a flag, or removes it when the value is empty
+func setFlagDefault(config *Config, flag, value string) error {
+	flag = strings.TrimPrefix(flag, "--")
+	if !aliasNamePattern.MatchString(flag) {
+		return fmt.Errorf("invalid flag name %q", flag)
+	}
+	if value == "" {
+		delete(config.Defaults, flag)
+		return nil
+	}
+	if config.Defaults == nil {
+		config.Defaults = make(map[string]string)
+	}
+	config.Defaults[flag] = value
+	return nil
+}
+
+// splitCommandLine splits an alias into arguments the way a shell would, honouring single and
+// double quotes and backslash escapes
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// expandAliases replaces a leading alias in the command line with what it stands for, before
+// Cobra parses it. Built-in commands always win, so an alias can't replace one. The config is
+// only loaded when the first argument could be an alias.
+func expandAliases(rootCmd *cobra.Command, args []string) ([]string, error) {
+	var aliases map[string]string
+	seen := make(map[string]bool)
+	for range maxAliasDepth {
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return args, nil
+		}
+		if cmd, _, err := rootCmd.Find(args); err == nil && cmd != rootCmd {
+			return args, nil
+		}
+
+		if aliases == nil {
+			config, err := loadConfig()
+			if err != nil {
+				return nil, err
+			}
+			aliases = config.Aliases
+			if aliases == nil {
+				return args, nil
+			}
+		}
+		expansion, ok := aliases[args[0]]
+		if !ok {
+			return args, nil
+		}
+		if seen[args[0]] {
+			return nil, fmt.Errorf("alias %s expands to itself", args[0])
+		}
+		seen[args[0]] = true
+
+		expanded, err := splitCommandLine(expansion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alias %s: %w", args[0], err)
+		}
+		args = append(expanded, args[1:]...)
+	}
+	return nil, fmt.Errorf("aliases nested more than %d deep", maxAliasDepth)
+}
+
+// applyFlagDefaults sets flags the user didn't give from the configured defaults. Defaults for
+// flags the command doesn't have are skipped, so defaults.push only applies to rmit bot.
+func applyFlagDefaults(cmd *cobra.Command, defaults map[string]string) error {
+	names := make([]string, 0, len(defaults))
+	for name := range defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed || flag.Annotations[noFlagDefaultAnnotation] != nil {
+			continue
+		}
+		if err := cmd.Flags().Set(name, defaults[name]); err != nil {
+			return fmt.Errorf("invalid default for --%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// sortedEntries returns a map's entries sorted by key
+func sortedEntries(values map[string]string) [][2]string {
+	entries := make([][2]string, 0, len(values))
+	for key, value := range values {
+		entries = append(entries, [2]string{key, value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i][0] < entries[j][0] })
+	return entries
+}
+
+// loadFlagDefaults applies the configured flag defaults to the command about to run
+func loadFlagDefaults(cmd *cobra.Command) {
+	config, err := loadConfig()
+	if err != nil {
+		log.Printf("Warning: flag defaults not applied: %v", err)
+		return
+	}
+	if err := applyFlagDefaults(cmd, config.Defaults); err != nil {
+		log.Fatalf("%s %v", red("Error:"), err)
+	}
+}
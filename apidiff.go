@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"sort"
+	"strings"
+)
+
+// APIChange describes a change to the exported API of a Go package
+type APIChange struct {
+	Package string
+	Symbol  string
+	Kind    string // "adds", "removes" or "changes"
+}
+
+// String formats the change as a fact for the prompt
+func (c APIChange) String() string {
+	return fmt.Sprintf("%s exported %s in package %s", c.Kind, c.Symbol, c.Package)
+}
+
+// gitShow returns the contents of a file at the given revision
+func gitShow(rev, filePath string) (string, error) {
+	output, err := gitCommand("show", rev+":"+filePath).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s at %s: %w", filePath, rev, err)
+	}
+	return string(output), nil
+}
+
+// renderNode prints an AST node back to Go source on a single line
+func renderNode(fset *token.FileSet, node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, node); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(buf.String()), " ")
+}
+
+// exportedFields strips unexported fields from struct types so they don't count as API changes
+func exportedFields(expr ast.Expr) ast.Expr {
+	st, ok := expr.(*ast.StructType)
+	if !ok || st.Fields == nil {
+		return expr
+	}
+
+	fields := &ast.FieldList{}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			fields.List = append(fields.List, field)
+			continue
+		}
+		var names []*ast.Ident
+		for _, name := range field.Names {
+			if name.IsExported() {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			fields.List = append(fields.List, &ast.Field{Names: names, Type: field.Type})
+		}
+	}
+
+	return &ast.StructType{Fields: fields}
+}
+
+// receiverName returns the receiver type of a method, e.g. "*Client", and whether it is exported
+func receiverName(fset *token.FileSet, recv *ast.FieldList) (string, bool) {
+	if recv == nil || len(recv.List) == 0 {
+		return "", false
+	}
+
+	expr := recv.List[0].Type
+	name := renderNode(fset, expr)
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if index, ok := expr.(*ast.IndexExpr); ok {
+		expr = index.X
+	}
+	if index, ok := expr.(*ast.IndexListExpr); ok {
+		expr = index.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	return name, ok && ident.IsExported()
+}
+
+// exportedAPI parses a Go source file and returns its exported symbols mapped to their signatures
+func exportedAPI(src string) (string, map[string]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return "", nil, err
+	}
+
+	symbols := make(map[string]string)
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			key := "func " + d.Name.Name
+			if d.Recv != nil {
+				recv, exported := receiverName(fset, d.Recv)
+				if !exported {
+					continue
+				}
+				key = fmt.Sprintf("method (%s).%s", recv, d.Name.Name)
+			}
+			symbols[key] = renderNode(fset, d.Type)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						symbols["type "+s.Name.Name] = renderNode(fset, exportedFields(s.Type))
+					}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						if name.IsExported() {
+							signature := ""
+							if s.Type != nil {
+								signature = renderNode(fset, s.Type)
+							}
+							symbols[kind+" "+name.Name] = signature
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return file.Name.Name, symbols, nil
+}
+
+// isPublicGoFile reports whether a path is a Go file that can contribute to a module's public API
+func isPublicGoFile(p string) bool {
+	if !strings.HasSuffix(p, ".go") || strings.HasSuffix(p, "_test.go") {
+		return false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == "internal" || part == "vendor" || part == "testdata" {
+			return false
+		}
+	}
+	return true
+}
+
+// detectAPIChanges compares the exported API of changed Go files between HEAD and the working tree
+func detectAPIChanges(files []*FileDiff) []APIChange {
+	before := make(map[string]map[string]string)
+	after := make(map[string]map[string]string)
+	skip := make(map[string]bool)
+
+	collect := func(dir, src string, into map[string]map[string]string) {
+		pkg, symbols, err := exportedAPI(src)
+		// Commands have no importable API, and unparsable files would look like removals
+		if err != nil || pkg == "main" {
+			skip[dir] = true
+			return
+		}
+		if into[dir] == nil {
+			into[dir] = make(map[string]string)
+		}
+		for key, signature := range symbols {
+			into[dir][key] = signature
+		}
+	}
+
+	for _, f := range files {
+		if !isPublicGoFile(f.Path) && !isPublicGoFile(f.OldPath) {
+			continue
+		}
+		if !f.New {
+			if src, err := gitShow("HEAD", f.OldPath); err == nil {
+				collect(path.Dir(f.OldPath), src, before)
+			}
+		}
+		if !f.Deleted {
+			if src, err := readRepoFile(f.Path); err == nil {
+				collect(path.Dir(f.Path), src, after)
+			}
+		}
+	}
+
+	var changes []APIChange
+	dirs := make(map[string]bool)
+	for dir := range before {
+		dirs[dir] = true
+	}
+	for dir := range after {
+		dirs[dir] = true
+	}
+
+	for dir := range dirs {
+		if skip[dir] {
+			continue
+		}
+		pkg := dir
+		if pkg == "." {
+			pkg = "(root)"
+		}
+		for key, signature := range after[dir] {
+			old, existed := before[dir][key]
+			switch {
+			case !existed:
+				changes = append(changes, APIChange{Package: pkg, Symbol: key, Kind: "adds"})
+			case old != signature:
+				changes = append(changes, APIChange{Package: pkg, Symbol: key, Kind: "changes"})
+			}
+		}
+		for key := range before[dir] {
+			if _, exists := after[dir][key]; !exists {
+				changes = append(changes, APIChange{Package: pkg, Symbol: key, Kind: "removes"})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Symbol < changes[j].Symbol
+	})
+
+	return changes
+}
+
+// semverImpact suggests the version bump implied by a set of API changes
+func semverImpact(changes []APIChange) string {
+	impact := "patch (no exported API changes)"
+	for _, change := range changes {
+		if change.Kind != "adds" {
+			return "major (breaking changes to the exported API)"
+		}
+		impact = "minor (backwards compatible API additions)"
+	}
+	return impact
+}
+
+// apiInsights describes exported Go API changes for the prompt
+func apiInsights(files []*FileDiff) []string {
+	if !repoFileExists("go.mod") {
+		return nil
+	}
+
+	changes := detectAPIChanges(files)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var insights []string
+	for _, change := range changes {
+		insights = append(insights, change.String())
+	}
+	insights = append(insights, "Suggested semver impact: "+semverImpact(changes))
+
+	return insights
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Maximum width or height of images sent to vision models
+const (
+	thumbnailSize  = 384
+	attachmentSize = 1280
+)
+
+// MIME types of images that can be attached to a prompt
+var imageMIMETypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// Binary asset formats rmit can describe, by extension
+var assetFormats = map[string]string{
+	".png":   "PNG image",
+	".jpg":   "JPEG image",
+	".jpeg":  "JPEG image",
+	".gif":   "GIF image",
+	".webp":  "WebP image",
+	".ico":   "ICO icon",
+	".bmp":   "BMP image",
+	".ttf":   "TrueType font",
+	".otf":   "OpenType font",
+	".woff":  "WOFF font",
+	".woff2": "WOFF2 font",
+	".mp4":   "MP4 video",
+	".webm":  "WebM video",
+	".mp3":   "MP3 audio",
+	".wav":   "WAV audio",
+	".pdf":   "PDF document",
+}
+
+// formatBytes formats a byte count for humans
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// imageDimensions returns "WxH" for decodable images, or an empty string
+func imageDimensions(data []byte) string {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", cfg.Width, cfg.Height)
+}
+
+// readAssetVersions returns the old (HEAD) and new (working tree) contents of a binary file
+func readAssetVersions(f *FileDiff) ([]byte, []byte) {
+	var oldData, newData []byte
+	if !f.New {
+		if content, err := gitShow("HEAD", f.OldPath); err == nil {
+			oldData = []byte(content)
+		}
+	}
+	if !f.Deleted {
+		if root, err := getRepoRoot(); err == nil {
+			newData, _ = readCheckoutFile(root, f.Path)
+		}
+	}
+	return oldData, newData
+}
+
+// describeAsset describes a changed binary asset, e.g. "PNG image, 1200x630 → 1920x1080, 45.2 KB → 80.1 KB (+34.9 KB)"
+func describeAsset(format string, oldData, newData []byte, isNew, deleted bool) string {
+	switch {
+	case isNew:
+		parts := []string{"new " + format}
+		if dims := imageDimensions(newData); dims != "" {
+			parts = append(parts, dims)
+		}
+		return strings.Join(append(parts, formatBytes(len(newData))), ", ")
+	case deleted:
+		return fmt.Sprintf("removed %s, %s", format, formatBytes(len(oldData)))
+	}
+
+	parts := []string{format}
+	oldDims, newDims := imageDimensions(oldData), imageDimensions(newData)
+	if oldDims != newDims {
+		parts = append(parts, oldDims+" → "+newDims)
+	} else if newDims != "" {
+		parts = append(parts, newDims+" (unchanged dimensions)")
+	}
+
+	delta := len(newData) - len(oldData)
+	sign := "+"
+	if delta < 0 {
+		sign = "-"
+		delta = -delta
+	}
+	parts = append(parts, fmt.Sprintf("%s → %s (%s%s)", formatBytes(len(oldData)), formatBytes(len(newData)), sign, formatBytes(delta)))
+
+	return strings.Join(parts, ", ")
+}
+
+// binaryAssetSummary describes changed images, fonts and other media with their metadata
+func binaryAssetSummary(f *FileDiff) *FileSummary {
+	format, ok := assetFormats[strings.ToLower(path.Ext(f.Path))]
+	if !ok || !f.Binary {
+		return nil
+	}
+
+	oldData, newData := readAssetVersions(f)
+	return &FileSummary{
+		Path:  f.Path,
+		Title: "Asset change",
+		Lines: []string{describeAsset(format, oldData, newData, f.New, f.Deleted)},
+	}
+}
+
+// imageThumbnail downscales an image to fit within maxSize and returns it as a JPEG data URL
+func imageThumbnail(data []byte, maxSize int) (string, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	scale := 1.0
+	if width > maxSize || height > maxSize {
+		scale = float64(maxSize) / float64(max(width, height))
+	}
+	thumbWidth := max(1, int(float64(width)*scale))
+	thumbHeight := max(1, int(float64(height)*scale))
+
+	// Nearest neighbour is plenty for giving a model an idea of the image
+	thumb := image.NewRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		for x := 0; x < thumbWidth; x++ {
+			thumb.Set(x, y, src.At(bounds.Min.X+int(float64(x)/scale), bounds.Min.Y+int(float64(y)/scale)))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+
+	return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// imageThumbnailParts builds before/after thumbnail message parts for changed images
+func imageThumbnailParts(files []*FileDiff) []ContentPart {
+	var parts []ContentPart
+	for _, f := range files {
+		if !f.Binary || !strings.HasSuffix(assetFormats[strings.ToLower(path.Ext(f.Path))], "image") {
+			continue
+		}
+
+		oldData, newData := readAssetVersions(f)
+		for _, version := range []struct {
+			label string
+			data  []byte
+		}{{"Before", oldData}, {"After", newData}} {
+			if len(version.data) == 0 {
+				continue
+			}
+			url, err := imageThumbnail(version.data, thumbnailSize)
+			if err != nil {
+				continue
+			}
+			parts = append(parts,
+				ContentPart{Type: "text", Text: fmt.Sprintf("%s: %s", version.label, f.Path)},
+				ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}},
+			)
+		}
+	}
+	return parts
+}
+
+// imageAttachmentPart reads an image file given with --attach and builds a message part for it
+func imageAttachmentPart(filePath string) (ContentPart, error) {
+	mimeType, ok := imageMIMETypes[strings.ToLower(filepath.Ext(filePath))]
+	if !ok {
+		return ContentPart{}, fmt.Errorf("unsupported attachment %s: only png, jpeg, gif and webp images are supported", filePath)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ContentPart{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	// Large screenshots are downscaled; formats the standard library can't decode are sent as is
+	url, err := imageThumbnail(data, attachmentSize)
+	if err != nil {
+		url = "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+	}
+
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}, nil
+}
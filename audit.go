@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// auditFileName is the append-only log of API calls, enabled with rmit set audit_log true
+const auditFileName = ".rmit_audit.jsonl"
+
+// Outcomes of an audited API call
+const (
+	auditOK          = "ok"
+	auditRateLimited = "rate_limited"
+	auditRejected    = "rejected"
+	auditError       = "error"
+)
+
+// AuditRecord is one API call in the audit log. It identifies the prompt by hash and never
+// contains the diff, the prompt or the response.
+type AuditRecord struct {
+	Time             time.Time `json:"time"`
+	Repo             string    `json:"repo,omitempty"`
+	APIURL           string    `json:"api_url"`
+	Model            string    `json:"model"`
+	PromptHash       string    `json:"prompt_hash"`
+	PromptBytes      int       `json:"prompt_bytes"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	SecretsDetected  int       `json:"secrets_detected"` // credentials sent in the prompt, e.g. with --no-redact
+	Status           int       `json:"status,omitempty"` // HTTP status, 0 if the provider couldn't be reached
+	Outcome          string    `json:"outcome"`
+	DurationMs       int64     `json:"duration_ms"`
+}
+
+// auditMu serializes appends from concurrent generations in rmit serve
+var auditMu sync.Mutex
+
+// auditPath returns the audit log location
+func auditPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, auditFileName), nil
+}
+
+// appendJSONLine appends a value as one JSON line to a file that is only ever appended to
+func appendJSONLine(path string, value any) error {
+	line, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// promptText returns the text of all messages in a request, unescaped, for secret detection
+func promptText(request OpenRouterRequest) string {
+	var texts []string
+	for _, message := range request.Messages {
+		switch content := message.Content.(type) {
+		case string:
+			texts = append(texts, content)
+		case []any:
+			for _, part := range content {
+				if part, ok := part.(map[string]any); ok {
+					if text, ok := part["text"].(string); ok {
+						texts = append(texts, text)
+					}
+				}
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// countSecrets counts credentials in a prompt using the redaction patterns. Redacted ones
+// aren't counted.
+func countSecrets(prompt string, known ...string) int {
+	_, count := redactText(prompt, secretPatterns, known)
+	return count
+}
+
+// auditOutcome classifies an API call by its HTTP status
+func auditOutcome(status int) string {
+	switch {
+	case status == 200:
+		return auditOK
+	case status == 429:
+		return auditRateLimited
+	case status == 401 || status == 402 || status == 403:
+		return auditRejected
+	}
+	return auditError
+}
+
+// auditAPICall records an API call when the audit log is enabled. Failing to write it is
+// reported but doesn't stop generation.
+func auditAPICall(config *Config, jsonBody []byte, status int, responseBody []byte, started time.Time) {
+	if !config.AuditLog || noPersist {
+		return
+	}
+
+	var request OpenRouterRequest
+	_ = json.Unmarshal(jsonBody, &request)
+	sum := sha256.Sum256(jsonBody)
+	record := AuditRecord{
+		Time:            started.UTC(),
+		APIURL:          chatURL(config),
+		Model:           request.Model,
+		PromptHash:      hex.EncodeToString(sum[:]),
+		PromptBytes:     len(jsonBody),
+		SecretsDetected: countSecrets(promptText(request), apiKeySecrets(config)...),
+		Status:          status,
+		Outcome:         auditOutcome(status),
+		DurationMs:      time.Since(started).Milliseconds(),
+	}
+	if repo, err := getRepoRoot(); err == nil {
+		record.Repo = repo
+	}
+	var response OpenRouterResponse
+	if json.Unmarshal(responseBody, &response) == nil && response.Usage != nil {
+		record.PromptTokens = response.Usage.prompt()
+		record.CompletionTokens = response.Usage.completion()
+	}
+
+	auditLog, err := auditPath()
+	if err == nil {
+		err = appendJSONLine(auditLog, record)
+	}
+	if err != nil {
+		log.Printf("Warning: couldn't write audit log: %v", err)
+	}
+}
+
+// readAuditLog loads the audit log, skipping lines that can't be parsed
+func readAuditLog() ([]AuditRecord, error) {
+	auditLog, err := auditPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(auditLog)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record AuditRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// parseSince parses --since as a duration ago ("24h", "7d") or a date ("2025-01-31")
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		value = days + "h"
+		if duration, err := time.ParseDuration(value); err == nil {
+			return now.Add(-duration * 24), nil
+		}
+	}
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q, expected e.g. 24h, 7d or 2025-01-31", value)
+}
+
+// newAuditCmd creates the audit command that queries the audit log
+func newAuditCmd() *cobra.Command {
+	var (
+		since   string
+		repo    string
+		model   string
+		outcome string
+		asJSON  bool
+	)
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Query the audit log of API calls",
+		Long: "Show API calls recorded in ~/" + auditFileName + " (enable it with rmit set audit_log true): when, from which repository, " +
+			"with which model, the prompt hash, token counts, secrets detected and the outcome. Diffs and messages are never logged.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			var after time.Time
+			if since != "" {
+				var err error
+				if after, err = parseSince(since, time.Now()); err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+			}
+
+			records, err := readAuditLog()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error reading audit log:"), err)
+			}
+
+			var matched []AuditRecord
+			for _, record := range records {
+				if record.Time.Before(after) ||
+					(repo != "" && !strings.Contains(record.Repo, repo)) ||
+					(model != "" && !strings.Contains(record.Model, model)) ||
+					(outcome != "" && record.Outcome != outcome) {
+					continue
+				}
+				matched = append(matched, record)
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				for _, record := range matched {
+					encoder.Encode(record)
+				}
+				return
+			}
+
+			fmt.Printf("%s\n", blue("📜 Audit log:"))
+			fmt.Print(rule())
+			promptTokens, completionTokens := 0, 0
+			for _, record := range matched {
+				result := green(record.Outcome)
+				if record.Outcome != auditOK {
+					result = red(fmt.Sprintf("%s (%d)", record.Outcome, record.Status))
+				}
+				fmt.Printf("%s %s %s %s\n", cyan(record.Time.Local().Format("2006-01-02 15:04:05")), result, blue(record.Model), record.Repo)
+				fmt.Printf("  %s %s  %s %d/%d", green("prompt:"), record.PromptHash[:16], green("tokens:"), record.PromptTokens, record.CompletionTokens)
+				if record.SecretsDetected > 0 {
+					fmt.Printf("  %s", yellow(fmt.Sprintf("%d secret(s) detected", record.SecretsDetected)))
+				}
+				fmt.Println()
+				promptTokens += record.PromptTokens
+				completionTokens += record.CompletionTokens
+			}
+			fmt.Print(rule())
+			fmt.Printf("%s %s\n", green("calls:"), cyan(len(matched)))
+			fmt.Printf("%s %s\n", green("tokens:"), cyan(fmt.Sprintf("%d prompt, %d completion", promptTokens, completionTokens)))
+		},
+	}
+
+	auditCmd.Flags().StringVar(&since, "since", "", "Only show calls since a time ago or a date, e.g. 24h, 7d or 2025-01-31")
+	auditCmd.Flags().StringVar(&repo, "repo", "", "Only show calls from repositories whose path contains this")
+	auditCmd.Flags().StringVarP(&model, "model", "m", "", "Only show calls to models whose name contains this")
+	auditCmd.Flags().StringVar(&outcome, "outcome", "", "Only show calls with this outcome: ok, rate_limited, rejected or error")
+	auditCmd.Flags().BoolVar(&asJSON, "json", false, "Print matching records as JSON lines")
+	// A default model for generating shouldn't silently filter the audit
+	auditCmd.Flags().SetAnnotation("model", noFlagDefaultAnnotation, []string{"true"})
+	return auditCmd
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diffs larger than this always ask before committing, however good the message looks
+const (
+	autoCommitMaxFiles = 5
+	autoCommitMaxLines = 150
+)
+
+// autoCommitRisks returns why a change needs a human look before committing. Large diffs,
+// leftovers, migrations, breaking API changes and binary or deleted files are all risky.
+func autoCommitRisks(files []*FileDiff, guardFindings []GuardFinding) []string {
+	var risks []string
+	if len(files) > autoCommitMaxFiles {
+		risks = append(risks, fmt.Sprintf("%d files changed (more than %d)", len(files), autoCommitMaxFiles))
+	}
+	lines := 0
+	for _, f := range files {
+		lines += len(f.Added) + len(f.Removed)
+		if f.Binary {
+			risks = append(risks, "binary file "+f.Path)
+		}
+		if f.Deleted {
+			risks = append(risks, "deleted file "+f.Path)
+		}
+	}
+	if lines > autoCommitMaxLines {
+		risks = append(risks, fmt.Sprintf("%d lines changed (more than %d)", lines, autoCommitMaxLines))
+	}
+	if len(guardFindings) > 0 {
+		risks = append(risks, fmt.Sprintf("%d leftover(s) found", len(guardFindings)))
+	}
+	if len(detectMigrations(files)) > 0 {
+		risks = append(risks, "database migration")
+	}
+	if repoFileExists("go.mod") {
+		for _, change := range detectAPIChanges(files) {
+			if change.Kind != "adds" {
+				risks = append(risks, "breaking change to the exported API")
+				break
+			}
+		}
+	}
+	return risks
+}
+
+// shouldAutoCommit decides whether a message is good enough, and the change safe enough, to
+// commit without asking. The reason explains the decision either way.
+func shouldAutoCommit(style, message string, files []*FileDiff, guardFindings []GuardFinding, threshold int) (bool, string) {
+	if risks := autoCommitRisks(files, guardFindings); len(risks) > 0 {
+		return false, "needs a look: " + strings.Join(risks, ", ")
+	}
+	score, notes := scoreMessage(style, message, files)
+	if score < threshold {
+		reason := fmt.Sprintf("message scored %d, below the threshold of %d", score, threshold)
+		if len(notes) > 0 {
+			reason += " (" + strings.Join(notes, ", ") + ")"
+		}
+		return false, reason
+	}
+	return true, fmt.Sprintf("message scored %d on a small, clean diff", score)
+}
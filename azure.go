@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// azureAPIVersion is the Azure OpenAI API version used when azure_api_version isn't set
+const azureAPIVersion = "2024-10-21"
+
+// errNoAzureEndpoint is returned when the azure provider is used without an endpoint
+var errNoAzureEndpoint = errors.New("no Azure OpenAI endpoint, set one with rmit set azure_endpoint https://<resource>.openai.azure.com")
+
+// azureProvider speaks the OpenAI chat completions format of an Azure OpenAI resource, where
+// the deployment is part of the URL and keys go in the api-key header
+type azureProvider struct{}
+
+// azureURL returns the chat completions endpoint of a deployment
+func azureURL(config *Config, deployment string) string {
+	if config.AzureEndpoint == "" {
+		return ""
+	}
+	return strings.TrimSuffix(config.AzureEndpoint, "/") + "/openai/deployments/" + url.PathEscape(deployment) +
+		"/chat/completions?api-version=" + url.QueryEscape(azureVersion(config))
+}
+
+// azureVersion returns the API version requests are sent with
+func azureVersion(config *Config) string {
+	if config.AzureAPIVersion != "" {
+		return config.AzureAPIVersion
+	}
+	return azureAPIVersion
+}
+
+// azureDeployment returns the deployment requests for a model go to: azure_deployment, or the
+// model name itself, since deployments are often named after their model
+func azureDeployment(config *Config, model string) string {
+	if config.AzureDeployment != "" {
+		return config.AzureDeployment
+	}
+	return model
+}
+
+// DefaultURL returns the endpoint of the deployment for the default model
+func (azureProvider) DefaultURL(config *Config) string {
+	return azureURL(config, azureDeployment(config, config.DefaultModel))
+}
+
+// NeedsKey reports that Azure OpenAI requires an API key
+func (azureProvider) NeedsKey() bool {
+	return true
+}
+
+// Generate sends a chat completion request to the deployment for the request's model, unless
+// api_url points somewhere else
+func (p azureProvider) Generate(ctx context.Context, config *Config, request ChatRequest) (string, error) {
+	turn, err := p.GenerateTurn(ctx, config, request)
+	return turn.Text, err
+}
+
+// GenerateTurn sends a chat completion request, with tools when the request has them, to the
+// deployment for the request's model
+func (p azureProvider) GenerateTurn(ctx context.Context, config *Config, request ChatRequest) (ChatTurn, error) {
+	if chatURL(config) == p.DefaultURL(config) {
+		if config.AzureEndpoint == "" {
+			return ChatTurn{}, errNoAzureEndpoint
+		}
+		deployed := *config
+		deployed.APIURL = azureURL(config, azureDeployment(config, request.Model))
+		config = &deployed
+	}
+	return chatCompletionTurn(ctx, config, request, func(req *http.Request, apiKey string) {
+		req.Header.Set("api-key", apiKey)
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Supported commit body styles
+const (
+	bodyStyleParagraph = "paragraph"
+	bodyStyleBullets   = "bullets"
+)
+
+// validateBodyStyle checks if the body style is supported
+func validateBodyStyle(style string) error {
+	switch style {
+	case bodyStyleParagraph, bodyStyleBullets:
+		return nil
+	}
+	return fmt.Errorf("unknown body style %q, valid styles are: %s, %s", style, bodyStyleParagraph, bodyStyleBullets)
+}
+
+// groupChangedFiles groups changed files by directory, in deterministic order
+func groupChangedFiles(files []*FileDiff) ([]string, map[string][]string) {
+	groups := make(map[string][]string)
+	for _, f := range files {
+		dir := path.Dir(f.Path)
+		if dir == "." {
+			dir = "(root)"
+		}
+		groups[dir] = append(groups[dir], path.Base(f.Path))
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+		sort.Strings(groups[name])
+	}
+	sort.Strings(names)
+
+	return names, groups
+}
+
+// bulletBodyInstructions asks the model for a subject and one summary per file group as JSON
+func bulletBodyInstructions(names []string, groups map[string][]string) string {
+	var instructions strings.Builder
+	instructions.WriteString("Respond only with a JSON object of the form " +
+		`{"subject": "<commit subject line>", "changes": {"<group>": "<one line summary of the change in that group>"}}` +
+		", with one entry in changes for each of these file groups:\n")
+	for _, name := range names {
+		fmt.Fprintf(&instructions, "- %s (%s)\n", name, strings.Join(groups[name], ", "))
+	}
+	instructions.WriteString("Summaries should start with a lowercase verb and must not repeat the subject.\n\n")
+	return instructions.String()
+}
+
+// assembleBulletMessage builds "subject + bullet list" from the model's JSON response,
+// ordering bullets by file group. It returns false if the response isn't the expected JSON.
+func assembleBulletMessage(response string, names []string) (string, bool) {
+	// Models like to wrap JSON in code fences
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+
+	var parsed struct {
+		Subject string            `json:"subject"`
+		Changes map[string]string `json:"changes"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(response)), &parsed); err != nil || parsed.Subject == "" {
+		return "", false
+	}
+
+	var message strings.Builder
+	message.WriteString(strings.TrimSpace(parsed.Subject))
+
+	seen := make(map[string]bool)
+	var bullets []string
+	for _, name := range names {
+		if summary := strings.TrimSpace(parsed.Changes[name]); summary != "" && !seen[summary] {
+			seen[summary] = true
+			bullets = append(bullets, "- "+summary)
+		}
+	}
+	if len(bullets) > 0 {
+		message.WriteString("\n\n" + strings.Join(bullets, "\n"))
+	}
+
+	return message.String(), true
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Default identity used for bot commits when the runner has no git identity configured
+const (
+	defaultBotName  = "github-actions[bot]"
+	defaultBotEmail = "41898282+github-actions[bot]@users.noreply.github.com"
+)
+
+// inGitHubActions reports whether rmit is running inside a GitHub Actions job
+func inGitHubActions() bool {
+	return os.Getenv("GITHUB_ACTIONS") == "true"
+}
+
+// escapeAnnotation escapes a value for use in a GitHub Actions workflow command
+func escapeAnnotation(value string) string {
+	value = strings.ReplaceAll(value, "%", "%25")
+	value = strings.ReplaceAll(value, "\r", "%0D")
+	value = strings.ReplaceAll(value, "\n", "%0A")
+	return value
+}
+
+// botError reports an error as a GitHub Actions annotation (or plain stderr outside Actions) and exits
+func botError(title string, err error) {
+	if inGitHubActions() {
+		fmt.Printf("::error title=rmit::%s\n", escapeAnnotation(fmt.Sprintf("%s %v", title, err)))
+	} else {
+		fmt.Fprintf(os.Stderr, "%s %v\n", title, err)
+	}
+	os.Exit(1)
+}
+
+// botNotice reports an informational message as a GitHub Actions annotation
+func botNotice(message string) {
+	if inGitHubActions() {
+		fmt.Printf("::notice title=rmit::%s\n", escapeAnnotation(message))
+	} else {
+		fmt.Println(message)
+	}
+}
+
+// botWarning reports a problem as a GitHub Actions annotation
+func botWarning(message string) {
+	if inGitHubActions() {
+		fmt.Printf("::warning title=rmit::%s\n", escapeAnnotation(message))
+	} else {
+		fmt.Fprintln(os.Stderr, message)
+	}
+}
+
+// setBotOutput writes a step output when running in GitHub Actions
+func setBotOutput(name, value string) error {
+	outputPath := os.Getenv("GITHUB_OUTPUT")
+	if outputPath == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(outputPath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open GITHUB_OUTPUT: %w", err)
+	}
+	defer f.Close()
+
+	// Multiline values use the heredoc syntax
+	_, err = fmt.Fprintf(f, "%s<<RMIT_EOF\n%s\nRMIT_EOF\n", name, value)
+	return err
+}
+
+// runGit runs a git command, forwarding its output to stderr so stdout stays clean
+func runGit(env []string, args ...string) error {
+	cmd := gitCommandEnv(env, args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// botIdentityEnv returns author/committer env vars when git has no identity configured
+func botIdentityEnv() []string {
+	if out, err := gitCommand("config", "user.email").Output(); err == nil && strings.TrimSpace(string(out)) != "" {
+		return nil
+	}
+
+	name := os.Getenv("RMIT_BOT_NAME")
+	if name == "" {
+		name = defaultBotName
+	}
+	email := os.Getenv("RMIT_BOT_EMAIL")
+	if email == "" {
+		email = defaultBotEmail
+	}
+
+	return []string{
+		"GIT_AUTHOR_NAME=" + name,
+		"GIT_AUTHOR_EMAIL=" + email,
+		"GIT_COMMITTER_NAME=" + name,
+		"GIT_COMMITTER_EMAIL=" + email,
+	}
+}
+
+// newBotCmd creates the non-interactive bot command for CI and automation
+func newBotCmd() *cobra.Command {
+	var (
+		commit   bool
+		push     bool
+		remote   string
+		model    string
+		trailers []string
+		status   bool
+		revs     string
+	)
+
+	botCmd := &cobra.Command{
+		Use:   "bot",
+		Short: "Non-interactive mode for CI and automation",
+		Long: "Generate a commit message without a TTY, configured through environment variables " +
+			"(RMIT_API_KEY or OPENROUTER_API_KEY, RMIT_API_URL, RMIT_MODEL). Only the message is printed to stdout. " +
+			"Errors are reported as GitHub Actions annotations when running in Actions. " +
+			"With --status it instead checks the messages of pushed commits, like rmit check, and posts the verdict " +
+			"as a commit status using GITHUB_TOKEN.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if push && !commit {
+				botError("Invalid flags:", errors.New("--push requires --commit"))
+			}
+			if status && commit {
+				botError("Invalid flags:", errors.New("--status can't be combined with --commit"))
+			}
+
+			config, err := loadConfig()
+			if err != nil {
+				botError("Error loading configuration:", err)
+			}
+
+			// Checking pushed commits needs no model, only the GitHub token
+			if status {
+				if revs == "" {
+					if revs, err = eventRange(); err != nil {
+						botError("Error finding pushed commits:", err)
+					}
+				}
+				failed, err := postMessageStatuses(config, revs)
+				if err != nil {
+					botError("Error posting commit statuses:", err)
+				}
+				if err := setBotOutput("violations", fmt.Sprint(failed)); err != nil {
+					botError("Error writing step output:", err)
+				}
+				if failed == 0 {
+					botNotice("All commit messages follow the convention")
+				}
+				return
+			}
+			if err := validateAPIKeyPool(config); err != nil {
+				botError("Invalid API key:", err)
+			}
+
+			// Bots usually commit generated files, which may be untracked
+			if commit {
+				if err := runGit(nil, "add", "-A"); err != nil {
+					botError("Error staging changes:", err)
+				}
+			}
+
+			diff, err := getGitDiff()
+			if errors.Is(err, errNoChanges) {
+				botNotice("No changes to commit")
+				if err := setBotOutput("committed", "false"); err != nil {
+					botError("Error writing step output:", err)
+				}
+				return
+			}
+			if err != nil {
+				botError("Error getting git diff:", err)
+			}
+
+			commitTrailers, err := collectTrailers(config, trailers)
+			if err != nil {
+				botError("Invalid trailer:", err)
+			}
+			if missing := missingTrailers(commitTrailers, config.RequiredTrailers); len(missing) > 0 {
+				botError("Missing required trailers:", fmt.Errorf("%s (pass them with --trailer)", strings.Join(missing, ", ")))
+			}
+
+			message, err := suggestCommitMessage(config, diff, GenerateOptions{Model: model, Trailers: commitTrailers})
+			if err != nil {
+				botError("Error generating commit message:", err)
+			}
+			fmt.Println(message)
+			if err := setBotOutput("message", message); err != nil {
+				botError("Error writing step output:", err)
+			}
+
+			if !commit {
+				return
+			}
+
+			if err := runGit(botIdentityEnv(), "commit", "-m", message); err != nil {
+				botError("Error creating commit:", err)
+			}
+			if err := setBotOutput("committed", "true"); err != nil {
+				botError("Error writing step output:", err)
+			}
+
+			if push {
+				if err := runGit(nil, "push", remote, "HEAD"); err != nil {
+					botError("Error pushing commit:", err)
+				}
+			}
+		},
+	}
+
+	botCmd.Flags().BoolVar(&commit, "commit", false, "Stage all changes and create a commit with the generated message")
+	botCmd.Flags().BoolVar(&push, "push", false, "Push the commit to the remote (requires --commit)")
+	botCmd.Flags().StringVar(&remote, "remote", "origin", "Remote to push to")
+	botCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation (overrides RMIT_MODEL and default_model)")
+	botCmd.Flags().StringArrayVar(&trailers, "trailer", nil, "Add a trailer such as \"Refs: #42\" (repeatable)")
+	botCmd.Flags().BoolVar(&status, "status", false, "Check the messages of pushed commits and post the verdict as a GitHub commit status instead of generating")
+	botCmd.Flags().StringVar(&revs, "status-range", "", "Commits to check with --status, e.g. origin/main..HEAD (defaults to the commits of the triggering push or pull request)")
+
+	return botCmd
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Project context cache, kept in the repository's git directory so it is never committed. Only
+// the project description is cached: scope_map comes from the configuration, which is read on
+// every run anyway, and rmit doesn't derive scopes or style examples from history.
+const (
+	projectCacheGitPath = "rmit-cache"
+	projectCacheFile    = "project.json"
+	projectCacheVersion = 1 // bump when the description format changes
+	projectCacheMaxAge  = 24 * time.Hour
+)
+
+// ManifestStamp is how a manifest looked when the project context was cached
+type ManifestStamp struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ProjectCache is the cached project context of a repository
+type ProjectCache struct {
+	Version     int                      `json:"version"`
+	Created     time.Time                `json:"created"`
+	Fingerprint string                   `json:"fingerprint"` // the manifests in the index when the repository was scanned
+	Manifests   map[string]ManifestStamp `json:"manifests"`
+	Info        string                   `json:"info"`
+}
+
+// projectCachePath returns where the project context is cached
+func projectCachePath() (string, error) {
+	out, err := gitCommand("rev-parse", "--git-path", projectCacheGitPath).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	dir, err := filepath.Abs(strings.TrimSpace(string(out)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, projectCacheFile), nil
+}
+
+// manifestFingerprint returns the staged manifests of the repository with their blob hashes.
+// It only reads the index, so it is cheap even in huge repositories, and it changes whenever a
+// manifest is added, removed or edited and staged.
+func manifestFingerprint(root string) (string, error) {
+	args := []string{"-C", root, "ls-files", "-s", "--"}
+	for name := range projectManifests {
+		args = append(args, ":(glob)**/"+name)
+	}
+	out, err := gitCommand(args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list manifests: %w", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// manifestStamp returns a manifest's size and modification time
+func manifestStamp(root, file string) (ManifestStamp, bool) {
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return ManifestStamp{}, false
+	}
+	return ManifestStamp{Size: info.Size(), ModTime: info.ModTime()}, true
+}
+
+// cachedProjectInfo returns the cached project context if it still describes the repository: no
+// manifest was added, removed or edited since it was scanned. Language shares are allowed to
+// drift for a day.
+func cachedProjectInfo(root string) (string, bool) {
+	fingerprint, err := manifestFingerprint(root)
+	if err != nil {
+		return "", false
+	}
+	cachePath, err := projectCachePath()
+	if err != nil {
+		return "", false
+	}
+	content, err := os.ReadFile(cachePath)
+	if err != nil {
+		return "", false
+	}
+	var cache ProjectCache
+	if json.Unmarshal(content, &cache) != nil || cache.Version != projectCacheVersion ||
+		cache.Fingerprint != fingerprint || time.Since(cache.Created) > projectCacheMaxAge {
+		return "", false
+	}
+	// Manifests edited on disk but not staged yet don't change the fingerprint
+	for file, stamp := range cache.Manifests {
+		current, ok := manifestStamp(root, file)
+		if !ok || current.Size != stamp.Size || !current.ModTime.Equal(stamp.ModTime) {
+			return "", false
+		}
+	}
+	return cache.Info, true
+}
+
+// saveProjectInfo caches the project context for the next run. Failing to cache isn't an
+// error, the repository is scanned again next time.
+func saveProjectInfo(root, info string, manifests []string) {
+	if noPersist {
+		return
+	}
+	fingerprint, err := manifestFingerprint(root)
+	if err != nil {
+		return
+	}
+	cache := ProjectCache{Version: projectCacheVersion, Created: time.Now(), Fingerprint: fingerprint, Manifests: make(map[string]ManifestStamp), Info: info}
+	for _, file := range manifests {
+		if stamp, ok := manifestStamp(root, file); ok {
+			cache.Manifests[file] = stamp
+		}
+	}
+
+	cachePath, err := projectCachePath()
+	if err != nil {
+		return
+	}
+	content, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return
+	}
+	// Written to a temporary file and renamed, so concurrent runs never read half a cache
+	tmp := cachePath + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, cachePath); err != nil {
+		os.Remove(tmp)
+	}
+}
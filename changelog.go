@@ -0,0 +1,339 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// changelogTrailerKey is the trailer that files a commit under a changelog section
+const changelogTrailerKey = "Changelog"
+
+// changelogSkip is the Changelog trailer value of commits left out of the changelog
+const changelogSkip = "skip"
+
+// changelogSections are the Keep a Changelog sections, in the order they are written
+var changelogSections = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// typeSections are the sections commits without a Changelog trailer are filed under by their
+// conventional type. Other types, like chore, docs or test, are left out.
+var typeSections = map[string]string{
+	"feat":     "Added",
+	"fix":      "Fixed",
+	"perf":     "Changed",
+	"refactor": "Changed",
+	"revert":   "Removed",
+}
+
+// canonicalChangelogSection normalizes a section name given on the command line, e.g. "fixed" to "Fixed"
+func canonicalChangelogSection(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, changelogSkip) {
+		return changelogSkip, nil
+	}
+	for _, section := range changelogSections {
+		if strings.EqualFold(value, section) {
+			return section, nil
+		}
+	}
+	return "", fmt.Errorf("unknown changelog section %q, valid sections are: %s and %s", value, strings.Join(changelogSections, ", "), changelogSkip)
+}
+
+// changelogSection returns the section a commit belongs in: the one its Changelog trailer
+// names, or else the one its type implies. Commits that belong in none return "".
+func changelogSection(message string) string {
+	for _, t := range messageTrailers(message) {
+		if t.Key != changelogTrailerKey {
+			continue
+		}
+		if section, err := canonicalChangelogSection(t.Value); err == nil && section != changelogSkip {
+			return section
+		}
+		return ""
+	}
+	return typeSections[strings.ToLower(commitType(message))]
+}
+
+// changelogEntry turns a commit subject into a changelog line, keeping the scope
+func changelogEntry(subject string) string {
+	match := conventionalSubjectPattern.FindStringSubmatch(subject)
+	if match == nil {
+		return subject
+	}
+	entry := match[4]
+	if scope := strings.Trim(match[2], "()"); scope != "" {
+		entry = fmt.Sprintf("**%s:** %s", scope, entry)
+	}
+	if match[3] == "!" {
+		entry += " (breaking)"
+	}
+	return entry
+}
+
+// changelogFile is the changelog --write prepends to by default
+const changelogFile = "CHANGELOG.md"
+
+// changelogHeader starts a new changelog file
+const changelogHeader = "# Changelog\n\nAll notable changes to this project will be documented in this file.\n\n" +
+	"The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).\n"
+
+// changelogCommitLimit caps the characters of a commit message sent to the model when filing it
+const changelogCommitLimit = 2000
+
+// unfiledCommit is a commit that has neither a Changelog trailer nor a conventional type, and so
+// can only be filed by the model
+type unfiledCommit struct {
+	Message string
+}
+
+// ChangelogFiling is where the model files a commit and how it words the entry
+type ChangelogFiling struct {
+	Commit  int    `json:"commit"`
+	Section string `json:"section"`
+	Entry   string `json:"entry"`
+}
+
+// fileCommits asks the model to file commits under changelog sections, or skip them, and to
+// word an entry for each
+func fileCommits(config *Config, model string, commits []unfiledCommit) ([]ChangelogFiling, error) {
+	var texts []string
+	for i, commit := range commits {
+		message := strings.TrimSpace(commit.Message)
+		if len(message) > changelogCommitLimit {
+			message = message[:changelogCommitLimit] + "\n[truncated]"
+		}
+		texts = append(texts, fmt.Sprintf("Commit %d:\n%s", i+1, message))
+	}
+	prompt := "File each of these commits under a Keep a Changelog section, one of " + strings.Join(changelogSections, ", ") +
+		", or \"" + changelogSkip + "\" when users wouldn't care, e.g. for refactoring, tests, CI or documentation. " +
+		"For the others, write a short changelog entry saying what changed for users, without a trailing period. " +
+		"Respond only with a JSON object of the form " + `{"commits": [{"commit": 1, "section": "Added", "entry": "<entry>"}]}` +
+		".\n\n" + strings.Join(texts, "\n\n")
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var filed struct {
+		Commits []ChangelogFiling `json:"commits"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &filed); err != nil {
+		return nil, fmt.Errorf("the model didn't return changelog sections")
+	}
+	return filed.Commits, nil
+}
+
+// renderChangelog writes the commits of a range as Keep a Changelog sections. With a config,
+// commits without a Changelog trailer or conventional type are filed by the model instead of
+// being left out.
+func renderChangelog(config *Config, model, revisions string) (string, error) {
+	out, err := gitOutput("rev-list", "--no-merges", "--reverse", revisions, "--")
+	if err != nil || out == "" {
+		return "", err
+	}
+
+	entries := make(map[string][]string)
+	var unfiled []unfiledCommit
+	for _, commit := range strings.Split(out, "\n") {
+		message, err := gitOutput("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return "", err
+		}
+		if section := changelogSection(message); section != "" {
+			subject, _, _ := strings.Cut(message, "\n")
+			entries[section] = append(entries[section], changelogEntry(subject))
+		} else if config != nil && commitType(message) == "" && !hasChangelogTrailer(message) {
+			unfiled = append(unfiled, unfiledCommit{Message: message})
+		}
+	}
+
+	if len(unfiled) > 0 {
+		ui.Info(fmt.Sprintf("📝 Filing %d commit(s) without a conventional type...", len(unfiled)))
+		filings, err := fileCommits(config, model, unfiled)
+		if err != nil {
+			return "", err
+		}
+		// Entries the model files are added after the conventional ones of their section
+		for _, filing := range filings {
+			section, err := canonicalChangelogSection(filing.Section)
+			if err != nil || section == changelogSkip || filing.Commit < 1 || filing.Commit > len(unfiled) || strings.TrimSpace(filing.Entry) == "" {
+				continue
+			}
+			entries[section] = append(entries[section], strings.TrimSpace(filing.Entry))
+		}
+	}
+
+	var changelog strings.Builder
+	for _, section := range changelogSections {
+		if len(entries[section]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&changelog, "### %s\n\n", section)
+		for _, entry := range entries[section] {
+			fmt.Fprintf(&changelog, "- %s\n", entry)
+		}
+		changelog.WriteString("\n")
+	}
+	return strings.TrimSpace(changelog.String()), nil
+}
+
+// hasChangelogTrailer reports whether a commit was filed, or skipped, with a Changelog trailer
+func hasChangelogTrailer(message string) bool {
+	for _, t := range messageTrailers(message) {
+		if t.Key == changelogTrailerKey {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseName names the release of a range: the tag it ends at, else Unreleased
+func releaseName(revisions string) string {
+	end := revisions
+	if _, after, ok := strings.Cut(revisions, ".."); ok {
+		end = after
+	}
+	if end == "" {
+		end = "HEAD"
+	}
+	if tag, err := gitOutput("describe", "--tags", "--exact-match", end); err == nil && tag != "" {
+		return tag
+	}
+	return "Unreleased"
+}
+
+// releaseHeading is the Keep a Changelog heading of a release, dated unless it's unreleased
+func releaseHeading(release string) string {
+	if release == "Unreleased" {
+		return "## [Unreleased]"
+	}
+	return fmt.Sprintf("## [%s] - %s", strings.TrimPrefix(release, "v"), time.Now().Format("2006-01-02"))
+}
+
+// prependChangelog writes a release's sections into a changelog file above the previous
+// releases, creating the file if needed. A release that is already in the file is replaced.
+func prependChangelog(path, release, sections string) error {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		content = []byte(changelogHeader)
+	} else if err != nil {
+		return err
+	}
+
+	heading := releaseHeading(release)
+	marker := heading
+	if i := strings.Index(marker, "] "); i != -1 {
+		marker = marker[:i+1] // the date may differ
+	}
+	section := heading + "\n\n" + sections + "\n\n"
+	lines := strings.SplitAfter(string(content), "\n")
+
+	// The release replaces itself, else goes above the first release, below Unreleased
+	start, end := -1, len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, marker) {
+			start = i
+			for j := i + 1; j < len(lines); j++ {
+				if strings.HasPrefix(lines[j], "## ") {
+					end = j
+					break
+				}
+			}
+			break
+		}
+	}
+	if start == -1 {
+		start = len(lines)
+		for i, line := range lines {
+			if strings.HasPrefix(line, "## ") && (release == "Unreleased" || !strings.HasPrefix(line, "## [Unreleased]")) {
+				start = i
+				break
+			}
+		}
+		end = start
+		if start == len(lines) && !strings.HasSuffix(string(content), "\n\n") {
+			section = "\n" + section
+		}
+	}
+	updated := strings.Join(lines[:start], "") + section + strings.Join(lines[end:], "")
+	return os.WriteFile(path, []byte(strings.TrimRight(updated, "\n")+"\n"), 0644)
+}
+
+// newChangelogCmd creates the changelog command that groups commits into changelog sections
+func newChangelogCmd() *cobra.Command {
+	var (
+		ai      bool
+		model   string
+		write   bool
+		file    string
+		release string
+	)
+
+	changelogCmd := &cobra.Command{
+		Use:   "changelog [range]",
+		Short: "Write a changelog of the commits since the last tag",
+		Long: "Group the commits of a range into Keep a Changelog sections (" + strings.Join(changelogSections, ", ") + ") and print them as Markdown. " +
+			"Commits are filed under the section their " + changelogTrailerKey + " trailer names, set with rmit --changelog when committing, " +
+			"and otherwise under the section their conventional type implies. With --ai, the model files the commits that have neither. " +
+			"The range defaults to the commits since the last tag. With --write, the release is prepended to " + changelogFile + " instead.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			revisions := "HEAD"
+			if len(args) == 1 {
+				revisions = args[0]
+			} else if tag, err := gitOutput("describe", "--tags", "--abbrev=0"); err == nil {
+				revisions = tag + "..HEAD"
+			}
+
+			var config *Config
+			if ai {
+				loaded, err := loadConfig()
+				if err != nil {
+					log.Fatalf("%s %v", red("Error loading configuration:"), err)
+				}
+				config = loaded
+			}
+			changelog, err := renderChangelog(config, model, revisions)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error writing changelog:"), err)
+			}
+			if changelog == "" {
+				ui.Success("✅ No changes for the changelog")
+				return
+			}
+			if !write && file == "" {
+				fmt.Println(changelog)
+				return
+			}
+
+			if file == "" {
+				root, err := getRepoRoot()
+				if err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+				file = filepath.Join(root, changelogFile)
+			}
+			if release == "" {
+				release = releaseName(revisions)
+			}
+			if err := prependChangelog(file, release, changelog); err != nil {
+				log.Fatalf("%s %v", red("Error writing changelog:"), err)
+			}
+			ui.Success(fmt.Sprintf("✅ Wrote %s to %s", release, file))
+		},
+	}
+
+	changelogCmd.Flags().BoolVar(&ai, "ai", false, "Let the model file commits without a Changelog trailer or conventional type, instead of leaving them out")
+	changelogCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use with --ai (overrides default_model from config)")
+	changelogCmd.Flags().BoolVarP(&write, "write", "w", false, "Prepend the release to "+changelogFile+" in the repository root instead of printing it")
+	changelogCmd.Flags().StringVarP(&file, "file", "f", "", "Prepend the release to this changelog file instead of printing it")
+	changelogCmd.Flags().StringVar(&release, "release", "", "Name of the release in the changelog (default: the tag the range ends at, else Unreleased)")
+	return changelogCmd
+}
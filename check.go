@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// CommitViolation is an existing commit whose message breaks the configured convention
+type CommitViolation struct {
+	Commit   string
+	Subject  string
+	Problems []string
+}
+
+// messageTrailers returns the trailers in the last paragraph of a message
+func messageTrailers(message string) []Trailer {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) < 2 || !isTrailerBlock(last) {
+		return nil
+	}
+	var trailers []Trailer
+	for _, line := range strings.Split(last, "\n") {
+		if t, err := parseTrailer(line); err == nil {
+			trailers = append(trailers, t)
+		}
+	}
+	return trailers
+}
+
+// checkMessage returns how a commit message breaks the convention rmit generates messages by:
+// what --max-retries-on-invalid rejects under the commit style, plus the configured subject
+// prefix and suffix and required trailers
+func checkMessage(config *Config, templates map[string][]string, message string) []string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if subject == offlinePlaceholder {
+		return []string{"placeholder message, run rmit flush"}
+	}
+
+	trailers := messageTrailers(message)
+	affixes := subjectAffixes(config, trailers)
+	problems := messageProblems(commitStyle(config), affixes.strip(message), templates)
+	if prefix := strings.TrimSpace(affixes.Prefix); prefix != "" && !strings.HasPrefix(subject, prefix) {
+		problems = append(problems, fmt.Sprintf("subject doesn't start with %q", prefix))
+	}
+	if suffix := strings.TrimSpace(affixes.Suffix); suffix != "" && !strings.HasSuffix(subject, suffix) {
+		problems = append(problems, fmt.Sprintf("subject doesn't end with %q", suffix))
+	}
+	if missing := missingTrailers(trailers, config.RequiredTrailers); len(missing) > 0 {
+		problems = append(problems, "missing trailers "+strings.Join(missing, ", "))
+	}
+	return problems
+}
+
+// rangeCommits lists the commits to check, oldest first. A range like origin/main..HEAD checks
+// every commit in it and a single revision only that commit.
+func rangeCommits(revisions string) ([]string, error) {
+	args := []string{revisions}
+	if !strings.Contains(revisions, "..") {
+		args = append(args, "--no-walk")
+	}
+	return listCommits(args...)
+}
+
+// listCommits lists the commits rev-list selects with args, oldest first. Merges, and fixup!
+// and squash! commits that are meant to be squashed away, are skipped.
+func listCommits(args ...string) ([]string, error) {
+	out, err := gitOutput(append(append([]string{"rev-list", "--no-merges", "--reverse"}, args...), "--")...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+
+	var commits []string
+	for _, commit := range strings.Split(out, "\n") {
+		subject, err := gitOutput("log", "-1", "--format=%s", commit)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(subject, "fixup! ") && !strings.HasPrefix(subject, "squash! ") {
+			commits = append(commits, commit)
+		}
+	}
+	return commits, nil
+}
+
+// checkCommits checks the message of every commit in a range
+func checkCommits(config *Config, revisions string) (int, []CommitViolation, error) {
+	commits, err := rangeCommits(revisions)
+	if err != nil {
+		return 0, nil, err
+	}
+	violations, err := checkCommitList(config, commits)
+	return len(commits), violations, err
+}
+
+// checkCommitList checks the message of each commit
+func checkCommitList(config *Config, commits []string) ([]CommitViolation, error) {
+	repoConfig, err := loadRepoConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []CommitViolation
+	for _, commit := range commits {
+		message, err := gitOutput("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return nil, err
+		}
+		if problems := checkMessage(config, repoConfig.Templates, message); len(problems) > 0 {
+			subject, _, _ := strings.Cut(message, "\n")
+			violations = append(violations, CommitViolation{Commit: commit, Subject: subject, Problems: problems})
+		}
+	}
+	return violations, nil
+}
+
+// suggestRewrite generates a message for a commit's changes, keeping the trailers it already has
+func suggestRewrite(config *Config, model, commit string) (string, error) {
+	diff, err := gitOutput("show", "--format=", "--no-color", commit)
+	if err != nil {
+		return "", err
+	}
+	message, err := gitOutput("log", "-1", "--format=%B", commit)
+	if err != nil {
+		return "", err
+	}
+	// The file list comes from the commit, not from whatever is changed in the working tree
+	files := []string{}
+	for _, f := range parseDiff(diff) {
+		files = append(files, f.Path)
+	}
+	return suggestCommitMessage(config, diff, GenerateOptions{Model: model, Trailers: messageTrailers(message), Files: files})
+}
+
+// newCheckCmd creates the check command that validates the messages of existing commits
+func newCheckCmd() *cobra.Command {
+	var (
+		model      string
+		noRewrites bool
+	)
+
+	checkCmd := &cobra.Command{
+		Use:   "check [range]",
+		Short: "Check existing commit messages against the configured convention",
+		Long: "Check the messages of existing commits the way rmit checks its own: the commit_style format, a specific subject " +
+			"of at most 72 characters, template sections, the subject prefix and suffix, and required trailers. " +
+			"Each violation comes with a suggested rewrite generated from the commit's changes. " +
+			"The range defaults to the commits not pushed yet (@{upstream}..HEAD); a single revision checks only that commit. " +
+			"Exits with status 1 when a message breaks the convention, e.g. for a pre-push hook.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			revisions := "@{upstream}..HEAD"
+			if len(args) == 1 {
+				revisions = args[0]
+			} else if _, err := gitOutput("rev-parse", "--verify", "-q", "@{upstream}"); err != nil {
+				log.Fatalf("%s the branch has no upstream, pass a range like origin/main..HEAD", red("Error:"))
+			}
+
+			checked, violations, err := checkCommits(config, revisions)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error checking commits:"), err)
+			}
+			if checked == 0 {
+				ui.Success("✅ No commits to check")
+				return
+			}
+
+			for _, violation := range violations {
+				lines := []string{fmt.Sprintf("\n✗ %s %s", violation.Commit[:12], violation.Subject)}
+				for _, problem := range violation.Problems {
+					lines = append(lines, "  - "+problem)
+				}
+				ui.Warn(strings.Join(lines, "\n"))
+				if noRewrites {
+					continue
+				}
+				suggestion, err := suggestRewrite(config, model, violation.Commit)
+				if err != nil {
+					log.Printf("Warning: couldn't suggest a rewrite for %s: %v", violation.Commit[:12], err)
+					continue
+				}
+				ui.Panel("💡 SUGGESTED MESSAGE:", suggestion)
+			}
+
+			if len(violations) > 0 {
+				ui.Error(fmt.Sprintf("\n❌ %d of %d commit(s) break the convention", len(violations), checked))
+				os.Exit(1)
+			}
+			ui.Success(fmt.Sprintf("✅ All %d commit(s) follow the convention", checked))
+		},
+	}
+
+	checkCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for suggested rewrites (overrides default_model from config)")
+	checkCmd.Flags().BoolVar(&noRewrites, "no-rewrites", false, "Only list violations, without asking the model for rewrites")
+	return checkCmd
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+)
+
+// CommitChunk is one commit of a chunked changeset
+type CommitChunk struct {
+	Name  string
+	Files []*FileDiff
+}
+
+// paths returns the paths to commit, including the old paths of renamed files
+func (c *CommitChunk) paths() []string {
+	var paths []string
+	for _, f := range c.Files {
+		if f.OldPath != "" && f.OldPath != f.Path {
+			paths = append(paths, f.OldPath)
+		}
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// topLevelDir returns the first path component of a directory, "(root)" for the top level
+func topLevelDir(dir string) string {
+	if dir == "." || dir == "" {
+		return "(root)"
+	}
+	top, _, _ := strings.Cut(dir, "/")
+	return top
+}
+
+// planChunks splits changed files into commits of at most max files. Files are grouped by
+// directory; directories under the same top-level directory share a commit while they fit, and
+// directories with more files than fit are split.
+func planChunks(files []*FileDiff, max int) []*CommitChunk {
+	byDir := make(map[string][]*FileDiff)
+	for _, f := range files {
+		dir := path.Dir(f.Path)
+		byDir[dir] = append(byDir[dir], f)
+	}
+	dirs := make([]string, 0, len(byDir))
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+		sort.Slice(byDir[dir], func(i, j int) bool { return byDir[dir][i].Path < byDir[dir][j].Path })
+	}
+	sort.Strings(dirs)
+
+	var chunks []*CommitChunk
+	var current *CommitChunk
+	for _, dir := range dirs {
+		dirFiles := byDir[dir]
+		for len(dirFiles) > 0 {
+			// Start a new commit for another feature area or when this one is full
+			if current == nil || topLevelDir(dir) != current.Name || len(current.Files) == max {
+				current = &CommitChunk{Name: topLevelDir(dir)}
+				chunks = append(chunks, current)
+			}
+			n := min(max-len(current.Files), len(dirFiles))
+			if n < len(dirFiles) && len(current.Files) > 0 {
+				// Keep a directory together where it fits in a commit of its own
+				if len(dirFiles) <= max {
+					current = nil
+					continue
+				}
+			}
+			current.Files = append(current.Files, dirFiles[:n]...)
+			dirFiles = dirFiles[n:]
+		}
+	}
+
+	// Name chunks after the directories they cover, which is more specific than the top level
+	for _, chunk := range chunks {
+		seen := make(map[string]bool)
+		var names []string
+		for _, f := range chunk.Files {
+			dir := path.Dir(f.Path)
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			if dir == "." {
+				dir = "(root)"
+			}
+			names = append(names, dir)
+		}
+		if len(names) <= 3 {
+			chunk.Name = strings.Join(names, ", ")
+		}
+	}
+	return chunks
+}
+
+// printChunkPlan shows the commits a chunked changeset will be split into
+func printChunkPlan(chunks []*CommitChunk) {
+	var plan []string
+	for i, chunk := range chunks {
+		plan = append(plan, fmt.Sprintf("  %s %s %s", cyan(fmt.Sprintf("%2d.", i+1)), chunk.Name, yellow(fmt.Sprintf("(%d file(s))", len(chunk.Files)))))
+		for _, f := range chunk.Files {
+			plan = append(plan, "      "+f.Path)
+		}
+	}
+	ui.Panel(fmt.Sprintf("📦 COMMIT PLAN (%d commits):", len(chunks)), strings.Join(plan, "\n"))
+}
+
+// runChunkedCommits generates a message for each chunk and commits it. Each chunk is
+// described from its own diff against the commit made before it. Chunks are committed as they
+// are staged, or as they are in the working tree with stageAll.
+func runChunkedCommits(config *Config, opts GenerateOptions, chunks []*CommitChunk, transcriptMode string, stageAll bool) {
+	for i, chunk := range chunks {
+		ui.Info(fmt.Sprintf("\nGenerating commit message %d/%d (%s)...", i+1, len(chunks), chunk.Name))
+
+		var diff string
+		var err error
+		if stageAll {
+			diff, err = describedDiff(chunk.paths(), nil)
+		} else {
+			diff, err = stagedDiff(chunk.paths()...)
+		}
+		if err != nil {
+			log.Fatalf("%s %v", red("Error getting git diff:"), err)
+		}
+
+		chunkOpts := opts
+		if opts.Transcript != nil {
+			chunkOpts.Transcript = &Transcript{}
+		}
+		message, local := localCommitMessage(diff)
+		if local {
+			message = appendTrailers(message, opts.Trailers)
+		} else if message, err = generateCommitMessage(config, diff, chunkOpts); err != nil {
+			log.Fatalf("%s %v (%d of %d commits made)", red("Error generating commit message:"), err, i, len(chunks))
+		}
+
+		ui.Panel(fmt.Sprintf("✨ COMMIT %d/%d:", i+1, len(chunks)), message)
+
+		if err := makeAttestedCommit(config, message, chunkOpts.Transcript, chunk.paths(), !stageAll); err != nil {
+			log.Fatalf("%s %v (%d of %d commits made)", red("Error creating commit:"), err, i, len(chunks))
+		}
+		printTranscriptSaved(chunkOpts.Transcript, transcriptMode, apiKeySecrets(config))
+	}
+	ui.Success(fmt.Sprintf("✅ %d commits created successfully", len(chunks)))
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+)
+
+// Request bodies smaller than this aren't worth compressing
+const compressThreshold = 32 * 1024
+
+// gzipBody compresses a request body
+func gzipBody(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to compress request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress request: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// shouldCompress reports whether a request body should be sent gzip encoded
+func shouldCompress(config *Config, body []byte) bool {
+	return config.CompressRequests && len(body) >= compressThreshold
+}
+
+// rejectsCompression reports whether the provider refused a gzip encoded body
+func rejectsCompression(status int) bool {
+	return status == http.StatusUnsupportedMediaType || status == http.StatusBadRequest
+}
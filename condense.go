@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// maxPromptCompression is the largest prompt_compression target, beyond which nothing useful is left
+const maxPromptCompression = 90
+
+var (
+	// importLinePattern matches import statements in the common languages
+	importLinePattern = regexp.MustCompile(`^\s*(?:import\b|from\s+\S+\s+import\b|#include\b|use\s+[\w:{}, ]+;|using\s+[\w.]+;|require\s*[\('"]|(?:const|let|var)\s+\w+\s*=\s*require\()`)
+	// goImportSpecPattern matches an import spec inside a Go import block, e.g. `"fmt"` or `yaml "gopkg.in/yaml.v3"`
+	goImportSpecPattern = regexp.MustCompile(`^\s*(?:[\w.]+\s+)?"[^"]+"\s*$`)
+	// fixturePathPattern matches test fixtures, golden files and snapshots
+	fixturePathPattern = regexp.MustCompile(`(?:^|/)(?:testdata|fixtures?|__fixtures__|__snapshots__)/|\.(?:golden|snap)$|(?:^|/)[^/]*fixture[^/]*$`)
+)
+
+// commentPrefixes returns what comment lines start with in a file, or nil if the language is unknown
+func commentPrefixes(filePath string) []string {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".go", ".js", ".jsx", ".ts", ".tsx", ".mjs", ".java", ".kt", ".scala", ".swift", ".rs", ".c", ".h",
+		".cc", ".cpp", ".hpp", ".cs", ".php", ".dart", ".proto":
+		return []string{"//", "/*", "*/", "*"}
+	case ".py", ".rb", ".sh", ".bash", ".zsh", ".pl", ".r", ".yml", ".yaml", ".toml", ".tf", ".conf", ".cfg":
+		return []string{"#"}
+	case ".sql", ".lua", ".hs":
+		return []string{"--"}
+	case ".html", ".xml", ".vue", ".svelte":
+		return []string{"<!--", "-->"}
+	case ".el", ".clj", ".lisp", ".ini":
+		return []string{";"}
+	}
+	switch path.Base(filePath) {
+	case "Makefile", "Dockerfile", ".gitignore":
+		return []string{"#"}
+	}
+	return nil
+}
+
+// estimateTokens roughly estimates the tokens in a prompt, about four characters each
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// promptHunk is a hunk of the diff sent to the model, or the file headers and other text
+// around hunks, which are kept as they are
+type promptHunk struct {
+	Path       string
+	Header     string // the "@@ ... @@" line, empty for text that isn't a hunk
+	Lines      []string
+	Compressed bool
+}
+
+// changedLines returns the added and removed lines of a hunk without their +/- markers
+func (h *promptHunk) changedLines() (added, removed []string) {
+	for _, line := range h.Lines {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			added = append(added, line[1:])
+		case strings.HasPrefix(line, "-"):
+			removed = append(removed, line[1:])
+		}
+	}
+	return added, removed
+}
+
+// splitHunks splits a diff into hunks, keeping everything between them as plain text
+func splitHunks(diff string) []*promptHunk {
+	var hunks []*promptHunk
+	var current *promptHunk
+	filePath := ""
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			if idx := strings.LastIndex(line, " b/"); idx != -1 {
+				filePath = line[idx+3:]
+			}
+		}
+		inHunk := current != nil && current.Header != ""
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			current = &promptHunk{Path: filePath, Header: line}
+			hunks = append(hunks, current)
+			continue
+		case inHunk && line != "" && strings.ContainsAny(line[:1], " +-\\"):
+		case inHunk || current == nil:
+			current = &promptHunk{Path: filePath}
+			hunks = append(hunks, current)
+		}
+		current.Lines = append(current.Lines, line)
+	}
+	return hunks
+}
+
+// joinHunks puts split hunks back together into a diff
+func joinHunks(hunks []*promptHunk) string {
+	var diff strings.Builder
+	for _, h := range hunks {
+		if h.Header != "" {
+			diff.WriteString(h.Header + "\n")
+		}
+		for _, line := range h.Lines {
+			diff.WriteString(line + "\n")
+		}
+	}
+	return diff.String()
+}
+
+// A compressionPass rewrites a hunk to fewer lines, returning nil when it doesn't apply
+type compressionPass struct {
+	Name    string
+	Rewrite func(h *promptHunk) []string
+}
+
+// compressionPasses are applied in order, least lossy first, until the target is reached
+var compressionPasses = []compressionPass{
+	{"comment-only changes", compressComments},
+	{"import reordering", compressImports},
+	{"test fixture churn", compressFixtures},
+	{"unchanged context lines", compressContext},
+}
+
+// compressComments replaces hunks that only change comments and blank lines with a count
+func compressComments(h *promptHunk) []string {
+	prefixes := commentPrefixes(h.Path)
+	if prefixes == nil {
+		return nil
+	}
+	added, removed := h.changedLines()
+	comments := 0
+	for _, line := range append(added, removed...) {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		isComment := false
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				isComment = true
+				break
+			}
+		}
+		if !isComment {
+			return nil
+		}
+		comments++
+	}
+	if comments == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("(comments only: %d line(s) added, %d removed)", len(added), len(removed))}
+}
+
+// compressImports replaces hunks that only reorder the same imports with a count
+func compressImports(h *promptHunk) []string {
+	added, removed := h.changedLines()
+	if len(added) == 0 {
+		return nil
+	}
+	isImport := func(line string) bool {
+		return importLinePattern.MatchString(line) || (path.Ext(h.Path) == ".go" && goImportSpecPattern.MatchString(line))
+	}
+	normalize := func(lines []string) []string {
+		var imports []string
+		for _, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if !isImport(line) {
+				return nil
+			}
+			imports = append(imports, strings.TrimSpace(line))
+		}
+		sort.Strings(imports)
+		return imports
+	}
+	addedImports, removedImports := normalize(added), normalize(removed)
+	if addedImports == nil || strings.Join(addedImports, "\n") != strings.Join(removedImports, "\n") {
+		return nil
+	}
+	return []string{fmt.Sprintf("(imports reordered: %d line(s), none added or removed)", len(addedImports))}
+}
+
+// compressFixtures replaces hunks in test fixtures, golden files and snapshots with a count
+func compressFixtures(h *promptHunk) []string {
+	if !fixturePathPattern.MatchString(h.Path) {
+		return nil
+	}
+	added, removed := h.changedLines()
+	return []string{fmt.Sprintf("(test fixture churn: %d line(s) added, %d removed)", len(added), len(removed))}
+}
+
+// compressContext drops the unchanged lines around a hunk's changes
+func compressContext(h *promptHunk) []string {
+	var changed []string
+	for _, line := range h.Lines {
+		if !strings.HasPrefix(line, " ") {
+			changed = append(changed, line)
+		}
+	}
+	if len(changed) == len(h.Lines) {
+		return nil
+	}
+	return changed
+}
+
+// CompressionStep records what one compression pass did
+type CompressionStep struct {
+	Name   string
+	Hunks  int
+	Files  int
+	Tokens int
+}
+
+// CompressionReport describes how a prompt was compressed
+type CompressionReport struct {
+	Target int
+	Before int
+	After  int
+	Steps  []CompressionStep
+}
+
+// reduction is how much smaller the prompt got, in percent
+func (r *CompressionReport) reduction() int {
+	if r.Before == 0 {
+		return 0
+	}
+	return (r.Before - r.After) * 100 / r.Before
+}
+
+// compressPromptDiff applies compression passes to the diff of a prompt until the whole prompt is
+// estimated to be target percent smaller. Hunk headers are always kept, so the model still knows
+// where every change is.
+func compressPromptDiff(prefix, diff string, target int) (string, *CompressionReport) {
+	report := &CompressionReport{Target: target, Before: estimateTokens(prefix + diff)}
+	report.After = report.Before
+	hunks := splitHunks(diff)
+
+	for _, pass := range compressionPasses {
+		if report.reduction() >= target {
+			break
+		}
+		step := CompressionStep{Name: pass.Name}
+		files := make(map[string]bool)
+		for _, h := range hunks {
+			if h.Header == "" || h.Compressed {
+				continue
+			}
+			lines := pass.Rewrite(h)
+			if lines == nil {
+				continue
+			}
+			step.Tokens += estimateTokens(strings.Join(h.Lines, "\n")) - estimateTokens(strings.Join(lines, "\n"))
+			h.Lines = lines
+			h.Compressed = true
+			step.Hunks++
+			files[h.Path] = true
+		}
+		if step.Hunks == 0 {
+			continue
+		}
+		step.Files = len(files)
+		diff = joinHunks(hunks)
+		report.After = estimateTokens(prefix + diff)
+		report.Steps = append(report.Steps, step)
+	}
+	return diff, report
+}
+
+// printCompressionReport shows what was compressed and whether the target was reached
+func printCompressionReport(w io.Writer, report *CompressionReport) {
+	fmt.Fprintf(w, "\n%s\n", blue(fmt.Sprintf("🗜️  PROMPT COMPRESSION (target %d%%):", report.Target)))
+	fmt.Fprint(w, rule())
+	if len(report.Steps) == 0 {
+		fmt.Fprintf(w, "  Nothing to compress\n")
+	}
+	for _, step := range report.Steps {
+		fmt.Fprintf(w, "  %-24s %s %s\n", step.Name, fmt.Sprintf("%d hunk(s) in %d file(s)", step.Hunks, step.Files), cyan(fmt.Sprintf("-%d tokens", step.Tokens)))
+	}
+	fmt.Fprint(w, rule())
+	summary := fmt.Sprintf("~%d → ~%d tokens (%d%% smaller)", report.Before, report.After, report.reduction())
+	if report.reduction() >= report.Target {
+		fmt.Fprintf(w, "%s\n", green("✅ "+summary))
+	} else {
+		fmt.Fprintf(w, "%s\n", yellow("⚠️  "+summary+", the target wasn't reached without dropping changed lines"))
+	}
+}
@@ -6,6 +6,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Configuration
@@ -13,15 +18,154 @@ type Config struct {
 	APIKey       string `json:"api_key"`
 	APIURL       string `json:"api_url"`
 	DefaultModel string `json:"default_model"`
+
+	// The API key is kept in the OS keyring instead of the config file, see rmit set api_key --keyring
+	APIKeyKeyring bool `json:"api_key_keyring"`
+
+	// Model asked instead when the default one keeps refusing or answering with nothing
+	FallbackModel string `json:"fallback_model"`
+
+	// Backend the requests are sent to: openrouter (default), openai, anthropic, azure or ollama
+	Provider string `json:"provider"`
+
+	// Azure OpenAI resource endpoint, deployment (the model name when empty) and API version
+	AzureEndpoint   string `json:"azure_endpoint"`
+	AzureDeployment string `json:"azure_deployment"`
+	AzureAPIVersion string `json:"azure_api_version"`
+
+	// Pool of API keys rotated between requests, each "key" or "key*weight"
+	APIKeys []string `json:"api_keys"`
+
+	// Attach before/after thumbnails of changed images for vision-capable models
+	ImageThumbnails bool `json:"image_thumbnails"`
+
+	// How the commit body is written: "paragraph" (free form) or "bullets" (one bullet per file group)
+	BodyStyle string `json:"body_style"`
+
+	// The commit convention subjects follow: conventional (the default), angular, gitmoji, plain or custom
+	CommitStyle string `json:"commit_style"`
+
+	// The instructions for the custom commit style, e.g. "Start the subject with the ticket number"
+	CommitStylePrompt string `json:"commit_style_prompt"`
+
+	// How messages read: default, terse, narrative, changelog (user-facing subjects) or beginner (extra explanation)
+	WritingStyle string `json:"writing_style"`
+
+	// Generate only a subject line by default, as with --subject-only
+	SubjectOnly bool `json:"subject_only"`
+
+	// Conventional commit scopes by path prefix, e.g. {"internal/auth": "auth"}
+	ScopeMap map[string]string `json:"scope_map"`
+
+	// Text put around every subject, e.g. "[backend] " and " ({ticket})"
+	SubjectPrefix string `json:"subject_prefix"`
+	SubjectSuffix string `json:"subject_suffix"`
+
+	// Trailers added to every commit, e.g. {"Risk": "low"}
+	Trailers map[string]string `json:"trailers"`
+
+	// Trailer keys every commit must have, asked for when no value is known
+	RequiredTrailers []string `json:"required_trailers"`
+
+	// Files left out of the prompt, in .gitignore syntax, on top of the lockfiles, minified and
+	// vendored files left out by default. A pattern starting with ! sends matching files again.
+	IgnorePatterns []string `json:"ignore_patterns"`
+
+	// Use .rmit/intent.md and rmit: comments in the diff as the author's intent; off by default
+	ReadIntent bool `json:"read_intent"`
+
+	// Save the prompt/response transcript after committing: "off", "file" or "notes"
+	Transcripts string `json:"transcripts"`
+
+	// How often a request is retried after a rate limit, a server error or a dropped connection
+	MaxRetries int `json:"max_retries"`
+
+	// How long a request may take before it is given up on, 0 for no limit
+	Timeout time.Duration `json:"timeout"`
+
+	// Gzip encode large request bodies, for providers that accept Content-Encoding: gzip
+	CompressRequests bool `json:"compress_requests"`
+
+	// Generate this many messages, ranked locally, to choose from; 0 or 1 generates one
+	Candidates int `json:"candidates"`
+
+	// Built-in prompt version to use; 0 follows the latest, a number pins an older one
+	PromptVersion int `json:"prompt_version"`
+
+	// A text/template file that replaces the built-in prompt, unless the repository has .rmit/prompt.tmpl
+	PromptTemplate string `json:"prompt_template"`
+
+	// Commit without asking when the message scores at least this on a small, clean diff; 0 always asks
+	AutoCommitThreshold int `json:"auto_commit_threshold"`
+
+	// Refuse automatic commits less than this long after the last one, 0 for no limit
+	AutoCommitMinInterval time.Duration `json:"auto_commit_min_interval"`
+
+	// Refuse automatic commits once this many were made in the last hour, 0 for no limit
+	AutoCommitMaxPerHour int `json:"auto_commit_max_per_hour"`
+
+	// Let the model ask up to this many questions about an ambiguous diff before generating, 0 to never ask
+	InteractiveQuestions int `json:"interactive_questions"`
+
+	// Let the model call up to this many read-only tools for context the diff doesn't show, 0 for no tools
+	MaxToolCalls int `json:"max_tool_calls"`
+
+	// Estimated tokens the tools may add to a prompt in all, 0 for the default
+	MaxToolTokens int `json:"max_tool_tokens"`
+
+	// The tools the model may call, all of them when empty
+	AllowedTools []string `json:"allowed_tools"`
+
+	// Compress the diff in the prompt until it's this many percent smaller, 0 to send it as is
+	PromptCompression int `json:"prompt_compression"`
+
+	// Append a record of every API call to ~/.rmit_audit.jsonl, see rmit audit
+	AuditLog bool `json:"audit_log"`
+
+	// Attach a signed provenance attestation to commits with a generated message, see rmit provenance
+	Provenance bool `json:"provenance"`
+
+	// Data retention of provider hosts, e.g. {"gateway.example.com": "none"}, overriding the built-in registry
+	ProviderRetention map[string]string `json:"provider_retention"`
+
+	// What to do when a confidential repository would go to a provider that may retain it: "warn" or "block"
+	ConfidentialPolicy string `json:"confidential_policy"`
+
+	// What to do with API keys, tokens and private keys in a prompt: "redact", "abort" or "off"
+	SecretPolicy string `json:"secret_policy"`
+
+	// Extra regular expressions for secrets, a first capture group is kept, like the name in NAME=value
+	SecretPatterns []string `json:"secret_patterns"`
+
+	// Extra request body fields for the provider, e.g. {"top_k": 40, "provider": {"order": ["groq"]}}
+	ModelParams map[string]any `json:"model_params"`
+
+	// rmit server to generate with instead of calling the API directly, and its access token
+	Server      string `json:"server"`
+	ServerToken string `json:"server_token"`
+
+	// Command aliases expanded before parsing, e.g. {"qc": "--subject-only --commit"}
+	Aliases map[string]string `json:"aliases"`
+
+	// Values for flags that weren't given, by flag name, e.g. {"model": "openai/gpt-4o"}
+	Defaults map[string]string `json:"defaults"`
 }
 
 // Default configuration values
 const (
 	defaultAPIURL  = "https://openrouter.ai/api/v1/chat/completions"
 	defaultModel   = "openai/gpt-3.5-turbo"
+	defaultBody    = bodyStyleParagraph
 	configFileName = ".rmitconfig"
 )
 
+// configKeys are the keys rmit set and rmit get accept
+var configKeys = []string{
+	"api_key", "api_keys", "api_url", "provider", "azure_endpoint", "azure_deployment", "azure_api_version", "default_model", "fallback_model", "image_thumbnails", "body_style", "commit_style", "commit_style_prompt", "writing_style", "subject_only", "scope_map",
+	"subject_prefix", "subject_suffix", "trailers", "required_trailers", "ignore_patterns", "read_intent", "transcripts", "max_retries", "timeout", "compress_requests", "prompt_compression", "prompt_version", "prompt_template", "candidates", "auto_commit_threshold", "auto_commit_min_interval", "auto_commit_max_per_hour", "interactive_questions", "max_tool_calls", "max_tool_tokens", "allowed_tools", "audit_log", "provenance",
+	"provider_retention", "confidential_policy", "secret_policy", "secret_patterns", "model_params", "server", "server_token",
+}
+
 // getConfigPath returns the path to the configuration file
 func getConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
@@ -44,8 +188,23 @@ func ensureConfigDir() (string, error) {
 	return homeDir, nil
 }
 
-// loadConfig loads configuration from file or initializes defaults
+// loadConfig loads the configuration in effect: ~/.rmitconfig, overridden by the repository's
+// .rmit.json, then git config rmit.*, then RMIT_* environment variables. Flags override all of them.
 func loadConfig() (*Config, error) {
+	defer profilePhase(phaseConfig)()
+	config, err := loadFileConfig()
+	if err != nil {
+		return nil, err
+	}
+	applyRepoConfigFile(config)
+	applyGitConfig(config)
+	applyEnvOverrides(config)
+	return config, nil
+}
+
+// loadFileConfig loads configuration from file or initializes defaults. rmit set and rmit login
+// start from it so values from git config are never written to the file.
+func loadFileConfig() (*Config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, err
@@ -53,8 +212,13 @@ func loadConfig() (*Config, error) {
 
 	// Initialize default config
 	config := &Config{
-		APIURL:       defaultAPIURL,
-		DefaultModel: defaultModel,
+		APIURL:             defaultAPIURL,
+		DefaultModel:       defaultModel,
+		Transcripts:        transcriptOff,
+		MaxRetries:         defaultMaxRetries,
+		Timeout:            defaultTimeout,
+		ConfidentialPolicy: confidentialWarn,
+		SecretPolicy:       secretRedact,
 	}
 
 	// Try to read API key from environment first
@@ -67,25 +231,17 @@ func loadConfig() (*Config, error) {
 	data, err := os.ReadFile(configPath)
 	if err == nil {
 		// File exists, try to unmarshal
-		var configMap map[string]string
+		var configMap map[string]json.RawMessage
 		if err := json.Unmarshal(data, &configMap); err != nil {
 			log.Printf("Warning: failed to parse config file (will use defaults): %v", err)
 		} else {
-			// Apply values from file
-			if apiKey, ok := configMap["api_key"]; ok && apiKey != "" {
-				config.APIKey = apiKey
-			}
-			if apiURL, ok := configMap["api_url"]; ok && apiURL != "" {
-				config.APIURL = apiURL
-			}
-			if model, ok := configMap["default_model"]; ok && model != "" {
-				config.DefaultModel = model
-			}
+			applyConfigMap(config, configMap)
 		}
 	} else if !os.IsNotExist(err) {
 		// Error is not "file not found"
 		log.Printf("Warning: failed to read config file (will use defaults): %v", err)
 	}
+	loadKeyringAPIKey(config)
 
 	// Validate and apply defaults
 	if err := validateConfig(config); err != nil {
@@ -95,8 +251,409 @@ func loadConfig() (*Config, error) {
 	return config, nil
 }
 
+// applyConfigMap applies the values of a config file, skipping ones that don't parse
+func applyConfigMap(config *Config, configMap map[string]json.RawMessage) {
+	if apiKey, ok := configString(configMap, "api_key"); ok && apiKey != "" {
+		config.APIKey = apiKey
+	}
+	if keyring, ok := configString(configMap, "api_key_keyring"); ok {
+		config.APIKeyKeyring, _ = strconv.ParseBool(keyring)
+	}
+	if apiURL, ok := configString(configMap, "api_url"); ok && apiURL != "" {
+		config.APIURL = apiURL
+	}
+	if provider, ok := configString(configMap, "provider"); ok && validateProvider(provider) == nil {
+		config.Provider = provider
+	}
+	if endpoint, ok := configString(configMap, "azure_endpoint"); ok {
+		config.AzureEndpoint = endpoint
+	}
+	if deployment, ok := configString(configMap, "azure_deployment"); ok {
+		config.AzureDeployment = deployment
+	}
+	if version, ok := configString(configMap, "azure_api_version"); ok {
+		config.AzureAPIVersion = version
+	}
+	if model, ok := configString(configMap, "default_model"); ok && model != "" {
+		config.DefaultModel = model
+	}
+	if model, ok := configString(configMap, "fallback_model"); ok {
+		config.FallbackModel = model
+	}
+	if bodyStyle, ok := configString(configMap, "body_style"); ok && bodyStyle != "" {
+		config.BodyStyle = bodyStyle
+	}
+	if style, ok := configString(configMap, "commit_style"); ok {
+		if err := validateCommitStyle(style); err != nil {
+			log.Printf("Warning: %v, using %s", err, styleConventional)
+		} else {
+			config.CommitStyle = style
+		}
+	}
+	if stylePrompt, ok := configString(configMap, "commit_style_prompt"); ok {
+		config.CommitStylePrompt = stylePrompt
+	}
+	if style, ok := configString(configMap, "writing_style"); ok {
+		if err := validateWritingStyle(style); err != nil {
+			log.Printf("Warning: %v, using %s", err, writingDefault)
+		} else {
+			config.WritingStyle = style
+		}
+	}
+	if thumbnails, ok := configString(configMap, "image_thumbnails"); ok {
+		config.ImageThumbnails, _ = strconv.ParseBool(thumbnails)
+	}
+	if subjectOnly, ok := configString(configMap, "subject_only"); ok {
+		config.SubjectOnly, _ = strconv.ParseBool(subjectOnly)
+	}
+	if transcripts, ok := configString(configMap, "transcripts"); ok && transcripts != "" {
+		config.Transcripts = transcripts
+	}
+	if value, ok := configString(configMap, "max_retries"); ok {
+		retries, err := parseMaxRetries(value)
+		if err != nil {
+			log.Printf("Warning: %v, using %d", err, defaultMaxRetries)
+			retries = defaultMaxRetries
+		}
+		config.MaxRetries = retries
+	}
+	if value, ok := configString(configMap, "timeout"); ok {
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			log.Printf("Warning: %v, using %s", err, defaultTimeout)
+			timeout = defaultTimeout
+		}
+		config.Timeout = timeout
+	}
+	if compress, ok := configString(configMap, "compress_requests"); ok {
+		config.CompressRequests, _ = strconv.ParseBool(compress)
+	}
+	if compression, ok := configString(configMap, "prompt_compression"); ok {
+		config.PromptCompression, _ = strconv.Atoi(compression)
+	}
+	if value, ok := configString(configMap, "prompt_version"); ok {
+		version, err := parsePromptVersion(value)
+		if err != nil {
+			log.Printf("Warning: %v, using the latest prompts", err)
+		}
+		config.PromptVersion = version
+	}
+	if path, ok := configString(configMap, "prompt_template"); ok {
+		config.PromptTemplate = path
+	}
+	// num_candidates is another name for candidates
+	if candidates, ok := configString(configMap, "num_candidates"); ok {
+		config.Candidates, _ = strconv.Atoi(candidates)
+	}
+	if candidates, ok := configString(configMap, "candidates"); ok {
+		config.Candidates, _ = strconv.Atoi(candidates)
+	}
+	if threshold, ok := configString(configMap, "auto_commit_threshold"); ok {
+		config.AutoCommitThreshold, _ = strconv.Atoi(threshold)
+	}
+	if value, ok := configString(configMap, "auto_commit_min_interval"); ok {
+		interval, err := parseAutoCommitInterval(value)
+		if err != nil {
+			log.Printf("Warning: %v, ignoring it", err)
+		}
+		config.AutoCommitMinInterval = interval
+	}
+	if perHour, ok := configString(configMap, "auto_commit_max_per_hour"); ok {
+		config.AutoCommitMaxPerHour, _ = strconv.Atoi(perHour)
+	}
+	if questions, ok := configString(configMap, "interactive_questions"); ok {
+		config.InteractiveQuestions, _ = strconv.Atoi(questions)
+	}
+	if toolCalls, ok := configString(configMap, "max_tool_calls"); ok {
+		config.MaxToolCalls, _ = strconv.Atoi(toolCalls)
+	}
+	if toolTokens, ok := configString(configMap, "max_tool_tokens"); ok {
+		config.MaxToolTokens, _ = strconv.Atoi(toolTokens)
+	}
+	if auditLog, ok := configString(configMap, "audit_log"); ok {
+		config.AuditLog, _ = strconv.ParseBool(auditLog)
+	}
+	if provenance, ok := configString(configMap, "provenance"); ok {
+		config.Provenance, _ = strconv.ParseBool(provenance)
+	}
+	if policy, ok := configString(configMap, "confidential_policy"); ok && policy != "" {
+		config.ConfidentialPolicy = policy
+	}
+	if policy, ok := configString(configMap, "secret_policy"); ok && policy != "" {
+		config.SecretPolicy = policy
+	}
+	if prefix, ok := configString(configMap, "subject_prefix"); ok {
+		config.SubjectPrefix = prefix
+	}
+	if suffix, ok := configString(configMap, "subject_suffix"); ok {
+		config.SubjectSuffix = suffix
+	}
+	if server, ok := configString(configMap, "server"); ok {
+		config.Server = server
+	}
+	if token, ok := configString(configMap, "server_token"); ok {
+		config.ServerToken = token
+	}
+	if readIntent, ok := configString(configMap, "read_intent"); ok {
+		config.ReadIntent, _ = strconv.ParseBool(readIntent)
+	}
+	if apiKeys, ok := configMap["api_keys"]; ok {
+		if err := json.Unmarshal(apiKeys, &config.APIKeys); err != nil {
+			log.Printf("Warning: failed to parse api_keys in config file: %v", err)
+		}
+	}
+	if scopeMap, ok := configMap["scope_map"]; ok {
+		if err := json.Unmarshal(scopeMap, &config.ScopeMap); err != nil {
+			log.Printf("Warning: failed to parse scope_map in config file: %v", err)
+		}
+	}
+	if retention, ok := configMap["provider_retention"]; ok {
+		if err := json.Unmarshal(retention, &config.ProviderRetention); err != nil {
+			log.Printf("Warning: failed to parse provider_retention in config file: %v", err)
+		}
+	}
+	if params, ok := configMap["model_params"]; ok {
+		if err := json.Unmarshal(params, &config.ModelParams); err != nil {
+			log.Printf("Warning: failed to parse model_params in config file: %v", err)
+		}
+	}
+	if aliases, ok := configMap["aliases"]; ok {
+		if err := json.Unmarshal(aliases, &config.Aliases); err != nil {
+			log.Printf("Warning: failed to parse aliases in config file: %v", err)
+		}
+	}
+	if defaults, ok := configMap["defaults"]; ok {
+		if err := json.Unmarshal(defaults, &config.Defaults); err != nil {
+			log.Printf("Warning: failed to parse defaults in config file: %v", err)
+		}
+	}
+	if trailers, ok := configMap["trailers"]; ok {
+		if err := json.Unmarshal(trailers, &config.Trailers); err != nil {
+			log.Printf("Warning: failed to parse trailers in config file: %v", err)
+		}
+	}
+	if required, ok := configMap["required_trailers"]; ok {
+		if err := json.Unmarshal(required, &config.RequiredTrailers); err != nil {
+			log.Printf("Warning: failed to parse required_trailers in config file: %v", err)
+		}
+	}
+	if patterns, ok := configMap["ignore_patterns"]; ok {
+		if err := json.Unmarshal(patterns, &config.IgnorePatterns); err != nil {
+			log.Printf("Warning: failed to parse ignore_patterns in config file: %v", err)
+		}
+	}
+	if tools, ok := configMap["allowed_tools"]; ok {
+		if err := json.Unmarshal(tools, &config.AllowedTools); err != nil {
+			log.Printf("Warning: failed to parse allowed_tools in config file: %v", err)
+		}
+	}
+	if patterns, ok := configMap["secret_patterns"]; ok {
+		if err := json.Unmarshal(patterns, &config.SecretPatterns); err != nil {
+			log.Printf("Warning: failed to parse secret_patterns in config file: %v", err)
+		}
+	}
+}
+
+// repoConfigFiles are the shared config files a repository can commit, in the same format as
+// ~/.rmitconfig. The first one that exists is used.
+var repoConfigFiles = []string{".rmit.json", ".rmit/config.json"}
+
+// repoProtectedKeys can't be set by a repository's config file: a cloned repository could
+// otherwise send your key, or your diffs, to a server of its choosing, or a file of yours to
+// the model as the prompt template, or turn secret redaction off. Repositories have
+// .rmit/prompt.tmpl for their own prompt.
+var repoProtectedKeys = map[string]bool{
+	"api_key": true, "api_key_keyring": true, "api_keys": true, "api_url": true, "azure_endpoint": true, "server": true, "server_token": true,
+	"prompt_template": true, "secret_policy": true,
+}
+
+// repoConfigWarned makes sure problems with the repository's config file are reported once per
+// run, although the configuration is loaded several times
+var repoConfigWarned sync.Once
+
+// readRepoConfigFile returns the repository's shared config file and its contents, if it has one
+func readRepoConfigFile() (string, []byte, bool) {
+	for _, name := range repoConfigFiles {
+		if content, err := readRepoFile(name); err == nil {
+			return name, []byte(content), true
+		}
+	}
+	return "", nil, false
+}
+
+// repoConfigKeys returns the repository's config file and the settings it overrides, sorted
+func repoConfigKeys() (string, []string) {
+	name, data, ok := readRepoConfigFile()
+	var configMap map[string]json.RawMessage
+	if !ok || json.Unmarshal(data, &configMap) != nil {
+		return "", nil
+	}
+	var keys []string
+	for key := range configMap {
+		if !repoProtectedKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return name, keys
+}
+
+// applyRepoConfigFile applies the settings a team shares in the repository's config file on top
+// of ~/.rmitconfig. Settings that could redirect requests or credentials are reported and skipped.
+func applyRepoConfigFile(config *Config) {
+	name, data, ok := readRepoConfigFile()
+	if !ok {
+		return
+	}
+	var warnings []string
+	defer repoConfigWarned.Do(func() {
+		for _, warning := range warnings {
+			log.Printf("Warning: %s", warning)
+		}
+	})
+
+	var configMap map[string]json.RawMessage
+	if err := json.Unmarshal(data, &configMap); err != nil {
+		warnings = append(warnings, fmt.Sprintf("failed to parse %s, ignoring it: %v", name, err))
+		return
+	}
+	for key := range configMap {
+		if repoProtectedKeys[key] {
+			warnings = append(warnings, fmt.Sprintf("ignoring %s in %s, it can only be set in your own config", key, name))
+			delete(configMap, key)
+		}
+	}
+	sort.Strings(warnings)
+	applyConfigMap(config, configMap)
+}
+
+// gitConfigAliases are short git config names for configuration keys
+var gitConfigAliases = map[string]string{
+	"model":         "default_model",
+	"numcandidates": "candidates",
+}
+
+// gitConfigKey maps a git config variable to a configuration key. Git config variables can't
+// contain underscores and are case-insensitive, so rmit.defaultModel is default_model.
+func gitConfigKey(variable string) (string, bool) {
+	variable = strings.ToLower(variable)
+	if strings.HasPrefix(variable, aliasKeyPrefix) || strings.HasPrefix(variable, defaultKeyPrefix) {
+		return variable, true
+	}
+	if key, ok := gitConfigAliases[variable]; ok {
+		return key, true
+	}
+	for _, key := range configKeys {
+		if strings.ReplaceAll(key, "_", "") == variable {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// gitConfigSettings returns the rmit.* settings in git config as name and value pairs
+func gitConfigSettings() [][2]string {
+	out, err := gitCommand("config", "--get-regexp", `^rmit\.`).Output()
+	if err != nil {
+		// No rmit settings, or not in a repository and none set globally
+		return nil
+	}
+	var settings [][2]string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		name, value, _ := strings.Cut(line, " ")
+		settings = append(settings, [2]string{name, value})
+	}
+	return settings
+}
+
+// applyGitConfig applies settings from git config rmit.*, which teams can share per repository
+// through .git/config includes. Invalid values are reported and skipped.
+func applyGitConfig(config *Config) {
+	for _, setting := range gitConfigSettings() {
+		name, value := setting[0], setting[1]
+		variable := strings.TrimPrefix(name, "rmit.")
+		key, ok := gitConfigKey(variable)
+		if !ok {
+			log.Printf("Warning: unknown git config setting %s", name)
+			continue
+		}
+		if err := setConfigValue(config, key, value); err != nil {
+			log.Printf("Warning: ignoring git config %s: %v", name, err)
+		}
+	}
+}
+
+// configString reads a config value as a string. Non-string JSON values such as
+// true or 42 are returned as their literal text.
+func configString(configMap map[string]json.RawMessage, key string) (string, bool) {
+	raw, ok := configMap[key]
+	if !ok {
+		return "", false
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw), true
+	}
+	return value, true
+}
+
+// parseCompressionTarget parses a prompt compression target in percent, e.g. "30" or "30%"
+func parseCompressionTarget(value string) (int, error) {
+	target, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	if err != nil {
+		return 0, err
+	}
+	if target < 0 || target > maxPromptCompression {
+		return 0, fmt.Errorf("must be between 0 and %d percent", maxPromptCompression)
+	}
+	return target, nil
+}
+
+// parseMaxRetries parses a max_retries value
+func parseMaxRetries(value string) (int, error) {
+	retries, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || retries < 0 || retries > maxMaxRetries {
+		return 0, fmt.Errorf("invalid max retries %q: must be a number between 0 and %d", value, maxMaxRetries)
+	}
+	return retries, nil
+}
+
+// parseModelParams parses the JSON object given to rmit set model_params; an empty value clears it
+func parseModelParams(value string) (map[string]any, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var params map[string]any
+	if err := json.Unmarshal([]byte(value), &params); err != nil {
+		return nil, fmt.Errorf("expected a JSON object: %w", err)
+	}
+	return params, nil
+}
+
+// formatModelParams formats model_params as compact JSON
+func formatModelParams(params map[string]any) string {
+	if len(params) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(params)
+	return string(data)
+}
+
+// formatConfigMap formats a map config value as sorted "key=value" pairs, the way rmit set reads it
+func formatConfigMap(values map[string]string) string {
+	var entries []string
+	for key, value := range values {
+		entries = append(entries, key+"="+value)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
 // saveConfig saves the configuration to disk
 func saveConfig(config *Config) error {
+	if noPersist {
+		return errNoPersist
+	}
 	// Ensure config directory exists
 	_, err := ensureConfigDir()
 	if err != nil {
@@ -115,12 +672,153 @@ func saveConfig(config *Config) error {
 	if config.DefaultModel == "" {
 		config.DefaultModel = defaultModel
 	}
+	if config.BodyStyle == "" {
+		config.BodyStyle = defaultBody
+	}
 
 	// Create a clean map for marshaling
-	configMap := map[string]string{
+	configMap := map[string]any{
 		"api_key":       config.APIKey,
 		"api_url":       config.APIURL,
 		"default_model": config.DefaultModel,
+		"body_style":    config.BodyStyle,
+	}
+	if config.Provider != "" {
+		configMap["provider"] = config.Provider
+	}
+	if config.FallbackModel != "" {
+		configMap["fallback_model"] = config.FallbackModel
+	}
+	if config.AzureEndpoint != "" {
+		configMap["azure_endpoint"] = config.AzureEndpoint
+	}
+	if config.AzureDeployment != "" {
+		configMap["azure_deployment"] = config.AzureDeployment
+	}
+	if config.AzureAPIVersion != "" {
+		configMap["azure_api_version"] = config.AzureAPIVersion
+	}
+	if config.CommitStyle != "" && config.CommitStyle != styleConventional {
+		configMap["commit_style"] = config.CommitStyle
+	}
+	if config.CommitStylePrompt != "" {
+		configMap["commit_style_prompt"] = config.CommitStylePrompt
+	}
+	if config.WritingStyle != "" && config.WritingStyle != writingDefault {
+		configMap["writing_style"] = config.WritingStyle
+	}
+	if config.ImageThumbnails {
+		configMap["image_thumbnails"] = "true"
+	}
+	if config.SubjectOnly {
+		configMap["subject_only"] = "true"
+	}
+	if config.Transcripts != "" && config.Transcripts != transcriptOff {
+		configMap["transcripts"] = config.Transcripts
+	}
+	if config.MaxRetries != defaultMaxRetries {
+		configMap["max_retries"] = strconv.Itoa(config.MaxRetries)
+	}
+	if config.Timeout != defaultTimeout {
+		configMap["timeout"] = config.Timeout.String()
+	}
+	if config.CompressRequests {
+		configMap["compress_requests"] = "true"
+	}
+	if config.PromptCompression > 0 {
+		configMap["prompt_compression"] = strconv.Itoa(config.PromptCompression)
+	}
+	if config.PromptVersion > 0 {
+		configMap["prompt_version"] = strconv.Itoa(config.PromptVersion)
+	}
+	if config.PromptTemplate != "" {
+		configMap["prompt_template"] = config.PromptTemplate
+	}
+	if config.Candidates > 1 {
+		configMap["candidates"] = strconv.Itoa(config.Candidates)
+	}
+	if config.AutoCommitThreshold > 0 {
+		configMap["auto_commit_threshold"] = strconv.Itoa(config.AutoCommitThreshold)
+	}
+	if config.AutoCommitMinInterval > 0 {
+		configMap["auto_commit_min_interval"] = config.AutoCommitMinInterval.String()
+	}
+	if config.AutoCommitMaxPerHour > 0 {
+		configMap["auto_commit_max_per_hour"] = strconv.Itoa(config.AutoCommitMaxPerHour)
+	}
+	if config.InteractiveQuestions > 0 {
+		configMap["interactive_questions"] = strconv.Itoa(config.InteractiveQuestions)
+	}
+	if config.MaxToolCalls > 0 {
+		configMap["max_tool_calls"] = strconv.Itoa(config.MaxToolCalls)
+	}
+	if config.MaxToolTokens > 0 {
+		configMap["max_tool_tokens"] = strconv.Itoa(config.MaxToolTokens)
+	}
+	if len(config.AllowedTools) > 0 {
+		configMap["allowed_tools"] = config.AllowedTools
+	}
+	if config.AuditLog {
+		configMap["audit_log"] = "true"
+	}
+	if config.Provenance {
+		configMap["provenance"] = "true"
+	}
+	if config.ConfidentialPolicy != "" && config.ConfidentialPolicy != confidentialWarn {
+		configMap["confidential_policy"] = config.ConfidentialPolicy
+	}
+	if config.SecretPolicy != "" && config.SecretPolicy != secretRedact {
+		configMap["secret_policy"] = config.SecretPolicy
+	}
+	if len(config.SecretPatterns) > 0 {
+		configMap["secret_patterns"] = config.SecretPatterns
+	}
+	if len(config.ProviderRetention) > 0 {
+		configMap["provider_retention"] = config.ProviderRetention
+	}
+	if len(config.ModelParams) > 0 {
+		configMap["model_params"] = config.ModelParams
+	}
+	if config.SubjectPrefix != "" {
+		configMap["subject_prefix"] = config.SubjectPrefix
+	}
+	if config.SubjectSuffix != "" {
+		configMap["subject_suffix"] = config.SubjectSuffix
+	}
+	if config.Server != "" {
+		configMap["server"] = config.Server
+	}
+	if config.ServerToken != "" {
+		configMap["server_token"] = config.ServerToken
+	}
+	if config.ReadIntent {
+		configMap["read_intent"] = "true"
+	}
+	if config.APIKeyKeyring {
+		// The key itself is in the OS keyring
+		delete(configMap, "api_key")
+		configMap["api_key_keyring"] = "true"
+	}
+	if len(config.APIKeys) > 0 {
+		configMap["api_keys"] = config.APIKeys
+	}
+	if len(config.ScopeMap) > 0 {
+		configMap["scope_map"] = config.ScopeMap
+	}
+	if len(config.Trailers) > 0 {
+		configMap["trailers"] = config.Trailers
+	}
+	if len(config.Aliases) > 0 {
+		configMap["aliases"] = config.Aliases
+	}
+	if len(config.Defaults) > 0 {
+		configMap["defaults"] = config.Defaults
+	}
+	if len(config.RequiredTrailers) > 0 {
+		configMap["required_trailers"] = config.RequiredTrailers
+	}
+	if len(config.IgnorePatterns) > 0 {
+		configMap["ignore_patterns"] = config.IgnorePatterns
 	}
 
 	// Marshal to JSON with indentation
@@ -137,6 +835,23 @@ func saveConfig(config *Config) error {
 	return nil
 }
 
+// applyEnvOverrides applies RMIT_* environment variables on top of the loaded configuration.
+// This lets automation such as CI jobs configure rmit entirely through secrets and env vars.
+func applyEnvOverrides(config *Config) {
+	if apiKey := os.Getenv("RMIT_API_KEY"); apiKey != "" {
+		config.APIKey = apiKey
+	}
+	if apiURL := os.Getenv("RMIT_API_URL"); apiURL != "" {
+		config.APIURL = apiURL
+	}
+	if provider := os.Getenv("RMIT_PROVIDER"); provider != "" && validateProvider(provider) == nil {
+		config.Provider = provider
+	}
+	if model := os.Getenv("RMIT_MODEL"); model != "" {
+		config.DefaultModel = model
+	}
+}
+
 // validateConfig checks if the configuration is valid
 func validateConfig(config *Config) error {
 	if config == nil {
@@ -150,6 +865,268 @@ func validateConfig(config *Config) error {
 	if config.DefaultModel == "" {
 		config.DefaultModel = defaultModel
 	}
+	if config.BodyStyle == "" {
+		config.BodyStyle = defaultBody
+	}
+
+	return nil
+}
+
+// setConfigValue parses and validates a value for a configuration key, as given to rmit set
+func setConfigValue(config *Config, key, value string) error {
+	if name, ok := strings.CutPrefix(key, aliasKeyPrefix); ok {
+		return setAlias(config, name, value)
+	}
+	if flag, ok := strings.CutPrefix(key, defaultKeyPrefix); ok {
+		return setFlagDefault(config, flag, value)
+	}
 
+	switch key {
+	case "api_key":
+		if err := validateAPIKey(value); err != nil {
+			return fmt.Errorf("invalid API key: %w", err)
+		}
+		config.APIKey = value
+	case "api_keys":
+		keys, err := parseAPIKeys(value)
+		if err != nil {
+			return fmt.Errorf("invalid API key pool: %w", err)
+		}
+		config.APIKeys = keys
+	case "api_url":
+		if err := validateAPIURL(value); err != nil {
+			return fmt.Errorf("invalid API URL: %w", err)
+		}
+		config.APIURL = value
+	case "provider":
+		if err := validateProvider(value); err != nil {
+			return err
+		}
+		config.Provider = value
+	case "azure_endpoint":
+		if value != "" {
+			if err := validateAPIURL(value); err != nil {
+				return fmt.Errorf("invalid Azure endpoint: %w", err)
+			}
+		}
+		config.AzureEndpoint = value
+	case "azure_deployment":
+		config.AzureDeployment = value
+	case "azure_api_version":
+		config.AzureAPIVersion = value
+	case "default_model":
+		config.DefaultModel = value
+	case "fallback_model":
+		config.FallbackModel = value
+	case "image_thumbnails":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for image_thumbnails: %w", err)
+		}
+		config.ImageThumbnails = enabled
+	case "body_style":
+		if err := validateBodyStyle(value); err != nil {
+			return fmt.Errorf("invalid body style: %w", err)
+		}
+		config.BodyStyle = value
+	case "commit_style":
+		if err := validateCommitStyle(value); err != nil {
+			return err
+		}
+		config.CommitStyle = value
+	case "commit_style_prompt":
+		config.CommitStylePrompt = value
+	case "writing_style":
+		if err := validateWritingStyle(value); err != nil {
+			return err
+		}
+		config.WritingStyle = value
+	case "subject_only":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for subject_only: %w", err)
+		}
+		config.SubjectOnly = enabled
+	case "scope_map":
+		scopeMap, err := parseScopeMap(value)
+		if err != nil {
+			return fmt.Errorf("invalid scope map: %w", err)
+		}
+		config.ScopeMap = scopeMap
+	case "trailers":
+		trailerMap, err := parseTrailerMap(value)
+		if err != nil {
+			return fmt.Errorf("invalid trailers: %w", err)
+		}
+		config.Trailers = trailerMap
+	case "required_trailers":
+		config.RequiredTrailers = parseTrailerKeys(value)
+	case "ignore_patterns":
+		config.IgnorePatterns = parseIgnoreList(value)
+	case "max_retries":
+		retries, err := parseMaxRetries(value)
+		if err != nil {
+			return err
+		}
+		config.MaxRetries = retries
+	case "timeout":
+		timeout, err := parseTimeout(value)
+		if err != nil {
+			return err
+		}
+		config.Timeout = timeout
+	case "compress_requests":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for compress_requests: %w", err)
+		}
+		config.CompressRequests = enabled
+	case "prompt_compression":
+		target, err := parseCompressionTarget(value)
+		if err != nil {
+			return fmt.Errorf("invalid prompt compression: %w", err)
+		}
+		config.PromptCompression = target
+	case "prompt_version":
+		version, err := parsePromptVersion(value)
+		if err != nil {
+			return err
+		}
+		config.PromptVersion = version
+	case "prompt_template":
+		// A template that doesn't parse is reported now rather than on the next commit
+		if value != "" {
+			text, err := readPromptTemplateFile(value)
+			if err != nil {
+				return err
+			}
+			if _, err := parsePromptTemplate(filepath.Base(value), text); err != nil {
+				return err
+			}
+		}
+		config.PromptTemplate = value
+	case "candidates", "num_candidates":
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 || count > maxCandidates {
+			return fmt.Errorf("invalid candidates: must be a number between 1 and %d", maxCandidates)
+		}
+		config.Candidates = count
+	case "auto_commit_threshold":
+		threshold, err := strconv.Atoi(value)
+		if err != nil || threshold < 0 {
+			return fmt.Errorf("invalid auto commit threshold: must be a score of 0 or more")
+		}
+		config.AutoCommitThreshold = threshold
+	case "auto_commit_min_interval":
+		interval, err := parseAutoCommitInterval(value)
+		if err != nil {
+			return err
+		}
+		config.AutoCommitMinInterval = interval
+	case "auto_commit_max_per_hour":
+		perHour, err := strconv.Atoi(value)
+		if err != nil || perHour < 0 {
+			return fmt.Errorf("invalid auto commit limit: must be a number of commits, or 0 for no limit")
+		}
+		config.AutoCommitMaxPerHour = perHour
+	case "interactive_questions":
+		questions, err := strconv.Atoi(value)
+		if err != nil || questions < 0 {
+			return fmt.Errorf("invalid interactive questions: must be a number of questions, or 0 to never ask")
+		}
+		config.InteractiveQuestions = questions
+	case "max_tool_calls":
+		toolCalls, err := strconv.Atoi(value)
+		if err != nil || toolCalls < 0 {
+			return fmt.Errorf("invalid max tool calls: must be a number of calls, or 0 for no tools")
+		}
+		config.MaxToolCalls = toolCalls
+	case "max_tool_tokens":
+		toolTokens, err := strconv.Atoi(value)
+		if err != nil || toolTokens < 0 {
+			return fmt.Errorf("invalid max tool tokens: must be a number of tokens, or 0 for the default of %d", defaultToolTokens)
+		}
+		config.MaxToolTokens = toolTokens
+	case "allowed_tools":
+		tools, err := parseToolNames(value)
+		if err != nil {
+			return fmt.Errorf("invalid allowed tools: %w", err)
+		}
+		config.AllowedTools = tools
+	case "transcripts":
+		if err := validateTranscriptMode(value); err != nil {
+			return fmt.Errorf("invalid transcript mode: %w", err)
+		}
+		config.Transcripts = value
+	case "read_intent":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for read_intent: %w", err)
+		}
+		config.ReadIntent = enabled
+	case "audit_log":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for audit_log: %w", err)
+		}
+		config.AuditLog = enabled
+	case "provenance":
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for provenance: %w", err)
+		}
+		config.Provenance = enabled
+	case "provider_retention":
+		retention, err := parseProviderRetention(value)
+		if err != nil {
+			return fmt.Errorf("invalid provider retention: %w", err)
+		}
+		config.ProviderRetention = retention
+	case "confidential_policy":
+		if err := validateConfidentialPolicy(value); err != nil {
+			return fmt.Errorf("invalid confidential policy: %w", err)
+		}
+		config.ConfidentialPolicy = value
+	case "secret_policy":
+		if err := validateSecretPolicy(value); err != nil {
+			return fmt.Errorf("invalid secret policy: %w", err)
+		}
+		config.SecretPolicy = value
+	case "secret_patterns":
+		var patterns []string
+		if strings.TrimSpace(value) != "" {
+			patterns = strings.Split(value, "\n")
+		}
+		if _, err := compileSecretPatterns(patterns); err != nil {
+			return err
+		}
+		config.SecretPatterns = patterns
+	case "subject_prefix", "subject_suffix":
+		if err := validateSubjectAffix(value); err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		if key == "subject_prefix" {
+			config.SubjectPrefix = value
+		} else {
+			config.SubjectSuffix = value
+		}
+	case "server":
+		if value != "" {
+			if err := validateAPIURL(value); err != nil {
+				return fmt.Errorf("invalid server URL: %w", err)
+			}
+		}
+		config.Server = value
+	case "model_params":
+		params, err := parseModelParams(value)
+		if err != nil {
+			return fmt.Errorf("invalid model params: %w", err)
+		}
+		config.ModelParams = params
+	case "server_token":
+		config.ServerToken = value
+	default:
+		return fmt.Errorf("unknown configuration key: %s. Valid keys are: %s, %s<name>, %s<flag>", key, strings.Join(configKeys, ", "), aliasKeyPrefix, defaultKeyPrefix)
+	}
 	return nil
 }
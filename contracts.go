@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Patterns for the subset of protobuf syntax needed to compare contracts
+var (
+	protoBlockPattern = regexp.MustCompile(`^\s*(message|service|enum)\s+(\w+)\s*\{`)
+	protoFieldPattern = regexp.MustCompile(`^\s*((?:repeated|optional)\s+)?(map\s*<[^>]+>|[\w.]+)\s+(\w+)\s*=\s*(\d+)`)
+	protoRPCPattern   = regexp.MustCompile(`^\s*rpc\s+(\w+)\s*\(\s*(stream\s+)?([\w.]+)\s*\)\s*returns\s*\(\s*(stream\s+)?([\w.]+)\s*\)`)
+)
+
+// HTTP methods that define OpenAPI operations
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// describeSymbol formats a contract symbol with its signature, if any
+func describeSymbol(key, signature string) string {
+	if signature == "" {
+		return key
+	}
+	return fmt.Sprintf("%s (%s)", key, signature)
+}
+
+// compareContracts compares two symbol → signature maps and describes the differences.
+// Keys are "<kind> <name>", e.g. "field User.email".
+func compareContracts(before, after map[string]string) []string {
+	var keys []string
+	for key := range after {
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, exists := after[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		old, existed := before[key]
+		signature, exists := after[key]
+		switch {
+		case !existed:
+			lines = append(lines, "adds "+describeSymbol(key, signature))
+		case !exists:
+			lines = append(lines, "removes "+describeSymbol(key, old))
+		case old != signature:
+			lines = append(lines, fmt.Sprintf("changes %s from %s to %s", key, old, signature))
+		}
+	}
+
+	return lines
+}
+
+// parseProto extracts messages, fields, enums and RPCs from a .proto file
+func parseProto(src string) map[string]string {
+	symbols := make(map[string]string)
+	var stack []string
+
+	for _, line := range strings.Split(src, "\n") {
+		if idx := strings.Index(line, "//"); idx != -1 {
+			line = line[:idx]
+		}
+
+		if match := protoBlockPattern.FindStringSubmatch(line); match != nil {
+			name := strings.Join(append(stack, match[2]), ".")
+			symbols[match[1]+" "+name] = ""
+			stack = append(stack, match[2])
+		} else if match := protoRPCPattern.FindStringSubmatch(line); match != nil && len(stack) > 0 {
+			symbols["rpc "+strings.Join(stack, ".")+"."+match[1]] = fmt.Sprintf("%s%s → %s%s", match[2], match[3], match[4], match[5])
+		} else if match := protoFieldPattern.FindStringSubmatch(line); match != nil && len(stack) > 0 {
+			symbols["field "+strings.Join(stack, ".")+"."+match[3]] = fmt.Sprintf("%s%s = %s", match[1], strings.Join(strings.Fields(match[2]), ""), match[4])
+		}
+
+		// Blocks opened and closed on the same line were handled above
+		opened := strings.Count(line, "{")
+		closed := strings.Count(line, "}")
+		if protoBlockPattern.MatchString(line) {
+			opened--
+		}
+		for i := 0; i < closed-opened && len(stack) > 0; i++ {
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	return symbols
+}
+
+// parseOpenAPI extracts endpoints, parameters and schema fields from an OpenAPI or Swagger document.
+// It returns false if the document is not an API spec.
+func parseOpenAPI(src string) (map[string]string, bool) {
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return nil, false
+	}
+	if _, ok := doc["openapi"]; !ok {
+		if _, ok := doc["swagger"]; !ok {
+			return nil, false
+		}
+	}
+
+	symbols := make(map[string]string)
+
+	paths, _ := doc["paths"].(map[string]any)
+	for route, item := range paths {
+		operations, _ := item.(map[string]any)
+		for _, method := range openAPIMethods {
+			operation, ok := operations[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			endpoint := strings.ToUpper(method) + " " + route
+			summary, _ := operation["summary"].(string)
+			symbols["endpoint "+endpoint] = summary
+
+			parameters, _ := operation["parameters"].([]any)
+			for _, p := range parameters {
+				param, _ := p.(map[string]any)
+				name, _ := param["name"].(string)
+				if name == "" {
+					continue
+				}
+				in, _ := param["in"].(string)
+				required, _ := param["required"].(bool)
+				signature := in
+				if required {
+					signature += ", required"
+				}
+				symbols["parameter "+name+" of "+endpoint] = signature
+			}
+		}
+	}
+
+	// OpenAPI 3 keeps schemas under components, Swagger 2 under definitions
+	schemas, _ := doc["definitions"].(map[string]any)
+	if components, ok := doc["components"].(map[string]any); ok {
+		schemas, _ = components["schemas"].(map[string]any)
+	}
+	for name, s := range schemas {
+		schema, _ := s.(map[string]any)
+		symbols["schema "+name] = schemaType(schema)
+
+		properties, _ := schema["properties"].(map[string]any)
+		for field, p := range properties {
+			property, _ := p.(map[string]any)
+			symbols["field "+name+"."+field] = schemaType(property)
+		}
+	}
+
+	return symbols, true
+}
+
+// schemaType renders a JSON schema's type for display
+func schemaType(schema map[string]any) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return path.Base(ref)
+	}
+	typ, _ := schema["type"].(string)
+	if typ == "array" {
+		if items, ok := schema["items"].(map[string]any); ok {
+			return "array of " + schemaType(items)
+		}
+	}
+	if format, ok := schema["format"].(string); ok {
+		typ += " (" + format + ")"
+	}
+	if typ == "" {
+		typ = "object"
+	}
+	return typ
+}
+
+// isOpenAPICandidate reports whether a file could be an OpenAPI or Swagger document
+func isOpenAPICandidate(p string) bool {
+	switch path.Ext(p) {
+	case ".yaml", ".yml", ".json":
+	default:
+		return false
+	}
+	lower := strings.ToLower(p)
+	return strings.Contains(lower, "api") || strings.Contains(lower, "swagger")
+}
+
+// contractSummary summarizes endpoint and message level changes in .proto and OpenAPI files
+func contractSummary(f *FileDiff) *FileSummary {
+	isProto := strings.HasSuffix(f.Path, ".proto")
+	if f.Binary || (!isProto && !isOpenAPICandidate(f.Path)) {
+		return nil
+	}
+
+	var oldSrc, newSrc string
+	if !f.New {
+		oldSrc, _ = gitShow("HEAD", f.OldPath)
+	}
+	if !f.Deleted {
+		newSrc, _ = readRepoFile(f.Path)
+	}
+
+	var before, after map[string]string
+	title := "Protobuf contract changes"
+	if isProto {
+		before, after = parseProto(oldSrc), parseProto(newSrc)
+	} else {
+		var oldOK, newOK bool
+		before, oldOK = parseOpenAPI(oldSrc)
+		after, newOK = parseOpenAPI(newSrc)
+		if !oldOK && !newOK {
+			return nil
+		}
+		title = "OpenAPI contract changes"
+	}
+
+	lines := compareContracts(before, after)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return &FileSummary{Path: f.Path, Title: title, Lines: lines, InBody: true}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// KeyInfo is OpenRouter's description of an API key from /auth/key
+type KeyInfo struct {
+	Label          string   `json:"label"`
+	Usage          float64  `json:"usage"`
+	Limit          *float64 `json:"limit"`
+	LimitRemaining *float64 `json:"limit_remaining"`
+	IsFreeTier     bool     `json:"is_free_tier"`
+	RateLimit      *struct {
+		Requests int    `json:"requests"`
+		Interval string `json:"interval"`
+	} `json:"rate_limit"`
+}
+
+// Credits is the account balance from OpenRouter's /credits
+type Credits struct {
+	TotalCredits float64 `json:"total_credits"`
+	TotalUsage   float64 `json:"total_usage"`
+}
+
+// apiBaseURL derives the provider's API root from the chat completions URL
+func apiBaseURL(apiURL string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(apiURL, "/"), "/chat/completions")
+}
+
+// isOpenRouter reports whether the configured API is OpenRouter
+func isOpenRouter(apiURL string) bool {
+	parsed, err := url.Parse(apiURL)
+	return err == nil && strings.HasSuffix(parsed.Hostname(), "openrouter.ai")
+}
+
+// maskKey shortens an API key for display
+func maskKey(key string) string {
+	if len(key) <= 12 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:8] + "…" + key[len(key)-4:]
+}
+
+// getAccountJSON fetches an account endpoint and decodes its "data" field
+func getAccountJSON(endpoint, apiKey string, out any) error {
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error: %s (status code: %d)", string(body), resp.StatusCode)
+	}
+
+	wrapper := struct {
+		Data any `json:"data"`
+	}{Data: out}
+	if err := json.Unmarshal(body, &wrapper); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// printKeyStatus shows rmit's own view of a key: rate limit waits and recent failures
+func printKeyStatus(config *Config, key string, state *keyPoolState, limits map[string]int64) {
+	id := rateLimitKey(chatURL(config), key)
+	if until := time.Unix(limits[id], 0); time.Now().Before(until) {
+		fmt.Printf("  %s %s\n", yellow("rate limited for:"), cyan(time.Until(until).Round(time.Second)))
+	}
+	if failure, ok := state.Failures[id]; ok && failure.Count > 0 {
+		fmt.Printf("  %s %s\n", yellow("recent failures:"), cyan(fmt.Sprintf("%d, last at %s", failure.Count, time.Unix(failure.Last, 0).Format(time.Kitchen))))
+	}
+}
+
+// newCreditsCmd creates the credits command that shows remaining credits and rate limits
+func newCreditsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "credits",
+		Short: "Show remaining credits and rate limits",
+		Long:  "Show the remaining credits, spending limit and rate limits of each configured API key, to explain why requests fail",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			pool := apiKeyPool(config)
+			if len(pool) == 0 {
+				log.Fatalf("%s %v", red("Invalid API key:"), validateAPIKey(""))
+			}
+
+			openRouter := isOpenRouter(chatURL(config))
+			base := apiBaseURL(chatURL(config))
+			state := readKeyPoolState()
+			limits := readRateLimits()
+
+			fmt.Printf("%s\n", blue("💳 Credits and limits:"))
+			fmt.Print(rule())
+
+			if openRouter {
+				var credits Credits
+				if err := getAccountJSON(base+"/credits", pool[0].Key, &credits); err == nil {
+					fmt.Printf("%s %s\n", green("account balance:"), cyan(fmt.Sprintf("$%.2f of $%.2f remaining", credits.TotalCredits-credits.TotalUsage, credits.TotalCredits)))
+				}
+			} else {
+				fmt.Printf("%s\n", yellow("Credit and quota details are only available for OpenRouter; showing rmit's local rate limit state."))
+			}
+
+			for _, poolKey := range pool {
+				fmt.Printf("%s %s\n", green("key:"), blue(maskKey(poolKey.Key)))
+				if openRouter {
+					var info KeyInfo
+					if err := getAccountJSON(base+"/auth/key", poolKey.Key, &info); err != nil {
+						fmt.Printf("  %s %v\n", red("error:"), err)
+					} else {
+						if info.Label != "" {
+							fmt.Printf("  %s %s\n", green("label:"), cyan(info.Label))
+						}
+						fmt.Printf("  %s %s\n", green("usage:"), cyan(fmt.Sprintf("$%.4f", info.Usage)))
+						if info.Limit != nil && info.LimitRemaining != nil {
+							fmt.Printf("  %s %s\n", green("limit:"), cyan(fmt.Sprintf("$%.2f remaining of $%.2f", *info.LimitRemaining, *info.Limit)))
+						} else {
+							fmt.Printf("  %s %s\n", green("limit:"), cyan("none"))
+						}
+						if info.IsFreeTier {
+							fmt.Printf("  %s %s\n", green("tier:"), cyan("free"))
+						}
+						if info.RateLimit != nil {
+							fmt.Printf("  %s %s\n", green("rate limit:"), cyan(fmt.Sprintf("%d requests per %s", info.RateLimit.Requests, info.RateLimit.Interval)))
+						}
+					}
+				}
+				printKeyStatus(config, poolKey.Key, state, limits)
+			}
+
+			fmt.Print(rule())
+		},
+	}
+}
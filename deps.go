@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dependency manifests whose version changes can be parsed from the diff
+var manifestPatterns = map[string]*regexp.Regexp{
+	"go.mod":        regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+\.[^\s()]+)\s+(v[^\s]+)`),
+	"package.json":  regexp.MustCompile(`^\s*"([^"]+)":\s*"([~^>=<]*\d[^"]*)"`),
+	"composer.json": regexp.MustCompile(`^\s*"([^"]+/[^"]+)":\s*"([~^>=<]*\d[^"]*)"`),
+	"Cargo.toml":    regexp.MustCompile(`^\s*([A-Za-z0-9_\-]+)\s*=\s*(?:"([^"]+)"|\{.*version\s*=\s*"([^"]+)")`),
+}
+
+// requirementsPattern matches pinned pip requirements (requirements.txt, requirements-dev.txt, ...)
+var requirementsPattern = regexp.MustCompile(`^\s*([A-Za-z0-9_.\-\[\]]+)\s*==\s*([^\s;#]+)`)
+
+// Lockfiles that change alongside manifests but carry no useful signal of their own
+var lockfiles = map[string]bool{
+	"go.sum":            true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+	"Cargo.lock":        true,
+	"composer.lock":     true,
+	"Gemfile.lock":      true,
+	"Pipfile.lock":      true,
+	"poetry.lock":       true,
+	"uv.lock":           true,
+}
+
+// Keys in manifests that look like dependencies but are not
+var nonDependencyKeys = map[string]bool{
+	"version":      true,
+	"name":         true,
+	"edition":      true,
+	"rust-version": true,
+	"description":  true,
+	"license":      true,
+	"node":         true,
+	"npm":          true,
+}
+
+// Lines that open a section of a manifest
+var (
+	jsonSectionPattern  = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*[{\[]`)
+	tomlSectionPattern  = regexp.MustCompile(`^\s*\[+\s*([^\]]+?)\s*\]+`)
+	goModBlockPattern   = regexp.MustCompile(`^\s*(\w+)\s*\($`)
+	goModRequirePattern = regexp.MustCompile(`^\s*require\s+[^\s(]`)
+)
+
+// jsonDependencySections are the package.json and composer.json objects listing dependencies
+var jsonDependencySections = map[string]bool{
+	"dependencies":         true,
+	"devDependencies":      true,
+	"peerDependencies":     true,
+	"optionalDependencies": true,
+	"require":              true,
+	"require-dev":          true,
+}
+
+// DependencyChange describes a single dependency version change
+type DependencyChange struct {
+	Name string
+	From string
+	To   string
+}
+
+// String formats the change the way dependency bots do
+func (c DependencyChange) String() string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("add %s %s", c.Name, c.To)
+	case c.To == "":
+		return fmt.Sprintf("remove %s %s", c.Name, c.From)
+	default:
+		return fmt.Sprintf("bump %s from %s to %s", c.Name, c.From, c.To)
+	}
+}
+
+// manifestPattern returns the version pattern for a dependency manifest, or nil if the file is not one
+func manifestPattern(filePath string) *regexp.Regexp {
+	name := path.Base(filePath)
+	if pattern, ok := manifestPatterns[name]; ok {
+		return pattern
+	}
+	if strings.HasPrefix(name, "requirements") && strings.HasSuffix(name, ".txt") {
+		return requirementsPattern
+	}
+	return nil
+}
+
+// parseDependencyVersions extracts name → version pairs from manifest lines
+func parseDependencyVersions(pattern *regexp.Regexp, lines []string) map[string]string {
+	versions := make(map[string]string)
+	for _, line := range lines {
+		match := pattern.FindStringSubmatch(line)
+		if match == nil || nonDependencyKeys[match[1]] {
+			continue
+		}
+		// The first non-empty capture after the name is the version
+		for _, version := range match[2:] {
+			if version != "" {
+				versions[match[1]] = version
+				break
+			}
+		}
+	}
+	return versions
+}
+
+// manifestSection reports whether a manifest line opens or closes a section, and if so whether
+// the lines that follow list dependencies
+func manifestSection(name, line string) (inDependencies, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case name == "package.json" || name == "composer.json":
+		if match := jsonSectionPattern.FindStringSubmatch(line); match != nil {
+			return jsonDependencySections[match[1]], true
+		}
+		if strings.HasPrefix(trimmed, "}") || strings.HasPrefix(trimmed, "]") {
+			// Dependency objects don't nest, so a closing brace is back at the top level
+			return false, true
+		}
+	case name == "Cargo.toml":
+		if match := tomlSectionPattern.FindStringSubmatch(line); match != nil {
+			return strings.HasSuffix(match[1], "dependencies") || strings.Contains(match[1], "dependencies."), true
+		}
+	case name == "go.mod":
+		if match := goModBlockPattern.FindStringSubmatch(line); match != nil {
+			return match[1] == "require", true
+		}
+		if trimmed == ")" {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// onlyDependencyLines reports whether every changed line of a manifest is a dependency or the
+// punctuation around one, inside the sections listing dependencies. Sections are followed
+// through the hunk context; a line whose section isn't in view is judged by itself.
+func onlyDependencyLines(f *FileDiff, pattern *regexp.Regexp) bool {
+	name := path.Base(f.Path)
+	known, inDependencies := false, false
+	inHunk := false
+	for _, line := range strings.Split(f.Text, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			inHunk, known = true, false
+			continue
+		}
+		if !inHunk || line == "" || !strings.ContainsAny(line[:1], " +-") {
+			continue
+		}
+		changed, content := line[0] != ' ', line[1:]
+		opens := false
+		if deps, ok := manifestSection(name, content); ok {
+			known, inDependencies = true, deps
+			opens = jsonSectionPattern.MatchString(content) || tomlSectionPattern.MatchString(content) || goModBlockPattern.MatchString(content)
+		}
+		if !changed {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(content)
+		switch {
+		case opens:
+			if !inDependencies {
+				return false
+			}
+		case trimmed == "" || strings.Trim(trimmed, "{}[](),") == "" || strings.HasPrefix(trimmed, "#"):
+			// Punctuation, blank lines and comments
+		default:
+			match := pattern.FindStringSubmatch(content)
+			if match == nil || nonDependencyKeys[match[1]] {
+				return false
+			}
+			if known && !inDependencies && !(name == "go.mod" && goModRequirePattern.MatchString(content)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// detectDependencyChanges returns the dependency changes in a diff and whether the diff
+// consists only of dependency changes to manifests, and lockfiles
+func detectDependencyChanges(files []*FileDiff) ([]DependencyChange, bool) {
+	if len(files) == 0 {
+		return nil, false
+	}
+
+	var changes []DependencyChange
+	seen := make(map[string]bool)
+
+	for _, f := range files {
+		if lockfiles[path.Base(f.Path)] {
+			continue
+		}
+		pattern := manifestPattern(f.Path)
+		if pattern == nil || !onlyDependencyLines(f, pattern) {
+			return nil, false
+		}
+
+		before := parseDependencyVersions(pattern, f.Removed)
+		after := parseDependencyVersions(pattern, f.Added)
+
+		for name, to := range after {
+			from := before[name]
+			if from == to || seen[name] {
+				continue
+			}
+			seen[name] = true
+			changes = append(changes, DependencyChange{Name: name, From: from, To: to})
+		}
+		for name, from := range before {
+			if _, ok := after[name]; ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			changes = append(changes, DependencyChange{Name: name, From: from})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, true
+}
+
+// dependencyBumpMessage builds a commit message locally for diffs that only touch
+// dependency manifests and lockfiles. It returns false when the model is needed.
+func dependencyBumpMessage(diff string) (string, bool) {
+	files := parseDiff(diff)
+	changes, depsOnly := detectDependencyChanges(files)
+	if !depsOnly {
+		return "", false
+	}
+
+	switch len(changes) {
+	case 0:
+		// Only lockfiles changed, or manifest edits we couldn't parse
+		for _, f := range files {
+			if !lockfiles[path.Base(f.Path)] {
+				return "", false
+			}
+		}
+		return "chore(deps): update " + strings.Join(diffPaths(files), ", "), true
+	case 1:
+		return "chore(deps): " + changes[0].String(), true
+	}
+
+	var message strings.Builder
+	fmt.Fprintf(&message, "chore(deps): update %d dependencies\n\n", len(changes))
+	for _, change := range changes {
+		message.WriteString("- " + change.String() + "\n")
+	}
+
+	return strings.TrimSpace(message.String()), true
+}
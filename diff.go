@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileDiff holds the changes made to a single file in a unified git diff
+type FileDiff struct {
+	Path    string
+	OldPath string
+	Text    string
+	Added   []string
+	Removed []string
+	New     bool
+	Deleted bool
+	Binary  bool
+}
+
+// parseDiff splits a unified git diff into per-file sections
+func parseDiff(diff string) []*FileDiff {
+	var files []*FileDiff
+	var current *FileDiff
+	var text strings.Builder
+	inHunk := false
+
+	flush := func() {
+		if current != nil {
+			current.Text = text.String()
+			files = append(files, current)
+		}
+		current = nil
+		inHunk = false
+		text.Reset()
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			current = &FileDiff{}
+			// "diff --git a/old b/new"
+			if idx := strings.LastIndex(line, " b/"); idx != -1 {
+				current.Path = line[idx+3:]
+				current.OldPath = strings.TrimPrefix(line[len("diff --git "):idx], "a/")
+			}
+		}
+		if current == nil {
+			continue
+		}
+
+		// Text after the last hunk (e.g. extra instructions appended to the diff) is not part of the file
+		if inHunk && line != "" && !strings.ContainsAny(line[:1], " +-\\@") {
+			flush()
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			inHunk = true
+		}
+		text.WriteString(line + "\n")
+
+		switch {
+		case strings.HasPrefix(line, "new file mode"):
+			current.New = true
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.Deleted = true
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			current.Binary = true
+			inHunk = true
+		case strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- "):
+			// File headers, the path was already taken from the diff line
+		case strings.HasPrefix(line, "+"):
+			current.Added = append(current.Added, line[1:])
+		case strings.HasPrefix(line, "-"):
+			current.Removed = append(current.Removed, line[1:])
+		}
+	}
+	flush()
+
+	return files
+}
+
+// diffPaths returns the paths of all files in a parsed diff
+func diffPaths(files []*FileDiff) []string {
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// A diffAnalyzer inspects the parsed diff and returns facts worth telling the model
+type diffAnalyzer func(files []*FileDiff) []string
+
+// diffAnalyzers are run on every diff before generation
+var diffAnalyzers = []diffAnalyzer{
+	migrationInsights,
+	apiInsights,
+	translationInsights,
+}
+
+// collectDiffInsights runs all diff analyzers and gathers their facts
+func collectDiffInsights(files []*FileDiff) []string {
+	var insights []string
+	for _, analyze := range diffAnalyzers {
+		insights = append(insights, analyze(files)...)
+	}
+	return insights
+}
+
+// FileSummary is a structured description of a file that replaces its raw diff in the prompt
+type FileSummary struct {
+	Path   string
+	Title  string
+	Lines  []string
+	InBody bool // also append the summary to the commit message body
+}
+
+// A fileSummarizer returns a summary for files it understands, or nil
+type fileSummarizer func(f *FileDiff) *FileSummary
+
+// fileSummarizers are tried in order for every changed file
+var fileSummarizers = []fileSummarizer{
+	contractSummary,
+	infraSummary,
+	assetSummary,
+	binaryAssetSummary,
+	noiseSummary,
+}
+
+// summarizeFiles runs the file summarizers over the diff
+func summarizeFiles(files []*FileDiff) []*FileSummary {
+	var summaries []*FileSummary
+	for _, f := range files {
+		for _, summarize := range fileSummarizers {
+			if summary := summarize(f); summary != nil {
+				summaries = append(summaries, summary)
+				break
+			}
+		}
+	}
+	return summaries
+}
+
+// promptDiff replaces the raw diff of summarized files with a pointer to their summary
+func promptDiff(diff string, files []*FileDiff, summaries []*FileSummary) string {
+	summarized := make(map[string]bool)
+	for _, summary := range summaries {
+		summarized[summary.Path] = true
+	}
+
+	for _, f := range files {
+		if !summarized[f.Path] {
+			continue
+		}
+		placeholder := fmt.Sprintf("diff --git a/%s b/%s\n(diff omitted, see the summary of %s above)\n", f.OldPath, f.Path, f.Path)
+		diff = strings.Replace(diff, strings.TrimSuffix(f.Text, "\n"), strings.TrimSuffix(placeholder, "\n"), 1)
+	}
+
+	return diff
+}
+
+// summaryInsights formats file summaries for the prompt
+func summaryInsights(summaries []*FileSummary) []string {
+	var insights []string
+	for _, summary := range summaries {
+		insights = append(insights, fmt.Sprintf("%s in %s: %s", summary.Title, summary.Path, strings.Join(summary.Lines, "; ")))
+	}
+	return insights
+}
+
+// appendSummariesToBody adds body-worthy summaries to the end of the commit message
+func appendSummariesToBody(message string, summaries []*FileSummary) string {
+	var body strings.Builder
+	for _, summary := range summaries {
+		if !summary.InBody {
+			continue
+		}
+		fmt.Fprintf(&body, "\n\n%s (%s):", summary.Title, summary.Path)
+		for _, line := range summary.Lines {
+			body.WriteString("\n- " + line)
+		}
+	}
+	return message + body.String()
+}
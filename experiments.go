@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// experimentsFileName is where the outcome of every experiment run is recorded
+const experimentsFileName = ".rmit_experiments.jsonl"
+
+// Outcomes of an experiment run
+const (
+	trialAccepted = "accepted" // committed with the message as generated
+	trialRefined  = "refined"  // committed after asking for a detailed, shorter or guided message
+	trialRejected = "rejected" // the commit was canceled
+)
+
+// minTrialsPerVariant is how many runs each variant needs before a winner is called
+const minTrialsPerVariant = 20
+
+// Experiment compares prompt templates or models on real commits, defined in .rmit/config.yml
+type Experiment struct {
+	Name     string    `yaml:"name"`
+	Paused   bool      `yaml:"paused"`
+	Variants []Variant `yaml:"variants"`
+}
+
+// Variant is one arm of an experiment. Prompt replaces the default instructions at the top of
+// the prompt and Model the default model; Weight is its share of the traffic.
+type Variant struct {
+	Name   string `yaml:"name"`
+	Model  string `yaml:"model"`
+	Prompt string `yaml:"prompt"`
+	Weight int    `yaml:"weight"`
+}
+
+// Trial is a run enrolled in an experiment, and how the user reacted to its message
+type Trial struct {
+	Experiment string
+	Variant    string
+	Model      string
+	Prompt     string
+	Retries    int
+	Refined    bool
+}
+
+// retry counts a regeneration; runs outside experiments have a nil trial and aren't counted
+func (t *Trial) retry() {
+	if t != nil {
+		t.Retries++
+	}
+}
+
+// refine marks the message as changed on request, e.g. made more detailed or shorter
+func (t *Trial) refine() {
+	if t != nil {
+		t.Refined = true
+	}
+}
+
+// TrialRecord is one experiment run in the results file
+type TrialRecord struct {
+	Time       time.Time `json:"time"`
+	Repo       string    `json:"repo,omitempty"`
+	Experiment string    `json:"experiment"`
+	Variant    string    `json:"variant"`
+	Model      string    `json:"model"`
+	Outcome    string    `json:"outcome"`
+	Retries    int       `json:"retries"`
+}
+
+// startTrial enrolls a run in the first experiment that isn't paused, picking a variant by
+// weight. Runs with a model given on the command line stay out of experiments comparing models,
+// and runs with --no-persist out of all of them.
+func startTrial(experiments []Experiment, model string) *Trial {
+	// Without a record of the outcome a run would only skew the split
+	if noPersist {
+		return nil
+	}
+	for _, experiment := range experiments {
+		if experiment.Paused || len(experiment.Variants) == 0 {
+			continue
+		}
+
+		total := 0
+		for _, variant := range experiment.Variants {
+			if variant.Model != "" && model != "" {
+				return nil
+			}
+			total += max(variant.Weight, 1)
+		}
+		pick := rand.IntN(total)
+		for _, variant := range experiment.Variants {
+			if pick -= max(variant.Weight, 1); pick < 0 {
+				return &Trial{Experiment: experiment.Name, Variant: variant.Name, Model: variant.Model, Prompt: variant.Prompt}
+			}
+		}
+	}
+	return nil
+}
+
+// experimentsPath returns the experiment results location
+func experimentsPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, experimentsFileName), nil
+}
+
+// recordTrial appends the outcome of an experiment run to the results file
+func recordTrial(trial *Trial, model, outcome string) {
+	if trial == nil {
+		return
+	}
+	record := TrialRecord{
+		Time:       time.Now().UTC(),
+		Experiment: trial.Experiment,
+		Variant:    trial.Variant,
+		Model:      model,
+		Outcome:    outcome,
+		Retries:    trial.Retries,
+	}
+	if repo, err := getRepoRoot(); err == nil {
+		record.Repo = repo
+	}
+
+	results, err := experimentsPath()
+	if err == nil {
+		err = appendJSONLine(results, record)
+	}
+	if err != nil {
+		log.Printf("Warning: couldn't record experiment result: %v", err)
+	}
+}
+
+// readTrials loads the experiment results, skipping lines that can't be parsed
+func readTrials() ([]TrialRecord, error) {
+	results, err := experimentsPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(results)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open experiment results: %w", err)
+	}
+	defer f.Close()
+
+	var records []TrialRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record TrialRecord
+		if json.Unmarshal(scanner.Bytes(), &record) == nil {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
+
+// VariantResult tallies the outcomes of one variant
+type VariantResult struct {
+	Variant  string
+	Runs     int
+	Accepted int
+	Refined  int
+	Rejected int
+	Retries  int
+}
+
+// acceptRate is the share of runs committed with the message as generated
+func (r *VariantResult) acceptRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Accepted) / float64(r.Runs)
+}
+
+// tallyTrials groups results by experiment and variant, both sorted by name
+func tallyTrials(records []TrialRecord) (map[string][]*VariantResult, []string) {
+	byVariant := make(map[string]map[string]*VariantResult)
+	for _, record := range records {
+		if byVariant[record.Experiment] == nil {
+			byVariant[record.Experiment] = make(map[string]*VariantResult)
+		}
+		result := byVariant[record.Experiment][record.Variant]
+		if result == nil {
+			result = &VariantResult{Variant: record.Variant}
+			byVariant[record.Experiment][record.Variant] = result
+		}
+		result.Runs++
+		result.Retries += record.Retries
+		switch record.Outcome {
+		case trialAccepted:
+			result.Accepted++
+		case trialRefined:
+			result.Refined++
+		case trialRejected:
+			result.Rejected++
+		}
+	}
+
+	results := make(map[string][]*VariantResult)
+	var names []string
+	for name, variants := range byVariant {
+		names = append(names, name)
+		for _, result := range variants {
+			results[name] = append(results[name], result)
+		}
+		sort.Slice(results[name], func(i, j int) bool { return results[name][i].Variant < results[name][j].Variant })
+	}
+	sort.Strings(names)
+	return results, names
+}
+
+// compareVariants says which of the two best variants is ahead, using a two-proportion z-test on
+// their accept rates to tell a real difference from noise
+func compareVariants(results []*VariantResult) string {
+	if len(results) < 2 {
+		return "needs at least two variants with results"
+	}
+	ranked := append([]*VariantResult(nil), results...)
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].acceptRate() > ranked[j].acceptRate() })
+	best, next := ranked[0], ranked[1]
+	if best.Runs < minTrialsPerVariant || next.Runs < minTrialsPerVariant {
+		return fmt.Sprintf("not enough data yet, each variant needs %d runs", minTrialsPerVariant)
+	}
+
+	pooled := float64(best.Accepted+next.Accepted) / float64(best.Runs+next.Runs)
+	stderr := math.Sqrt(pooled * (1 - pooled) * (1/float64(best.Runs) + 1/float64(next.Runs)))
+	if stderr == 0 {
+		return "no difference between the variants"
+	}
+	z := (best.acceptRate() - next.acceptRate()) / stderr
+	if z < 1.96 {
+		return fmt.Sprintf("%s leads %s, but the difference isn't significant yet (z = %.2f)", best.Variant, next.Variant, z)
+	}
+	return fmt.Sprintf("%s beats %s on accept rate (z = %.2f, significant at 95%%)", best.Variant, next.Variant, z)
+}
+
+// newExperimentsCmd creates the experiments command that reports on experiment results
+func newExperimentsCmd() *cobra.Command {
+	experimentsCmd := &cobra.Command{
+		Use:   "experiments",
+		Short: "Analyze prompt and model experiments",
+		Long: "Experiments defined in " + repoConfigFile + " split generations between variants (prompt templates or models). " +
+			"How each message was received is recorded in ~/" + experimentsFileName + ".",
+	}
+
+	var repo string
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Compare the accept rates of experiment variants",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			records, err := readTrials()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error reading experiment results:"), err)
+			}
+			var matched []TrialRecord
+			for _, record := range records {
+				if repo == "" || strings.Contains(record.Repo, repo) {
+					matched = append(matched, record)
+				}
+			}
+			if len(matched) == 0 {
+				fmt.Printf("%s\n", yellow("No experiment results recorded yet"))
+				return
+			}
+
+			results, names := tallyTrials(matched)
+			for _, name := range names {
+				fmt.Printf("%s\n", blue("🧪 EXPERIMENT: "+name))
+				fmt.Print(rule())
+				fmt.Printf("  %-16s %6s %9s %8s %9s %8s %8s\n", "variant", "runs", "accepted", "refined", "rejected", "retries", "accept")
+				for _, result := range results[name] {
+					fmt.Printf("  %-16s %6d %9d %8d %9d %8.1f %s\n", result.Variant, result.Runs, result.Accepted, result.Refined, result.Rejected,
+						float64(result.Retries)/float64(result.Runs), cyan(fmt.Sprintf("%7.0f%%", result.acceptRate()*100)))
+				}
+				fmt.Print(rule())
+				fmt.Printf("%s %s\n\n", green("verdict:"), compareVariants(results[name]))
+			}
+		},
+	}
+	reportCmd.Flags().StringVar(&repo, "repo", "", "Only count runs in repositories whose path contains this")
+
+	experimentsCmd.AddCommand(reportCmd)
+	return experimentsCmd
+}
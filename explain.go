@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// explainDiffLimit caps the diff sent along with the message to explain, the choices are made
+// from the whole diff but explaining them doesn't need all of it
+const explainDiffLimit = 12000
+
+// explainPrompt asks why a commit message was chosen for a diff, in the terms of the commit
+// style: the type and scope for conventions that have them, the gitmoji, or only the subject
+func explainPrompt(config *Config, diff, message string) string {
+	var choice string
+	switch commitStyle(config) {
+	case styleConventional, styleAngular:
+		choice = "why this type fits the changes better than the other types, and why this scope (or why there is none)"
+	case styleGitmoji:
+		choice = "why this gitmoji fits the changes better than the others"
+	default:
+		choice = "how the subject follows the team's convention"
+	}
+
+	files := parseDiff(diff)
+	changes := promptDiff(diff, files, summarizeFiles(files))
+	if len(changes) > explainDiffLimit {
+		changes = changes[:explainDiffLimit] + "\n[diff truncated]"
+	}
+
+	return "A developer learning to write good commit messages wants to understand why this commit message was written for " +
+		"these changes. In at most five short bullet points, explain " + choice + ", what the key change is and why the " +
+		"subject leads with it, and what else in the diff was considered but left to the body or left out, and why. " +
+		"Don't rewrite the message, only respond with the bullet points.\n\n" +
+		"Commit message:\n" + message + "\n\nChanges:\n" + changes
+}
+
+// explainChoices asks the model for the reasoning behind a commit message, for --explain-choices.
+// The explanation is only shown, it's never part of the commit.
+func explainChoices(config *Config, model, diff, message string) (string, error) {
+	explanation, err := askModel(config, model, explainPrompt(config, diff, message))
+	if err != nil {
+		return "", fmt.Errorf("failed to explain the message: %w", err)
+	}
+	return strings.TrimSpace(stripCodeFence(explanation)), nil
+}
+
+// printExplanation shows why the model chose a message. It's there to learn from, so a failure
+// is only a warning.
+func printExplanation(config *Config, model, diff, message string) {
+	ui.Info("\n🎓 Asking why this message was chosen...")
+	explanation, err := explainChoices(config, model, diff, message)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("⚠️  %v", err))
+		return
+	}
+	ui.Panel("🎓 WHY THIS MESSAGE (not committed):", explanation)
+}
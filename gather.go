@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+// PromptContext is what a prompt is built from, gathered from git and the working tree
+type PromptContext struct {
+	Diff        string
+	ProjectInfo string
+	RepoConfig  *RepoConfig
+}
+
+// gatherPromptContext collects the prompt context concurrently, as each part waits on git or the
+// file system on its own. The diff is collected with collectDiff unless it is nil, and the project
+// information unless the prompt is subject-only. Project information only adds context, so its
+// error is a warning; the other errors are all returned together, so one failure doesn't hide
+// another.
+func gatherPromptContext(opts GenerateOptions, collectDiff func() (string, error)) (*PromptContext, error) {
+	gathered := &PromptContext{}
+	var diffErr, projectErr, repoConfigErr error
+	var wg sync.WaitGroup
+	run := func(part func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			part()
+		}()
+	}
+
+	if collectDiff != nil {
+		run(func() { gathered.Diff, diffErr = collectDiff() })
+	}
+	if !opts.SubjectOnly {
+		run(func() { gathered.ProjectInfo, projectErr = getProjectInfo() })
+	}
+	run(func() { gathered.RepoConfig, repoConfigErr = loadRepoConfig() })
+	wg.Wait()
+
+	if err := errors.Join(diffErr, repoConfigErr); err != nil {
+		return nil, err
+	}
+	if projectErr != nil {
+		log.Printf("Warning: couldn't get project info: %v", projectErr)
+	}
+	return gathered, nil
+}
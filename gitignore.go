@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxIgnorePromptPaths caps how many untracked paths are shown to the model
+const maxIgnorePromptPaths = 200
+
+// ignoredDirs are directories that hold dependencies, build output, caches or editor state
+var ignoredDirs = map[string]string{
+	"node_modules":     "dependencies",
+	"bower_components": "dependencies",
+	".venv":            "virtual environment",
+	"venv":             "virtual environment",
+	"dist":             "build output",
+	"build":            "build output",
+	"out":              "build output",
+	"target":           "build output",
+	".next":            "build output",
+	".nuxt":            "build output",
+	".gradle":          "build cache",
+	".terraform":       "provider cache",
+	"__pycache__":      "bytecode cache",
+	".pytest_cache":    "test cache",
+	".mypy_cache":      "type checker cache",
+	"coverage":         "coverage report",
+	".idea":            "editor settings",
+}
+
+// ignoredFiles are file name patterns of artifacts, logs and OS clutter
+var ignoredFiles = map[string]string{
+	"*.log":     "log file",
+	"*.pyc":     "bytecode",
+	"*.class":   "bytecode",
+	"*.o":       "object file",
+	"*.exe":     "executable",
+	"*.swp":     "editor swap file",
+	".DS_Store": "OS metadata",
+	"Thumbs.db": "OS metadata",
+}
+
+// envFilePattern matches local environment files; templates such as .env.example are meant to be shared
+var envFilePattern = regexp.MustCompile(`^\.env(\.[\w.-]+)?$`)
+
+// IgnoreSuggestion is a .gitignore entry for untracked files
+type IgnoreSuggestion struct {
+	Entry  string
+	Reason string
+	Paths  []string
+}
+
+// untrackedPaths lists untracked paths relative to the repository root, with untracked
+// directories collapsed into one entry ending in a slash
+func untrackedPaths() ([]string, error) {
+	defer profilePhase(phaseGit)()
+	out, err := gitOutput("ls-files", "--others", "--exclude-standard", "--directory", "--full-name", "--", ":/")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// isEnvFile reports whether a file name is a local environment file
+func isEnvFile(name string) bool {
+	if !envFilePattern.MatchString(name) {
+		return false
+	}
+	for _, shared := range []string{".example", ".sample", ".template", ".dist", ".defaults"} {
+		if strings.HasSuffix(name, shared) {
+			return false
+		}
+	}
+	return true
+}
+
+// ignoreMatches reports whether a .gitignore entry covers a path, for the simple entries
+// suggested here: directory names, file names and globs
+func ignoreMatches(entry, p string) bool {
+	entry = strings.TrimPrefix(entry, "/")
+	if dir, ok := strings.CutSuffix(entry, "/"); ok {
+		return p == dir+"/" || strings.HasPrefix(p, dir+"/") || strings.Contains(p, "/"+dir+"/")
+	}
+	if matched, _ := path.Match(entry, p); matched {
+		return true
+	}
+	matched, _ := path.Match(entry, path.Base(strings.TrimSuffix(p, "/")))
+	return matched
+}
+
+// heuristicIgnores suggests entries for untracked paths that look like artifacts or env files
+func heuristicIgnores(paths []string) []*IgnoreSuggestion {
+	var suggestions []*IgnoreSuggestion
+	byEntry := make(map[string]*IgnoreSuggestion)
+	add := func(entry, reason, p string) {
+		s, ok := byEntry[entry]
+		if !ok {
+			s = &IgnoreSuggestion{Entry: entry, Reason: reason}
+			byEntry[entry] = s
+			suggestions = append(suggestions, s)
+		}
+		s.Paths = append(s.Paths, p)
+	}
+
+paths:
+	for _, p := range paths {
+		parts := strings.Split(strings.TrimSuffix(p, "/"), "/")
+		for i, part := range parts {
+			// The last part is only a directory if git collapsed it into one
+			if i == len(parts)-1 && !strings.HasSuffix(p, "/") {
+				break
+			}
+			if reason, ok := ignoredDirs[part]; ok {
+				add(part+"/", reason, p)
+				continue paths
+			}
+		}
+
+		name := parts[len(parts)-1]
+		if isEnvFile(name) {
+			add(name, "environment file, may contain secrets", p)
+			continue
+		}
+		for pattern, reason := range ignoredFiles {
+			if matched, _ := path.Match(pattern, name); matched {
+				add(pattern, reason, p)
+				break
+			}
+		}
+	}
+	return suggestions
+}
+
+// modelIgnores asks the model which other untracked paths should be ignored. Only paths are
+// sent, never file contents.
+func modelIgnores(config *Config, model string, paths []string, suggestions []*IgnoreSuggestion) ([]*IgnoreSuggestion, error) {
+	var rest []string
+	for _, p := range paths {
+		covered := false
+		for _, s := range suggestions {
+			if ignoreMatches(s.Entry, p) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			rest = append(rest, p)
+		}
+	}
+	if len(rest) == 0 {
+		return nil, nil
+	}
+	if len(rest) > maxIgnorePromptPaths {
+		rest = rest[:maxIgnorePromptPaths]
+	}
+
+	var known []string
+	for _, s := range suggestions {
+		known = append(known, s.Entry)
+	}
+	prompt := "These paths are untracked in a git repository; paths ending in / are whole directories. " +
+		"The .gitignore entries " + strings.Join(known, ", ") + " are already suggested. " +
+		"Which of the remaining paths are build artifacts, compiled binaries, dependencies, caches, logs, editor files or local environment files that should be ignored? " +
+		"Source code, documentation and configuration meant to be shared must not be ignored. " +
+		"Respond only with .gitignore entries, one per line, or NONE.\n\n" + strings.Join(rest, "\n")
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries that don't match any untracked path are made up and dropped
+	var extra []*IgnoreSuggestion
+	for _, line := range strings.Split(response, "\n") {
+		entry := strings.Trim(strings.TrimSpace(line), "`-* ")
+		if entry == "" || entry == "NONE" || strings.HasPrefix(entry, "#") || strings.HasPrefix(entry, "!") {
+			continue
+		}
+		s := &IgnoreSuggestion{Entry: entry, Reason: "suggested by the model"}
+		for _, p := range rest {
+			if ignoreMatches(entry, p) {
+				s.Paths = append(s.Paths, p)
+			}
+		}
+		if len(s.Paths) > 0 {
+			extra = append(extra, s)
+		}
+	}
+	return extra, nil
+}
+
+// appendGitignore adds entries to the .gitignore at the repository root
+func appendGitignore(entries []string) error {
+	root, err := getRepoRoot()
+	if err != nil {
+		return err
+	}
+	gitignore := filepath.Join(root, ".gitignore")
+
+	existing, err := os.ReadFile(gitignore)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+	content := strings.Join(entries, "\n") + "\n"
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		content = "\n" + content
+	}
+
+	f, err := os.OpenFile(gitignore, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open .gitignore: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		return fmt.Errorf("failed to write .gitignore: %w", err)
+	}
+	return nil
+}
+
+// offerGitignore looks for untracked artifacts and env files, which committing every change
+// would include, and offers to ignore them. Without a prompt, e.g. with --commit, the
+// suggestions are only listed.
+func offerGitignore(config *Config, model string, interactive bool) {
+	paths, err := untrackedPaths()
+	if err != nil {
+		log.Printf("Warning: couldn't list untracked files: %v", err)
+		return
+	}
+	suggestions := heuristicIgnores(paths)
+	if len(suggestions) == 0 {
+		return
+	}
+
+	if interactive && config.Server == "" {
+		extra, err := modelIgnores(config, model, paths, suggestions)
+		if err != nil {
+			log.Printf("Warning: couldn't get .gitignore suggestions from the model: %v", err)
+		}
+		suggestions = append(suggestions, extra...)
+	}
+
+	lines := []string{"\n🙈 Untracked files that look like they shouldn't be committed:"}
+	for _, s := range suggestions {
+		shown := strings.Join(s.Paths, ", ")
+		if len(s.Paths) > 3 {
+			shown = fmt.Sprintf("%s and %d more", strings.Join(s.Paths[:3], ", "), len(s.Paths)-3)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", cyan(s.Entry), yellow("("+s.Reason+")"), shown))
+	}
+	if !interactive {
+		lines = append(lines, "They will be committed; add them to .gitignore to leave them out.")
+	}
+	ui.Warn(strings.Join(lines, "\n"))
+	if !interactive {
+		return
+	}
+
+	answer, err := readUserInput("Add these entries to .gitignore? [Y/n]: ")
+	if err != nil {
+		return
+	}
+	if answer != "y" && answer != "yes" {
+		ui.Warn("⚠️ Leaving .gitignore as it is, these files will be committed")
+		return
+	}
+
+	var entries []string
+	for _, s := range suggestions {
+		entries = append(entries, s.Entry)
+	}
+	if err := appendGitignore(entries); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	ui.Success("✅ Updated .gitignore")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// jsExtensions are the files console.log, debugger and .only leftovers are looked for in
+var jsExtensions = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".vue", ".svelte"}
+
+// GuardRule is a kind of line that is almost never meant to be committed
+type GuardRule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	Extensions []string // files the rule applies to, all files if empty
+}
+
+// guardRules are checked against every added line before committing
+var guardRules = []GuardRule{
+	{Name: "conflict marker", Pattern: regexp.MustCompile(`^(?:(?:<<<<<<<|\|\|\|\|\|\|\||>>>>>>>)(?: |$)|=======$)`)},
+	{Name: "TODO(remove)", Pattern: regexp.MustCompile(`(?i)\bTODO\s*\(\s*remove\s*\)`)},
+	{Name: "debug statement", Pattern: regexp.MustCompile(`\bconsole\.log\(|^\s*debugger;?\s*$`), Extensions: jsExtensions},
+	{Name: "debug statement", Pattern: regexp.MustCompile(`\bfmt\.Println\(`), Extensions: []string{".go"}},
+	{Name: "focused test", Pattern: regexp.MustCompile(`\b(?:describe|context|suite|it|test)\.only\(|\bf(?:it|describe)\(`), Extensions: jsExtensions},
+	{Name: "focused test", Pattern: regexp.MustCompile(`\bF(?:It|Describe|Context|When|Entry|DescribeTable|Specify)\(`), Extensions: []string{".go"}},
+}
+
+// GuardFinding is an added line matching a guard rule
+type GuardFinding struct {
+	Path string
+	Line int
+	Rule string
+	Text string
+}
+
+// appliesTo reports whether a rule checks the given file
+func (r GuardRule) appliesTo(path string) bool {
+	if len(r.Extensions) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range r.Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// findGuardViolations returns added lines with conflict markers, debug leftovers, TODO(remove)
+// comments and focused tests
+func findGuardViolations(files []*FileDiff) []GuardFinding {
+	var findings []GuardFinding
+	for _, f := range files {
+		forEachAddedLine(f, func(line int, text string) {
+			for _, rule := range guardRules {
+				if rule.appliesTo(f.Path) && rule.Pattern.MatchString(text) {
+					findings = append(findings, GuardFinding{Path: f.Path, Line: line, Rule: rule.Name, Text: strings.TrimSpace(text)})
+					break
+				}
+			}
+		})
+	}
+	return findings
+}
+
+// printGuardWarning lists the offending lines
+func printGuardWarning(findings []GuardFinding) {
+	if len(findings) == 0 {
+		return
+	}
+
+	lines := []string{"\n🚧 These added lines look like they shouldn't be committed:"}
+	for _, finding := range findings {
+		text := finding.Text
+		if len(text) > 80 {
+			text = text[:77] + "..."
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s %s", cyan(fmt.Sprintf("%s:%d", finding.Path, finding.Line)), yellow("("+finding.Rule+")"), text))
+	}
+	ui.Warn(strings.Join(lines, "\n"))
+}
+
+// confirmGuard asks whether to commit despite the findings. Anything but yes keeps the commit
+// from being made.
+func confirmGuard(findings []GuardFinding) bool {
+	if len(findings) == 0 {
+		return true
+	}
+
+	question := fmt.Sprintf("Commit anyway with %d suspicious line(s)? [y/N]: ", len(findings))
+	if answer, ok := assumeAnswer(question); ok {
+		return answer == "y"
+	}
+	line, err := ui.Prompt(question)
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(line)
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// helpPages holds the help topics and examples, written as text files in help/
+//
+//go:embed help/*.md
+var helpPages embed.FS
+
+// helpTopics are the pages shown by rmit help <topic>
+var helpTopics = []string{"providers", "templates", "hooks", "privacy"}
+
+// readHelpPage returns a page's title, from its first "# " line, and the text after it
+func readHelpPage(name string) (string, string, error) {
+	data, err := helpPages.ReadFile(path.Join("help", name+".md"))
+	if err != nil {
+		return "", "", fmt.Errorf("no help page %q", name)
+	}
+	title, body, _ := strings.Cut(string(data), "\n")
+	return strings.TrimPrefix(title, "# "), strings.TrimSpace(body), nil
+}
+
+// newHelpTopicCmds creates a command per help topic. They have no Run, so Cobra lists them under
+// "Additional help topics" and rmit help <topic> shows the page.
+func newHelpTopicCmds() []*cobra.Command {
+	var cmds []*cobra.Command
+	for _, topic := range helpTopics {
+		title, body, err := readHelpPage(topic)
+		if err != nil {
+			log.Fatalf("%s %v", red("Error loading help:"), err)
+		}
+		cmds = append(cmds, &cobra.Command{
+			Use:   topic,
+			Short: title,
+			Long:  title + "\n\n" + body,
+		})
+	}
+	return cmds
+}
+
+// ExampleWorkflow is a titled, copy-pasteable set of commands
+type ExampleWorkflow struct {
+	Title    string
+	Commands string
+}
+
+// exampleWorkflows parses help/examples.md, where every workflow starts with a "# " title
+func exampleWorkflows() []ExampleWorkflow {
+	data, err := helpPages.ReadFile("help/examples.md")
+	if err != nil {
+		return nil
+	}
+	var workflows []ExampleWorkflow
+	for _, section := range strings.Split("\n"+string(data), "\n# ")[1:] {
+		title, commands, _ := strings.Cut(section, "\n")
+		workflows = append(workflows, ExampleWorkflow{Title: strings.TrimSpace(title), Commands: strings.TrimSpace(commands)})
+	}
+	return workflows
+}
+
+// newExamplesCmd creates the examples command that prints copy-pasteable workflows
+func newExamplesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "examples [search]",
+		Short: "Show copy-pasteable workflows",
+		Long: "Show common rmit workflows as commands ready to copy, with titles as shell comments. " +
+			"Give a word to only show the workflows that mention it, e.g. rmit examples commit.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			shown := 0
+			for _, workflow := range exampleWorkflows() {
+				if len(args) == 1 && !strings.Contains(strings.ToLower(workflow.Title+"\n"+workflow.Commands), strings.ToLower(args[0])) {
+					continue
+				}
+				fmt.Printf("%s\n%s\n\n", blue("# "+workflow.Title), workflow.Commands)
+				shown++
+			}
+			if shown == 0 {
+				log.Fatalf("%s %q. Run rmit examples to see them all", red("No examples mention"), args[0])
+			}
+		},
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// hookMarker identifies hooks written by rmit hook install, so they can be updated and removed
+const hookMarker = "# Installed by rmit hook install"
+
+// hookPath returns where git looks for the prepare-commit-msg hook, following core.hooksPath
+func hookPath() (string, error) {
+	out, err := gitCommand("rev-parse", "--git-path", "hooks/prepare-commit-msg").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	return filepath.Abs(strings.TrimSpace(string(out)))
+}
+
+// hookScript returns the prepare-commit-msg hook that runs rmit. rmit is called by name when
+// it's on the PATH, so upgrades don't break the hook, and by its full path otherwise.
+func hookScript() string {
+	command := "rmit"
+	if _, err := exec.LookPath("rmit"); err != nil {
+		if executable, err := os.Executable(); err == nil {
+			command = "'" + strings.ReplaceAll(executable, "'", `'\''`) + "'"
+		}
+	}
+	return "#!/bin/sh\n" + hookMarker + "\n" + command + ` hook run --stage prepare-commit-msg "$@"` + "\n"
+}
+
+// installHook writes the prepare-commit-msg hook. A hook rmit didn't write is only replaced with force.
+func installHook(force bool) (string, error) {
+	path, err := hookPath()
+	if err != nil {
+		return "", err
+	}
+	if existing, err := os.ReadFile(path); err == nil && !strings.Contains(string(existing), hookMarker) && !force {
+		return "", fmt.Errorf("%s already exists and wasn't installed by rmit, use --force to replace it", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("failed to create hooks directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hookScript()), 0755); err != nil {
+		return "", fmt.Errorf("failed to write hook: %w", err)
+	}
+	// WriteFile keeps the mode of a file that already exists
+	if err := os.Chmod(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to make hook executable: %w", err)
+	}
+	return path, nil
+}
+
+// uninstallHook removes the prepare-commit-msg hook if rmit installed it
+func uninstallHook() (string, error) {
+	path, err := hookPath()
+	if err != nil {
+		return "", err
+	}
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("no prepare-commit-msg hook is installed")
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !strings.Contains(string(existing), hookMarker) {
+		return "", fmt.Errorf("%s wasn't installed by rmit, remove it yourself if you're sure", path)
+	}
+	if err := os.Remove(path); err != nil {
+		return "", fmt.Errorf("failed to remove hook: %w", err)
+	}
+	return path, nil
+}
+
+// getStagedDiff gets only the staged changes, which is what git is about to commit
+func getStagedDiff() (string, error) {
+	stagedCmd := gitCommand("diff", "--staged")
+	stagedOutput, err := stagedCmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged changes: %w", err)
+	}
+
+	if len(stagedOutput) == 0 {
+		return "", fmt.Errorf("no staged changes detected")
+	}
+
+	return string(stagedOutput), nil
+}
+
+// hasUserMessage reports whether a commit message file already contains non-comment text
+func hasUserMessage(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// runPrepareCommitMsgHook fills the commit message file git passes to prepare-commit-msg.
+// Arguments follow git's hook convention: <file> [source] [sha]. The pre-commit framework
+// only passes the file and exposes the source through PRE_COMMIT_COMMIT_MSG_SOURCE.
+func runPrepareCommitMsgHook(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing commit message file argument")
+	}
+	msgFile := args[0]
+
+	source := os.Getenv("PRE_COMMIT_COMMIT_MSG_SOURCE")
+	if len(args) > 1 {
+		source = args[1]
+	}
+
+	// Any source (message, template, merge, squash, commit) means the user or git
+	// already provided a message, e.g. via -m, -F, -c or --amend. Never override it.
+	if source != "" {
+		return nil
+	}
+
+	existing, err := os.ReadFile(msgFile)
+	if err != nil {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+	if hasUserMessage(string(existing)) {
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	diff, err := getStagedDiff()
+	if err != nil {
+		return err
+	}
+
+	trailers, err := collectTrailers(config, nil)
+	if err != nil {
+		return err
+	}
+
+	opts := GenerateOptions{Trailers: trailers}
+	if config.ReadIntent {
+		opts.Context, _ = readIntent(parseDiff(diff))
+	}
+
+	message, err := suggestCommitMessage(config, diff, opts)
+	if err != nil {
+		return err
+	}
+
+	// Hooks can't prompt, so missing required trailers are left for the user to fill in the editor
+	content := message + "\n" + trailerPlaceholders(missingTrailers(trailers, config.RequiredTrailers)) + string(existing)
+	if err := os.WriteFile(msgFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+
+	return nil
+}
+
+// newHookCmd creates the hook command used by git hooks and the pre-commit framework
+func newHookCmd() *cobra.Command {
+	var (
+		stage      string
+		force      bool
+		model      string
+		noRewrites bool
+	)
+
+	hookCmd := &cobra.Command{
+		Use:   "hook",
+		Short: "Git hook integration",
+		Long:  "Commands used when rmit runs from git hooks or the pre-commit framework",
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run [hook args...]",
+		Short: "Run rmit as a git hook",
+		Long: "Run rmit as a git hook. With --stage prepare-commit-msg the generated message is written " +
+			"to the commit message file, unless a message was already provided (e.g. with git commit -m). " +
+			"With --stage update or pre-receive, on a git server, the messages of pushed commits are checked like rmit check " +
+			"and a push that breaks the convention is rejected with a suggested message for each offending commit.",
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			switch stage {
+			case "prepare-commit-msg":
+				// git shows the hook's output around the editor, so nothing but problems is printed and
+				// nothing is asked
+				ui = newQuietUI(os.Stderr, os.Stdin)
+				if assumedAnswer == "" {
+					assumedAnswer = "n"
+				}
+				// A failing hook would abort the commit, so errors only produce a warning
+				if err := runPrepareCommitMsgHook(args); err != nil {
+					fmt.Fprintf(os.Stderr, "%s %v\n", yellow("rmit: commit message not generated:"), err)
+				}
+			case "update", "pre-receive":
+				var updates []RefUpdate
+				if stage == "update" {
+					if len(args) != 3 {
+						log.Fatalf("%s the update hook takes <ref> <old> <new>", red("Error:"))
+					}
+					updates = []RefUpdate{{Ref: args[0], Old: args[1], New: args[2]}}
+				} else {
+					var err error
+					if updates, err = readRefUpdates(os.Stdin); err != nil {
+						log.Fatalf("%s %v", red("Error reading ref updates:"), err)
+					}
+				}
+				// Server hooks have no terminal, and their output goes back to the pusher
+				ui = newQuietUI(os.Stderr, os.Stdin)
+				accepted, err := runReceiveHook(os.Stderr, updates, model, noRewrites)
+				if err != nil {
+					// Failing closed keeps unchecked commits out; the pusher sees why
+					fmt.Fprintf(os.Stderr, "rmit: push rejected, commit messages couldn't be checked: %v\n", err)
+					os.Exit(1)
+				}
+				if !accepted {
+					os.Exit(1)
+				}
+			default:
+				log.Fatalf("%s %s. Supported stages are: prepare-commit-msg, update, pre-receive", red("Unsupported hook stage:"), stage)
+			}
+		},
+	}
+	runCmd.Flags().StringVar(&stage, "stage", "prepare-commit-msg", "Hook stage to run: prepare-commit-msg, update or pre-receive")
+	runCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for suggested messages in update and pre-receive (overrides default_model from config)")
+	runCmd.Flags().BoolVar(&noRewrites, "no-rewrites", false, "Reject pushes in update and pre-receive without asking the model for suggested messages")
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Install a prepare-commit-msg hook that fills in the message for git commit",
+		Long: "Installs a prepare-commit-msg hook in this repository, so plain git commit opens the editor with a generated " +
+			"message to edit or accept. Messages given with -m or -F, merges and amends are left alone. " +
+			"Follows core.hooksPath; an existing hook that rmit didn't write is only replaced with --force.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := installHook(force)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error installing hook:"), err)
+			}
+			ui.Success("✅ Installed " + path)
+			ui.Info("git commit will now open the editor with a generated message")
+		},
+	}
+	installCmd.Flags().BoolVar(&force, "force", false, "Replace an existing prepare-commit-msg hook")
+
+	uninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "Remove the prepare-commit-msg hook installed by rmit",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			path, err := uninstallHook()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error removing hook:"), err)
+			}
+			ui.Success("✅ Removed " + path)
+		},
+	}
+
+	hookCmd.AddCommand(runCmd, installCmd, uninstallCmd)
+	return hookCmd
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Files with fewer changed lines than this are sent to the model unsummarized
+const largeFileDiffLines = 40
+
+var (
+	// localePattern matches locale codes such as fr, pt-BR or zh_Hans
+	localePattern = regexp.MustCompile(`^[a-z]{2,3}([-_][A-Za-z]{2,4})?$`)
+	// jsonEntryPattern matches a "key": value line in a translation JSON file
+	jsonEntryPattern = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*(.+?),?\s*$`)
+	// jestSnapshotPattern matches the start of a Jest snapshot entry
+	jestSnapshotPattern = regexp.MustCompile("^exports\\[`(.+)`\\]")
+)
+
+// Directory names that hold translation files
+var translationDirs = map[string]bool{
+	"locales":      true,
+	"locale":       true,
+	"i18n":         true,
+	"l10n":         true,
+	"lang":         true,
+	"langs":        true,
+	"translations": true,
+}
+
+// translationLocale returns the locale of a translation file and whether the file is one
+func translationLocale(p string) (string, bool) {
+	ext := path.Ext(p)
+	name := strings.TrimSuffix(path.Base(p), ext)
+
+	switch ext {
+	case ".po", ".pot":
+		if localePattern.MatchString(name) {
+			return name, true
+		}
+		// gettext layout: locale/fr/LC_MESSAGES/messages.po
+		for _, part := range strings.Split(path.Dir(p), "/") {
+			if localePattern.MatchString(part) {
+				return part, true
+			}
+		}
+		return name, true
+	case ".json", ".yaml", ".yml", ".arb":
+	default:
+		return "", false
+	}
+
+	// Only files inside translation directories, named after a locale or nested in a locale directory
+	parts := strings.Split(path.Dir(p), "/")
+	inTranslationDir := false
+	for _, part := range parts {
+		if translationDirs[strings.ToLower(part)] {
+			inTranslationDir = true
+		}
+	}
+	if !inTranslationDir {
+		return "", false
+	}
+	if localePattern.MatchString(name) {
+		return name, true
+	}
+	if last := parts[len(parts)-1]; localePattern.MatchString(last) {
+		return last, true
+	}
+	return "", false
+}
+
+// translationEntries extracts translated key → value pairs from changed JSON/YAML lines
+func translationEntries(lines []string) map[string]string {
+	entries := make(map[string]string)
+	for _, line := range lines {
+		if match := jsonEntryPattern.FindStringSubmatch(line); match != nil && match[2] != "{" {
+			entries[match[1]] = match[2]
+		}
+	}
+	return entries
+}
+
+// countMsgstr counts changed gettext translations
+func countMsgstr(lines []string) int {
+	count := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "msgstr") {
+			count++
+		}
+	}
+	return count
+}
+
+// TranslationChange counts string changes in one translation file
+type TranslationChange struct {
+	Locale  string
+	Added   int
+	Updated int
+	Removed int
+}
+
+// detectTranslationChange counts added, updated and removed strings in a translation file
+func detectTranslationChange(f *FileDiff) (TranslationChange, bool) {
+	locale, ok := translationLocale(f.Path)
+	if !ok {
+		return TranslationChange{}, false
+	}
+
+	change := TranslationChange{Locale: locale}
+
+	if strings.HasSuffix(f.Path, ".po") || strings.HasSuffix(f.Path, ".pot") {
+		// gettext entries are matched by position, since usually only msgstr lines change
+		added := countMsgstr(f.Added)
+		removed := countMsgstr(f.Removed)
+		change.Updated = min(added, removed)
+		change.Added = added - change.Updated
+		change.Removed = removed - change.Updated
+		return change, true
+	}
+
+	added := translationEntries(f.Added)
+	removed := translationEntries(f.Removed)
+	for key, value := range added {
+		old, existed := removed[key]
+		switch {
+		case !existed:
+			change.Added++
+		case old != value:
+			change.Updated++
+		}
+	}
+	for key := range removed {
+		if _, exists := added[key]; !exists {
+			change.Removed++
+		}
+	}
+
+	return change, true
+}
+
+// String formats the change, e.g. "updated 14, added 2 translation strings"
+func (c TranslationChange) String() string {
+	var parts []string
+	if c.Updated > 0 {
+		parts = append(parts, fmt.Sprintf("updated %d", c.Updated))
+	}
+	if c.Added > 0 {
+		parts = append(parts, fmt.Sprintf("added %d", c.Added))
+	}
+	if c.Removed > 0 {
+		parts = append(parts, fmt.Sprintf("removed %d", c.Removed))
+	}
+	if len(parts) == 0 {
+		return "reformatted"
+	}
+	return strings.Join(parts, ", ") + " translation strings"
+}
+
+// isSnapshotFile reports whether a path is a test snapshot or golden file
+func isSnapshotFile(p string) bool {
+	return strings.HasSuffix(p, ".snap") || strings.HasSuffix(p, ".golden") || strings.Contains(p, "__snapshots__/")
+}
+
+// countSnapshots counts snapshot entries in changed lines
+func countSnapshots(lines []string) map[string]bool {
+	names := make(map[string]bool)
+	for _, line := range lines {
+		if match := jestSnapshotPattern.FindStringSubmatch(line); match != nil {
+			names[match[1]] = true
+		}
+	}
+	return names
+}
+
+// assetSummary summarizes large translation and snapshot diffs instead of sending thousands of lines
+func assetSummary(f *FileDiff) *FileSummary {
+	if f.Binary || len(f.Added)+len(f.Removed) < largeFileDiffLines {
+		return nil
+	}
+
+	if change, ok := detectTranslationChange(f); ok {
+		return &FileSummary{
+			Path:  f.Path,
+			Title: "Translation changes",
+			Lines: []string{fmt.Sprintf("%s in %s", change, change.Locale)},
+		}
+	}
+
+	if isSnapshotFile(f.Path) {
+		line := fmt.Sprintf("+%d/-%d lines", len(f.Added), len(f.Removed))
+		names := countSnapshots(f.Added)
+		for name := range countSnapshots(f.Removed) {
+			names[name] = true
+		}
+		if len(names) > 0 {
+			line = fmt.Sprintf("updated %d snapshots (%s)", len(names), line)
+		}
+		return &FileSummary{Path: f.Path, Title: "Test snapshot changes", Lines: []string{line}}
+	}
+
+	return nil
+}
+
+// translationInsights aggregates translation changes across all files, e.g. "updated 14 translation strings in fr, de"
+func translationInsights(files []*FileDiff) []string {
+	total := TranslationChange{}
+	locales := make(map[string]bool)
+
+	for _, f := range files {
+		change, ok := detectTranslationChange(f)
+		if !ok {
+			continue
+		}
+		total.Added += change.Added
+		total.Updated += change.Updated
+		total.Removed += change.Removed
+		locales[change.Locale] = true
+	}
+
+	if len(locales) == 0 {
+		return nil
+	}
+
+	var names []string
+	for locale := range locales {
+		names = append(names, locale)
+	}
+	sort.Strings(names)
+
+	return []string{fmt.Sprintf("Translations: %s in %s", total, strings.Join(names, ", "))}
+}
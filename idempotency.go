@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Duplicate-send protection
+const (
+	idempotencyTTL     = 2 * time.Minute
+	idempotencyWait    = 2 * time.Minute
+	previousCommitScan = 50
+)
+
+// generationAttempts counts generations per diff in this process, so retries get a fresh key
+var (
+	generationAttempts   = make(map[string]int)
+	generationAttemptsMu sync.Mutex
+)
+
+// nextIdempotencyKey returns a key for the next generation from this prompt. The same prompt
+// generated concurrently by two rmit processes gets the same key; an explicit retry gets a new one.
+func nextIdempotencyKey(model, prompt string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + prompt))
+	hash := hex.EncodeToString(sum[:12])
+	generationAttemptsMu.Lock()
+	defer generationAttemptsMu.Unlock()
+	generationAttempts[hash]++
+	return fmt.Sprintf("rmit-%s-%d", hash, generationAttempts[hash])
+}
+
+// idempotencyPath returns where a response, or the lock for an in-flight request, is kept
+func idempotencyPath(key, ext string) string {
+	return filepath.Join(os.TempDir(), key+ext)
+}
+
+// cachedResponse returns the response stored for a key if it is still fresh
+func cachedResponse(key string) ([]byte, bool) {
+	responsePath := idempotencyPath(key, ".json")
+	info, err := os.Stat(responsePath)
+	if err != nil || time.Since(info.ModTime()) > idempotencyTTL {
+		return nil, false
+	}
+	body, err := os.ReadFile(responsePath)
+	return body, err == nil
+}
+
+// sendIdempotent sends a request at most once per idempotency key. If another rmit process is
+// already sending the same request, it waits for that response instead of sending a duplicate.
+func sendIdempotent(key string, send func() ([]byte, error)) ([]byte, error) {
+	// Sharing goes through files holding the response, which --no-persist rules out
+	if noPersist {
+		return send()
+	}
+	if body, ok := cachedResponse(key); ok {
+		return body, nil
+	}
+
+	lockPath := idempotencyPath(key, ".lock")
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if errors.Is(err, os.ErrExist) {
+		// Only trust locks young enough to belong to a request that is still running
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) < idempotencyWait {
+			for deadline := info.ModTime().Add(idempotencyWait); time.Now().Before(deadline); time.Sleep(500 * time.Millisecond) {
+				if body, ok := cachedResponse(key); ok {
+					return body, nil
+				}
+				if _, statErr := os.Stat(lockPath); statErr != nil {
+					break
+				}
+			}
+		}
+		os.Remove(lockPath)
+	} else if err == nil {
+		lock.Close()
+		defer os.Remove(lockPath)
+	}
+
+	body, err := send()
+	if err != nil {
+		return nil, err
+	}
+	_ = os.WriteFile(idempotencyPath(key, ".json"), body, 0600)
+	return body, nil
+}
+
+// patchIDs runs git patch-id over a diff or log output and returns "patch-id commit" pairs
+func patchIDs(input string) ([][2]string, error) {
+	cmd := gitCommand("patch-id", "--stable")
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute patch id: %w", err)
+	}
+
+	var ids [][2]string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			ids = append(ids, [2]string{fields[0], fields[1]})
+		}
+	}
+	return ids, nil
+}
+
+// findPreviousCommit looks for a recent commit, including ones dropped by a reset or amend,
+// with exactly the same changes as the diff, and returns its hash and message
+func findPreviousCommit(diff string) (string, string, bool) {
+	defer profilePhase(phaseGit)()
+	ids, err := patchIDs(diff)
+	if err != nil || len(ids) != 1 {
+		return "", "", false
+	}
+	want := ids[0][0]
+
+	// The reflog remembers commits that are no longer on the branch, e.g. after git reset --soft
+	history, err := gitCommand("log", "-g", "-p", fmt.Sprintf("-n%d", previousCommitScan), "--format=commit %H").Output()
+	if err != nil {
+		return "", "", false
+	}
+	commits, err := patchIDs(string(history))
+	if err != nil {
+		return "", "", false
+	}
+
+	for _, commit := range commits {
+		if commit[0] != want {
+			continue
+		}
+		message, err := gitCommand("log", "-1", "--format=%B", commit[1]).Output()
+		if err != nil {
+			return "", "", false
+		}
+		return commit[1], strings.TrimSpace(string(message)), true
+	}
+	return "", "", false
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// ignoreFile lists a repository's own ignore patterns, one per line, like .gitignore
+const ignoreFile = ".rmitignore"
+
+// defaultIgnorePatterns are files that dominate a diff without saying anything about the change:
+// lockfiles, minified assets and vendored dependencies
+var defaultIgnorePatterns = func() []string {
+	var patterns []string
+	for name := range lockfiles {
+		patterns = append(patterns, name)
+	}
+	sort.Strings(patterns)
+	return append(patterns, "*.min.js", "*.min.css", "*.js.map", "vendor/", "third_party/", "node_modules/")
+}()
+
+// ignorePattern is one line of an ignore list
+type ignorePattern struct {
+	glob    string
+	negated bool // a pattern starting with ! sends matching files again
+	dir     bool // a pattern ending with / matches everything under a directory
+	rooted  bool // a pattern with a / in it matches from the repository root, others match at any depth
+}
+
+// parseIgnorePatterns parses ignore patterns in .gitignore syntax, skipping blank lines and
+// # comments
+func parseIgnorePatterns(lines []string) []ignorePattern {
+	var patterns []ignorePattern
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var pattern ignorePattern
+		line, pattern.negated = strings.CutPrefix(line, "!")
+		line, pattern.dir = strings.CutSuffix(line, "/")
+		pattern.rooted = strings.Contains(line, "/")
+		pattern.glob = strings.TrimPrefix(line, "/")
+		if pattern.glob != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// matches reports whether the pattern matches a path relative to the repository root. A pattern
+// matching one of the path's directories matches the path too.
+func (p ignorePattern) matches(file string) bool {
+	parts := strings.Split(file, "/")
+	for i := range parts {
+		// Directory patterns only match the directories, not the file itself
+		if p.dir && i == len(parts)-1 {
+			break
+		}
+		candidate := parts[i]
+		if p.rooted {
+			candidate = strings.Join(parts[:i+1], "/")
+		}
+		if ok, _ := path.Match(p.glob, candidate); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIgnoreList parses the comma separated patterns rmit set ignore_patterns takes
+func parseIgnoreList(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// ignorePatterns returns the patterns in effect: the defaults, then ignore_patterns, then the
+// repository's .rmitignore. Like in .gitignore, the last pattern that matches a file decides.
+func ignorePatterns(config *Config) []ignorePattern {
+	lines := append(append([]string{}, defaultIgnorePatterns...), config.IgnorePatterns...)
+	if content, err := readRepoFile(ignoreFile); err == nil {
+		lines = append(lines, strings.Split(content, "\n")...)
+	}
+	return parseIgnorePatterns(lines)
+}
+
+// ignoredDiffFiles returns the files of a diff that are left out of the prompt. The model is still
+// told they changed, and they're committed like any other file.
+func ignoredDiffFiles(config *Config, files []*FileDiff) []string {
+	patterns := ignorePatterns(config)
+	var ignored []string
+	for _, f := range files {
+		if isIgnored(patterns, f.Path) {
+			ignored = append(ignored, f.Path)
+		}
+	}
+	return ignored
+}
+
+// isIgnored reports whether ignore patterns leave a file out: the last pattern that matches it
+// decides
+func isIgnored(patterns []ignorePattern, file string) bool {
+	ignore := false
+	for _, pattern := range patterns {
+		if pattern.matches(file) {
+			ignore = !pattern.negated
+		}
+	}
+	return ignore
+}
+
+// ignoredInsight tells the model about files that changed but were left out by ignore patterns
+func ignoredInsight(ignored []string) []string {
+	if len(ignored) == 0 {
+		return nil
+	}
+	return []string{"These files also changed but were left out of the diff as lockfiles, generated or vendored files: " + strings.Join(ignored, ", ")}
+}
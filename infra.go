@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// hclBlockPattern matches the start of a top level Terraform block
+var hclBlockPattern = regexp.MustCompile(`^(resource|data|module)\s+"([^"]+)"(?:\s+"([^"]+)")?\s*\{`)
+
+// parseTerraform extracts resource, data and module blocks from HCL source, keyed by address
+func parseTerraform(src string) map[string]string {
+	blocks := make(map[string]string)
+	var address string
+	var body strings.Builder
+	depth := 0
+
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if depth == 0 {
+			match := hclBlockPattern.FindStringSubmatch(trimmed)
+			if match == nil {
+				continue
+			}
+			switch match[1] {
+			case "resource":
+				address = match[2] + "." + match[3]
+			case "data":
+				address = "data." + match[2] + "." + match[3]
+			case "module":
+				address = "module." + match[2]
+			}
+			body.Reset()
+		}
+
+		// Whitespace and comments don't change infrastructure
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(trimmed, "//") {
+			body.WriteString(strings.Join(strings.Fields(trimmed), " ") + "\n")
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 {
+			depth = 0
+			blocks[address] = body.String()
+		}
+	}
+
+	return blocks
+}
+
+// parseKubernetes extracts Kubernetes objects from a (multi-document) manifest, keyed by kind/namespace/name.
+// It returns false if the file contains no Kubernetes objects.
+func parseKubernetes(src string) (map[string]string, bool) {
+	objects := make(map[string]string)
+	decoder := yaml.NewDecoder(strings.NewReader(src))
+
+	for {
+		var doc map[string]any
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, false
+		}
+
+		kind, _ := doc["kind"].(string)
+		if _, ok := doc["apiVersion"]; !ok || kind == "" {
+			continue
+		}
+		metadata, _ := doc["metadata"].(map[string]any)
+		name, _ := metadata["name"].(string)
+		key := kind + "/" + name
+		if namespace, ok := metadata["namespace"].(string); ok {
+			key = kind + "/" + namespace + "/" + name
+		}
+
+		// Re-marshal so formatting differences don't count as changes
+		canonical, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, false
+		}
+		objects[key] = string(canonical)
+	}
+
+	return objects, len(objects) > 0
+}
+
+// parsePulumiYAML extracts resources from a Pulumi YAML program, keyed by type and name
+func parsePulumiYAML(src string) map[string]string {
+	var doc struct {
+		Resources map[string]map[string]any `yaml:"resources"`
+	}
+	resources := make(map[string]string)
+	if err := yaml.Unmarshal([]byte(src), &doc); err != nil {
+		return resources
+	}
+
+	for name, resource := range doc.Resources {
+		typ, _ := resource["type"].(string)
+		canonical, _ := yaml.Marshal(resource)
+		resources[typ+"."+name] = string(canonical)
+	}
+
+	return resources
+}
+
+// planSummary describes block changes the way terraform plan does
+func planSummary(before, after map[string]string, noun string) []string {
+	var added, changed, destroyed []string
+	for address, body := range after {
+		old, existed := before[address]
+		switch {
+		case !existed:
+			added = append(added, address)
+		case old != body:
+			changed = append(changed, address)
+		}
+	}
+	for address := range before {
+		if _, exists := after[address]; !exists {
+			destroyed = append(destroyed, address)
+		}
+	}
+
+	if len(added)+len(changed)+len(destroyed) == 0 {
+		return nil
+	}
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(destroyed)
+
+	lines := []string{fmt.Sprintf("%d %s to add, %d to change, %d to destroy", len(added), noun, len(changed), len(destroyed))}
+	for _, address := range added {
+		lines = append(lines, "add "+address)
+	}
+	for _, address := range changed {
+		lines = append(lines, "change "+address)
+	}
+	for _, address := range destroyed {
+		lines = append(lines, "destroy "+address)
+	}
+
+	return lines
+}
+
+// infraSummary summarizes Terraform, Pulumi YAML and Kubernetes manifest changes as added/changed/destroyed resources
+func infraSummary(f *FileDiff) *FileSummary {
+	ext := path.Ext(f.Path)
+	if f.Binary || (ext != ".tf" && ext != ".yaml" && ext != ".yml") {
+		return nil
+	}
+
+	var oldSrc, newSrc string
+	if !f.New {
+		oldSrc, _ = gitShow("HEAD", f.OldPath)
+	}
+	if !f.Deleted {
+		newSrc, _ = readRepoFile(f.Path)
+	}
+
+	if ext == ".tf" {
+		lines := planSummary(parseTerraform(oldSrc), parseTerraform(newSrc), "resources")
+		if lines == nil {
+			return nil
+		}
+		return &FileSummary{Path: f.Path, Title: "Terraform changes", Lines: lines, InBody: true}
+	}
+
+	if strings.HasPrefix(path.Base(f.Path), "Pulumi.") {
+		lines := planSummary(parsePulumiYAML(oldSrc), parsePulumiYAML(newSrc), "resources")
+		if lines == nil {
+			return nil
+		}
+		return &FileSummary{Path: f.Path, Title: "Pulumi changes", Lines: lines, InBody: true}
+	}
+
+	before, oldOK := parseKubernetes(oldSrc)
+	after, newOK := parseKubernetes(newSrc)
+	if !oldOK && !newOK {
+		return nil
+	}
+	lines := planSummary(before, after, "objects")
+	if lines == nil {
+		return nil
+	}
+	return &FileSummary{Path: f.Path, Title: "Kubernetes changes", Lines: lines, InBody: true}
+}
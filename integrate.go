@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// stdinContextCommand is the shell command integrations run to get a message for the staged changes
+const stdinContextCommand = "git diff --cached | rmit --" + stdinContextFlag
+
+// lazygitCommand is the custom command added to lazygit's files panel
+var lazygitCommand = map[string]any{
+	"key":         "<c-g>",
+	"context":     "files",
+	"description": "Commit with an rmit generated message",
+	"loadingText": "Generating commit message...",
+	"command":     `git commit -e -m "$(` + stdinContextCommand + `)"`,
+	"output":      "terminal",
+}
+
+// tigBinding is the tig status view binding
+const tigBinding = `bind status <Ctrl-G> !sh -c "git commit -e -m \"$(` + stdinContextCommand + `)\""`
+
+// magitSnippet defines a command that inserts the message in magit's commit buffer
+const magitSnippet = `;;; rmit-magit.el --- rmit commit messages for magit  -*- lexical-binding: t -*-
+
+(defun rmit-insert-commit-message ()
+  "Insert an rmit generated message for the staged changes at point."
+  (interactive)
+  (let* ((default-directory (or (magit-toplevel) default-directory))
+         (status nil)
+         (message (with-temp-buffer
+                    (setq status (call-process-shell-command "` + stdinContextCommand + `" nil '(t nil)))
+                    (string-trim (buffer-string)))))
+    (pcase status
+      (0 (insert message))
+      (2 (user-error "rmit: no staged changes"))
+      (_ (user-error "rmit: couldn't generate a commit message (exit code %s)" status)))))
+
+(with-eval-after-load 'git-commit
+  (define-key git-commit-mode-map (kbd "C-c C-g") #'rmit-insert-commit-message))
+
+(provide 'rmit-magit)
+`
+
+// integrationPath returns the config file an integration is written to
+func integrationPath(tool string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	switch tool {
+	case "lazygit":
+		if configFile := os.Getenv("LG_CONFIG_FILE"); configFile != "" {
+			return strings.Split(configFile, ",")[0], nil
+		}
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get config directory: %w", err)
+		}
+		return filepath.Join(configDir, "lazygit", "config.yml"), nil
+	case "tig":
+		return filepath.Join(homeDir, ".tigrc"), nil
+	case "magit":
+		return filepath.Join(homeDir, ".emacs.d", "rmit-magit.el"), nil
+	}
+	return "", fmt.Errorf("unknown tool %q, supported tools are: lazygit, tig, magit", tool)
+}
+
+// addLazygitCommand adds the rmit custom command to a lazygit config, keeping everything else as is
+func addLazygitCommand(content string) (string, bool, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return "", false, fmt.Errorf("failed to parse lazygit config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", false, errors.New("lazygit config is not a mapping")
+	}
+
+	var commands *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "customCommands" {
+			commands = root.Content[i+1]
+		}
+	}
+	if commands == nil {
+		commands = &yaml.Node{Kind: yaml.SequenceNode}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "customCommands"}, commands)
+	}
+
+	// Already integrated if any custom command runs rmit --stdin-context
+	for _, command := range commands.Content {
+		for i := 0; i+1 < len(command.Content); i += 2 {
+			if command.Content[i].Value == "command" && strings.Contains(command.Content[i+1].Value, "--"+stdinContextFlag) {
+				return content, false, nil
+			}
+		}
+	}
+
+	var entry yaml.Node
+	if err := entry.Encode(lazygitCommand); err != nil {
+		return "", false, fmt.Errorf("failed to encode custom command: %w", err)
+	}
+	commands.Content = append(commands.Content, &entry)
+
+	var out strings.Builder
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return "", false, fmt.Errorf("failed to write lazygit config: %w", err)
+	}
+	return out.String(), true, nil
+}
+
+// integrationConfig returns the new content of a tool's config file and whether it changed
+func integrationConfig(tool, existing string) (string, bool, error) {
+	switch tool {
+	case "lazygit":
+		return addLazygitCommand(existing)
+	case "tig":
+		if strings.Contains(existing, "--"+stdinContextFlag) {
+			return existing, false, nil
+		}
+		if existing != "" && !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+		return existing + "# Commit with an rmit generated message\n" + tigBinding + "\n", true, nil
+	case "magit":
+		return magitSnippet, existing != magitSnippet, nil
+	}
+	return "", false, fmt.Errorf("unknown tool %q, supported tools are: lazygit, tig, magit", tool)
+}
+
+// newIntegrateCmd creates the integrate command that sets up rmit in git TUIs and editors
+func newIntegrateCmd() *cobra.Command {
+	var printOnly bool
+
+	integrateCmd := &cobra.Command{
+		Use:   "integrate <lazygit|tig|magit>",
+		Short: "Add rmit to lazygit, tig or magit",
+		Long: "Write a custom command for lazygit, tig or magit that generates a message for the staged changes with " +
+			"rmit --stdin-context. Existing configuration is kept; running it twice does nothing.",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"lazygit", "tig", "magit"},
+		Run: func(cmd *cobra.Command, args []string) {
+			tool := args[0]
+			configPath, err := integrationPath(tool)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+
+			existing, err := os.ReadFile(configPath)
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatalf("%s %v", red("Error reading config:"), err)
+			}
+			content, changed, err := integrationConfig(tool, string(existing))
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+
+			if printOnly {
+				fmt.Print(content)
+				return
+			}
+			if !changed {
+				fmt.Printf("%s %s\n", green("✅ rmit is already set up in"), blue(configPath))
+				return
+			}
+			if noPersist {
+				log.Fatalf("%s %v, use --print to see what would be added to %s", red("Error:"), errNoPersist, configPath)
+			}
+
+			if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+				log.Fatalf("%s %v", red("Error creating config directory:"), err)
+			}
+			if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+				log.Fatalf("%s %v", red("Error writing config:"), err)
+			}
+			fmt.Printf("%s %s\n", green("✅ rmit added to"), blue(configPath))
+
+			switch tool {
+			case "lazygit":
+				fmt.Printf("%s\n", yellow("Press Ctrl+G in the files panel to commit with a generated message."))
+			case "tig":
+				fmt.Printf("%s\n", yellow("Press Ctrl+G in the status view to commit with a generated message."))
+			case "magit":
+				fmt.Printf("%s\n", yellow("Add (load \"~/.emacs.d/rmit-magit.el\") to your init file, then press C-c C-g in the commit buffer."))
+			}
+		},
+	}
+
+	integrateCmd.Flags().BoolVar(&printOnly, "print", false, "Print the resulting config instead of writing it")
+	return integrateCmd
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// intentFile is where authors can write down why they're changing things while they work
+const intentFile = ".rmit/intent.md"
+
+var (
+	// intentMarkerPattern matches "// rmit: ..." and "# TODO(rmit): ..." style comments
+	intentMarkerPattern = regexp.MustCompile(`(?://|#|--|;|/\*|^\s*\*)\s*(?:TODO\s*\(rmit\)|rmit):\s*(.+?)\s*(?:\*/)?\s*$`)
+	// hunkHeaderPattern matches the new file position in a hunk header
+	hunkHeaderPattern = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)`)
+)
+
+// IntentMarker is an rmit: comment added in the diff
+type IntentMarker struct {
+	Path string
+	Line int
+	Text string
+}
+
+// findIntentMarkers returns rmit: comments on added lines, with their line numbers in the new file
+func findIntentMarkers(files []*FileDiff) []IntentMarker {
+	var markers []IntentMarker
+	for _, f := range files {
+		forEachAddedLine(f, func(line int, text string) {
+			if match := intentMarkerPattern.FindStringSubmatch(text); match != nil {
+				markers = append(markers, IntentMarker{Path: f.Path, Line: line, Text: match[1]})
+			}
+		})
+	}
+	return markers
+}
+
+// forEachAddedLine calls fn with each line added to a text file and its line number in the new file
+func forEachAddedLine(f *FileDiff, fn func(line int, text string)) {
+	if f.Binary || f.Deleted {
+		return
+	}
+
+	line := 0
+	for _, text := range strings.Split(f.Text, "\n") {
+		if match := hunkHeaderPattern.FindStringSubmatch(text); match != nil {
+			line, _ = strconv.Atoi(match[1])
+			continue
+		}
+		if line == 0 || strings.HasPrefix(text, "+++ ") {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(text, "+"):
+			fn(line, text[1:])
+			line++
+		case strings.HasPrefix(text, " "):
+			line++
+		}
+	}
+}
+
+// readIntent gathers the author's intent from .rmit/intent.md and rmit: markers in the diff
+func readIntent(files []*FileDiff) (string, []IntentMarker) {
+	var parts []string
+	if content, err := readRepoFile(intentFile); err == nil && strings.TrimSpace(content) != "" {
+		parts = append(parts, strings.TrimSpace(content))
+	}
+
+	markers := findIntentMarkers(files)
+	for _, marker := range markers {
+		parts = append(parts, fmt.Sprintf("%s (%s)", marker.Text, marker.Path))
+	}
+
+	return strings.Join(parts, "\n"), markers
+}
+
+// joinContext combines intent from several sources into a single context string
+func joinContext(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "\n")
+}
+
+// printIntentReminder suggests cleaning up the intent markers and file once they've been used
+func printIntentReminder(markers []IntentMarker) {
+	hasFile := repoFileExists(intentFile)
+	if len(markers) == 0 && !hasFile {
+		return
+	}
+
+	lines := []string{"\n📝 Author intent was read from:"}
+	if hasFile {
+		lines = append(lines, fmt.Sprintf("  %s %s", cyan(intentFile), yellow("(clear it once this commit is done)")))
+	}
+	for _, marker := range markers {
+		lines = append(lines, fmt.Sprintf("  %s %s", cyan(fmt.Sprintf("%s:%d", marker.Path, marker.Line)), yellow("(consider removing the rmit: marker)")))
+	}
+	ui.Info(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,422 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// issueDiffLimit caps how much of the diff is sent when checking requirements
+const issueDiffLimit = 20000
+
+var (
+	// githubIssueURLPattern matches GitHub issue and pull request URLs
+	githubIssueURLPattern = regexp.MustCompile(`^/([^/]+)/([^/]+)/(?:issues|pull)/([0-9]+)`)
+	// gitlabIssueURLPattern matches GitLab issue URLs, including subgroups
+	gitlabIssueURLPattern = regexp.MustCompile(`^/(.+?)/-/issues/([0-9]+)`)
+	// remoteURLPattern splits git@host:owner/repo.git style remotes
+	remoteURLPattern = regexp.MustCompile(`^[^@/]+@([^:]+):(.+)$`)
+)
+
+// Issue is an issue or ticket a commit implements
+type Issue struct {
+	Tracker string // "github" or "gitlab"
+	APIURL  string
+	WebURL  string
+	Repo    string
+	Number  int
+	Title   string
+	Body    string
+}
+
+// Requirement is one requirement of an issue and whether the diff appears to address it
+type Requirement struct {
+	Requirement string `json:"requirement"`
+	Addressed   bool   `json:"addressed"`
+	Evidence    string `json:"evidence"`
+}
+
+// reference returns how a commit message refers to the issue
+func (i *Issue) reference() string {
+	if i.Tracker == "gitlab" {
+		return fmt.Sprintf("%s#%d", i.Repo, i.Number)
+	}
+	return fmt.Sprintf("#%d", i.Number)
+}
+
+// issueFromURL resolves an issue from a GitHub or GitLab URL. Hosts other than github.com and
+// gitlab.com are treated as GitHub Enterprise unless their name contains "gitlab".
+func issueFromURL(raw string) (*Issue, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid issue URL %q", raw)
+	}
+	base := parsed.Scheme + "://" + parsed.Host
+
+	if match := gitlabIssueURLPattern.FindStringSubmatch(parsed.Path); match != nil {
+		number, _ := strconv.Atoi(match[2])
+		return gitlabIssue(base, match[1], number), nil
+	}
+	if match := githubIssueURLPattern.FindStringSubmatch(parsed.Path); match != nil {
+		number, _ := strconv.Atoi(match[3])
+		return githubIssue(base, match[1]+"/"+match[2], number), nil
+	}
+	return nil, fmt.Errorf("unsupported issue URL %q, expected a GitHub or GitLab issue", raw)
+}
+
+// RemoteRepo is the repository a git remote points to on GitHub or GitLab
+type RemoteRepo struct {
+	Name string // the git remote
+	Base string // scheme and host, e.g. https://github.com
+	Path string // owner/repo, or the GitLab project path
+}
+
+// isGitLab reports whether the repository is hosted on GitLab
+func (r *RemoteRepo) isGitLab() bool {
+	return strings.Contains(r.Base, "gitlab")
+}
+
+// remoteRepo resolves where a remote is hosted from its URL
+func remoteRepo(name string) (*RemoteRepo, error) {
+	remote, err := gitOutput("remote", "get-url", name)
+	if err != nil {
+		return nil, fmt.Errorf("no %s remote", name)
+	}
+
+	scheme, host, repoPath := "https", "", ""
+	if match := remoteURLPattern.FindStringSubmatch(remote); match != nil {
+		host, repoPath = match[1], match[2]
+	} else if parsed, err := url.Parse(remote); err == nil && parsed.Host != "" {
+		if parsed.Scheme == "http" {
+			scheme = "http"
+		}
+		host, repoPath = parsed.Host, strings.TrimPrefix(parsed.Path, "/")
+	} else {
+		return nil, fmt.Errorf("can't tell where the %s remote %q is hosted", name, remote)
+	}
+	return &RemoteRepo{Name: name, Base: scheme + "://" + host, Path: strings.TrimSuffix(strings.TrimSuffix(repoPath, "/"), ".git")}, nil
+}
+
+// forkParent returns the repository a GitHub or GitLab repository was forked from, if any
+func forkParent(repo *RemoteRepo) *RemoteRepo {
+	if repo.isGitLab() {
+		body, err := trackerRequest("GET", fmt.Sprintf("%s/api/v4/projects/%s", repo.Base, url.PathEscape(repo.Path)), true, nil)
+		if err != nil {
+			return nil
+		}
+		var project struct {
+			ForkedFrom *struct {
+				Path string `json:"path_with_namespace"`
+			} `json:"forked_from_project"`
+		}
+		if json.Unmarshal(body, &project) != nil || project.ForkedFrom == nil {
+			return nil
+		}
+		return &RemoteRepo{Name: repo.Name, Base: repo.Base, Path: project.ForkedFrom.Path}
+	}
+
+	body, err := trackerRequest("GET", fmt.Sprintf("%s/repos/%s", githubAPI(repo.Base), repo.Path), false, nil)
+	if err != nil {
+		return nil
+	}
+	var repository struct {
+		Parent *struct {
+			FullName string `json:"full_name"`
+		} `json:"parent"`
+	}
+	if json.Unmarshal(body, &repository) != nil || repository.Parent == nil {
+		return nil
+	}
+	return &RemoteRepo{Name: repo.Name, Base: repo.Base, Path: repository.Parent.FullName}
+}
+
+// baseRepo returns the repository issues and pull requests live in. In a fork that is the
+// upstream repository: the given remote if any, else a remote named upstream, else the
+// repository origin was forked from.
+func baseRepo(remote string) (*RemoteRepo, error) {
+	if remote != "" {
+		return remoteRepo(remote)
+	}
+	if repo, err := remoteRepo("upstream"); err == nil {
+		return repo, nil
+	}
+	repo, err := remoteRepo("origin")
+	if err != nil {
+		return nil, err
+	}
+	if parent := forkParent(repo); parent != nil {
+		return parent, nil
+	}
+	return repo, nil
+}
+
+// issueFromNumber resolves an issue number against the base repository
+func issueFromNumber(number int, remote string) (*Issue, error) {
+	repo, err := baseRepo(remote)
+	if err != nil {
+		return nil, fmt.Errorf("%w, pass the issue URL instead", err)
+	}
+	if repo.isGitLab() {
+		return gitlabIssue(repo.Base, repo.Path, number), nil
+	}
+	return githubIssue(repo.Base, repo.Path, number), nil
+}
+
+// githubAPI returns the REST API root for a GitHub or GitHub Enterprise host
+func githubAPI(base string) string {
+	if strings.HasSuffix(base, "://github.com") {
+		return "https://api.github.com"
+	}
+	return base + "/api/v3"
+}
+
+// trackerRequest calls a GitHub or GitLab API, authenticating with GITHUB_TOKEN (or GH_TOKEN)
+// and GITLAB_TOKEN when set, for private repositories
+func trackerRequest(method, endpoint string, gitlab bool, payload []byte) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "rmit/"+rmitVersion)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if gitlab {
+		if token := os.Getenv("GITLAB_TOKEN"); token != "" {
+			req.Header.Set("PRIVATE-TOKEN", token)
+		}
+	} else {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token := githubToken(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s returned status code %d", endpoint, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// githubToken returns the GitHub token from the environment
+func githubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+// githubIssue returns a GitHub issue to fetch
+func githubIssue(base, repo string, number int) *Issue {
+	return &Issue{
+		Tracker: "github",
+		APIURL:  fmt.Sprintf("%s/repos/%s/issues/%d", githubAPI(base), repo, number),
+		WebURL:  fmt.Sprintf("%s/%s/issues/%d", base, repo, number),
+		Repo:    repo,
+		Number:  number,
+	}
+}
+
+// gitlabIssue returns a GitLab issue to fetch
+func gitlabIssue(base, project string, number int) *Issue {
+	return &Issue{
+		Tracker: "gitlab",
+		APIURL:  fmt.Sprintf("%s/api/v4/projects/%s/issues/%d", base, url.PathEscape(project), number),
+		WebURL:  fmt.Sprintf("%s/%s/-/issues/%d", base, project, number),
+		Repo:    project,
+		Number:  number,
+	}
+}
+
+// fetch loads the issue's title and description
+func (i *Issue) fetch() error {
+	body, err := trackerRequest("GET", i.APIURL, i.Tracker == "gitlab", nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch issue %s: %w", i.WebURL, err)
+	}
+
+	// GitHub calls the description "body", GitLab "description"
+	var issue struct {
+		Title       string `json:"title"`
+		Body        string `json:"body"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(body, &issue); err != nil {
+		return fmt.Errorf("failed to parse issue: %w", err)
+	}
+	i.Title = issue.Title
+	i.Body = issue.Body + issue.Description
+	return nil
+}
+
+// issueContext frames the issue as the intent behind the changes
+func issueContext(issue *Issue) string {
+	return fmt.Sprintf("These changes implement issue %s: %s\n\n%s\n\n"+
+		"In the body, explain which of the issue's requirements the changes implement and how.",
+		issue.reference(), issue.Title, strings.TrimSpace(issue.Body))
+}
+
+// checkRequirements asks the model which of the issue's requirements the diff addresses
+func checkRequirements(config *Config, model string, issue *Issue, diff string) ([]Requirement, error) {
+	if len(diff) > issueDiffLimit {
+		diff = diff[:issueDiffLimit] + "\n[diff truncated]"
+	}
+
+	prompt := "List the concrete requirements of this issue and, for each, whether the diff appears to address it. " +
+		"Respond only with a JSON object of the form " +
+		`{"requirements": [{"requirement": "<short requirement>", "addressed": true, "evidence": "<where in the diff, or why not>"}]}` +
+		".\n\nIssue: " + issue.Title + "\n" + strings.TrimSpace(issue.Body) + "\n\nDiff:\n" + diff
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var coverage struct {
+		Requirements []Requirement `json:"requirements"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &coverage); err != nil {
+		return nil, fmt.Errorf("the model didn't return a requirements list")
+	}
+	return coverage.Requirements, nil
+}
+
+// printRequirements shows which requirements the diff addresses, flagging the ones it doesn't
+func printRequirements(requirements []Requirement) {
+	var lines []string
+	unaddressed := 0
+	for _, r := range requirements {
+		if r.Addressed {
+			lines = append(lines, fmt.Sprintf("  %s %s %s", green("✓"), r.Requirement, cyan(r.Evidence)))
+		} else {
+			unaddressed++
+			lines = append(lines, fmt.Sprintf("  %s %s %s", red("✗"), r.Requirement, yellow(r.Evidence)))
+		}
+	}
+	ui.Panel("📋 REQUIREMENTS:", strings.Join(lines, "\n"))
+	if unaddressed > 0 {
+		ui.Warn(fmt.Sprintf("⚠️  %d requirement(s) don't appear to be addressed by these changes", unaddressed))
+	}
+}
+
+// newFromIssueCmd creates the from-issue command that writes a message linking changes to an issue
+func newFromIssueCmd() *cobra.Command {
+	var (
+		model      string
+		autoCommit bool
+		stageAll   bool
+		remote     string
+	)
+
+	fromIssueCmd := &cobra.Command{
+		Use:   "from-issue <number|url>",
+		Short: "Generate a commit message that links the changes to an issue's requirements",
+		Long: "Fetch a GitHub or GitLab issue, by URL or by number in the base repository (upstream when working in a fork), and generate a commit message " +
+			"explaining how the changes implement it, with a Refs trailer. Requirements the changes don't appear to address are flagged. " +
+			"Set GITHUB_TOKEN or GITLAB_TOKEN for private repositories.",
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			var issue *Issue
+			if number, err := strconv.Atoi(strings.TrimPrefix(args[0], "#")); err == nil {
+				issue, err = issueFromNumber(number, remote)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+			} else if issue, err = issueFromURL(args[0]); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			if err := issue.fetch(); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			fmt.Printf("%s %s %s\n", green("🎫 Issue"), cyan(issue.reference()), issue.Title)
+
+			var commitPaths []string
+			if stageAll {
+				commitPaths = allPathspec
+			}
+			diff, err := describedDiff(commitPaths, nil)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error getting git diff:"), err)
+			}
+
+			// Refer to the issue rather than closing it; not every requirement may be done
+			commitTrailers, err := collectTrailers(config, []string{"Refs: " + issue.reference()})
+			if err != nil {
+				log.Fatalf("%s %v", red("Invalid trailer:"), err)
+			}
+
+			opts := GenerateOptions{
+				Model:    model,
+				Trailers: commitTrailers,
+				Context:  issueContext(issue),
+			}
+			if config.Provenance {
+				opts.Transcript = &Transcript{}
+			}
+
+			ui.Info("\nGenerating commit message...")
+			message, err := generateCommitMessage(config, diff, opts)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error generating commit message:"), err)
+			}
+
+			ui.Panel("✨ GENERATED COMMIT MESSAGE:", message)
+
+			requirements, err := checkRequirements(config, model, issue, diff)
+			if err != nil {
+				ui.Warn(fmt.Sprintf("⚠️ Couldn't check the issue's requirements: %v", err))
+			} else {
+				printRequirements(requirements)
+			}
+
+			if !autoCommit {
+				response, err := readUserInput("Create commit with this message? [y/n]: ")
+				if err != nil {
+					log.Fatalf("%s %v", red("Error reading user input:"), err)
+				}
+				if response != "y" && response != "yes" {
+					ui.Warn("⚠️ Commit canceled")
+					return
+				}
+			}
+			if err := makeAttestedCommit(config, message, opts.Transcript, commitPaths, false); err != nil {
+				log.Fatalf("%s %v", red("Error creating commit:"), err)
+			}
+			ui.Success("✅ Commit created successfully")
+		},
+	}
+
+	fromIssueCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation")
+	fromIssueCmd.Flags().StringVar(&remote, "remote", "", "Remote of the repository the issue is in (default: upstream if present, else the repository origin was forked from, else origin)")
+	fromIssueCmd.Flags().BoolVarP(&autoCommit, "commit", "c", false, "Automatically create commit with generated message")
+	fromIssueCmd.Flags().BoolVarP(&stageAll, "all", "a", false, "Stage and commit every change, untracked files included, instead of only the staged changes")
+	return fromIssueCmd
+}
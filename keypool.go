@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key pool rotation
+const (
+	keyPoolFileName  = ".rmit_keys"
+	keyFailureWindow = 10 * time.Minute
+)
+
+// PoolKey is an API key with its share of requests in weighted round-robin rotation
+type PoolKey struct {
+	Key    string
+	Weight int
+}
+
+// keyPoolState is shared between rmit processes so rotation and failures carry over between runs
+type keyPoolState struct {
+	Next     int                        `json:"next"`
+	Failures map[string]keyFailureState `json:"failures"`
+}
+
+// keyFailureState tracks consecutive failures of one key, identified by hash
+type keyFailureState struct {
+	Count int   `json:"count"`
+	Last  int64 `json:"last"`
+}
+
+// parsePoolKey parses "key" or "key*weight"
+func parsePoolKey(entry string) (PoolKey, error) {
+	key, weightStr, hasWeight := strings.Cut(strings.TrimSpace(entry), "*")
+	poolKey := PoolKey{Key: strings.TrimSpace(key), Weight: 1}
+	if err := validateAPIKey(poolKey.Key); err != nil {
+		return PoolKey{}, err
+	}
+	if hasWeight {
+		weight, err := strconv.Atoi(strings.TrimSpace(weightStr))
+		if err != nil || weight < 1 {
+			return PoolKey{}, fmt.Errorf("invalid weight %q, expected a positive number", weightStr)
+		}
+		poolKey.Weight = weight
+	}
+	return poolKey, nil
+}
+
+// parseAPIKeys parses a comma separated key pool as used by rmit set api_keys
+func parseAPIKeys(value string) ([]string, error) {
+	var keys []string
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		if _, err := parsePoolKey(entry); err != nil {
+			return nil, err
+		}
+		keys = append(keys, strings.TrimSpace(entry))
+	}
+	return keys, nil
+}
+
+// apiKeyPool returns the configured key pool, or the single api_key when no pool is set
+func apiKeyPool(config *Config) []PoolKey {
+	var pool []PoolKey
+	for _, entry := range config.APIKeys {
+		if poolKey, err := parsePoolKey(entry); err == nil {
+			pool = append(pool, poolKey)
+		}
+	}
+	if len(pool) == 0 && config.APIKey != "" {
+		pool = append(pool, PoolKey{Key: config.APIKey, Weight: 1})
+	}
+	return pool
+}
+
+// validateAPIKeyPool checks that at least one API key is configured, if the provider needs one
+func validateAPIKeyPool(config *Config) error {
+	if len(apiKeyPool(config)) == 0 && configProvider(config).NeedsKey() {
+		return validateAPIKey("")
+	}
+	return nil
+}
+
+// apiKeySecrets returns every configured key, for redaction
+func apiKeySecrets(config *Config) []string {
+	var secrets []string
+	for _, poolKey := range apiKeyPool(config) {
+		secrets = append(secrets, poolKey.Key)
+	}
+	return secrets
+}
+
+// keyPoolPath returns the file holding the shared rotation state
+func keyPoolPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, keyPoolFileName), nil
+}
+
+// readKeyPoolState loads the rotation state, starting fresh if there is none
+func readKeyPoolState() *keyPoolState {
+	if noPersist && memoryKeyPoolState != nil {
+		return memoryKeyPoolState
+	}
+	state := &keyPoolState{Failures: make(map[string]keyFailureState)}
+	if statePath, err := keyPoolPath(); err == nil {
+		if data, err := os.ReadFile(statePath); err == nil {
+			_ = json.Unmarshal(data, state)
+		}
+	}
+	if state.Failures == nil {
+		state.Failures = make(map[string]keyFailureState)
+	}
+	return state
+}
+
+// memoryKeyPoolState is the rotation state of this process with --no-persist
+var memoryKeyPoolState *keyPoolState
+
+// writeKeyPoolState saves the rotation state; failing to save only loses rotation history
+func writeKeyPoolState(state *keyPoolState) {
+	if noPersist {
+		memoryKeyPoolState = state
+		return
+	}
+	statePath, err := keyPoolPath()
+	if err != nil {
+		return
+	}
+	if data, err := json.Marshal(state); err == nil {
+		_ = os.WriteFile(statePath, data, 0600)
+	}
+}
+
+// keyAvailableAt returns when a key can next be used, considering rate limits and recent failures
+func keyAvailableAt(config *Config, key string, state *keyPoolState, limits map[string]int64) time.Time {
+	id := rateLimitKey(chatURL(config), key)
+	available := time.Unix(limits[id], 0)
+	if failure, ok := state.Failures[id]; ok && failure.Count > 0 {
+		if retry := time.Unix(failure.Last, 0).Add(keyFailureWindow); retry.After(available) {
+			available = retry
+		}
+	}
+	return available
+}
+
+// selectAPIKey picks the next key in weighted round-robin order, skipping keys that are
+// rate limited or recently failed. When every key is blocked, the one available soonest is used.
+func selectAPIKey(config *Config) string {
+	pool := apiKeyPool(config)
+	switch len(pool) {
+	case 0:
+		return config.APIKey
+	case 1:
+		return pool[0].Key
+	}
+
+	// Each key appears in the rotation as many times as its weight
+	var rotation []string
+	for _, poolKey := range pool {
+		for i := 0; i < poolKey.Weight; i++ {
+			rotation = append(rotation, poolKey.Key)
+		}
+	}
+
+	state := readKeyPoolState()
+	limits := readRateLimits()
+	now := time.Now()
+
+	best := ""
+	var bestAt time.Time
+	for i := 0; i < len(rotation); i++ {
+		key := rotation[(state.Next+i)%len(rotation)]
+		at := keyAvailableAt(config, key, state, limits)
+		if !at.After(now) {
+			state.Next = (state.Next + i + 1) % len(rotation)
+			writeKeyPoolState(state)
+			return key
+		}
+		if best == "" || at.Before(bestAt) {
+			best, bestAt = key, at
+		}
+	}
+	return best
+}
+
+// recordKeyResult tracks whether a request with a key succeeded, so failing keys are skipped for a while
+func recordKeyResult(config *Config, key string, ok bool) {
+	if len(apiKeyPool(config)) <= 1 {
+		return
+	}
+
+	state := readKeyPoolState()
+	id := rateLimitKey(chatURL(config), key)
+	if ok {
+		if _, failed := state.Failures[id]; !failed {
+			return
+		}
+		delete(state.Failures, id)
+	} else {
+		failure := state.Failures[id]
+		failure.Count++
+		failure.Last = time.Now().Unix()
+		state.Failures[id] = failure
+	}
+	writeKeyPoolState(state)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// The API key's entry in the OS keyring
+const (
+	keyringService = "rmit"
+	keyringAccount = "api_key"
+)
+
+// errKeyringNotFound is returned when the keyring has no API key for rmit
+var errKeyringNotFound = errors.New("no API key for rmit in the OS keyring")
+
+// storeAPIKey sets the API key, writing it to the OS keyring when the configuration keeps it
+// there. Otherwise it's written to the config file by saveConfig.
+func storeAPIKey(config *Config, key string) error {
+	if config.APIKeyKeyring {
+		if err := keyringSet(keyringService, keyringAccount, key); err != nil {
+			return fmt.Errorf("failed to store the API key in the OS keyring: %w", err)
+		}
+	}
+	config.APIKey = key
+	return nil
+}
+
+// The API key read from the OS keyring. The configuration is loaded several times per run, the
+// keyring is only asked once.
+var (
+	keyringOnce sync.Once
+	keyringKey  string
+)
+
+// loadKeyringAPIKey reads the API key from the OS keyring when the config file says it's kept
+// there. A missing keyring is reported, and rmit goes on without a key.
+func loadKeyringAPIKey(config *Config) {
+	if !config.APIKeyKeyring {
+		return
+	}
+	keyringOnce.Do(func() {
+		key, err := keyringGet(keyringService, keyringAccount)
+		if err != nil {
+			log.Printf("Warning: failed to read the API key from the OS keyring: %v", err)
+			return
+		}
+		keyringKey = key
+	})
+	if keyringKey != "" {
+		config.APIKey = keyringKey
+	}
+}
+
+// forgetKeyringAPIKey removes the API key from the OS keyring once it's kept in the config file
+// again. It's only a warning when that fails, the key isn't used anymore.
+func forgetKeyringAPIKey() {
+	if err := keyringDelete(keyringService, keyringAccount); err != nil && !errors.Is(err, errKeyringNotFound) {
+		log.Printf("Warning: failed to remove the API key from the OS keyring: %v", err)
+	}
+}
+
+// apiKeyStatus describes a set API key for rmit get, without showing it
+func apiKeyStatus(config *Config) string {
+	if config.APIKeyKeyring {
+		return "[SET, OS keyring]"
+	}
+	return "[SET]"
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// securityNotFound is the exit code of security when the keychain has no such item
+const securityNotFound = 44
+
+// keyringGet reads a password from the macOS Keychain
+func keyringGet(service, account string) (string, error) {
+	out, err := exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w").Output()
+	if err != nil {
+		return "", keychainError(err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
+
+// keyringSet stores a password in the macOS Keychain, replacing an existing one. The password
+// is passed on stdin so it never shows up in the process list.
+func keyringSet(service, account, secret string) error {
+	cmd := exec.Command("security", "-i")
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+		keychainQuote(service), keychainQuote(account), keychainQuote(secret)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security failed: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// keyringDelete removes a password from the macOS Keychain
+func keyringDelete(service, account string) error {
+	if err := exec.Command("security", "delete-generic-password", "-s", service, "-a", account).Run(); err != nil {
+		return keychainError(err)
+	}
+	return nil
+}
+
+// keychainQuote quotes an argument for security's interactive mode, which splits words like a
+// shell
+func keychainQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// keychainError turns security's exit code for a missing item into errKeyringNotFound
+func keychainError(err error) error {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == securityNotFound {
+		return errKeyringNotFound
+	}
+	return fmt.Errorf("security failed: %w", err)
+}
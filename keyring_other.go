@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !windows
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// errKeyringUnsupported is returned on systems without a supported OS keyring
+var errKeyringUnsupported = fmt.Errorf("no OS keyring is supported on %s", runtime.GOOS)
+
+func keyringGet(service, account string) (string, error) {
+	return "", errKeyringUnsupported
+}
+
+func keyringSet(service, account, secret string) error {
+	return errKeyringUnsupported
+}
+
+func keyringDelete(service, account string) error {
+	return errKeyringUnsupported
+}
@@ -0,0 +1,62 @@
+//go:build linux || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// The Secret Service (GNOME Keyring, KWallet) is reached through secret-tool from libsecret
+
+// keyringGet reads a password from the Secret Service
+func keyringGet(service, account string) (string, error) {
+	out, err := secretTool(nil, "lookup", "service", service, "account", account)
+	if err != nil {
+		return "", err
+	}
+	// lookup prints nothing, and may exit 1, when there's no such item
+	if out == "" {
+		return "", errKeyringNotFound
+	}
+	return out, nil
+}
+
+// keyringSet stores a password in the Secret Service, replacing an existing one. The password
+// is passed on stdin so it never shows up in the process list.
+func keyringSet(service, account, secret string) error {
+	_, err := secretTool(strings.NewReader(secret), "store", "--label", "rmit API key", "service", service, "account", account)
+	return err
+}
+
+// keyringDelete removes a password from the Secret Service
+func keyringDelete(service, account string) error {
+	_, err := secretTool(nil, "clear", "service", service, "account", account)
+	return err
+}
+
+// secretTool runs secret-tool and returns its output
+func secretTool(stdin *strings.Reader, args ...string) (string, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return "", errors.New("secret-tool not found, install libsecret-tools (or libsecret) for the Secret Service")
+	}
+	cmd := exec.Command("secret-tool", args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("secret-tool %s failed: %s", args[0], message)
+		}
+		if args[0] == "lookup" {
+			return "", errKeyringNotFound
+		}
+		return "", fmt.Errorf("secret-tool %s failed: %w", args[0], err)
+	}
+	return strings.TrimSuffix(string(out), "\n"), nil
+}
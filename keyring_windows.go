@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// The Windows Credential Manager, through advapi32
+var (
+	advapi32      = windows.NewLazySystemDLL("advapi32.dll")
+	procCredRead  = advapi32.NewProc("CredReadW")
+	procCredWrite = advapi32.NewProc("CredWriteW")
+	procCredDel   = advapi32.NewProc("CredDeleteW")
+	procCredFree  = advapi32.NewProc("CredFree")
+)
+
+// Values of the CREDENTIALW fields rmit uses
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+)
+
+// credential is the CREDENTIALW structure
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// credentialTarget names a credential by service and account, e.g. rmit:api_key
+func credentialTarget(service, account string) (*uint16, error) {
+	return windows.UTF16PtrFromString(service + ":" + account)
+}
+
+// keyringGet reads a password from the Credential Manager
+func keyringGet(service, account string) (string, error) {
+	target, err := credentialTarget(service, account)
+	if err != nil {
+		return "", err
+	}
+	var cred *credential
+	if ret, _, err := procCredRead.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&cred))); ret == 0 {
+		return "", credentialError("CredRead", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(cred)))
+	return string(unsafe.Slice(cred.CredentialBlob, cred.CredentialBlobSize)), nil
+}
+
+// keyringSet stores a password in the Credential Manager, replacing an existing one
+func keyringSet(service, account, secret string) error {
+	target, err := credentialTarget(service, account)
+	if err != nil {
+		return err
+	}
+	userName, err := windows.UTF16PtrFromString(account)
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.New("the API key is empty")
+	}
+	blob := []byte(secret)
+	cred := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           userName,
+	}
+	if ret, _, err := procCredWrite.Call(uintptr(unsafe.Pointer(&cred)), 0); ret == 0 {
+		return credentialError("CredWrite", err)
+	}
+	return nil
+}
+
+// keyringDelete removes a password from the Credential Manager
+func keyringDelete(service, account string) error {
+	target, err := credentialTarget(service, account)
+	if err != nil {
+		return err
+	}
+	if ret, _, err := procCredDel.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0); ret == 0 {
+		return credentialError("CredDelete", err)
+	}
+	return nil
+}
+
+// credentialError turns the Credential Manager's error for a missing credential into
+// errKeyringNotFound
+func credentialError(call string, err error) error {
+	if errors.Is(err, windows.ERROR_NOT_FOUND) {
+		return errKeyringNotFound
+	}
+	return fmt.Errorf("%s failed: %w", call, err)
+}
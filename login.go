@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// OpenRouter OAuth PKCE endpoints
+const (
+	openRouterAuthURL    = "https://openrouter.ai/auth"
+	openRouterKeyURL     = "https://openrouter.ai/api/v1/auth/keys"
+	loginCallbackPath    = "/callback"
+	loginTimeout         = 5 * time.Minute
+	defaultLoginCallback = 3000
+)
+
+// pkceVerifier returns a random code verifier and its S256 challenge
+func pkceVerifier() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate code verifier: %w", err)
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// openBrowser opens a URL in the default browser
+func openBrowser(target string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", target)
+	default:
+		cmd = exec.Command("xdg-open", target)
+	}
+	return cmd.Start()
+}
+
+// waitForAuthCode serves the OAuth callback on localhost and returns the authorization code
+func waitForAuthCode(listener net.Listener) (string, error) {
+	codes := make(chan string, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != loginCallbackPath {
+			http.NotFound(w, r)
+			return
+		}
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			http.Error(w, "Missing authorization code", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(w, "Authorization received. You can close this window and return to the terminal.")
+		select {
+		case codes <- code:
+		default:
+		}
+	})}
+
+	go server.Serve(listener)
+	defer server.Shutdown(context.Background())
+
+	select {
+	case code := <-codes:
+		return code, nil
+	case <-time.After(loginTimeout):
+		return "", fmt.Errorf("timed out waiting for authorization")
+	}
+}
+
+// exchangeAuthCode trades an authorization code for an OpenRouter API key
+func exchangeAuthCode(code, verifier string) (string, error) {
+	jsonBody, err := json.Marshal(map[string]string{
+		"code":                  code,
+		"code_verifier":         verifier,
+		"code_challenge_method": "S256",
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create request body: %w", err)
+	}
+
+	resp, err := http.Post(openRouterKeyURL, "application/json", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API error: %s (status code: %d)", string(body), resp.StatusCode)
+	}
+
+	var keyResp struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(body, &keyResp); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if keyResp.Key == "" {
+		return "", fmt.Errorf("no key in response")
+	}
+	return keyResp.Key, nil
+}
+
+// newLoginCmd creates the login command that obtains an OpenRouter key through the browser
+func newLoginCmd() *cobra.Command {
+	var (
+		port      int
+		noBrowser bool
+		keyring   bool
+	)
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Log in to OpenRouter in the browser",
+		Long: "Authorize rmit with your OpenRouter account using OAuth (PKCE). OpenRouter issues an API key " +
+			"that is saved to the configuration, so there is no key to create and paste by hand.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// The key would be lost with nothing to save it to
+			if noPersist {
+				log.Fatalf("%s %v", red("Error:"), errNoPersist)
+			}
+			verifier, challenge, err := pkceVerifier()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error starting login:"), err)
+			}
+
+			listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+			if err != nil {
+				log.Fatalf("%s %v", red("Error starting callback server:"), err)
+			}
+
+			authURL := openRouterAuthURL + "?" + url.Values{
+				"callback_url":          {fmt.Sprintf("http://localhost:%d%s", port, loginCallbackPath)},
+				"code_challenge":        {challenge},
+				"code_challenge_method": {"S256"},
+			}.Encode()
+
+			fmt.Printf("%s\n", blue("🔑 Open this URL to authorize rmit:"))
+			fmt.Printf("%s\n\n", cyan(authURL))
+			if !noBrowser {
+				if err := openBrowser(authURL); err != nil {
+					fmt.Printf("%s %v\n", yellow("⚠️ Couldn't open a browser:"), err)
+				}
+			}
+			fmt.Printf("%s\n", yellow("Waiting for authorization..."))
+
+			code, err := waitForAuthCode(listener)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error during login:"), err)
+			}
+
+			key, err := exchangeAuthCode(code, verifier)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error obtaining API key:"), err)
+			}
+
+			config, err := loadFileConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+			if keyring {
+				config.APIKeyKeyring = true
+			}
+			if err := storeAPIKey(config, key); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			if err := saveConfig(config); err != nil {
+				log.Fatalf("%s %v", red("Error saving configuration:"), err)
+			}
+
+			if config.APIKeyKeyring {
+				fmt.Printf("%s\n", green("✅ Logged in, API key saved to the OS keyring"))
+				return
+			}
+			fmt.Printf("%s\n", green("✅ Logged in, API key saved to configuration"))
+		},
+	}
+
+	loginCmd.Flags().IntVar(&port, "port", defaultLoginCallback, "Local port for the OAuth callback")
+	loginCmd.Flags().BoolVar(&keyring, "keyring", false, "Keep the API key in the OS keyring instead of the config file")
+	loginCmd.Flags().BoolVar(&noBrowser, "no-browser", false, "Print the authorization URL without opening a browser")
+
+	return loginCmd
+}
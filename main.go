@@ -1,45 +1,89 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
 
 // OpenRouter request structure
 type OpenRouterRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model    string       `json:"model"`
+	Messages []Message    `json:"messages"`
+	Stream   bool         `json:"stream,omitempty"`
+	Tools    []OpenAITool `json:"tools,omitempty"`
 }
 
-// Message structure for OpenRouter API
+// Message structure for chat requests. Content is either a string or a []ContentPart for multimodal messages
 type Message struct {
 	Role    string `json:"role"`
-	Content string `json:"content"`
+	Content any    `json:"content"`
+	// The tools an assistant message calls, and the call a "tool" message answers
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ContentPart is one part of a multimodal message
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by URL or data URL
+type ImageURL struct {
+	URL string `json:"url"`
 }
 
 // OpenRouter response structure
 type OpenRouterResponse struct {
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 		} `json:"message"`
+		FinishReason string `json:"finish_reason,omitempty"` // "content_filter" when the answer was blocked
 	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// Usage is the token count the provider reports for a request
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+
+	// Anthropic's names for the same counts
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+}
+
+// prompt returns the tokens of the prompt, whichever name the provider uses
+func (u *Usage) prompt() int {
+	return u.PromptTokens + u.InputTokens
+}
+
+// completion returns the tokens of the response, whichever name the provider uses
+func (u *Usage) completion() int {
+	return u.CompletionTokens + u.OutputTokens
 }
 
-// getGitDiff gets the current changes in the git repository
-func getGitDiff() (string, error) {
+// rmitVersion is shown in the banner and recorded in provenance attestations
+const rmitVersion = "v1.1.0"
+
+// errNoChanges is returned when there is nothing to describe
+var errNoChanges = errors.New("no changes detected in the repository")
+
+// getGitDiff gets the current changes in the git repository, limited to paths when given
+func getGitDiff(paths ...string) (string, error) {
+	defer profilePhase(phaseGit)()
 	// Check if git is installed
 	_, err := exec.LookPath("git")
 	if err != nil {
@@ -47,13 +91,18 @@ func getGitDiff() (string, error) {
 	}
 
 	// Check if current directory is a git repository
-	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+	cmd := gitCommand("rev-parse", "--is-inside-work-tree")
 	if err := cmd.Run(); err != nil {
 		return "", fmt.Errorf("current directory is not a git repository")
 	}
 
+	var pathspec []string
+	if len(paths) > 0 {
+		pathspec = append([]string{"--"}, paths...)
+	}
+
 	// Get staged changes
-	stagedCmd := exec.Command("git", "diff", "--staged")
+	stagedCmd := gitCommand(append([]string{"diff", "--staged"}, pathspec...)...)
 	stagedOutput, err := stagedCmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get staged changes: %w", err)
@@ -61,14 +110,14 @@ func getGitDiff() (string, error) {
 
 	// Get unstaged changes if no staged changes
 	if len(stagedOutput) == 0 {
-		unstagedCmd := exec.Command("git", "diff")
+		unstagedCmd := gitCommand(append([]string{"diff"}, pathspec...)...)
 		unstagedOutput, err := unstagedCmd.Output()
 		if err != nil {
 			return "", fmt.Errorf("failed to get unstaged changes: %w", err)
 		}
 
 		if len(unstagedOutput) == 0 {
-			return "", fmt.Errorf("no changes detected in the repository")
+			return "", errNoChanges
 		}
 
 		return string(unstagedOutput), nil
@@ -77,6 +126,61 @@ func getGitDiff() (string, error) {
 	return string(stagedOutput), nil
 }
 
+// describedDiff returns the diff the model describes. It is the changes to the --describe-only
+// paths if given, otherwise the changes that will be committed: the staged changes, or the
+// committed paths as they are in the working tree.
+func describedDiff(commitPaths, describeOnly []string) (string, error) {
+	// Without a staging area the changes that will be committed are the working copy's
+	if activeVCS != nil {
+		paths := vcsPaths(commitPaths)
+		if len(describeOnly) > 0 {
+			paths = describeOnly
+		}
+		return activeVCS.Diff(paths)
+	}
+	if len(commitPaths) == 0 {
+		return stagedDiff(describeOnly...)
+	}
+
+	paths := commitPaths
+	if len(describeOnly) > 0 {
+		paths = describeOnly
+	}
+	return worktreeDiff(paths)
+}
+
+// getRepoRoot returns the top level directory of the current git repository
+func getRepoRoot() (string, error) {
+	if activeVCS != nil {
+		return activeVCS.Root()
+	}
+	defer profilePhase(phaseGit)()
+	output, err := gitCommand("rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find repository root: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// readRepoFile reads a file by its repository relative path
+func readRepoFile(repoPath string) (string, error) {
+	root, err := getRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	data, err := readCheckoutFile(root, repoPath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// repoFileExists reports whether a repository relative path exists and is not empty
+func repoFileExists(repoPath string) bool {
+	content, err := readRepoFile(repoPath)
+	return err == nil && strings.TrimSpace(content) != ""
+}
+
 // trackCodeChanges analyzes a message to identify and structure code changes
 func trackCodeChanges(message string) (map[string]string, error) {
 	changes := make(map[string]string)
@@ -111,214 +215,400 @@ func trackCodeChanges(message string) (map[string]string, error) {
 	return changes, nil
 }
 
-// getChangedFiles gets the names of files that have been changed
-func getChangedFiles() ([]string, error) {
-	// Check if git is installed
-	_, err := exec.LookPath("git")
-	if err != nil {
-		return nil, fmt.Errorf("git is not installed or not in PATH")
-	}
+// commitMenu is what can be done with a generated message
+var commitMenu = []MenuOption{
+	{Key: "y", Label: "Create commit with this message"},
+	{Key: "n", Label: "Cancel commit"},
+	{Key: "g", Label: "Generate more detailed message"},
+	{Key: "r", Label: "Retry with new generation"},
+	{Key: "s", Label: "Summarize message"},
+	{Key: "p", Label: "Provide feedback for the message"},
+}
 
-	// Get staged files
-	stagedCmd := exec.Command("git", "diff", "--staged", "--name-only")
-	stagedOutput, err := stagedCmd.Output()
+// readUserInput asks a question and returns the lowercase answer, "y" when the user just presses enter.
+// With --assume-yes or --assume-no the answer is given without asking.
+func readUserInput(question string) (string, error) {
+	if answer, ok := assumeAnswer(question); ok {
+		return answer, nil
+	}
+	input, err := ui.Prompt(question)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get staged files: %w", err)
+		return "", err
 	}
+	if input == "" {
+		return "y", nil
+	}
+	return strings.ToLower(input), nil
+}
 
-	// Get unstaged files if no staged files
-	if len(stagedOutput) == 0 {
-		unstagedCmd := exec.Command("git", "diff", "--name-only")
-		unstagedOutput, err := unstagedCmd.Output()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get unstaged files: %w", err)
-		}
+// GenerateOptions holds per-invocation settings for message generation
+type GenerateOptions struct {
+	Model       string            // overrides default_model when set
+	Attachments []string          // image files sent along with the diff to vision models
+	SubjectOnly bool              // generate a single short subject line without a body
+	Trailers    []Trailer         // appended to the generated message
+	Context     string            // the author's own description of the intent behind the change
+	Transcript  *Transcript       // records prompts and responses when set
+	Excluded    []string          // changed files the author left out of the diff sent to the model
+	Withheld    []string          // changed files outside the described paths, e.g. of --describe-only
+	OnDelta     func(text string) // receives the response as it streams in; streaming is only requested when set
+	Ctx         context.Context   // cancels the request when done, e.g. from rmit serve; nil never cancels
+	Trial       *Trial            // the experiment variant whose instructions are used, when enrolled
+	Type        string            // the conventional commit type to use instead of the model's choice
+	Scope       string            // the scope to use instead of the detected one
+	Files       []string          // the changed files to list, for diffs that aren't of the working tree
+	Gathered    *PromptContext    // the repository context when collected along with the diff, gathered on every generation when nil
+}
 
-		if len(unstagedOutput) == 0 {
-			return nil, fmt.Errorf("no changed files detected in the repository")
-		}
+// commitInstructions returns the instructions a full commit message prompt starts with: the
+// built-in ones for the commit style, or an experiment variant's, followed by the hints
+func commitInstructions(config *Config, opts GenerateOptions, version int, hints string) string {
+	if opts.Trial != nil && opts.Trial.Prompt != "" {
+		debugf("experiment %s, variant %s replaces the built-in instructions", opts.Trial.Experiment, opts.Trial.Variant)
+		return strings.TrimSpace(opts.Trial.Prompt) + " " + hints
+	}
+	return stylePrompt(config, promptCommit, version) + " " + hints
+}
 
-		return strings.Split(strings.TrimSpace(string(unstagedOutput)), "\n"), nil
+// generateCommitMessage uses the configured provider to generate a commit message based on git diff and project information
+func generateCommitMessage(config *Config, diff string, opts GenerateOptions) (string, error) {
+	defer profilePhase(phasePrompt)()
+	// Thin clients leave generation to a shared rmit server
+	if config.Server != "" {
+		return remoteCommitMessage(config, diff, opts)
 	}
 
-	return strings.Split(strings.TrimSpace(string(stagedOutput)), "\n"), nil
-}
+	model := opts.Model
+	if model == "" {
+		model = config.DefaultModel
+	}
 
-// getProjectInfo gets information about the project
-func getProjectInfo() (string, error) {
-	// Try to determine the project type based on files
-	files, err := filepath.Glob("*")
-	if err != nil {
-		return "", fmt.Errorf("failed to list files: %w", err)
-	}
-
-	var projectInfo strings.Builder
-	projectInfo.WriteString("Project files include: ")
-
-	// Look for specific project indicators
-	hasGoMod := false
-	hasPackageJSON := false
-	hasPomXML := false
-	hasCMake := false
-	hasPyProject := false
-
-	for _, file := range files {
-		switch file {
-		case "go.mod":
-			hasGoMod = true
-		case "package.json":
-			hasPackageJSON = true
-		case "pom.xml":
-			hasPomXML = true
-		case "CMakeLists.txt":
-			hasCMake = true
-		case "pyproject.toml":
-			hasPyProject = true
+	// Confidential repositories must not silently end up with a provider that keeps prompts
+	if err := checkConfidential(config); err != nil {
+		return "", err
+	}
+	if err := checkCommitStyle(config, opts); err != nil {
+		return "", err
+	}
+
+	// Per-type body templates, changed files and project information, gathered up front by the
+	// caller or all at once here
+	gathered := opts.Gathered
+	if gathered == nil {
+		var err error
+		if gathered, err = gatherPromptContext(opts, nil); err != nil {
+			return "", err
 		}
 	}
+	// The changed files are those of the diff being described, e.g. with --all or --describe-only
+	repoConfig, changedFiles := gathered.RepoConfig, opts.Files
+	if changedFiles == nil {
+		changedFiles = diffPaths(parseDiff(diff))
+	}
 
-	if hasGoMod {
-		projectInfo.WriteString("Go project. ")
+	// Build file list string
+	var fileListStr string
+	if len(changedFiles) > 0 {
+		fileListStr = fmt.Sprintf("Changed files: %s\n\n", strings.Join(changedFiles, ", "))
+	}
+
+	files := parseDiff(diff)
+
+	// Lockfiles, minified and vendored files cost the most tokens and say the least, so the model
+	// only hears that they changed. A diff of nothing else is sent as it is.
+	ignored := ignoredDiffFiles(config, files)
+	if len(ignored) > 0 && len(ignored) < len(files) {
+		debugf("ignored %s", strings.Join(ignored, ", "))
+		diff = excludeFromDiff(diff, ignored)
+		files = parseDiff(diff)
+	} else {
+		ignored = nil
 	}
-	if hasPackageJSON {
-		projectInfo.WriteString("JavaScript/Node.js project. ")
+
+	// Single-directory changes get a deterministic scope, the model only picks type, subject and
+	// body. Styles without types have nowhere to put one.
+	var scope string
+	if styleUsesTypes(commitStyle(config)) {
+		scopeFiles := files
+		for _, path := range append(ignored, opts.Excluded...) {
+			scopeFiles = append(scopeFiles, &FileDiff{Path: path})
+		}
+		scope = detectScope(scopeFiles, config.ScopeMap)
 	}
-	if hasPomXML {
-		projectInfo.WriteString("Java/Maven project. ")
+	if opts.Scope != "" {
+		scope = opts.Scope
 	}
-	if hasCMake {
-		projectInfo.WriteString("C/C++ project with CMake. ")
+	var scopeHint string
+	if scope != "" {
+		scopeHint = scopeInstruction(scope)
 	}
-	if hasPyProject {
-		projectInfo.WriteString("Python project. ")
+	if opts.Type != "" {
+		scopeHint += typeInstruction(opts.Type)
 	}
+	affixes := subjectAffixes(config, opts.Trailers)
+	scopeHint += affixes.instruction()
+	scopeHint += writingInstruction(config)
 
-	return projectInfo.String(), nil
-}
-
-// readUserInput reads a single character from the user
-func readUserInput() (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	input, err := reader.ReadString('\n')
+	version := promptVersion(config)
+	debugf("provider %s, model %s, prompt version v%d", providerName(config), model, version)
+	promptTemplate, err := loadPromptTemplate(config)
 	if err != nil {
 		return "", err
 	}
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return "y", nil
-	}
-	return strings.ToLower(input), nil
-}
 
-// generateCommitMessage uses OpenRouter to generate a commit message based on git diff and project information
-func generateCommitMessage(config *Config, diff string, model string) (string, error) {
-	if model == "" {
-		model = config.DefaultModel
-	}
+	var prompt string
+	var summaries []*FileSummary
+	var groupNames []string
+	bullets := false
+
+	if promptTemplate != nil {
+		// The team's own prompt, filled in with what rmit gathered and its instructions
+		data := PromptData{
+			ChangedFiles: strings.Join(changedFiles, ", "),
+			Branch:       getCurrentBranch(),
+			Context:      opts.Context,
+			SubjectOnly:  opts.SubjectOnly,
+		}
+		if opts.SubjectOnly {
+			data.Instructions = strings.Replace(stylePrompt(config, promptSubject, version), "{hints}", scopeHint, 1)
+			data.Diff = diff
+			if len(diff) > subjectOnlyDiffLimit {
+				data.Diff = diff[:subjectOnlyDiffLimit] + "\n[diff truncated]"
+			}
+		} else {
+			data.Instructions = commitInstructions(config, opts, version, scopeHint) +
+				templateInstructions(repoConfig.Templates) + "Only respond with the commit message, nothing else."
+			data.ProjectInfo = gathered.ProjectInfo
+			summaries = summarizeFiles(files)
+			data.Diff = promptDiff(diff, files, summaries)
+		}
+		if prompt, err = renderPromptTemplate(promptTemplate, data); err != nil {
+			return "", err
+		}
+		debugf("prompt template %s replaces the built-in prompt", promptTemplate.Name())
+	} else if opts.SubjectOnly {
+		// Subject-only mode skips the extra context and trims the diff to keep token usage minimal
+		prompt = subjectOnlyPrompt(config, fileListStr, diff, scopeHint, version)
+	} else {
+		projectInfo := gathered.ProjectInfo
+
+		// Prepare the prompt with more context
+		prompt = commitInstructions(config, opts, version, scopeHint)
+
+		// With bullet bodies the model summarizes each file group and rmit assembles the body
+		var groups map[string][]string
+		groupNames, groups = groupChangedFiles(files)
+		bullets = config.BodyStyle == bodyStyleBullets && len(files) > 0
+		if bullets {
+			prompt += bulletBodyInstructions(groupNames, groups)
+		} else {
+			prompt += templateInstructions(repoConfig.Templates)
+			prompt += "Only respond with the commit message, nothing else.\n\n"
+		}
 
-	// Get changed files for more context
-	changedFiles, err := getChangedFiles()
-	if err != nil {
-		// Non-fatal error, we can continue without this info
-		log.Printf("Warning: couldn't get changed files: %v", err)
+		if projectInfo != "" {
+			prompt += "Project information: " + projectInfo + "\n\n"
+		}
+
+		// Add facts derived from the diff itself, and summarize noisy files instead of sending them raw
+		summaries = summarizeFiles(files)
+		insights := append(collectDiffInsights(files), summaryInsights(summaries)...)
+		insights = append(insights, excludedInsight(opts.Excluded)...)
+		insights = append(insights, ignoredInsight(ignored)...)
+		if len(insights) > 0 {
+			prompt += "Additional context:\n- " + strings.Join(insights, "\n- ") + "\n\n"
+		}
+
+		// Opt-in compression drops low-value lines from the diff until the prompt is small enough
+		changes := promptDiff(diff, files, summaries)
+		if config.PromptCompression > 0 {
+			var report *CompressionReport
+			changes, report = compressPromptDiff(prompt+fileListStr, changes, config.PromptCompression)
+			printCompressionReport(os.Stderr, report)
+		}
+		prompt += fileListStr + "Changes:\n" + changes
 	}
 
-	// Get project information for more context
-	projectInfo, err := getProjectInfo()
-	if err != nil {
-		// Non-fatal error, we can continue without this info
-		log.Printf("Warning: couldn't get project info: %v", err)
+	// The author's intent goes first so the model reads the diff in its light. Prompt templates
+	// place it themselves.
+	if opts.Context != "" && promptTemplate == nil {
+		prompt = "The author describes the intent of these changes as:\n" + opts.Context +
+			"\nUse this to explain why the change was made, but describe only what the diff actually does.\n\n" + prompt
 	}
 
-	// Build file list string
-	var fileListStr string
-	if len(changedFiles) > 0 {
-		fileListStr = fmt.Sprintf("Changed files: %s\n\n", strings.Join(changedFiles, ", "))
+	// Attach user supplied images and before/after thumbnails of changed images for vision models
+	var images []ContentPart
+	for _, attachment := range opts.Attachments {
+		part, err := imageAttachmentPart(attachment)
+		if err != nil {
+			return "", err
+		}
+		images = append(images, part)
+	}
+	if config.ImageThumbnails {
+		images = append(images, imageThumbnailParts(files)...)
 	}
 
-	// Prepare the prompt with more context
-	prompt := "Generate a short, concise git commit message based on the following changes. " +
-		"Follow the conventional commit format (e.g., feat:, fix:, docs:, style:, refactor:, test:, chore:). " +
-		"Keep it under 50 characters if possible. " +
-		"Only respond with the commit message, nothing else.\n\n"
+	// Read-only tools let the model look beyond the diff, e.g. at the function a hunk is in
+	tools := newToolBox(config, append(slices.Clone(opts.Excluded), opts.Withheld...))
+	if opts.SubjectOnly {
+		tools = nil
+	}
+	if tools != nil {
+		prompt += fmt.Sprintf("\n\nIf the diff leaves unclear why something changed, you may call the provided tools, at most %d times, to read more of the repository. Don't call them when the diff is clear.", tools.Calls)
+	}
 
-	if projectInfo != "" {
-		prompt += "Project information: " + projectInfo + "\n\n"
+	var content any = prompt
+	if len(images) > 0 {
+		if len(opts.Attachments) > 0 {
+			prompt += "\n\nThe attached screenshots show the result of these changes; use them to describe user-visible changes accurately."
+		}
+		content = append([]ContentPart{{Type: "text", Text: prompt}}, images...)
 	}
 
-	prompt += fileListStr + "Changes:\n" + diff
+	request := ChatRequest{
+		Model:    model,
+		Messages: []Message{{Role: "user", Content: content}},
+		OnDelta:  opts.OnDelta,
+		Tools:    tools,
+		Shared:   true, // concurrent identical requests (e.g. a hook and a terminal) share one response
+	}
+	response, err := chatUntilAnswered(opts, config, request)
+	partial, salvaged := salvageResponse(err)
+	if err != nil && !salvaged {
+		return "", err
+	}
+	if salvaged {
+		response = partial
+	}
+	defer profilePhase(phasePost)()
 
-	// Create request body
-	requestBody := OpenRouterRequest{
-		Model: model,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
+	message := strings.TrimSpace(response)
+	if opts.Type != "" && !bullets {
+		// The template to check the body against depends on the type
+		message = applyType(message, opts.Type)
+	}
+	if !bullets && !opts.SubjectOnly {
+		message = enforceTemplate(opts, config, request, message, repoConfig.Templates)
 	}
+	if bullets {
+		if assembled, ok := assembleBulletMessage(message, groupNames); ok {
+			message = assembled
+		}
+	}
+	if opts.SubjectOnly {
+		message = subjectLine(message)
+	}
+	if opts.Type != "" {
+		message = applyType(message, opts.Type)
+	}
+	if scope != "" {
+		message = applyScope(message, scope)
+	}
+	message = affixes.apply(message, subjectLimit(opts))
+	message = fitSubject(message, subjectLimit(opts))
+	// A subject-only message stays a single line
+	if !opts.SubjectOnly {
+		message = ensureMigrationNote(message, detectMigrations(files))
+		message = appendSummariesToBody(message, summaries)
+	}
+	message = appendTrailers(message, opts.Trailers)
+	printToolReport(os.Stderr, tools)
+
+	opts.Transcript.record(TranscriptEntry{
+		Model:         model,
+		PromptVersion: version,
+		Prompt:        prompt,
+		Images:        len(images),
+		Retrieved:     tools.retrieved(),
+		Response:      response,
+		Message:       message,
+	})
+
+	if salvaged {
+		return message, fmt.Errorf("%w: %v", errIncompleteMessage, err)
+	}
+	return message, nil
+}
 
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request body: %w", err)
+// askModel sends a single prompt outside of commit message generation, e.g. to check or
+// suggest something, and returns the model's answer
+func askModel(config *Config, model, prompt string) (string, error) {
+	if err := checkConfidential(config); err != nil {
+		return "", err
+	}
+	if model == "" {
+		model = config.DefaultModel
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequest("POST", config.APIURL, bytes.NewBuffer(jsonBody))
+	response, err := chat(nil, config, ChatRequest{Model: model, Messages: []Message{{Role: "user", Content: prompt}}})
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return "", err
 	}
+	return strings.TrimSpace(response), nil
+}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+config.APIKey)
-	req.Header.Set("HTTP-Referer", "https://github.com/aixoio/rmit")
+// stripCodeFence removes the code fence models like to wrap JSON in
+func stripCodeFence(response string) string {
+	response = strings.TrimSpace(response)
+	response = strings.TrimPrefix(response, "```json")
+	response = strings.TrimPrefix(response, "```")
+	response = strings.TrimSuffix(response, "```")
+	return strings.TrimSpace(response)
+}
 
-	// Send request
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+// localCommitMessage returns a deterministic commit message for diffs that don't need the model
+func localCommitMessage(diff string) (string, bool) {
+	if message, ok := dependencyBumpMessage(diff); ok {
+		return message, true
 	}
-	defer resp.Body.Close()
+	return noiseCommitMessage(diff)
+}
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+// suggestCommitMessage uses a locally built message when possible and falls back to the model
+func suggestCommitMessage(config *Config, diff string, opts GenerateOptions) (string, error) {
+	if message, ok := localCommitMessage(diff); ok {
+		return finishLocalMessage(config, message, opts), nil
 	}
+	return generateCommitMessage(config, diff, opts)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API error: %s (status code: %d)", string(body), resp.StatusCode)
+// makeCommit creates a git commit with the provided message. Without paths the staged changes
+// are committed. With paths only those are, whatever else is staged: as they are in the working
+// tree, or as they are staged with stagedOnly.
+func makeCommit(message string, stagedOnly bool, paths ...string) error {
+	// jj and Sapling have no index to stage into or snapshot for rmit undo
+	if activeVCS != nil {
+		return activeVCS.Commit(message, vcsPaths(paths), slices.Equal(paths, allPathspec))
 	}
+	defer profilePhase(phaseGit)()
 
-	// Parse response
-	var openRouterResp OpenRouterResponse
-	if err := json.Unmarshal(body, &openRouterResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	// Keep the state before staging so rmit undo can restore it exactly. The snapshot is a ref in
+	// the repository, so there is none with --no-persist.
+	if !noPersist {
+		if err := saveUndoSnapshot(); err != nil {
+			log.Printf("Warning: %v, rmit undo won't be available", err)
+		}
 	}
 
-	if len(openRouterResp.Choices) == 0 {
-		return "", fmt.Errorf("no response from AI model")
+	if stagedOnly && len(paths) > 0 {
+		return commitStagedPaths(message, paths)
 	}
 
-	return strings.TrimSpace(openRouterResp.Choices[0].Message.Content), nil
-}
-
-// makeCommit creates a git commit with the provided message
-func makeCommit(message string) error {
-	// Stage all changes
-	addCmd := exec.Command("git", "add", ".")
-	addCmd.Stdout = os.Stdout
-	addCmd.Stderr = os.Stderr
-	if err := addCmd.Run(); err != nil {
-		return fmt.Errorf("failed to stage changes: %w", err)
+	commitArgs := []string{"commit", "-m", message}
+	if len(paths) > 0 {
+		// Untracked files have to be staged before they can be committed by path
+		pathspec := append([]string{"--"}, paths...)
+		addCmd := gitCommand(append([]string{"add", "-A"}, pathspec...)...)
+		addCmd.Stdout = commandOutput()
+		addCmd.Stderr = os.Stderr
+		if err := addCmd.Run(); err != nil {
+			return fmt.Errorf("failed to stage changes: %w", err)
+		}
+		commitArgs = append(commitArgs, pathspec...)
 	}
-
-	// Create commit
-	commitCmd := exec.Command("git", "commit", "-m", message)
-	commitCmd.Stdout = os.Stdout
+	commitCmd := gitCommand(commitArgs...)
+	commitCmd.Stdout = commandOutput()
 	commitCmd.Stderr = os.Stderr
 	return commitCmd.Run()
 }
@@ -341,173 +631,480 @@ func validateAPIURL(url string) error {
 
 func main() {
 	var (
-		autoCommit bool
-		model      string
+		autoCommit        bool
+		model             string
+		attachments       []string
+		subjectOnly       bool
+		trailers          []string
+		context           string
+		transcript        string
+		stdinDiff         bool
+		printOnly         bool
+		server            string
+		style             string
+		remote            string
+		execIn            string
+		vcsName           string
+		preview           bool
+		commitOnly        []string
+		describeOnly      []string
+		maxCommitFiles    int
+		compression       int
+		profile           bool
+		cpuProfile        string
+		memProfile        string
+		timeoutFlag       string
+		outputMode        string
+		commitType        string
+		commitScope       string
+		changelogLabel    string
+		stageAll          bool
+		assumeYes         bool
+		assumeNo          bool
+		maxInvalidRetries int
+		candidates        int
+		autoThreshold     int
+		explain           bool
+		keyring           bool
 	)
 
-	// Initialize colors
-	red := color.New(color.FgRed).SprintFunc()
-	green := color.New(color.FgGreen).SprintFunc()
-	blue := color.New(color.FgBlue).SprintFunc()
-	yellow := color.New(color.FgYellow).SprintFunc()
-	cyan := color.New(color.FgCyan).SprintFunc()
-	magenta := color.New(color.FgMagenta).SprintFunc()
-
-	// Print header
-	fmt.Printf("%s\n", blue("██████╗ ███╗   ███╗██╗████████╗"))
-	fmt.Printf("%s\n", blue("██╔══██╗████╗ ████║██║╚══██╔══╝"))
-	fmt.Printf("%s\n", blue("██████╔╝██╔████╔██║██║   ██║   "))
-	fmt.Printf("%s\n", blue("██╔══██╗██║╚██╔╝██║██║   ██║   "))
-	fmt.Printf("%s\n", blue("██║  ██║██║ ╚═╝ ██║██║   ██║   "))
-	fmt.Printf("%s\n", blue("╚═╝  ╚═╝╚═╝     ╚═╝╚═╝   ╚═╝   "))
-	fmt.Println()
-
-	// Print version info
-	fmt.Printf("%s %s\n", cyan("RMIT"), green("v1.1.0"))
-	fmt.Printf("%s\n", yellow("AI-powered commit message generator"))
-	fmt.Println(magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-	fmt.Println()
-
 	// Create root command
 	rootCmd := &cobra.Command{
 		Use:   "rmit",
 		Short: "Generate git commit messages with AI",
 		Long:  "rmit uses OpenRouter to generate descriptive git commit messages based on your changes",
 		Run: func(cmd *cobra.Command, args []string) {
+			// With --remote or --exec-in, git runs in a checkout on another machine or in a container.
+			// The repository's state lives there too, so nothing is kept locally for it, as with
+			// --no-persist.
+			if remote != "" && execIn != "" {
+				log.Fatalf("%s --remote can't be combined with --exec-in", red("Error:"))
+			}
+			if remote != "" || execIn != "" {
+				checkout, err := parseRemoteCheckout(remote)
+				if execIn != "" {
+					checkout, err = parseExecIn(execIn)
+				}
+				if err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+				remoteCheckout = checkout
+				noPersist = true
+			}
+			if err := setupVCS(vcsName); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			if activeVCS != nil && maxCommitFiles > 0 {
+				log.Fatalf("%s --max-commit-files needs git, it splits commits through the staging area", red("Error:"))
+			}
+
 			// Load configuration
 			config, err := loadConfig()
 			if err != nil {
 				log.Fatalf("%s %v", red("Error loading configuration:"), err)
 			}
+			if server != "" {
+				config.Server = server
+			}
+			if style != "" {
+				if err := applyStyleFlag(config, style); err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+			}
+			for kind, value := range map[string]string{"type": commitType, "scope": commitScope} {
+				if err := validatePinned(kind, value); err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+			}
+			if err := checkCommitStyle(config, GenerateOptions{Type: commitType, Scope: commitScope}); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			if changelogLabel != "" {
+				section, err := canonicalChangelogSection(changelogLabel)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+				trailers = append(trailers, changelogTrailerKey+": "+section)
+			}
+			if cmd.Flags().Changed("compress") {
+				if compression < 0 || compression > maxPromptCompression {
+					log.Fatalf("%s --compress must be between 0 and %d percent", red("Error:"), maxPromptCompression)
+				}
+				config.PromptCompression = compression
+			}
+
+			// Tools like lazygit or tig pipe in the diff, and scripts, editors and aliases describe the
+			// repository's changes; both only want the message back. Neither returns.
+			if stdinDiff || printOnly {
+				plainOpts := GenerateOptions{
+					Model:       model,
+					SubjectOnly: subjectOnly || (config.SubjectOnly && !cmd.Flags().Changed("subject-only")),
+					Context:     strings.TrimSpace(context),
+					Type:        commitType,
+					Scope:       commitScope,
+				}
+				if stdinDiff {
+					runPlain(cmd, config, plainOpts, trailers, stdinContextFlag, readStdinDiff)
+				}
+				printPaths := commitOnly
+				if stageAll {
+					printPaths = allPathspec
+				}
+				runPlain(cmd, config, plainOpts, trailers, printFlag, func() (string, error) {
+					return describedDiff(printPaths, describeOnly)
+				})
+			}
+			printBanner()
 
-			// Get git diff
-			diff, err := getGitDiff()
+			// Scripts and file watchers committing unattended are held to the configured pace before
+			// anything is generated, so a runaway loop spends neither history nor API credits
+			unattended := autoCommit || assumedAnswer == "y"
+			if reason := autoCommitThrottled(config, time.Now()); unattended && reason != "" {
+				log.Fatalf("%s %s", red("Automatic commit refused:"), reason)
+			}
+
+			// Committing every change would sweep up untracked artifacts, so offer to ignore them first
+			commitPaths := commitOnly
+			if stageAll {
+				// .gitignore suggestions edit files, which only works in a local git checkout
+				if remoteCheckout == nil && activeVCS == nil {
+					offerGitignore(config, model, !autoCommit)
+				}
+				commitPaths = allPathspec
+			}
+
+			// Get the diff to describe, which is what gets committed unless told otherwise, along with
+			// the rest of the prompt context
+			subjectOnly = subjectOnly || (config.SubjectOnly && !cmd.Flags().Changed("subject-only"))
+			gathered, err := gatherPromptContext(GenerateOptions{SubjectOnly: subjectOnly}, func() (string, error) {
+				return describedDiff(commitPaths, describeOnly)
+			})
 			if err != nil {
-				log.Fatalf("%s %v", red("Error getting git diff:"), err)
+				log.Fatalf("%s %v", red("Error reading the repository:"), err)
+			}
+			diff := gathered.Diff
+
+			// Trailers are known up front so every generated message gets the same ones
+			commitTrailers, err := collectTrailers(config, trailers)
+			if err != nil {
+				log.Fatalf("%s %v", red("Invalid trailer:"), err)
+			}
+			if missing := missingTrailers(commitTrailers, config.RequiredTrailers); len(missing) > 0 {
+				if autoCommit || assumedAnswer != "" {
+					log.Fatalf("%s %s (pass them with --trailer)", red("Missing required trailers:"), strings.Join(missing, ", "))
+				}
+				commitTrailers, err = promptTrailers(commitTrailers, missing)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error reading trailers:"), err)
+				}
+			}
+
+			opts := GenerateOptions{
+				Model:       model,
+				Attachments: attachments,
+				SubjectOnly: subjectOnly,
+				Trailers:    commitTrailers,
+				Context:     strings.TrimSpace(context),
+				Type:        commitType,
+				Scope:       commitScope,
+				Gathered:    gathered,
+				OnDelta:     ui.Stream,
+			}
+			// Tools mustn't show the model changes the prompt leaves out
+			if config.MaxToolCalls > 0 && (len(commitOnly) > 0 || len(describeOnly) > 0) {
+				opts.Withheld = withheldFiles(diff)
+			}
+
+			// Keep a record of what the model saw, saved once the commit exists
+			transcriptMode := config.Transcripts
+			if cmd.Flags().Changed("transcript") {
+				transcriptMode = transcript
+			}
+			if err := validateTranscriptMode(transcriptMode); err != nil {
+				log.Fatalf("%s %v", red("Invalid transcript mode:"), err)
+			}
+			// Provenance attestations are built from the same record
+			if transcriptMode != transcriptOff || config.Provenance {
+				opts.Transcript = &Transcript{}
+			}
+
+			// Intent written down while coding is added to any --context given on the command line
+			var intentMarkers []IntentMarker
+			if config.ReadIntent {
+				var intent string
+				intent, intentMarkers = readIntent(parseDiff(diff))
+				opts.Context = joinContext(opts.Context, intent)
+			}
+
+			// Interactive runs can be enrolled in an experiment defined by the repository's maintainers
+			if !autoCommit && assumedAnswer == "" {
+				if repoConfig, err := loadRepoConfig(); err == nil {
+					if opts.Trial = startTrial(repoConfig.Experiments, model); opts.Trial != nil && opts.Trial.Model != "" {
+						opts.Model = opts.Trial.Model
+					}
+				}
 			}
 
 			// Print which model is being used
-			modelToUse := model
-			if model == "" {
+			modelToUse := opts.Model
+			if modelToUse == "" {
 				modelToUse = config.DefaultModel
 			}
 
-			fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-			fmt.Printf("%s %s\n", green("🤖 USING MODEL:"), cyan(modelToUse))
-			fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+			ui.Panel("🤖 USING MODEL: "+modelToUse, "")
+
+			// Sprawling changesets become a sequence of commits, one per group of directories
+			if maxCommitFiles > 0 && len(commitOnly) == 0 {
+				if files := parseDiff(diff); len(files) > maxCommitFiles {
+					chunks := planChunks(files, maxCommitFiles)
+					printChunkPlan(chunks)
+					guardFindings := findGuardViolations(files)
+					printGuardWarning(guardFindings)
+					if !autoCommit {
+						response, err := readUserInput(fmt.Sprintf("Create these %d commits? [Y/n]: ", len(chunks)))
+						if err != nil {
+							log.Fatalf("%s %v", red("Error reading user input:"), err)
+						}
+						if response != "y" && response != "yes" {
+							ui.Warn("⚠️ Commit canceled")
+							return
+						}
+						if !confirmGuard(guardFindings) {
+							ui.Warn("⚠️ Commit canceled")
+							return
+						}
+					}
+					runChunkedCommits(config, opts, chunks, transcriptMode, stageAll)
+					return
+				}
+			}
 
-			// Generate commit message
-			fmt.Printf("\n%s\n", yellow("Generating commit message..."))
-			message, err := generateCommitMessage(config, diff, model)
-			if err != nil {
-				log.Fatalf("%s %v", red("Error generating commit message:"), err)
+			// Generate commit message, skipping the model when the diff can be described locally
+			// or was already committed once (e.g. before a git reset --soft)
+			sentDiff := diff
+			message, local := localCommitMessage(diff)
+			var generationErr error // set when the message was salvaged from a stalled response
+			reused := false
+			if !local && !autoCommit && activeVCS == nil {
+				if sha, previous, ok := findPreviousCommit(diff); ok {
+					ui.Panel("♻️  These exact changes were committed before as "+sha[:12], previous)
+					response, err := readUserInput("Reuse that message instead of generating a new one? [Y/n]: ")
+					if err != nil {
+						log.Fatalf("%s %v", red("Error reading user input:"), err)
+					}
+					if response == "y" || response == "yes" {
+						message, reused = previous, true
+					}
+				}
+			}
+			if reused || local {
+				// Nothing was generated, so there's nothing to learn about the variant
+				opts.Trial = nil
+			}
+			if reused {
+				ui.Info("\n♻️  Reusing the previous commit message")
+			} else if local {
+				ui.Info("\n📦 Dependency-only or formatting-only changes detected, message built locally")
+				message = finishLocalMessage(config, message, opts)
+			} else {
+				// Files can be left out of the prompt, e.g. huge generated files, and are still committed
+				if preview {
+					opts.Excluded, err = previewDiff(parseDiff(diff))
+					if err != nil {
+						log.Fatalf("%s %v", red("Error:"), err)
+					}
+					sentDiff = excludeFromDiff(diff, opts.Excluded)
+				}
+
+				// An ambiguous diff is better asked about than guessed at, when someone is there to answer
+				if config.InteractiveQuestions > 0 && !autoCommit && assumedAnswer == "" {
+					opts.Context = joinContext(opts.Context, askClarifyingQuestions(config, modelToUse, sentDiff, opts.Context))
+				}
+
+				// With several candidates the best ranked one is shown first
+				candidateCount := config.Candidates
+				if cmd.Flags().Changed("candidates") {
+					candidateCount = candidates
+				}
+				if candidateCount > 1 {
+					ui.Info(fmt.Sprintf("\nGenerating %d candidate commit messages...", min(candidateCount, maxCandidates)))
+					message, err = chooseCandidate(config, sentDiff, opts, min(candidateCount, maxCandidates), autoCommit || assumedAnswer != "")
+				} else {
+					ui.Info("\nGenerating commit message...")
+					message, err = generateCommitMessage(config, sentDiff, opts)
+				}
+				if err != nil {
+					if isOfflineError(err) && activeVCS == nil && offerOfflineCommit(diff, autoCommit, commitPaths) {
+						return
+					}
+					if !usePartialMessage(err, autoCommit || assumedAnswer != "") {
+						log.Fatalf("%s %v", red("Error generating commit message:"), err)
+					}
+				}
+				generationErr = err
+
+				// Broken messages can be regenerated the way a person would press r
+				if maxInvalidRetries > 0 {
+					repoConfig, _ := loadRepoConfig()
+					affixes := subjectAffixes(config, opts.Trailers)
+					for retry := 1; ; retry++ {
+						problems := messageProblems(commitStyle(config), affixes.strip(message), repoConfig.Templates)
+						if len(problems) == 0 {
+							break
+						}
+						if retry > maxInvalidRetries {
+							log.Fatalf("%s %s", red(fmt.Sprintf("Error: the message is still invalid after %d retries:", maxInvalidRetries)), strings.Join(problems, ", "))
+						}
+						ui.Warn(fmt.Sprintf("\n🔁 Invalid message (%s), retrying %d/%d...", strings.Join(problems, ", "), retry, maxInvalidRetries))
+						opts.Trial.retry()
+						if message, err = generateCommitMessage(config, sentDiff, opts); err != nil && !usePartialMessage(err, autoCommit || assumedAnswer != "") {
+							log.Fatalf("%s %v", red("Error regenerating commit message:"), err)
+						}
+						generationErr = err
+					}
+				}
 			}
 
 			// Output commit message with prominent formatting
-			fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-			fmt.Printf("%s\n", blue("✨ GENERATED COMMIT MESSAGE:"))
-			fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-			fmt.Printf("\n%s\n\n", cyan(message))
-			fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+			ui.Panel(messageTitle("✨ GENERATED COMMIT MESSAGE:", generationErr), message)
+			if explain && !local && !reused {
+				printExplanation(config, modelToUse, sentDiff, message)
+			}
+
+			// Warn about changes that deserve extra attention before committing
+			printMigrationWarning(detectMigrations(parseDiff(diff)))
+			if config.ReadIntent {
+				printIntentReminder(intentMarkers)
+			}
+
+			// Look for leftovers in everything that gets committed, not only what was described
+			committedDiff := diff
+			if len(describeOnly) > 0 {
+				if d, err := describedDiff(commitPaths, nil); err == nil {
+					committedDiff = d
+				}
+			}
+			guardFindings := findGuardViolations(parseDiff(committedDiff))
+			printGuardWarning(guardFindings)
+			if repoConfig, err := loadRepoConfig(); err == nil {
+				printTemplateWarning(message, repoConfig.Templates)
+			}
+
+			// Good messages on small, clean diffs are committed without asking, everything else still asks
+			threshold := config.AutoCommitThreshold
+			if cmd.Flags().Changed("auto-threshold") {
+				threshold = autoThreshold
+			}
+			commitNow := autoCommit
+			if !autoCommit && threshold > 0 && generationErr == nil {
+				var reason string
+				commitNow, reason = shouldAutoCommit(commitStyle(config), subjectAffixes(config, opts.Trailers).strip(message), parseDiff(committedDiff), guardFindings, threshold)
+				if throttled := autoCommitThrottled(config, time.Now()); commitNow && throttled != "" {
+					commitNow, reason = false, throttled
+				}
+				if commitNow {
+					ui.Success("\n🚀 Committing automatically: " + reason)
+				} else {
+					ui.Warn("\n✋ Asking before committing, " + reason)
+				}
+			}
 
 			// Handle commit based on auto-commit flag or user confirmation
-			if autoCommit {
+			if commitNow {
 				// Auto-commit mode - commit without confirmation
-				if err := makeCommit(message); err != nil {
+				if err := makeAttestedCommit(config, message, opts.Transcript, commitPaths, false); err != nil {
 					log.Fatalf("%s %v", red("Error creating commit:"), err)
 				}
-				fmt.Printf("%s\n", green("✅ Commit created successfully"))
+				ui.Success("✅ Commit created successfully")
+				recordAutoCommit(time.Now())
+				printTranscriptSaved(opts.Transcript, transcriptMode, apiKeySecrets(config))
 			} else {
 				// Ask for confirmation with additional options
-				fmt.Printf("\n%s\n", yellow("⚙️  OPTIONS:"))
-				fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-				fmt.Printf("  %s - Create commit with this message\n", green("y/yes"))
-				fmt.Printf("  %s - Cancel commit\n", red("n/no"))
-				fmt.Printf("  %s - Generate more detailed message\n", blue("g"))
-				fmt.Printf("  %s - Retry with new generation\n", blue("r"))
-				fmt.Printf("  %s - Summarize message\n", blue("s"))
-				fmt.Printf("  %s - Provide feedback for the message\n", blue("p"))
-				fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-
 				for {
-					fmt.Print(yellow("Create commit with this message? [y/n/g/r/s/p]: "))
-
-					response, err := readUserInput()
+					response, err := choose("Create commit with this message?", commitMenu)
 					if err != nil {
 						log.Fatalf("%s %v", red("Error reading user input:"), err)
 					}
 
 					if response == "y" || response == "yes" {
-						if err := makeCommit(message); err != nil {
+						if !confirmGuard(guardFindings) {
+							ui.Warn("⚠️ Commit canceled")
+							break
+						}
+						if err := makeAttestedCommit(config, message, opts.Transcript, commitPaths, false); err != nil {
 							log.Fatalf("%s %v", red("Error creating commit:"), err)
 						}
-						fmt.Printf("%s\n", green("✅ Commit created successfully"))
+						ui.Success("✅ Commit created successfully")
+						if unattended {
+							recordAutoCommit(time.Now())
+						}
+						printTranscriptSaved(opts.Transcript, transcriptMode, apiKeySecrets(config))
+						if opts.Trial != nil && opts.Trial.Refined {
+							recordTrial(opts.Trial, modelToUse, trialRefined)
+						} else {
+							recordTrial(opts.Trial, modelToUse, trialAccepted)
+						}
 						break
 					} else if response == "n" || response == "no" {
-						fmt.Printf("%s\n", yellow("⚠️ Commit canceled"))
+						ui.Warn("⚠️ Commit canceled")
+						recordTrial(opts.Trial, modelToUse, trialRejected)
 						break
 					} else if response == "g" {
-						fmt.Printf("%s\n", blue("🔍 Generating a more detailed commit message..."))
-						message, err = generateCommitMessage(config, diff+"\n\nPlease provide a more detailed commit message with additional context and explanations.", model)
-						if err != nil {
+						ui.Info("🔍 Generating a more detailed commit message...")
+						opts.Trial.refine()
+						detailedOpts := opts
+						detailedOpts.SubjectOnly = false
+						message, err = generateCommitMessage(config, sentDiff+"\n\nPlease provide a more detailed commit message with additional context and explanations.", detailedOpts)
+						if err != nil && !usePartialMessage(err, false) {
 							log.Fatalf("%s %v", red("Error generating detailed commit message:"), err)
 						}
-						fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("%s\n", blue("✨ GENERATED DETAILED COMMIT MESSAGE:"))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("\n%s\n\n", cyan(message))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+						ui.Panel(messageTitle("✨ GENERATED DETAILED COMMIT MESSAGE:", err), message)
+						if explain {
+							printExplanation(config, modelToUse, sentDiff, message)
+						}
 					} else if response == "r" {
-						fmt.Printf("%s\n", blue("🔄 Retrying with a new generation..."))
-						message, err = generateCommitMessage(config, diff, model)
-						if err != nil {
+						ui.Info("🔄 Retrying with a new generation...")
+						opts.Trial.retry()
+						message, err = generateCommitMessage(config, sentDiff, opts)
+						if err != nil && !usePartialMessage(err, false) {
 							log.Fatalf("%s %v", red("Error regenerating commit message:"), err)
 						}
-						fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("%s\n", blue("✨ REGENERATED COMMIT MESSAGE:"))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("\n%s\n\n", cyan(message))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+						ui.Panel(messageTitle("✨ REGENERATED COMMIT MESSAGE:", err), message)
+						if explain {
+							printExplanation(config, modelToUse, sentDiff, message)
+						}
 					} else if response == "s" {
-						fmt.Printf("%s\n", blue("📝 Summarizing the commit message..."))
-						summary, err := generateCommitMessage(config, "Please summarize this commit message in 50 characters or less:\n\n"+message, model)
+						ui.Info("📝 Summarizing the commit message...")
+						opts.Trial.refine()
+						summary, err := generateCommitMessage(config, "Please summarize this commit message in 50 characters or less:\n\n"+message, GenerateOptions{Model: opts.Model, Trailers: opts.Trailers, Context: opts.Context, Transcript: opts.Transcript})
 						if err != nil {
 							log.Fatalf("%s %v", red("Error summarizing commit message:"), err)
 						}
 						message = summary
-						fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("%s\n", blue("✨ SUMMARIZED COMMIT MESSAGE:"))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("\n%s\n\n", cyan(message))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+						ui.Panel("✨ SUMMARIZED COMMIT MESSAGE:", message)
 					} else if response == "p" {
-						fmt.Printf("%s\n", blue("🔍 Enter your feedback for the commit message:"))
-						fmt.Print("> ")
-
-						// Read a single line of input
-						reader := bufio.NewReader(os.Stdin)
-						feedbackLine, err := reader.ReadString('\n')
+						ui.Info("🔍 Enter your feedback for the commit message:")
+						feedback, err := ui.Prompt("> ")
 						if err != nil {
 							log.Fatalf("%s %v", red("Error reading feedback:"), err)
 						}
-						feedback := strings.TrimSpace(feedbackLine)
 
-						fmt.Printf("%s\n", blue("🎯 Generating commit message based on your feedback..."))
+						ui.Info("🎯 Generating commit message based on your feedback...")
+						opts.Trial.refine()
 
 						// Use the feedback directly in the prompt
-						promptWithGuidance := "Based on this diff:\n\n" + diff + "\n\nAnd considering this feedback: " + feedback + "\n\nGenerate an appropriate commit message."
-						message, err = generateCommitMessage(config, promptWithGuidance, model)
-						if err != nil {
+						promptWithGuidance := "Based on this diff:\n\n" + sentDiff + "\n\nAnd considering this feedback: " + feedback + "\n\nGenerate an appropriate commit message."
+						message, err = generateCommitMessage(config, promptWithGuidance, opts)
+						if err != nil && !usePartialMessage(err, false) {
 							log.Fatalf("%s %v", red("Error generating commit message with custom guidance:"), err)
 						}
 
-						fmt.Printf("\n%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("%s\n", blue("✨ FEEDBACK-BASED COMMIT MESSAGE:"))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
-						fmt.Printf("\n%s\n\n", cyan(message))
-						fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+						ui.Panel(messageTitle("✨ FEEDBACK-BASED COMMIT MESSAGE:", err), message)
+						if explain {
+							printExplanation(config, modelToUse, sentDiff, message)
+						}
 					} else {
-						fmt.Printf("%s\n", red("❌ Invalid option. Please choose y (yes), n (no), g (generate detailed), r (retry), s (shorter), or p (custom prompt)."))
+						ui.Error("❌ Invalid option. Please choose y (yes), n (no), g (generate detailed), r (retry), s (shorter), or p (custom prompt).")
 					}
 				}
 			}
@@ -519,47 +1116,69 @@ func main() {
 		Use:   "set [key] [value]",
 		Short: "Set configuration values",
 		Long:  "Set configuration values like API key, URL, and default model",
-		Args:  cobra.ExactArgs(2),
+		Args: func(cmd *cobra.Command, args []string) error {
+			// Flags aren't parsed after the key, so rmit set api_key --keyring <key> is handled here
+			if len(args) == 3 && slices.Contains(args[1:], "--keyring") {
+				return nil
+			}
+			return cobra.ExactArgs(2)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) == 3 {
+				keyring = true
+				args = slices.DeleteFunc(slices.Clone(args), func(arg string) bool { return arg == "--keyring" })
+			}
 			key := args[0]
 			value := args[1]
+			if keyring && key != "api_key" {
+				log.Fatalf("%s --keyring only applies to api_key", red("Error:"))
+			}
+			if noPersist {
+				log.Fatalf("%s %v", red("Error:"), errNoPersist)
+			}
 
 			// Load current config
-			config, err := loadConfig()
+			config, err := loadFileConfig()
 			if err != nil {
 				config = &Config{
 					APIURL:       defaultAPIURL,
 					DefaultModel: defaultModel,
+					MaxRetries:   defaultMaxRetries,
+					Timeout:      defaultTimeout,
 				}
 			}
 
-			// Update based on key
-			switch key {
-			case "api_key":
-				if err := validateAPIKey(value); err != nil {
-					log.Fatalf("%s %v", red("Invalid API key:"), err)
-				}
-				config.APIKey = value
-			case "api_url":
-				if err := validateAPIURL(value); err != nil {
-					log.Fatalf("%s %v", red("Invalid API URL:"), err)
+			if err := setConfigValue(config, key, value); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+
+			// A key set without --keyring goes back to the config file
+			wasKeyring := config.APIKeyKeyring
+			if key == "api_key" {
+				config.APIKeyKeyring = keyring
+				if err := storeAPIKey(config, config.APIKey); err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
 				}
-				config.APIURL = value
-			case "default_model":
-				config.DefaultModel = value
-			default:
-				log.Fatalf("%s %s. Valid keys are: api_key, api_url, default_model", red("Unknown configuration key:"), key)
 			}
 
 			// Save config
 			if err := saveConfig(config); err != nil {
 				log.Fatalf("%s %v", red("Error saving configuration:"), err)
 			}
+			if wasKeyring && !config.APIKeyKeyring {
+				forgetKeyringAPIKey()
+			}
 
-			fmt.Printf("%s %s = %s\n", green("✅ Configuration updated:"), blue(key), cyan(value))
+			if keyring {
+				ui.Success("✅ API key stored in the OS keyring")
+				return
+			}
+			ui.Success(fmt.Sprintf("✅ Configuration updated: %s = %s", key, value))
 		},
 	}
 
+	setCmd.Flags().BoolVar(&keyring, "keyring", false, "Keep the API key in the OS keyring (macOS Keychain, Windows Credential Manager, Secret Service) instead of the config file")
+
 	// Create get command
 	getCmd := &cobra.Command{
 		Use:   "get [key]",
@@ -576,52 +1195,343 @@ func main() {
 			// If no key specified, show all (except sensitive data like API key)
 			if len(args) == 0 {
 				fmt.Printf("%s\n", blue("📋 Current configuration:"))
-				fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+				fmt.Print(rule())
 				if config.APIKey != "" {
-					fmt.Printf("%s %s\n", green("api_key:"), blue("[SET]"))
+					fmt.Printf("%s %s\n", green("api_key:"), blue(apiKeyStatus(config)))
 				} else {
 					fmt.Printf("%s %s\n", green("api_key:"), red("[NOT SET]"))
 				}
-				fmt.Printf("%s %s\n", green("api_url:"), blue(config.APIURL))
+				if len(config.APIKeys) > 0 {
+					fmt.Printf("%s %s\n", green("api_keys:"), blue(fmt.Sprintf("[%d SET]", len(config.APIKeys))))
+				}
+				fmt.Printf("%s %s\n", green("api_url:"), blue(chatURL(config)))
+				fmt.Printf("%s %s\n", green("provider:"), blue(providerName(config)))
+				if config.AzureEndpoint != "" || providerName(config) == "azure" {
+					fmt.Printf("%s %s\n", green("azure_endpoint:"), blue(config.AzureEndpoint))
+					fmt.Printf("%s %s\n", green("azure_deployment:"), blue(azureDeployment(config, config.DefaultModel)))
+					fmt.Printf("%s %s\n", green("azure_api_version:"), blue(azureVersion(config)))
+				}
 				fmt.Printf("%s %s\n", green("default_model:"), blue(config.DefaultModel))
-				fmt.Printf("%s\n", magenta("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"))
+				if config.FallbackModel != "" {
+					fmt.Printf("%s %s\n", green("fallback_model:"), blue(config.FallbackModel))
+				}
+				fmt.Printf("%s %s\n", green("image_thumbnails:"), blue(config.ImageThumbnails))
+				fmt.Printf("%s %s\n", green("body_style:"), blue(config.BodyStyle))
+				fmt.Printf("%s %s\n", green("commit_style:"), blue(commitStyle(config)))
+				fmt.Printf("%s %s\n", green("writing_style:"), blue(writingStyle(config)))
+				if config.CommitStylePrompt != "" {
+					fmt.Printf("%s %s\n", green("commit_style_prompt:"), blue(config.CommitStylePrompt))
+				}
+				fmt.Printf("%s %s\n", green("subject_only:"), blue(config.SubjectOnly))
+				fmt.Printf("%s %s\n", green("scope_map:"), blue(formatConfigMap(config.ScopeMap)))
+				fmt.Printf("%s %s\n", green("trailers:"), blue(formatConfigMap(config.Trailers)))
+				fmt.Printf("%s %s\n", green("required_trailers:"), blue(strings.Join(config.RequiredTrailers, ",")))
+				fmt.Printf("%s %s\n", green("ignore_patterns:"), blue(strings.Join(config.IgnorePatterns, ",")))
+				fmt.Printf("%s %s\n", green("read_intent:"), blue(config.ReadIntent))
+				fmt.Printf("%s %s\n", green("transcripts:"), blue(config.Transcripts))
+				fmt.Printf("%s %s\n", green("max_retries:"), blue(config.MaxRetries))
+				fmt.Printf("%s %s\n", green("timeout:"), blue(config.Timeout))
+				fmt.Printf("%s %s\n", green("compress_requests:"), blue(config.CompressRequests))
+				fmt.Printf("%s %s\n", green("prompt_compression:"), blue(config.PromptCompression))
+				fmt.Printf("%s %s\n", green("prompt_version:"), blue(formatPromptVersion(config)))
+				if config.PromptTemplate != "" {
+					fmt.Printf("%s %s\n", green("prompt_template:"), blue(config.PromptTemplate))
+				}
+				fmt.Printf("%s %s\n", green("candidates:"), blue(config.Candidates))
+				fmt.Printf("%s %s\n", green("auto_commit_threshold:"), blue(config.AutoCommitThreshold))
+				fmt.Printf("%s %s\n", green("auto_commit_min_interval:"), blue(config.AutoCommitMinInterval))
+				fmt.Printf("%s %s\n", green("auto_commit_max_per_hour:"), blue(config.AutoCommitMaxPerHour))
+				fmt.Printf("%s %s\n", green("interactive_questions:"), blue(config.InteractiveQuestions))
+				fmt.Printf("%s %s\n", green("max_tool_calls:"), blue(config.MaxToolCalls))
+				fmt.Printf("%s %s\n", green("max_tool_tokens:"), blue(config.MaxToolTokens))
+				fmt.Printf("%s %s\n", green("allowed_tools:"), blue(strings.Join(config.AllowedTools, ",")))
+				fmt.Printf("%s %s\n", green("audit_log:"), blue(config.AuditLog))
+				fmt.Printf("%s %s\n", green("provenance:"), blue(config.Provenance))
+				fmt.Printf("%s %s\n", green("provider_retention:"), blue(formatConfigMap(config.ProviderRetention)))
+				fmt.Printf("%s %s\n", green("confidential_policy:"), blue(config.ConfidentialPolicy))
+				fmt.Printf("%s %s\n", green("secret_policy:"), blue(secretPolicy(config)))
+				if len(config.SecretPatterns) > 0 {
+					fmt.Printf("%s %s\n", green("secret_patterns:"), blue(strings.Join(config.SecretPatterns, "\n")))
+				}
+				fmt.Printf("%s %s\n", green("model_params:"), blue(formatModelParams(config.ModelParams)))
+				if config.SubjectPrefix != "" {
+					fmt.Printf("%s %s\n", green("subject_prefix:"), blue(fmt.Sprintf("%q", config.SubjectPrefix)))
+				}
+				if config.SubjectSuffix != "" {
+					fmt.Printf("%s %s\n", green("subject_suffix:"), blue(fmt.Sprintf("%q", config.SubjectSuffix)))
+				}
+				if config.Server != "" {
+					fmt.Printf("%s %s\n", green("server:"), blue(config.Server))
+				}
+				if config.ServerToken != "" {
+					fmt.Printf("%s %s\n", green("server_token:"), blue("[SET]"))
+				}
+				for _, entry := range sortedEntries(config.Aliases) {
+					fmt.Printf("%s %s\n", green(aliasKeyPrefix+entry[0]+":"), blue(entry[1]))
+				}
+				for _, entry := range sortedEntries(config.Defaults) {
+					fmt.Printf("%s %s\n", green(defaultKeyPrefix+entry[0]+":"), blue(entry[1]))
+				}
+				fmt.Print(rule())
 
 				// Show config file location
 				configPath, _ := getConfigPath()
 				fmt.Printf("\n%s %s\n", green("💾 Configuration stored at:"), blue(configPath))
+				if name, keys := repoConfigKeys(); len(keys) > 0 {
+					fmt.Printf("%s %s\n", green("📁 Overridden by "+name+":"), blue(strings.Join(keys, ", ")))
+				}
+				var overrides []string
+				for _, setting := range gitConfigSettings() {
+					overrides = append(overrides, setting[0])
+				}
+				if len(overrides) > 0 {
+					fmt.Printf("%s %s\n", green("🔧 Overridden by git config:"), blue(strings.Join(overrides, ", ")))
+				}
 				return
 			}
 
 			// Get specific key
 			key := args[0]
+			if name, ok := strings.CutPrefix(key, aliasKeyPrefix); ok {
+				fmt.Printf("%s\n", blue(config.Aliases[name]))
+				return
+			}
+			if flag, ok := strings.CutPrefix(key, defaultKeyPrefix); ok {
+				fmt.Printf("%s\n", blue(config.Defaults[flag]))
+				return
+			}
 			switch key {
 			case "api_key":
 				if config.APIKey != "" {
-					fmt.Printf("%s\n", blue("[SET]"))
+					fmt.Printf("%s\n", blue(apiKeyStatus(config)))
 				} else {
 					fmt.Printf("%s\n", red("[NOT SET]"))
 				}
+			case "api_keys":
+				fmt.Printf("%s\n", blue(fmt.Sprintf("[%d SET]", len(config.APIKeys))))
 			case "api_url":
-				fmt.Printf("%s\n", blue(config.APIURL))
+				fmt.Printf("%s\n", blue(chatURL(config)))
+			case "provider":
+				fmt.Printf("%s\n", blue(providerName(config)))
+			case "azure_endpoint":
+				fmt.Printf("%s\n", blue(config.AzureEndpoint))
+			case "azure_deployment":
+				fmt.Printf("%s\n", blue(azureDeployment(config, config.DefaultModel)))
+			case "azure_api_version":
+				fmt.Printf("%s\n", blue(azureVersion(config)))
 			case "default_model":
 				fmt.Printf("%s\n", blue(config.DefaultModel))
+			case "fallback_model":
+				fmt.Printf("%s\n", blue(config.FallbackModel))
+			case "image_thumbnails":
+				fmt.Printf("%s\n", blue(config.ImageThumbnails))
+			case "body_style":
+				fmt.Printf("%s\n", blue(config.BodyStyle))
+			case "commit_style":
+				fmt.Printf("%s\n", blue(commitStyle(config)))
+			case "commit_style_prompt":
+				fmt.Printf("%s\n", blue(config.CommitStylePrompt))
+			case "writing_style":
+				fmt.Printf("%s\n", blue(writingStyle(config)))
+			case "subject_only":
+				fmt.Printf("%s\n", blue(config.SubjectOnly))
+			case "scope_map":
+				fmt.Printf("%s\n", blue(formatConfigMap(config.ScopeMap)))
+			case "trailers":
+				fmt.Printf("%s\n", blue(formatConfigMap(config.Trailers)))
+			case "required_trailers":
+				fmt.Printf("%s\n", blue(strings.Join(config.RequiredTrailers, ",")))
+			case "ignore_patterns":
+				fmt.Printf("%s\n", blue(strings.Join(config.IgnorePatterns, ",")))
+			case "read_intent":
+				fmt.Printf("%s\n", blue(config.ReadIntent))
+			case "transcripts":
+				fmt.Printf("%s\n", blue(config.Transcripts))
+			case "max_retries":
+				fmt.Printf("%s\n", blue(config.MaxRetries))
+			case "timeout":
+				fmt.Printf("%s\n", blue(config.Timeout))
+			case "compress_requests":
+				fmt.Printf("%s\n", blue(config.CompressRequests))
+			case "prompt_compression":
+				fmt.Printf("%s\n", blue(config.PromptCompression))
+			case "prompt_version":
+				fmt.Printf("%s\n", blue(formatPromptVersion(config)))
+			case "prompt_template":
+				fmt.Printf("%s\n", blue(config.PromptTemplate))
+			case "candidates", "num_candidates":
+				fmt.Printf("%s\n", blue(config.Candidates))
+			case "auto_commit_threshold":
+				fmt.Printf("%s\n", blue(config.AutoCommitThreshold))
+			case "auto_commit_min_interval":
+				fmt.Printf("%s\n", blue(config.AutoCommitMinInterval))
+			case "auto_commit_max_per_hour":
+				fmt.Printf("%s\n", blue(config.AutoCommitMaxPerHour))
+			case "interactive_questions":
+				fmt.Printf("%s\n", blue(config.InteractiveQuestions))
+			case "max_tool_calls":
+				fmt.Printf("%s\n", blue(config.MaxToolCalls))
+			case "max_tool_tokens":
+				fmt.Printf("%s\n", blue(config.MaxToolTokens))
+			case "allowed_tools":
+				fmt.Printf("%s\n", blue(strings.Join(config.AllowedTools, ",")))
+			case "audit_log":
+				fmt.Printf("%s\n", blue(config.AuditLog))
+			case "provenance":
+				fmt.Printf("%s\n", blue(config.Provenance))
+			case "provider_retention":
+				fmt.Printf("%s\n", blue(formatConfigMap(config.ProviderRetention)))
+			case "confidential_policy":
+				fmt.Printf("%s\n", blue(config.ConfidentialPolicy))
+			case "secret_policy":
+				fmt.Printf("%s\n", blue(secretPolicy(config)))
+			case "secret_patterns":
+				fmt.Printf("%s\n", blue(strings.Join(config.SecretPatterns, "\n")))
+			case "model_params":
+				fmt.Printf("%s\n", blue(formatModelParams(config.ModelParams)))
+			case "subject_prefix":
+				fmt.Printf("%s\n", blue(fmt.Sprintf("%q", config.SubjectPrefix)))
+			case "subject_suffix":
+				fmt.Printf("%s\n", blue(fmt.Sprintf("%q", config.SubjectSuffix)))
+			case "server":
+				fmt.Printf("%s\n", blue(config.Server))
+			case "server_token":
+				if config.ServerToken != "" {
+					fmt.Printf("%s\n", blue("[SET]"))
+				} else {
+					fmt.Printf("%s\n", red("[NOT SET]"))
+				}
 			default:
-				log.Fatalf("%s %s. Valid keys are: api_key, api_url, default_model", red("Unknown configuration key:"), key)
+				log.Fatalf("%s %s. Valid keys are: %s", red("Unknown configuration key:"), key, strings.Join(configKeys, ", "))
 			}
 		},
 	}
 
+	// Values such as alias.qc "--subject-only --commit" start with dashes and aren't flags
+	setCmd.Flags().SetInterspersed(false)
+
 	// Add commands to root
 	rootCmd.AddCommand(setCmd)
 	rootCmd.AddCommand(getCmd)
+	rootCmd.AddCommand(newHookCmd())
+	rootCmd.AddCommand(newBotCmd())
+	rootCmd.AddCommand(newLoginCmd())
+	rootCmd.AddCommand(newCreditsCmd())
+	rootCmd.AddCommand(newFlushCmd())
+	rootCmd.AddCommand(newSuggestCmd())
+	rootCmd.AddCommand(newIntegrateCmd())
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newExperimentsCmd())
+	rootCmd.AddCommand(newProvenanceCmd())
+	rootCmd.AddCommand(newUndoCmd())
+	rootCmd.AddCommand(newFromIssueCmd())
+	rootCmd.AddCommand(newAddressReviewCmd())
+	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newChangelogCmd())
+	rootCmd.AddCommand(newRollupCmd())
+	rootCmd.AddCommand(newPRCmd())
+	rootCmd.AddCommand(newExamplesCmd())
+	rootCmd.AddCommand(newSelftestCmd())
+	rootCmd.AddCommand(newHelpTopicCmds()...)
 
 	// Add flags
 	rootCmd.Flags().BoolVarP(&autoCommit, "commit", "c", false, "Automatically create commit with generated message")
+	rootCmd.Flags().BoolVarP(&autoCommit, "yes", "y", false, "Commit with the generated message right away, without the menu (same as --commit)")
 	rootCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation (overrides default_model from config)")
+	rootCmd.Flags().StringVar(&commitType, "type", "", "Use this conventional commit type, e.g. fix, and let the model write the subject and body")
+	rootCmd.Flags().StringVar(&style, "style", "", "Commit convention (conventional, angular, gitmoji, plain, custom) and/or writing style (terse, narrative, changelog, beginner), e.g. gitmoji,terse (overrides commit_style and writing_style)")
+	rootCmd.Flags().StringVar(&commitScope, "scope", "", "Use this scope, e.g. auth, instead of the one detected from the changed paths")
+	rootCmd.Flags().BoolVar(&subjectOnly, "subject-only", false, "Generate only a short subject line (no body) using minimal tokens")
+	rootCmd.Flags().StringArrayVar(&attachments, "attach", nil, "Attach an image (e.g. a UI screenshot) for vision-capable models (repeatable)")
+	rootCmd.Flags().StringVar(&transcript, "transcript", transcriptFile, "Save the redacted prompt/response transcript after committing: off, file (.rmit/transcripts/) or notes (git notes --ref=rmit)")
+	rootCmd.Flags().Lookup("transcript").NoOptDefVal = transcriptFile
+	rootCmd.Flags().BoolVar(&printOnly, printFlag, false, "Print only the generated message to stdout, without committing, the banner, color or questions (exit codes as for --stdin-context)")
+	rootCmd.Flags().BoolVar(&stdinDiff, stdinContextFlag, false, "Read a prepared diff from stdin and print only the message (exit codes: 0 ok, 1 usage, 2 no changes, 3 generation failed)")
+	rootCmd.Flags().StringVar(&context, "context", "", "Describe the intent of the change, e.g. \"refactoring for the v2 API migration\"")
+	rootCmd.Flags().BoolVar(&explain, "explain-choices", false, "Also show why the model chose the type, scope and key change, to learn from (never committed)")
+	rootCmd.Flags().BoolVar(&preview, "preview", false, "Show the files about to be sent and toggle some out of the prompt (they're still committed)")
+	rootCmd.Flags().IntVar(&maxCommitFiles, "max-commit-files", 0, "Split changes to more files than this into several commits grouped by directory, each with its own message")
+	rootCmd.Flags().StringSliceVar(&commitOnly, "commit-only", nil, "Commit only these paths, as they are in the working tree, instead of the staged changes (comma separated or repeated)")
+	rootCmd.Flags().BoolVarP(&stageAll, "all", "a", false, "Stage and commit every change, untracked files included, instead of only the staged changes")
+	rootCmd.MarkFlagsMutuallyExclusive("all", "commit-only")
+	rootCmd.Flags().StringSliceVar(&describeOnly, "describe-only", nil, "Describe only the changes to these paths; what gets committed doesn't change (comma separated or repeated)")
+	rootCmd.Flags().IntVar(&candidates, "candidates", 1, fmt.Sprintf("Generate this many messages (up to %d), ranked locally with the best first, and pick one", maxCandidates))
+	rootCmd.Flags().IntVar(&maxInvalidRetries, "max-retries-on-invalid", 0, "Regenerate a message that is empty, not a conventional commit, generic, overlong or missing template sections up to this many times, then fail instead of committing it")
+	rootCmd.Flags().IntVar(&autoThreshold, "auto-threshold", 0, "Commit without asking when the message scores at least this on a small, clean diff; risky or large diffs still ask")
+	rootCmd.Flags().IntVar(&compression, "compress", 0, "Compress the diff in the prompt (comment-only changes, import reordering, fixture churn, context lines) until it's this many percent smaller")
+	rootCmd.Flags().StringVar(&remote, "remote", "", "Describe and commit the changes of a checkout on another machine over SSH, e.g. devbox:~/src/app")
+	rootCmd.Flags().StringVar(&vcsName, "vcs", vcsAuto, "Version control system of the working copy: git, jj, sapling or svn (auto detects it from .jj, .sl, .git or .svn)")
+	rootCmd.Flags().StringVar(&execIn, "exec-in", "", "Run git inside a container, for repositories mounted only in a dev container, e.g. docker:devcontainer or podman:app:/workspace")
+	rootCmd.Flags().StringVar(&server, "server", "", "Generate with a shared rmit server instead of calling the API directly, e.g. http://rmit.internal:7878")
+	rootCmd.Flags().StringArrayVar(&trailers, "trailer", nil, "Add a trailer such as \"Reviewed-by: Jane <jane@example.com>\" (repeatable)")
+	rootCmd.Flags().StringVar(&changelogLabel, "changelog", "", "File the commit under a changelog section (Added, Changed, Deprecated, Removed, Fixed, Security, or skip) with a Changelog trailer")
+
+	// Output and profiling flags apply to every command
+	rootCmd.PersistentFlags().BoolVar(&noRedact, "no-redact", false, "Send API keys, tokens and private keys in the changes to the model instead of redacting them")
+	rootCmd.PersistentFlags().BoolVar(&noPersist, "no-persist", false, "Write nothing to disk but the commit: no caches, logs, transcripts, undo snapshots or config changes, for shared machines")
+	rootCmd.PersistentFlags().StringVar(&timeoutFlag, "timeout", "", "Give up on a request to the model after this long, e.g. 90s or 5m, 0 for no limit (default: the timeout setting, 2m)")
+	rootCmd.PersistentFlags().BoolVar(&debugOutput, "debug", false, "Print the provider, model and prompt version of every generation to stderr")
+	rootCmd.PersistentFlags().BoolVar(&profile, "profile", false, "Print where the time went: git commands, prompt build, network, post-processing")
+	rootCmd.PersistentFlags().StringVar(&cpuProfile, "cpuprofile", "", "Write a CPU profile to this file")
+	rootCmd.PersistentFlags().StringVar(&memProfile, "memprofile", "", "Write a memory profile to this file when done")
+	rootCmd.PersistentFlags().StringVar(&outputMode, "output", outputColor, "How to show progress, messages and prompts: color, plain, json (one event per line), quiet (only warnings and errors) or a11y (for screen readers)")
+	rootCmd.PersistentFlags().BoolVar(&assumeYes, "assume-yes", false, "Answer yes to every question: commit, reuse a previous message, commit despite leftovers, update .gitignore")
+	rootCmd.PersistentFlags().BoolVar(&assumeNo, "assume-no", false, "Answer no to every question, e.g. to only generate and show the message without committing")
+	rootCmd.MarkFlagsMutuallyExclusive("assume-yes", "assume-no")
+	var stopCPUProfile func()
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		loadFlagDefaults(cmd)
+		if err := setOutputMode(outputMode); err != nil {
+			log.Fatalf("%s %v", red("Error:"), err)
+		}
+		if assumeYes {
+			assumedAnswer = "y"
+		} else if assumeNo {
+			assumedAnswer = "n"
+		}
+		if noPersistFromEnv() {
+			noPersist = true
+		}
+		if cmd.Flags().Changed("timeout") {
+			timeout, err := parseTimeout(timeoutFlag)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			timeoutOverride = &timeout
+		}
+		if profile {
+			profiler.start()
+		}
+		if cpuProfile != "" {
+			var err error
+			if stopCPUProfile, err = startCPUProfile(cpuProfile); err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+		}
+	}
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		if stopCPUProfile != nil {
+			stopCPUProfile()
+		}
+		if memProfile != "" {
+			if err := writeMemProfile(memProfile); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		profiler.report(os.Stderr)
+	}
 
 	// Disable the built-in completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
+	// Aliases from the config are expanded before Cobra sees the arguments
+	args, err := expandAliases(rootCmd, os.Args[1:])
+	if err != nil {
+		log.Fatalf("%s %v", red("Error:"), err)
+	}
+	rootCmd.SetArgs(args)
+
 	// Execute command
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Printf("%s\n", red(err))
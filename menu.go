@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MenuOption is one choice in a menu, picked by pressing its key
+type MenuOption struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// assumedAnswer answers every question without asking: "y" with --assume-yes, "n" with --assume-no
+var assumedAnswer string
+
+// assumeAnswer returns the assumed answer to a question, if any, showing it so the decision is
+// still in the output
+func assumeAnswer(question string) (string, bool) {
+	if assumedAnswer == "" {
+		return "", false
+	}
+	ui.Info(strings.TrimSuffix(strings.TrimSpace(question), ":") + " " + assumedAnswer + " (assumed)")
+	return assumedAnswer, true
+}
+
+// choose asks the user to pick an option, unless the answer is assumed
+func choose(question string, options []MenuOption) (string, error) {
+	if answer, ok := assumeAnswer(question); ok {
+		return answer, nil
+	}
+	return ui.Choose(question, options)
+}
+
+// errInterrupted is returned when the user presses Ctrl+C in a menu
+var errInterrupted = errors.New("interrupted")
+
+// menuKeys lists the option keys for a line-mode question, e.g. "[y/n/g]"
+func menuKeys(options []MenuOption) string {
+	keys := make([]string, len(options))
+	for i, option := range options {
+		keys[i] = option.Key
+	}
+	return "[" + strings.Join(keys, "/") + "]"
+}
+
+// chooseByLine asks for a choice as a typed line. The answer is returned lowercased, and an empty
+// answer picks the first option.
+func chooseByLine(prompt func(string) (string, error), question string, options []MenuOption) (string, error) {
+	answer, err := prompt(question + " " + menuKeys(options) + ": ")
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return options[0].Key, nil
+	}
+	return strings.ToLower(answer), nil
+}
+
+// Choose reads a single keypress when stdin and stdout are a terminal: the option's key picks it
+// right away, or the arrow keys move the highlight and Enter picks it. Otherwise, e.g. when input
+// is piped or on Windows, the options are listed and a line is read.
+func (t *terminalUI) Choose(question string, options []MenuOption) (string, error) {
+	t.endStream()
+	if t.tty != nil && t.in.Buffered() == 0 {
+		if restore, err := keypressMode(t.tty); err == nil {
+			defer restore()
+			return t.chooseByKey(question, options)
+		}
+	}
+
+	lines := make([]string, len(options))
+	for i, option := range options {
+		lines[i] = fmt.Sprintf("  %s - %s", option.Key, option.Label)
+	}
+	t.Panel("⚙️  OPTIONS:", strings.Join(lines, "\n"))
+	return chooseByLine(t.Prompt, question, options)
+}
+
+// chooseByKey shows the options with the first highlighted and reads keys until one is picked
+func (t *terminalUI) chooseByKey(question string, options []MenuOption) (string, error) {
+	defer profilePhase(phaseInput)()
+	cursor := 0
+	render := func() {
+		for i, option := range options {
+			line := fmt.Sprintf("  %s  %s", option.Key, option.Label)
+			if i == cursor {
+				line = t.paint(cyan, fmt.Sprintf("❯ %s  %s", option.Key, option.Label))
+			}
+			fmt.Fprintf(t.out, "\r\x1b[K%s\n", line)
+		}
+	}
+	// done replaces the menu with the question and the choice
+	done := func(choice string) {
+		fmt.Fprintf(t.out, "\x1b[%dA\r\x1b[J", len(options)+1)
+		fmt.Fprintf(t.out, "%s %s\n", t.paint(yellow, question), choice)
+	}
+
+	fmt.Fprintf(t.out, "\n%s %s\n", t.paint(yellow, question), "(press a key, or ↑/↓ and Enter)")
+	render()
+	for {
+		key, err := t.in.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch key {
+		case 3: // Ctrl+C
+			done("")
+			return "", errInterrupted
+		case 4: // Ctrl+D
+			done("")
+			return "", io.EOF
+		case '\r', '\n':
+			done(options[cursor].Label)
+			return options[cursor].Key, nil
+		case 0x1b:
+			// Arrow keys arrive as ESC [ A or ESC O A; a lone Escape is ignored
+			if t.in.Buffered() < 2 {
+				continue
+			}
+			t.in.ReadByte()
+			switch arrow, _ := t.in.ReadByte(); arrow {
+			case 'A':
+				cursor = (cursor + len(options) - 1) % len(options)
+			case 'B':
+				cursor = (cursor + 1) % len(options)
+			default:
+				continue
+			}
+			fmt.Fprintf(t.out, "\x1b[%dA", len(options))
+			render()
+		default:
+			for _, option := range options {
+				if strings.EqualFold(option.Key, string(key)) {
+					done(option.Label)
+					return option.Key, nil
+				}
+			}
+		}
+	}
+}
+
+func (j *jsonUI) Choose(question string, options []MenuOption) (string, error) {
+	return chooseByLine(func(question string) (string, error) {
+		j.out.Encode(UIEvent{Type: "prompt", Question: strings.TrimSpace(question), Options: options})
+		return readAnswer(j.in)
+	}, question, options)
+}
+
+func (q *quietUI) Choose(question string, options []MenuOption) (string, error) {
+	return chooseByLine(q.Prompt, question, options)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// requestDurationBuckets are the upper bounds, in seconds, of the request latency histogram
+var requestDurationBuckets = []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120}
+
+// serveMetrics collects what rmit serve exposes on /metrics. Methods are no-ops on nil, so
+// generation code can report to it whether or not it runs inside rmit serve.
+type serveMetrics struct {
+	mu                sync.Mutex
+	requests          map[string]int64 // finished generate requests by status
+	durationBuckets   []int64
+	durationSum       float64
+	durationCount     int64
+	promptTokens      int64
+	completionTokens  int64
+	providerResponses map[int]int64 // provider responses by HTTP status, 0 for network failures
+}
+
+// metrics is set by rmit serve
+var metrics *serveMetrics
+
+// newServeMetrics returns an empty metrics collector
+func newServeMetrics() *serveMetrics {
+	return &serveMetrics{
+		requests:          make(map[string]int64),
+		durationBuckets:   make([]int64, len(requestDurationBuckets)),
+		providerResponses: make(map[int]int64),
+	}
+}
+
+// observeRequest records a finished generate request
+func (m *serveMetrics) observeRequest(status string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[status]++
+	seconds := duration.Seconds()
+	for i, bound := range requestDurationBuckets {
+		if seconds <= bound {
+			m.durationBuckets[i]++
+		}
+	}
+	m.durationSum += seconds
+	m.durationCount++
+}
+
+// observeProvider records the HTTP status of a provider response, or 0 if it couldn't be reached
+func (m *serveMetrics) observeProvider(statusCode int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.providerResponses[statusCode]++
+}
+
+// addUsage adds the token usage reported in a chat completion response
+func (m *serveMetrics) addUsage(body []byte) {
+	if m == nil {
+		return
+	}
+	var response OpenRouterResponse
+	if err := json.Unmarshal(body, &response); err != nil || response.Usage == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.promptTokens += int64(response.Usage.prompt())
+	m.completionTokens += int64(response.Usage.completion())
+}
+
+// write renders the metrics in the Prometheus text exposition format
+func (m *serveMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP rmit_requests_total Generate requests handled, by final status.")
+	fmt.Fprintln(w, "# TYPE rmit_requests_total counter")
+	statuses := []string{jobDone, jobFailed, jobCanceled}
+	for _, status := range statuses {
+		fmt.Fprintf(w, "rmit_requests_total{status=%q} %d\n", status, m.requests[status])
+	}
+
+	fmt.Fprintln(w, "# HELP rmit_request_duration_seconds Time to generate a commit message.")
+	fmt.Fprintln(w, "# TYPE rmit_request_duration_seconds histogram")
+	for i, bound := range requestDurationBuckets {
+		fmt.Fprintf(w, "rmit_request_duration_seconds_bucket{le=%q} %d\n", strconv.FormatFloat(bound, 'g', -1, 64), m.durationBuckets[i])
+	}
+	fmt.Fprintf(w, "rmit_request_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.durationCount)
+	fmt.Fprintf(w, "rmit_request_duration_seconds_sum %g\n", m.durationSum)
+	fmt.Fprintf(w, "rmit_request_duration_seconds_count %d\n", m.durationCount)
+
+	fmt.Fprintln(w, "# HELP rmit_tokens_total Tokens used, as reported by the provider.")
+	fmt.Fprintln(w, "# TYPE rmit_tokens_total counter")
+	fmt.Fprintf(w, "rmit_tokens_total{type=\"prompt\"} %d\n", m.promptTokens)
+	fmt.Fprintf(w, "rmit_tokens_total{type=\"completion\"} %d\n", m.completionTokens)
+
+	fmt.Fprintln(w, "# HELP rmit_provider_responses_total Provider responses by HTTP status code; code \"0\" means the provider couldn't be reached.")
+	fmt.Fprintln(w, "# TYPE rmit_provider_responses_total counter")
+	var codes []int
+	for code := range m.providerResponses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(w, "rmit_provider_responses_total{code=\"%d\"} %d\n", code, m.providerResponses[code])
+	}
+}
+
+// handleMetrics serves /metrics for Prometheus
+func (m *serveMetrics) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.write(w)
+}
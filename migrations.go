@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Reversibility of a database migration
+const (
+	migrationReversible   = "reversible"
+	migrationIrreversible = "irreversible"
+	migrationUnknown      = "reversibility unknown"
+)
+
+// Migration describes a database migration file found in the diff
+type Migration struct {
+	Path          string
+	Framework     string
+	Reversibility string
+}
+
+// alembicDowngradeIsNoop reports whether an Alembic migration lacks a real downgrade()
+func alembicDowngradeIsNoop(content string) bool {
+	idx := strings.Index(content, "def downgrade(")
+	if idx == -1 {
+		return true
+	}
+
+	lines := strings.Split(content[idx:], "\n")
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, `"""`) {
+			continue
+		}
+		// An unindented line ends the function body
+		if !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			break
+		}
+		if trimmed != "pass" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// detectMigrations finds database migration files among the changed files
+func detectMigrations(files []*FileDiff) []Migration {
+	var migrations []Migration
+
+	for _, f := range files {
+		if f.Deleted {
+			continue
+		}
+		p := f.Path
+		name := path.Base(p)
+
+		switch {
+		case strings.HasSuffix(name, ".down.sql"):
+			// Counted together with the matching up migration
+		case strings.HasSuffix(name, ".up.sql"):
+			reversibility := migrationIrreversible
+			if repoFileExists(strings.TrimSuffix(p, ".up.sql") + ".down.sql") {
+				reversibility = migrationReversible
+			}
+			migrations = append(migrations, Migration{Path: p, Framework: "golang-migrate", Reversibility: reversibility})
+		case strings.Contains(p, "prisma/migrations/") && name == "migration.sql":
+			// Prisma Migrate only generates forward migrations
+			migrations = append(migrations, Migration{Path: p, Framework: "Prisma", Reversibility: migrationIrreversible})
+		case strings.Contains(p, "/versions/") && strings.HasSuffix(name, ".py"):
+			content, err := readRepoFile(p)
+			if err != nil || !strings.Contains(content, "def upgrade(") {
+				continue
+			}
+			reversibility := migrationReversible
+			if alembicDowngradeIsNoop(content) {
+				reversibility = migrationIrreversible
+			}
+			migrations = append(migrations, Migration{Path: p, Framework: "Alembic", Reversibility: reversibility})
+		case strings.Contains(p, "db/migrate/") && strings.HasSuffix(name, ".rb"):
+			content, _ := readRepoFile(p)
+			reversibility := migrationUnknown
+			switch {
+			case strings.Contains(content, "IrreversibleMigration"):
+				reversibility = migrationIrreversible
+			case strings.Contains(content, "def down") || strings.Contains(content, "def change"):
+				reversibility = migrationReversible
+			case strings.Contains(content, "def up"):
+				reversibility = migrationIrreversible
+			}
+			migrations = append(migrations, Migration{Path: p, Framework: "Rails", Reversibility: reversibility})
+		case strings.HasSuffix(name, ".sql") && strings.Contains(p, "migration"):
+			migrations = append(migrations, Migration{Path: p, Framework: "SQL", Reversibility: migrationUnknown})
+		}
+	}
+
+	return migrations
+}
+
+// migrationInsights describes migrations in the diff for the prompt
+func migrationInsights(files []*FileDiff) []string {
+	migrations := detectMigrations(files)
+	if len(migrations) == 0 {
+		return nil
+	}
+
+	var insights []string
+	for _, m := range migrations {
+		insights = append(insights, fmt.Sprintf("Database migration %s (%s, %s).", m.Path, m.Framework, m.Reversibility))
+	}
+	insights = append(insights, "This commit changes the database schema. The commit message must say so and state whether the migration is reversible.")
+
+	return insights
+}
+
+// ensureMigrationNote appends a schema change note to the message if the model left it out
+func ensureMigrationNote(message string, migrations []Migration) string {
+	if len(migrations) == 0 {
+		return message
+	}
+
+	lower := strings.ToLower(message)
+	if strings.Contains(lower, "migration") || strings.Contains(lower, "schema") {
+		return message
+	}
+
+	var note strings.Builder
+	note.WriteString("\n\nIncludes database schema migrations:\n")
+	for _, m := range migrations {
+		fmt.Fprintf(&note, "- %s (%s)\n", m.Path, m.Reversibility)
+	}
+
+	return message + strings.TrimRight(note.String(), "\n")
+}
+
+// printMigrationWarning warns interactively that the commit contains migrations
+func printMigrationWarning(migrations []Migration) {
+	if len(migrations) == 0 {
+		return
+	}
+
+	lines := []string{"\n⚠️  This commit includes database migrations:"}
+	for _, m := range migrations {
+		reversibility := green(m.Reversibility)
+		if m.Reversibility != migrationReversible {
+			reversibility = red(m.Reversibility)
+		}
+		lines = append(lines, fmt.Sprintf("  %s %s (%s)", cyan(m.Path), blue(m.Framework), reversibility))
+	}
+	ui.Warn(strings.Join(lines, "\n"))
+}
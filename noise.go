@@ -0,0 +1,326 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of changes that don't change what the code does
+const (
+	noiseImports = "imports"
+	noiseFormat  = "format"
+	noiseLicense = "license"
+)
+
+// licenseHeaderLines is how far into a file a license header may reach
+const licenseHeaderLines = 30
+
+var (
+	// hunkRangePattern matches the old and new start lines in a hunk header
+	hunkRangePattern = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)`)
+	// licenseKeywordPattern matches the lines license headers are made of
+	licenseKeywordPattern = regexp.MustCompile(`(?i)copyright|licen[cs]e|spdx-license-identifier|all rights reserved|\(c\)|©`)
+	// trailingCommaPattern matches a comma before a closing bracket, once whitespace is removed
+	trailingCommaPattern = regexp.MustCompile(`,([)\]}])`)
+)
+
+// prettierExtensions are formatted by tools that also rewrite quotes, semicolons and trailing commas
+var prettierExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".ts": true, ".tsx": true, ".mjs": true, ".cjs": true,
+	".json": true, ".css": true, ".scss": true, ".less": true, ".vue": true, ".svelte": true,
+}
+
+// withoutReorderedImports returns a file's changes with its imports taken out, if the same
+// imports were added as removed, i.e. they were only reordered
+func withoutReorderedImports(f *FileDiff) (*FileDiff, bool) {
+	isImport := func(line string) bool {
+		return importLinePattern.MatchString(line) || (path.Ext(f.Path) == ".go" && goImportSpecPattern.MatchString(line))
+	}
+	partition := func(lines []string) (imports, rest []string) {
+		for _, line := range lines {
+			switch {
+			case strings.TrimSpace(line) == "":
+			case isImport(line):
+				imports = append(imports, strings.TrimSpace(line))
+			default:
+				rest = append(rest, line)
+			}
+		}
+		sort.Strings(imports)
+		return imports, rest
+	}
+
+	addedImports, addedRest := partition(f.Added)
+	removedImports, removedRest := partition(f.Removed)
+	if len(addedImports) == 0 || strings.Join(addedImports, "\n") != strings.Join(removedImports, "\n") {
+		return nil, false
+	}
+
+	// The hunks keep their shape without the import lines, so the rest is judged run by run
+	var text strings.Builder
+	inHunk := false
+	for _, line := range strings.Split(f.Text, "\n") {
+		inHunk = inHunk || strings.HasPrefix(line, "@@")
+		if inHunk && line != "" && (line[0] == '+' || line[0] == '-') && (strings.TrimSpace(line[1:]) == "" || isImport(line[1:])) {
+			continue
+		}
+		text.WriteString(line + "\n")
+	}
+	return &FileDiff{Path: f.Path, Text: text.String(), Added: addedRest, Removed: removedRest}, true
+}
+
+// changeRun is a stretch of removed and added lines between unchanged lines of a hunk
+type changeRun struct {
+	Removed []string
+	Added   []string
+}
+
+// changeRuns splits a file's hunks into the runs of lines that changed together
+func changeRuns(f *FileDiff) []changeRun {
+	var runs []changeRun
+	open := false // whether the last run can take more lines
+	inHunk := false
+	for _, line := range strings.Split(f.Text, "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			inHunk, open = true, false
+		case !inHunk || line == "" || line[0] == '\\':
+			// File headers and "\ No newline at end of file"
+		case line[0] == '+' || line[0] == '-':
+			if !open {
+				runs = append(runs, changeRun{})
+				open = true
+			}
+			run := &runs[len(runs)-1]
+			if line[0] == '+' {
+				run.Added = append(run.Added, line[1:])
+			} else {
+				run.Removed = append(run.Removed, line[1:])
+			}
+		default:
+			open = false
+		}
+	}
+	return runs
+}
+
+// indentedExtensions are languages where indentation and line breaks are part of the code
+var indentedExtensions = map[string]bool{".py": true, ".yml": true, ".yaml": true, ".coffee": true, ".haml": true}
+
+// formatOnly reports whether a file's changes only touch whitespace and line breaks, or for
+// prettier-style formatters also quotes, semicolons and trailing commas. In languages where
+// indentation matters, only spacing within lines may change. Every run of changed lines has to
+// say the same before and after on its own, so moved, added or removed code isn't formatting.
+func formatOnly(f *FileDiff) bool {
+	ext := strings.ToLower(path.Ext(f.Path))
+	prettier := prettierExtensions[ext]
+	normalize := func(lines []string) string {
+		var code strings.Builder
+		for _, line := range lines {
+			if indentedExtensions[ext] || path.Base(f.Path) == "Makefile" {
+				indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+				code.WriteString(indent + strings.Join(strings.Fields(line), " ") + "\n")
+				continue
+			}
+			line = strings.TrimSpace(line)
+			if prettier {
+				line = strings.ReplaceAll(strings.TrimSuffix(line, ";"), "'", `"`)
+			}
+			code.WriteString(strings.Join(strings.Fields(line), ""))
+		}
+		if prettier {
+			return trailingCommaPattern.ReplaceAllString(code.String(), "$1")
+		}
+		return code.String()
+	}
+
+	runs := changeRuns(f)
+	changed := false
+	for _, run := range runs {
+		added, removed := normalize(run.Added), normalize(run.Removed)
+		if added != removed {
+			return false
+		}
+		changed = changed || added != ""
+	}
+	return changed
+}
+
+// licenseHeaderOnly reports whether a file's changes are all comments near the top of the file,
+// at least one of them mentioning a copyright or license
+func licenseHeaderOnly(f *FileDiff) bool {
+	prefixes := commentPrefixes(f.Path)
+	if prefixes == nil || f.New || f.Deleted {
+		return false
+	}
+
+	// Lines starting with * are only comments inside a /* */ block, *p = 1 is code
+	blocks := slices.Contains(prefixes, "/*")
+	var linePrefixes []string
+	for _, prefix := range prefixes {
+		if prefix != "*" && prefix != "*/" {
+			linePrefixes = append(linePrefixes, prefix)
+		}
+	}
+
+	mentionsLicense := false
+	oldLine, newLine := 0, 0
+	oldBlock, newBlock := false, false // whether a /* comment is open on each side
+	for _, text := range strings.Split(f.Text, "\n") {
+		if match := hunkRangePattern.FindStringSubmatch(text); match != nil {
+			oldLine, _ = strconv.Atoi(match[1])
+			newLine, _ = strconv.Atoi(match[2])
+			// A comment opened above the hunk isn't in view, so its lines don't count as comments
+			oldBlock, newBlock = false, false
+			continue
+		}
+		if newLine == 0 || strings.HasPrefix(text, "+++ ") || strings.HasPrefix(text, "--- ") || text == "" {
+			continue
+		}
+
+		var line int
+		var inBlock bool
+		content := text[1:]
+		switch text[0] {
+		case ' ':
+			oldLine++
+			newLine++
+			oldBlock, newBlock = blockCommentOpen(oldBlock, content), blockCommentOpen(newBlock, content)
+			continue
+		case '+':
+			line, inBlock = newLine, newBlock
+			newLine++
+			newBlock = blockCommentOpen(newBlock, content)
+		case '-':
+			line, inBlock = oldLine, oldBlock
+			oldLine++
+			oldBlock = blockCommentOpen(oldBlock, content)
+		default:
+			continue
+		}
+		if line > licenseHeaderLines {
+			return false
+		}
+		trimmed := strings.TrimSpace(content)
+		if trimmed == "" {
+			continue
+		}
+		isComment := blocks && inBlock
+		for _, prefix := range linePrefixes {
+			if strings.HasPrefix(trimmed, prefix) {
+				isComment = true
+				break
+			}
+		}
+		if !isComment {
+			return false
+		}
+		mentionsLicense = mentionsLicense || licenseKeywordPattern.MatchString(trimmed)
+	}
+	return mentionsLicense
+}
+
+// blockCommentOpen reports whether a /* */ comment is still open after a line, given whether it
+// was open before it
+func blockCommentOpen(open bool, line string) bool {
+	for {
+		marker := "/*"
+		if open {
+			marker = "*/"
+		}
+		i := strings.Index(line, marker)
+		if i == -1 {
+			return open
+		}
+		line, open = line[i+2:], !open
+	}
+}
+
+// noiseKind returns what kind of no-op change a file has, or "" if it changes the code
+func noiseKind(f *FileDiff) string {
+	if f.Binary || f.New || f.Deleted || (len(f.Added) == 0 && len(f.Removed) == 0) {
+		return ""
+	}
+	if rest, ok := withoutReorderedImports(f); ok {
+		if len(rest.Added) == 0 && len(rest.Removed) == 0 {
+			return noiseImports
+		}
+		if formatOnly(rest) {
+			return noiseFormat
+		}
+	}
+	switch {
+	case formatOnly(f):
+		return noiseFormat
+	case licenseHeaderOnly(f):
+		return noiseLicense
+	}
+	return ""
+}
+
+// noiseSummary keeps formatting, import order and license header changes out of the prompt
+// when they're mixed in with real changes
+func noiseSummary(f *FileDiff) *FileSummary {
+	switch noiseKind(f) {
+	case noiseImports:
+		return &FileSummary{Path: f.Path, Title: "Import order only", Lines: []string{"imports reordered, no code changes"}}
+	case noiseFormat:
+		return &FileSummary{Path: f.Path, Title: "Formatting only", Lines: []string{"reformatted, no code changes"}}
+	case noiseLicense:
+		return &FileSummary{Path: f.Path, Title: "License header only", Lines: []string{"license header updated, no code changes"}}
+	}
+	return nil
+}
+
+// noiseCommitMessage builds a style: or chore: message when no file changes what the code does
+func noiseCommitMessage(diff string) (string, bool) {
+	files := parseDiff(diff)
+	if len(files) == 0 {
+		return "", false
+	}
+
+	byKind := make(map[string][]string)
+	for _, f := range files {
+		kind := noiseKind(f)
+		if kind == "" {
+			return "", false
+		}
+		byKind[kind] = append(byKind[kind], f.Path)
+	}
+
+	target := path.Base(files[0].Path)
+	if len(files) > 1 {
+		target = fmt.Sprintf("%d files", len(files))
+	}
+
+	var subject string
+	switch {
+	case len(byKind) > 1 && len(byKind[noiseLicense]) > 0:
+		subject = "chore: update license headers and formatting in " + target
+	case len(byKind[noiseLicense]) > 0:
+		subject = "chore: update license headers in " + target
+	case len(byKind) > 1:
+		subject = "style: reformat and reorder imports in " + target
+	case len(byKind[noiseImports]) > 0:
+		subject = "style: reorder imports in " + target
+	default:
+		subject = "style: reformat " + target
+	}
+	if len(files) == 1 {
+		return subject, true
+	}
+
+	var message strings.Builder
+	message.WriteString(subject + "\n\n")
+	for _, kind := range []string{noiseFormat, noiseImports, noiseLicense} {
+		for _, filePath := range byKind[kind] {
+			fmt.Fprintf(&message, "- %s (%s)\n", filePath, kind)
+		}
+	}
+	return strings.TrimSpace(message.String()), true
+}
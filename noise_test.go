@@ -0,0 +1,136 @@
+package main
+
+import "testing"
+
+func TestNoiseCommitMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want string // "" when the model has to write the message
+	}{
+		{
+			name: "reformatted",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@
+ func main() {
+-	data :=   read(path)
++	data := read(path)
+ 	fmt.Println(data)
+ }
+`,
+			want: "style: reformat main.go",
+		},
+		{
+			name: "line broken differently",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ func main() {
+-	call(a, b)
++	call(a,
++		b)
+ }
+`,
+			want: "style: reformat main.go",
+		},
+		{
+			name: "moved line",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,5 @@
+ func main() {
+-	os.Remove(path)
+ 	data := read(path)
++	os.Remove(path)
+ 	fmt.Println(data)
+ }
+`,
+		},
+		{
+			name: "reordered within a run",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@
+ func main() {
+-	os.Remove(path)
+-	data := read(path)
++	data := read(path)
++	os.Remove(path)
+ }
+`,
+		},
+		{
+			name: "added line",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ func main() {
++	os.Remove(path)
+ 	data := read(path)
+ }
+`,
+		},
+		{
+			name: "reordered imports",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,5 @@
+ import (
+-	"os"
+ 	"fmt"
++	"os"
+ )
+`,
+			want: "style: reorder imports in main.go",
+		},
+		{
+			name: "license header in a block comment",
+			diff: `diff --git a/main.c b/main.c
+--- a/main.c
++++ b/main.c
+@@ -1,4 +1,4 @@
+ /*
+- * Copyright 2023 Example
++ * Copyright 2024 Example
+  */
+ #include <stdio.h>
+`,
+			want: "chore: update license headers in main.c",
+		},
+		{
+			name: "dereference near a license comment",
+			diff: `diff --git a/main.c b/main.c
+--- a/main.c
++++ b/main.c
+@@ -1,4 +1,5 @@
+-// Copyright 2023 Example
++// Copyright 2024 Example
+ int main(void) {
+-	*p = 1;
++	*p = 2;
+ }
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := noiseCommitMessage(tt.diff)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("noiseCommitMessage() = %q, want the model to write it", got)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("noiseCommitMessage() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
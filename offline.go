@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Offline queue
+const (
+	offlinePlaceholder  = "chore: pending commit message (rmit)"
+	offlineQueueGitPath = "rmit-queue"
+	queueKeyFileName    = ".rmit_queue_key"
+)
+
+// QueuedCommit is a commit made with a placeholder message while offline
+type QueuedCommit struct {
+	Commit  string    `json:"commit"`
+	Diff    string    `json:"diff"`
+	Created time.Time `json:"created"`
+}
+
+// isOfflineError reports whether generation failed because the API couldn't be reached
+func isOfflineError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// offlineQueueDir returns the queue directory inside the repository's git directory
+func offlineQueueDir() (string, error) {
+	out, err := gitCommand("rev-parse", "--git-path", offlineQueueGitPath).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	return filepath.Abs(strings.TrimSpace(string(out)))
+}
+
+// queueKey returns the key queued diffs are encrypted with, creating it on first use
+func queueKey() ([]byte, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	keyPath := filepath.Join(homeDir, queueKeyFileName)
+
+	if key, err := os.ReadFile(keyPath); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate queue key: %w", err)
+	}
+	if err := os.WriteFile(keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write queue key: %w", err)
+	}
+	return key, nil
+}
+
+// queueCipher returns the AES-GCM cipher for queued diffs
+func queueCipher() (cipher.AEAD, error) {
+	key, err := queueKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// queueCommit stores the diff of a placeholder commit, encrypted, for rmit flush
+func queueCommit(commit, diff string) error {
+	dir, err := offlineQueueDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	plaintext, err := json.Marshal(QueuedCommit{Commit: commit, Diff: diff, Created: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to encode queued commit: %w", err)
+	}
+	aead, err := queueCipher()
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+	if err := os.WriteFile(filepath.Join(dir, commit+".enc"), sealed, 0600); err != nil {
+		return fmt.Errorf("failed to write queued commit: %w", err)
+	}
+	return nil
+}
+
+// readQueue loads all queued commits, oldest first
+func readQueue() ([]QueuedCommit, error) {
+	dir, err := offlineQueueDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := filepath.Glob(filepath.Join(dir, "*.enc"))
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+
+	aead, err := queueCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []QueuedCommit
+	for _, entry := range entries {
+		sealed, err := os.ReadFile(entry)
+		if err != nil || len(sealed) < aead.NonceSize() {
+			continue
+		}
+		plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt %s (was %s replaced?): %w", filepath.Base(entry), "~/"+queueKeyFileName, err)
+		}
+		var queued QueuedCommit
+		if err := json.Unmarshal(plaintext, &queued); err == nil {
+			queue = append(queue, queued)
+		}
+	}
+
+	sort.Slice(queue, func(i, j int) bool { return queue[i].Created.Before(queue[j].Created) })
+	return queue, nil
+}
+
+// dequeueCommit removes a commit from the queue
+func dequeueCommit(commit string) {
+	if dir, err := offlineQueueDir(); err == nil {
+		os.Remove(filepath.Join(dir, commit+".enc"))
+	}
+}
+
+// gitOutput runs git and returns its trimmed output
+func gitOutput(args ...string) (string, error) {
+	defer profilePhase(phaseGit)()
+	out, err := gitCommand(args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// rewordCommits replaces the messages of commits on the current branch without touching
+// trees or the working directory, by recreating them and their descendants with commit-tree.
+// History after the oldest reworded commit must be linear.
+func rewordCommits(messages map[string]string, oldest string) error {
+	head, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	descendants, err := gitOutput("rev-list", "--reverse", "--ancestry-path", oldest+"..HEAD")
+	if err != nil {
+		return err
+	}
+	commits := []string{oldest}
+	if descendants != "" {
+		commits = append(commits, strings.Split(descendants, "\n")...)
+	}
+
+	rewritten := make(map[string]string)
+	for _, commit := range commits {
+		info, err := gitOutput("log", "-1", "--format=%T%x00%P%x00%an%x00%ae%x00%ad%x00%cn%x00%ce%x00%cd", "--date=raw", commit)
+		if err != nil {
+			return err
+		}
+		fields := strings.Split(info, "\x00")
+		parents := strings.Fields(fields[1])
+		if len(parents) > 1 {
+			return fmt.Errorf("commit %s is a merge; reword the queued commits manually", commit[:12])
+		}
+
+		message, ok := messages[commit]
+		if !ok {
+			if message, err = gitOutput("log", "-1", "--format=%B", commit); err != nil {
+				return err
+			}
+		}
+
+		args := []string{"commit-tree", fields[0], "-m", message}
+		for _, parent := range parents {
+			if mapped, ok := rewritten[parent]; ok {
+				parent = mapped
+			}
+			args = append(args, "-p", parent)
+		}
+		cmd := gitCommandEnv([]string{
+			"GIT_AUTHOR_NAME=" + fields[2], "GIT_AUTHOR_EMAIL=" + fields[3], "GIT_AUTHOR_DATE=" + fields[4],
+			"GIT_COMMITTER_NAME=" + fields[5], "GIT_COMMITTER_EMAIL=" + fields[6], "GIT_COMMITTER_DATE=" + fields[7],
+		}, args...)
+		out, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to recreate commit %s: %w", commit[:12], err)
+		}
+		rewritten[commit] = strings.TrimSpace(string(out))
+	}
+
+	return gitCommand("update-ref", "-m", "rmit flush", "HEAD", rewritten[commits[len(commits)-1]], head).Run()
+}
+
+// offerOfflineCommit commits with a placeholder message when the API can't be reached and queues
+// the diff for rmit flush. It returns false if the user would rather not commit now.
+func offerOfflineCommit(diff string, autoCommit bool, paths []string) bool {
+	// The queue keeps the diff on disk until rmit flush
+	if noPersist {
+		return false
+	}
+	ui.Warn("📴 The API can't be reached.")
+	if !autoCommit {
+		response, err := readUserInput("Commit with a placeholder message and generate the real one later with rmit flush? [Y/n]: ")
+		if err != nil || (response != "y" && response != "yes") {
+			return false
+		}
+	}
+
+	message := offlinePlaceholder + "\n\nThe message for this commit will be generated by rmit flush."
+	if err := makeCommit(message, false, paths...); err != nil {
+		log.Fatalf("%s %v", red("Error creating commit:"), err)
+	}
+	commit, err := gitOutput("rev-parse", "HEAD")
+	if err != nil {
+		log.Fatalf("%s %v", red("Error queueing commit:"), err)
+	}
+	if err := queueCommit(commit, diff); err != nil {
+		log.Fatalf("%s %v", red("Error queueing commit:"), err)
+	}
+
+	ui.Success("✅ Commit created with a placeholder message")
+	ui.Warn("Run rmit flush when you're back online to write its message.")
+	return true
+}
+
+// newFlushCmd creates the flush command that writes messages for commits made offline
+func newFlushCmd() *cobra.Command {
+	var model string
+
+	flushCmd := &cobra.Command{
+		Use:   "flush",
+		Short: "Generate messages for commits made while offline",
+		Long: "Generate proper messages for commits that were made with a placeholder while the API was unreachable, " +
+			"and reword them in place. Only commits on the current branch that still have the placeholder are changed.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			queue, err := readQueue()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error reading offline queue:"), err)
+			}
+			if len(queue) == 0 {
+				fmt.Printf("%s\n", green("✅ No queued commits"))
+				return
+			}
+
+			messages := make(map[string]string)
+			var oldest string
+			for _, queued := range queue {
+				// Commits that were dropped, rebased or already reworded by hand are left alone
+				subject, err := gitOutput("log", "-1", "--format=%s", queued.Commit)
+				if err != nil || subject != offlinePlaceholder || gitCommand("merge-base", "--is-ancestor", queued.Commit, "HEAD").Run() != nil {
+					fmt.Printf("%s %s\n", yellow("Skipping commit no longer pending on this branch:"), cyan(queued.Commit[:12]))
+					dequeueCommit(queued.Commit)
+					continue
+				}
+
+				fmt.Printf("%s %s\n", blue("Generating message for"), cyan(queued.Commit[:12]))
+				message, err := suggestCommitMessage(config, queued.Diff, GenerateOptions{Model: model})
+				if err != nil {
+					log.Fatalf("%s %v", red("Error generating commit message:"), err)
+				}
+				fmt.Printf("\n%s\n\n", cyan(message))
+
+				messages[queued.Commit] = message
+				if oldest == "" {
+					oldest = queued.Commit
+				}
+			}
+			if len(messages) == 0 {
+				return
+			}
+
+			if err := rewordCommits(messages, oldest); err != nil {
+				log.Fatalf("%s %v", red("Error rewording commits:"), err)
+			}
+			for commit := range messages {
+				dequeueCommit(commit)
+			}
+			fmt.Printf("%s\n", green(fmt.Sprintf("✅ Reworded %d commit(s)", len(messages))))
+		},
+	}
+
+	flushCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation (overrides default_model from config)")
+	return flushCmd
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ollamaURL is the chat endpoint of a local Ollama server
+const ollamaURL = "http://localhost:11434/api/chat"
+
+// ollamaProvider speaks Ollama's native chat API, so commit messages can be generated by a model
+// running on this machine without the diff leaving it
+type ollamaProvider struct{}
+
+// OllamaMessage is a message in Ollama's format: images go next to the text, base64 encoded
+type OllamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// OllamaRequest is an /api/chat request
+type OllamaRequest struct {
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+}
+
+// OllamaResponse is an /api/chat response, or one line of a streamed one
+type OllamaResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	Error           string `json:"error,omitempty"`
+	PromptEvalCount int    `json:"prompt_eval_count,omitempty"`
+	EvalCount       int    `json:"eval_count,omitempty"`
+}
+
+// DefaultURL returns the local Ollama chat endpoint
+func (ollamaProvider) DefaultURL(config *Config) string {
+	return ollamaURL
+}
+
+// NeedsKey reports that Ollama doesn't authenticate requests
+func (ollamaProvider) NeedsKey() bool {
+	return false
+}
+
+// ollamaMessage converts a message to Ollama's format. Images that aren't data URLs can't be
+// sent, Ollama only takes their contents.
+func ollamaMessage(message Message) OllamaMessage {
+	parts, ok := message.Content.([]ContentPart)
+	if !ok {
+		text, _ := message.Content.(string)
+		return OllamaMessage{Role: message.Role, Content: text}
+	}
+	converted := OllamaMessage{Role: message.Role}
+	var texts []string
+	for _, part := range parts {
+		if part.ImageURL == nil {
+			texts = append(texts, part.Text)
+			continue
+		}
+		if _, data, ok := strings.Cut(part.ImageURL.URL, ";base64,"); ok && strings.HasPrefix(part.ImageURL.URL, "data:") {
+			converted.Images = append(converted.Images, data)
+		}
+	}
+	converted.Content = strings.Join(texts, "\n\n")
+	return converted
+}
+
+// readOllamaStream passes each line of a streamed Ollama response to onDelta and returns the
+// whole response as Ollama sends it unstreamed
+func readOllamaStream(r io.Reader, onDelta func(string)) ([]byte, error) {
+	var content strings.Builder
+	var last OllamaResponse
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var chunk OllamaResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse streamed response: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("API error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			onDelta(chunk.Message.Content)
+		}
+		last = chunk
+		if chunk.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read streamed response: %w", err)
+	}
+	if content.Len() == 0 {
+		return nil, errEmptyResponse
+	}
+
+	last.Message.Content = content.String()
+	return json.Marshal(last)
+}
+
+// Generate sends an /api/chat request, streamed when the request has OnDelta
+func (ollamaProvider) Generate(ctx context.Context, config *Config, request ChatRequest) (string, error) {
+	messages := make([]OllamaMessage, 0, len(request.Messages))
+	for _, message := range request.Messages {
+		messages = append(messages, ollamaMessage(message))
+	}
+	jsonBody, err := json.Marshal(OllamaRequest{Model: request.Model, Messages: messages, Stream: request.OnDelta != nil})
+	if err != nil {
+		return "", fmt.Errorf("failed to create request body: %w", err)
+	}
+	body, err := sendChat(ctx, config, request, jsonBody, chatWire{
+		authorize: func(req *http.Request, apiKey string) {
+			// A reverse proxy in front of Ollama may want a key, Ollama itself doesn't
+			if apiKey != "" {
+				req.Header.Set("Authorization", "Bearer "+apiKey)
+			}
+		},
+		readStream: readOllamaStream,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return "", fmt.Errorf("%w; download the model with: ollama pull %s", err, request.Model)
+		}
+		return "", err
+	}
+
+	var response OllamaResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if response.Error != "" {
+		return "", fmt.Errorf("API error: %s", response.Error)
+	}
+	if response.Message.Content == "" {
+		return "", errEmptyResponse
+	}
+	return response.Message.Content, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+// noPersist is set by --no-persist, or RMIT_NO_PERSIST, for shared and pair workstations. rmit
+// then keeps nothing of its own on disk: no caches, logs, transcripts, experiment results, undo
+// snapshots, offline queue or configuration changes. The commit itself, and files explicitly
+// asked for such as rollup --file, are still written.
+var noPersist bool
+
+// errNoPersist is returned by operations whose only purpose is to save something
+var errNoPersist = errors.New("nothing can be saved with --no-persist")
+
+// noPersistFromEnv reports whether RMIT_NO_PERSIST asks for --no-persist, so a shared machine
+// can enforce it for every user of the account
+func noPersistFromEnv() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("RMIT_NO_PERSIST"))
+	return err == nil && enabled
+}
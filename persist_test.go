@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listFiles returns every file under dir with its size and modification time
+func listFiles(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		files[filepath.ToSlash(rel)] = fmt.Sprintf("%d bytes, modified %s", info.Size(), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("listing %s: %v", dir, err)
+	}
+	return files
+}
+
+// commitWrite reports whether git itself writes a file when committing
+func commitWrite(file string) bool {
+	for _, prefix := range []string{"repo/.git/objects/", "repo/.git/logs/", "repo/.git/refs/heads/"} {
+		if strings.HasPrefix(file, prefix) {
+			return true
+		}
+	}
+	return file == "repo/.git/index" || file == "repo/.git/COMMIT_EDITMSG"
+}
+
+func TestNoPersistWritesOnlyTheCommit(t *testing.T) {
+	root := t.TempDir()
+	home, tmp, repo := filepath.Join(root, "home"), filepath.Join(root, "tmp"), filepath.Join(root, "repo")
+	for _, dir := range []string{home, tmp, repo} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("TMPDIR", tmp)
+	for _, name := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME", "XDG_STATE_HOME"} {
+		t.Setenv(name, filepath.Join(home, strings.ToLower(strings.TrimPrefix(name, "XDG_"))))
+	}
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	for _, name := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(name, "rmit test")
+	}
+	for _, name := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(name, "test@example.com")
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(repo); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	if err := os.WriteFile("go.mod", []byte("module example.com/app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "go.mod")
+	git("commit", "-q", "-m", "initial commit")
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "main.go")
+	diff := git("diff", "--cached")
+
+	noPersist = true
+	t.Cleanup(func() { noPersist = false })
+	before := listFiles(t, root)
+
+	// Everything rmit would otherwise keep between runs
+	saveProjectInfo(repo, "Go module example.com/app", []string{"go.mod"})
+	auditAPICall(&Config{AuditLog: true}, []byte(`{"model":"test"}`), 200, []byte(`{}`), time.Now())
+	recordRateLimit("test-key", time.Now().Add(time.Minute))
+	recordAutoCommit(time.Now())
+	sent := false
+	if _, err := sendIdempotent(nextIdempotencyKey("test", "prompt"), func() ([]byte, error) {
+		sent = true
+		return []byte(`{}`), nil
+	}); err != nil || !sent {
+		t.Errorf("sendIdempotent didn't send the request directly: %v", err)
+	}
+	if offerOfflineCommit(diff, true, nil) {
+		t.Error("offerOfflineCommit queued a commit")
+	}
+	if err := saveConfig(&Config{APIKey: "secret"}); err != errNoPersist {
+		t.Errorf("saveConfig returned %v, want errNoPersist", err)
+	}
+
+	// The commit itself is still made, without an undo snapshot or transcript
+	if err := makeCommit("feat: add main package", false); err != nil {
+		t.Fatalf("makeCommit: %v", err)
+	}
+	transcript := &Transcript{Entries: []TranscriptEntry{{Model: "test", Prompt: diff, Message: "feat: add main package"}}}
+	for _, mode := range []string{transcriptFile, transcriptNotes} {
+		if saved, err := saveTranscript(transcript, mode, nil); saved != "" || err != nil {
+			t.Errorf("saveTranscript(%s) = %q, %v; want nothing saved", mode, saved, err)
+		}
+	}
+
+	if subject := git("log", "-1", "--format=%s"); subject != "feat: add main package" {
+		t.Errorf("HEAD is %q, want the new commit", subject)
+	}
+	if refs := git("for-each-ref", "refs/rmit/", "refs/notes/"); refs != "" {
+		t.Errorf("refs were written:\n%s", refs)
+	}
+	for file, stamp := range listFiles(t, root) {
+		if before[file] != stamp && !commitWrite(file) {
+			t.Errorf("%s was written", file)
+		}
+	}
+}
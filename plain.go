@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of --stdin-context and --print, which editor and TUI integrations rely on
+const (
+	exitOK         = 0 // the message was printed to stdout
+	exitUsage      = 1 // invalid flags or configuration
+	exitNoChanges  = 2 // there were no changes to describe
+	exitGeneration = 3 // the message couldn't be generated, e.g. the API failed
+)
+
+// Flags that print only the message: for a prepared diff read from stdin, or the repository's changes
+const (
+	stdinContextFlag = "stdin-context"
+	printFlag        = "print"
+)
+
+// readStdinDiff reads the diff prepared by the calling tool
+func readStdinDiff() (string, error) {
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read diff from stdin: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", errNoChanges
+	}
+	return string(data), nil
+}
+
+// plainFail reports an error on stderr and exits with one of the plain mode exit codes
+func plainFail(code int, title string, err error) {
+	fmt.Fprintf(os.Stderr, "rmit: %s %v\n", title, err)
+	os.Exit(code)
+}
+
+// runPlain generates a message for the diff readDiff returns and prints only the message, for
+// scripts, editors and aliases. Nothing is asked and nothing but the message goes to stdout:
+// warnings go to stderr, and the exit code says what went wrong.
+func runPlain(cmd *cobra.Command, config *Config, opts GenerateOptions, trailerFlags []string, flag string, readDiff func() (string, error)) {
+	if cmd.Flags().Changed("commit") || cmd.Flags().Changed("yes") {
+		plainFail(exitUsage, "invalid flags:", errors.New("--commit can't be combined with --"+flag))
+	}
+	ui = newQuietUI(os.Stderr, os.Stdin)
+	if assumedAnswer == "" {
+		assumedAnswer = "n"
+	}
+
+	diff, err := readDiff()
+	if errors.Is(err, errNoChanges) || errors.Is(err, errNothingStaged) {
+		plainFail(exitNoChanges, "no changes:", err)
+	}
+	if err != nil {
+		plainFail(exitUsage, "error reading diff:", err)
+	}
+
+	// There's no terminal to ask on, so required trailers must come from config, branch or flags
+	opts.Trailers, err = collectTrailers(config, trailerFlags)
+	if err != nil {
+		plainFail(exitUsage, "invalid trailer:", err)
+	}
+	if missing := missingTrailers(opts.Trailers, config.RequiredTrailers); len(missing) > 0 {
+		plainFail(exitUsage, "missing required trailers:", fmt.Errorf("%s (pass them with --trailer)", strings.Join(missing, ", ")))
+	}
+	if config.ReadIntent {
+		intent, _ := readIntent(parseDiff(diff))
+		opts.Context = joinContext(opts.Context, intent)
+	}
+
+	message, err := suggestCommitMessage(config, diff, opts)
+	if err != nil {
+		plainFail(exitGeneration, "error generating commit message:", err)
+	}
+	fmt.Println(message)
+	os.Exit(exitOK)
+}
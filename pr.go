@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// prTemplatePaths are where GitHub and GitLab look for a pull request template
+var prTemplatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	".gitlab/merge_request_templates/Default.md",
+}
+
+// PullRequest is a generated pull request title and description
+type PullRequest struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// defaultBase returns the branch pull requests go into by default: the branch origin's HEAD
+// points to, else main
+func defaultBase() string {
+	if ref, err := gitOutput("symbolic-ref", "--short", "-q", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	return "main"
+}
+
+// branchPoint returns the commit the current branch forked from the base, trying the local base
+// branch first and then origin's
+func branchPoint(base string) (string, error) {
+	for _, ref := range []string{base, "origin/" + base} {
+		if point, err := gitOutput("merge-base", ref, "HEAD"); err == nil && point != "" {
+			return point, nil
+		}
+	}
+	return "", fmt.Errorf("can't find where the branch forked from %s, pass --base", base)
+}
+
+// prTemplate returns the repository's pull request template, if it has one
+func prTemplate() string {
+	root, err := getRepoRoot()
+	if err != nil {
+		return ""
+	}
+	for _, path := range prTemplatePaths {
+		if content, err := readCheckoutFile(root, path); err == nil {
+			return strings.TrimSpace(string(content))
+		}
+	}
+	return ""
+}
+
+// writePullRequest asks the model for a pull request title and description of a branch's
+// commits and changes, filling in the repository's template when it has one
+func writePullRequest(config *Config, model, base string, commits []RollupItem, diff string) (*PullRequest, error) {
+	var texts []string
+	for _, commit := range commits {
+		texts = append(texts, commit.text())
+	}
+	changes := strings.Join(texts, "\n\n")
+	if len(changes) > maxRollupBatch {
+		changes = changes[:maxRollupBatch] + "\n[commits truncated]"
+	}
+	if len(diff) > issueDiffLimit {
+		diff = diff[:issueDiffLimit] + "\n[diff truncated]"
+	}
+
+	prompt := "Write the title and description of a pull request merging the commits below into " + base + ". " +
+		"The title is a short summary of the whole change in the imperative mood, under 72 characters. " +
+		"The description is Markdown for reviewers: start with one or two sentences on what the change does and why, " +
+		"then the notable changes as a list, then anything reviewers should check. Describe only what the commits and the diff show. "
+	if template := prTemplate(); template != "" {
+		prompt += "Fill in the repository's pull request template below for the description, keeping its headings, " +
+			"and leave out sections the changes give nothing for.\n\nTemplate:\n" + template + "\n\n"
+	}
+	prompt += "Respond only with a JSON object of the form " + `{"title": "<title>", "body": "<description>"}` +
+		".\n\nCommits:\n" + changes + "\n\nDiff:\n" + diff
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var pr PullRequest
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &pr); err != nil || strings.TrimSpace(pr.Title) == "" {
+		return nil, fmt.Errorf("the model didn't return a pull request title and description")
+	}
+	pr.Title, pr.Body = strings.TrimSpace(pr.Title), strings.TrimSpace(pr.Body)
+	return &pr, nil
+}
+
+// openWithGH opens the pull request with the GitHub CLI, which knows the account and the fork
+// setup. It returns the pull request's URL.
+func openWithGH(pr *PullRequest, base, branch string, draft bool) (string, error) {
+	args := []string{"pr", "create", "--base", base, "--head", branch, "--title", pr.Title, "--body-file", "-"}
+	if draft {
+		args = append(args, "--draft")
+	}
+	cmd := exec.Command("gh", args...)
+	cmd.Stdin = strings.NewReader(pr.Body)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("gh pr create failed: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// openWithAPI opens the pull request, or GitLab merge request, in the base repository through
+// the API. The branch is expected on origin, which in a fork isn't the base repository.
+func openWithAPI(pr *PullRequest, repo *RemoteRepo, base, branch string, draft bool) (string, error) {
+	head, err := remoteRepo("origin")
+	if err != nil {
+		return "", err
+	}
+
+	if repo.isGitLab() {
+		if os.Getenv("GITLAB_TOKEN") == "" {
+			return "", errors.New("set GITLAB_TOKEN to open merge requests")
+		}
+		request := map[string]any{"source_branch": branch, "target_branch": base, "title": pr.Title, "description": pr.Body}
+		if draft {
+			request["title"] = "Draft: " + pr.Title
+		}
+		if head.Path != repo.Path {
+			body, err := trackerRequest("GET", fmt.Sprintf("%s/api/v4/projects/%s", repo.Base, url.PathEscape(repo.Path)), true, nil)
+			if err != nil {
+				return "", err
+			}
+			var project struct {
+				ID int `json:"id"`
+			}
+			if err := json.Unmarshal(body, &project); err != nil {
+				return "", fmt.Errorf("failed to parse project: %w", err)
+			}
+			request["target_project_id"] = project.ID
+		}
+		payload, err := json.Marshal(request)
+		if err != nil {
+			return "", err
+		}
+		body, err := trackerRequest("POST", fmt.Sprintf("%s/api/v4/projects/%s/merge_requests", head.Base, url.PathEscape(head.Path)), true, payload)
+		if err != nil {
+			return "", err
+		}
+		var created struct {
+			WebURL string `json:"web_url"`
+		}
+		if err := json.Unmarshal(body, &created); err != nil {
+			return "", fmt.Errorf("failed to parse merge request: %w", err)
+		}
+		return created.WebURL, nil
+	}
+
+	if githubToken() == "" {
+		return "", errors.New("install gh or set GITHUB_TOKEN to open pull requests")
+	}
+	headRef := branch
+	if head.Path != repo.Path {
+		owner, _, _ := strings.Cut(head.Path, "/")
+		headRef = owner + ":" + branch
+	}
+	payload, err := json.Marshal(map[string]any{"title": pr.Title, "body": pr.Body, "head": headRef, "base": base, "draft": draft})
+	if err != nil {
+		return "", err
+	}
+	body, err := trackerRequest("POST", fmt.Sprintf("%s/repos/%s/pulls", githubAPI(repo.Base), repo.Path), false, payload)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil {
+		return "", fmt.Errorf("failed to parse pull request: %w", err)
+	}
+	return created.HTMLURL, nil
+}
+
+// newPRCmd creates the pr command that writes a pull request title and description for the current branch
+func newPRCmd() *cobra.Command {
+	var (
+		base   string
+		model  string
+		remote string
+		file   string
+		open   bool
+		draft  bool
+		yes    bool
+	)
+
+	prCmd := &cobra.Command{
+		Use:   "pr",
+		Short: "Write a pull request title and description for the current branch",
+		Long: "Summarize the commits and changes of the current branch since it forked from the base branch (--base, default: the branch origin's HEAD points to, else main) " +
+			"into a pull request title and Markdown description, following the repository's pull request template when it has one. " +
+			"With --open the pull request is opened with gh, or through the GitHub or GitLab API in the base repository (upstream when working in a fork) " +
+			"when gh isn't installed; push the branch first. Set GITHUB_TOKEN or GITLAB_TOKEN for the API.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			branch := getCurrentBranch()
+			if branch == "" {
+				log.Fatalf("%s check out the branch to open a pull request for", red("Error:"))
+			}
+			if base == "" {
+				base = defaultBase()
+			}
+			if branch == base {
+				log.Fatalf("%s %s is the base branch, check out a feature branch or pass --base", red("Error:"), branch)
+			}
+			point, err := branchPoint(base)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			commits, err := sinceItems(point)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error reading commits:"), err)
+			}
+			if len(commits) == 0 {
+				ui.Warn(fmt.Sprintf("No commits on %s since it forked from %s", branch, base))
+				return
+			}
+			ui.Info(fmt.Sprintf("🔍 Commits on %s since %s: %d", branch, base, len(commits)))
+			diff, err := gitOutput("diff", point, "HEAD")
+			if err != nil {
+				log.Fatalf("%s %v", red("Error getting git diff:"), err)
+			}
+
+			ui.Info("Writing the pull request...")
+			pr, err := writePullRequest(config, model, base, commits, diff)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error writing pull request:"), err)
+			}
+			ui.Panel("✨ PULL REQUEST TITLE:", pr.Title)
+			ui.Panel("📝 PULL REQUEST DESCRIPTION:", pr.Body)
+
+			if file != "" {
+				if err := os.WriteFile(file, []byte("# "+pr.Title+"\n\n"+pr.Body+"\n"), 0644); err != nil {
+					log.Fatalf("%s %v", red("Error writing pull request:"), err)
+				}
+				ui.Success("✅ Wrote " + file)
+			}
+			if !open {
+				return
+			}
+
+			repo, err := baseRepo(remote)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			if !yes {
+				response, err := readUserInput(fmt.Sprintf("Open this pull request into %s of %s? [y/n]: ", base, repo.Path))
+				if err != nil {
+					log.Fatalf("%s %v", red("Error reading user input:"), err)
+				}
+				if response != "y" && response != "yes" {
+					ui.Warn("⚠️ Pull request not opened")
+					return
+				}
+			}
+			var link string
+			if _, err := exec.LookPath("gh"); err == nil && !repo.isGitLab() && remote == "" {
+				link, err = openWithGH(pr, base, branch, draft)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error opening pull request:"), err)
+				}
+			} else if link, err = openWithAPI(pr, repo, base, branch, draft); err != nil {
+				log.Fatalf("%s %v", red("Error opening pull request:"), err)
+			}
+			ui.Success("✅ Opened " + link)
+		},
+	}
+
+	prCmd.Flags().StringVar(&base, "base", "", "Branch the pull request goes into (default: the branch origin's HEAD points to, else main)")
+	prCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use (overrides default_model from config)")
+	prCmd.Flags().StringVar(&remote, "remote", "", "Remote of the repository to open the pull request in (default: upstream if present, else the repository origin was forked from, else origin)")
+	prCmd.Flags().StringVarP(&file, "file", "f", "", "Also write the title and description to this file as Markdown")
+	prCmd.Flags().BoolVar(&open, "open", false, "Open the pull request with gh, or the GitHub or GitLab API")
+	prCmd.Flags().BoolVar(&draft, "draft", false, "Open the pull request as a draft")
+	prCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Open the pull request without asking")
+	return prCmd
+}
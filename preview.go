@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// excludeFromDiff removes the sections of excluded files from a diff. The files are still
+// committed; they're only left out of what the model sees.
+func excludeFromDiff(diff string, excluded []string) string {
+	if len(excluded) == 0 {
+		return diff
+	}
+	skip := make(map[string]bool, len(excluded))
+	for _, path := range excluded {
+		skip[path] = true
+	}
+
+	var kept strings.Builder
+	for _, f := range parseDiff(diff) {
+		if !skip[f.Path] {
+			kept.WriteString(f.Text)
+		}
+	}
+	return kept.String()
+}
+
+// excludedInsight tells the model about files that changed but aren't in the diff it got
+func excludedInsight(excluded []string) []string {
+	if len(excluded) == 0 {
+		return nil
+	}
+	return []string{"These files also changed but were left out of the diff by the author: " + strings.Join(excluded, ", ")}
+}
+
+// previewDiff lists the files about to be sent to the model and lets the user toggle files out
+// of the prompt by number. It returns the paths to leave out.
+func previewDiff(files []*FileDiff) ([]string, error) {
+	excluded := make([]bool, len(files))
+
+	for {
+		var lines []string
+		for i, f := range files {
+			stats := fmt.Sprintf("+%d -%d, %d bytes", len(f.Added), len(f.Removed), len(f.Text))
+			if excluded[i] {
+				lines = append(lines, fmt.Sprintf("  %s %s %s %s", cyan(fmt.Sprintf("%2d", i+1)), red("✗"), f.Path, red("(excluded)")))
+			} else {
+				lines = append(lines, fmt.Sprintf("  %s %s %s %s", cyan(fmt.Sprintf("%2d", i+1)), green("✓"), f.Path, yellow(stats)))
+			}
+		}
+		ui.Panel("🔎 FILES SENT TO THE MODEL:", strings.Join(lines, "\n"))
+		question := "Toggle files out of the prompt by number (they're still committed), or press Enter to send: "
+		if assumedAnswer != "" {
+			// Nothing to toggle without a person to ask, everything is sent
+			break
+		}
+		line, err := ui.Prompt(question)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		fields := strings.Fields(strings.ReplaceAll(line, ",", " "))
+		if len(fields) == 0 {
+			break
+		}
+		for _, field := range fields {
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 1 || n > len(files) {
+				ui.Error("Not a file number: " + field)
+				continue
+			}
+			excluded[n-1] = !excluded[n-1]
+		}
+	}
+
+	var paths []string
+	for i, f := range files {
+		if excluded[i] {
+			paths = append(paths, f.Path)
+		}
+	}
+	if len(paths) == len(files) {
+		return nil, fmt.Errorf("every file was excluded, there is nothing to describe")
+	}
+	return paths, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Phases time is attributed to with --profile
+const (
+	phaseStartup = "startup"
+	phaseConfig  = "config"
+	phaseGit     = "git commands"
+	phasePrompt  = "prompt build"
+	phaseNetwork = "network"
+	phasePost    = "post-processing"
+	phaseInput   = "waiting for input"
+	phaseOther   = "other"
+)
+
+// processStart is when rmit started, so startup is part of the profile
+var processStart = time.Now()
+
+// Profiler attributes wall time to phases. Entering a phase pauses the one it was entered from,
+// so every moment is counted exactly once and the phases add up to the total.
+type Profiler struct {
+	mu      sync.Mutex
+	enabled bool
+	started time.Time
+	since   time.Time
+	stack   []string
+	totals  map[string]time.Duration
+	counts  map[string]int
+}
+
+// profiler is the process-wide profiler, disabled unless --profile is given
+var profiler = &Profiler{}
+
+// start enables the profiler, counting everything before it as startup
+func (p *Profiler) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enabled = true
+	p.started = time.Now()
+	p.since = p.started
+	p.stack = []string{phaseOther}
+	p.totals = map[string]time.Duration{phaseStartup: p.started.Sub(processStart)}
+	p.counts = map[string]int{phaseStartup: 1}
+}
+
+// charge adds the time since the last switch to the current phase; p.mu must be held
+func (p *Profiler) charge() {
+	now := time.Now()
+	p.totals[p.stack[len(p.stack)-1]] += now.Sub(p.since)
+	p.since = now
+}
+
+// profilePhase attributes time to a phase until the returned function is called, e.g.
+// defer profilePhase(phaseGit)(). It does nothing unless profiling.
+func profilePhase(phase string) func() {
+	p := profiler
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled {
+		return func() {}
+	}
+	p.charge()
+	p.stack = append(p.stack, phase)
+	p.counts[phase]++
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.charge()
+		// Concurrent phases, e.g. candidates generated in parallel, may end out of order
+		for i := len(p.stack) - 1; i > 0; i-- {
+			if p.stack[i] == phase {
+				p.stack = append(p.stack[:i], p.stack[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// report prints where the time went, largest phase first
+func (p *Profiler) report(w io.Writer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled {
+		return
+	}
+	p.charge()
+
+	phases := make([]string, 0, len(p.totals))
+	var total time.Duration
+	for phase, duration := range p.totals {
+		phases = append(phases, phase)
+		total += duration
+	}
+	sort.Slice(phases, func(i, j int) bool { return p.totals[phases[i]] > p.totals[phases[j]] })
+
+	fmt.Fprintf(w, "\n%s\n", blue("⏱️  PROFILE:"))
+	fmt.Fprint(w, rule())
+	for _, phase := range phases {
+		duration := p.totals[phase]
+		share := 0.0
+		if total > 0 {
+			share = float64(duration) * 100 / float64(total)
+		}
+		calls := ""
+		if p.counts[phase] > 1 {
+			calls = fmt.Sprintf(" (%d calls)", p.counts[phase])
+		}
+		fmt.Fprintf(w, "  %-18s %10s %s%s\n", phase, duration.Round(time.Microsecond*100), cyan(fmt.Sprintf("%5.1f%%", share)), calls)
+	}
+	fmt.Fprint(w, rule())
+	fmt.Fprintf(w, "  %-18s %10s\n", "total", total.Round(time.Microsecond*100))
+}
+
+// startCPUProfile writes a CPU profile to path until the returned function is called
+func startCPUProfile(path string) (func(), error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CPU profile: %w", err)
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to start CPU profile: %w", err)
+	}
+	return func() {
+		pprof.StopCPUProfile()
+		f.Close()
+	}, nil
+}
+
+// writeMemProfile writes a heap profile to path
+func writeMemProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create memory profile: %w", err)
+	}
+	defer f.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("failed to write memory profile: %w", err)
+	}
+	return nil
+}
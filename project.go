@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// maxScannedFiles caps how many files project detection looks at in huge repositories
+const maxScannedFiles = 50000
+
+// maxListedProjects caps how many projects of a monorepo are described to the model
+const maxListedProjects = 8
+
+// languagesByExt maps source file extensions to their language. Markup, data and docs aren't
+// counted, so configuration heavy repositories still show their code's languages.
+var languagesByExt = map[string]string{
+	".go": "Go", ".py": "Python", ".java": "Java", ".kt": "Kotlin", ".kts": "Kotlin", ".scala": "Scala",
+	".js": "JavaScript", ".jsx": "JavaScript", ".mjs": "JavaScript", ".cjs": "JavaScript",
+	".ts": "TypeScript", ".tsx": "TypeScript", ".vue": "Vue", ".svelte": "Svelte",
+	".rb": "Ruby", ".rs": "Rust", ".php": "PHP", ".cs": "C#", ".swift": "Swift", ".dart": "Dart",
+	".c": "C", ".h": "C", ".cc": "C++", ".cpp": "C++", ".cxx": "C++", ".hpp": "C++",
+	".ex": "Elixir", ".exs": "Elixir", ".lua": "Lua", ".sh": "Shell", ".bash": "Shell",
+	".html": "HTML", ".css": "CSS", ".scss": "CSS", ".sass": "CSS", ".less": "CSS", ".sql": "SQL",
+}
+
+// projectManifests are the files that make a directory a project, and what kind
+var projectManifests = map[string]string{
+	"go.mod":           "Go module",
+	"package.json":     "JavaScript/Node.js package",
+	"pom.xml":          "Java/Maven project",
+	"build.gradle":     "Java/Gradle project",
+	"build.gradle.kts": "Kotlin/Gradle project",
+	"CMakeLists.txt":   "C/C++ project with CMake",
+	"pyproject.toml":   "Python project",
+	"requirements.txt": "Python project",
+	"Cargo.toml":       "Rust crate",
+	"composer.json":    "PHP project",
+	"Gemfile":          "Ruby project",
+}
+
+// frameworkMarkers are dependency names that reveal a framework, by the manifest they appear in
+var frameworkMarkers = map[string][][2]string{
+	"package.json":     {{"next", "Next.js"}, {"react", "React"}, {"vue", "Vue"}, {"@angular/core", "Angular"}, {"express", "Express"}},
+	"pyproject.toml":   {{"django", "Django"}, {"flask", "Flask"}, {"fastapi", "FastAPI"}},
+	"requirements.txt": {{"django", "Django"}, {"flask", "Flask"}, {"fastapi", "FastAPI"}},
+	"pom.xml":          {{"spring-boot", "Spring Boot"}, {"org.springframework", "Spring"}},
+	"build.gradle":     {{"spring-boot", "Spring Boot"}, {"org.springframework", "Spring"}},
+	"build.gradle.kts": {{"spring-boot", "Spring Boot"}, {"org.springframework", "Spring"}},
+	"Gemfile":          {{"rails", "Rails"}},
+}
+
+// DetectedProject is a project found in the repository, e.g. one package of a monorepo
+type DetectedProject struct {
+	Dir        string // relative to the repository root, "." for the root
+	Kind       string
+	Frameworks []string
+}
+
+// LanguageShare is how much of the repository's code is in a language
+type LanguageShare struct {
+	Language string
+	Percent  int
+}
+
+// repoFiles lists the files of the repository relative to its root, tracked or untracked but
+// not ignored, so build output and dependencies in .gitignore don't count
+func repoFiles(root string) ([]string, error) {
+	var files []string
+	if activeVCS != nil {
+		var err error
+		if files, err = activeVCS.Files(root); err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+	} else {
+		defer profilePhase(phaseGit)()
+		out, err := gitCommand("-C", root, "ls-files", "-z", "--cached", "--others", "--exclude-standard").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		files = strings.Split(strings.TrimRight(string(out), "\x00"), "\x00")
+		if len(files) == 1 && files[0] == "" {
+			return nil, nil
+		}
+	}
+	if len(files) > maxScannedFiles {
+		files = files[:maxScannedFiles]
+	}
+	return files, nil
+}
+
+// isVendored reports whether a file is third-party or generated code that shouldn't count
+// towards the repository's languages
+func isVendored(file string) bool {
+	for _, dir := range []string{"vendor/", "third_party/", "node_modules/"} {
+		if strings.HasPrefix(file, dir) || strings.Contains(file, "/"+dir) {
+			return true
+		}
+	}
+	return strings.HasSuffix(file, ".min.js") || strings.HasSuffix(file, ".min.css")
+}
+
+// languageShares estimates the languages of a repository by the bytes of their source files,
+// largest first. Languages under one percent are left out.
+func languageShares(root string, files []string) []LanguageShare {
+	bytesByLanguage := make(map[string]int64)
+	var total int64
+	for _, file := range files {
+		language, ok := languagesByExt[strings.ToLower(path.Ext(file))]
+		if !ok || isVendored(file) {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(file)))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		bytesByLanguage[language] += info.Size()
+		total += info.Size()
+	}
+	if total == 0 {
+		return nil
+	}
+
+	var shares []LanguageShare
+	for language, size := range bytesByLanguage {
+		if percent := int(size * 100 / total); percent >= 1 {
+			shares = append(shares, LanguageShare{Language: language, Percent: percent})
+		}
+	}
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Percent != shares[j].Percent {
+			return shares[i].Percent > shares[j].Percent
+		}
+		return shares[i].Language < shares[j].Language
+	})
+	return shares
+}
+
+// manifestFrameworks returns the frameworks a manifest depends on
+func manifestFrameworks(root, file, name string) []string {
+	content, err := readCheckoutFile(root, file)
+	if err != nil {
+		return nil
+	}
+
+	// package.json dependencies are matched exactly, "next" mustn't match "next-auth" alone
+	var deps map[string]bool
+	if name == "package.json" {
+		var pkg struct {
+			Dependencies    map[string]string `json:"dependencies"`
+			DevDependencies map[string]string `json:"devDependencies"`
+		}
+		if json.Unmarshal(content, &pkg) != nil {
+			return nil
+		}
+		deps = make(map[string]bool)
+		for dep := range pkg.Dependencies {
+			deps[dep] = true
+		}
+		for dep := range pkg.DevDependencies {
+			deps[dep] = true
+		}
+	}
+
+	lower := strings.ToLower(string(content))
+	var frameworks []string
+	for _, marker := range frameworkMarkers[name] {
+		if (deps != nil && deps[marker[0]]) || (deps == nil && strings.Contains(lower, marker[0])) {
+			frameworks = append(frameworks, marker[1])
+		}
+	}
+	// Spring Boot implies Spring, and Next.js implies React
+	if len(frameworks) > 1 && (frameworks[0] == "Spring Boot" || frameworks[0] == "Next.js") {
+		frameworks = frameworks[:1]
+	}
+	return frameworks
+}
+
+// detectProjects finds the projects in a repository by their manifests, in any directory
+func detectProjects(root string, files []string) []DetectedProject {
+	byDir := make(map[string]*DetectedProject)
+	var dirs []string
+	for _, file := range files {
+		name := path.Base(file)
+		kind, ok := projectManifests[name]
+		if !ok || isVendored(file) {
+			continue
+		}
+		dir := path.Dir(file)
+		project, ok := byDir[dir]
+		if !ok {
+			project = &DetectedProject{Dir: dir, Kind: kind}
+			byDir[dir] = project
+			dirs = append(dirs, dir)
+		}
+		for _, framework := range manifestFrameworks(root, file, name) {
+			if !containsString(project.Frameworks, framework) {
+				project.Frameworks = append(project.Frameworks, framework)
+			}
+		}
+		// Django projects have a manage.py rather than a dependency in every manifest
+		if kind == "Python project" && !containsString(project.Frameworks, "Django") && fileListed(files, path.Join(dir, "manage.py")) {
+			project.Frameworks = append(project.Frameworks, "Django")
+		}
+	}
+
+	// The shallowest projects describe the repository best
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], "/"), strings.Count(dirs[j], "/")
+		if dirs[i] == "." || dirs[j] == "." {
+			return dirs[i] == "."
+		}
+		if di != dj {
+			return di < dj
+		}
+		return dirs[i] < dirs[j]
+	})
+	projects := make([]DetectedProject, 0, len(dirs))
+	for _, dir := range dirs {
+		projects = append(projects, *byDir[dir])
+	}
+	return projects
+}
+
+// containsString reports whether a slice contains a string
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// fileListed reports whether a repository file is in the list
+func fileListed(files []string, file string) bool {
+	file = strings.TrimPrefix(file, "./")
+	for _, f := range files {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
+
+// manifestFiles returns the project manifests among the repository's files
+func manifestFiles(files []string) []string {
+	var manifests []string
+	for _, file := range files {
+		if _, ok := projectManifests[path.Base(file)]; ok && !isVendored(file) {
+			manifests = append(manifests, file)
+		}
+	}
+	return manifests
+}
+
+// getProjectInfo describes the repository for the prompt: the projects in it, with their
+// frameworks, and the share of each language. The description is cached in the git directory
+// and only rebuilt when HEAD's tree or a manifest changes.
+func getProjectInfo() (string, error) {
+	root, err := getRepoRoot()
+	if err != nil {
+		// A bare repository on a git server has no working tree to describe
+		if isBareRepo() {
+			return "", nil
+		}
+		return "", err
+	}
+	if info, ok := cachedProjectInfo(root); ok {
+		return info, nil
+	}
+	files, err := repoFiles(root)
+	if err != nil {
+		return "", err
+	}
+	info := describeProject(root, files)
+	saveProjectInfo(root, info, manifestFiles(files))
+	return info, nil
+}
+
+// describeProject scans the whole repository, not only the top level, so monorepos are described
+// too; files in .gitignore are skipped
+func describeProject(root string, files []string) string {
+	var info []string
+	projects := detectProjects(root, files)
+	if len(projects) > 0 {
+		var described []string
+		for i, project := range projects {
+			if i == maxListedProjects {
+				described = append(described, fmt.Sprintf("and %d more", len(projects)-maxListedProjects))
+				break
+			}
+			text := project.Kind
+			if len(project.Frameworks) > 0 {
+				text += " using " + strings.Join(project.Frameworks, ", ")
+			}
+			if project.Dir != "." {
+				text += " in " + project.Dir + "/"
+			}
+			described = append(described, text)
+		}
+		label := "Project"
+		if len(projects) > 1 {
+			label = "Projects"
+		}
+		info = append(info, label+": "+strings.Join(described, "; ")+".")
+	}
+
+	if shares := languageShares(root, files); len(shares) > 0 {
+		var described []string
+		for i, share := range shares {
+			if i == 5 {
+				break
+			}
+			described = append(described, fmt.Sprintf("%s %d%%", share.Language, share.Percent))
+		}
+		info = append(info, "Languages by size: "+strings.Join(described, ", ")+".")
+	}
+	return strings.Join(info, " ")
+}
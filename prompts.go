@@ -0,0 +1,136 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// builtinPrompts holds every version of the built-in instructions, as prompts/<name>-v<N>.txt.
+// A released version is never edited: changing a prompt means adding the next version, so
+// users can pin the old one with rmit set prompt_version and bisect quality regressions.
+//
+//go:embed prompts/*.txt
+var builtinPrompts embed.FS
+
+// Built-in prompts, each versioned on its own files
+const (
+	promptCommit  = "commit"  // the instructions at the top of a full commit message prompt
+	promptSubject = "subject" // the instructions of a subject-only prompt, {hints} marks where scope and type hints go
+)
+
+// conventionPlaceholder marks where a prompt asks for the configured commit style. Prompts
+// before v2 have none and always ask for conventional commits.
+const conventionPlaceholder = "{convention}"
+
+// styleVersionWarned makes sure a commit style the pinned prompt version ignores is reported once
+var styleVersionWarned sync.Once
+
+// promptLatest is the prompt_version value that follows new releases
+const promptLatest = "latest"
+
+// debugOutput is set by --debug to print what a generation is made of
+var debugOutput bool
+
+// promptVersions returns the embedded prompt versions, oldest first
+func promptVersions() []int {
+	entries, _ := builtinPrompts.ReadDir("prompts")
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".txt")
+		if i := strings.LastIndex(name, "-v"); i >= 0 {
+			if version, err := strconv.Atoi(name[i+2:]); err == nil {
+				seen[version] = true
+			}
+		}
+	}
+	versions := make([]int, 0, len(seen))
+	for version := range seen {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return versions
+}
+
+// latestPromptVersion returns the newest embedded prompt version
+func latestPromptVersion() int {
+	versions := promptVersions()
+	return versions[len(versions)-1]
+}
+
+// parsePromptVersion parses a prompt_version value: "latest" or a version number. Latest is
+// returned as 0 so the pin follows upgrades.
+func parsePromptVersion(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" || value == promptLatest {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.TrimPrefix(value, "v"))
+	if err == nil {
+		for _, known := range promptVersions() {
+			if version == known {
+				return version, nil
+			}
+		}
+	}
+	var known []string
+	for _, v := range promptVersions() {
+		known = append(known, strconv.Itoa(v))
+	}
+	return 0, fmt.Errorf("unknown prompt version %q, valid versions are: %s and %s", value, strings.Join(known, ", "), promptLatest)
+}
+
+// promptVersion returns the prompt version the configuration pins, or the latest
+func promptVersion(config *Config) int {
+	if config.PromptVersion > 0 {
+		return config.PromptVersion
+	}
+	return latestPromptVersion()
+}
+
+// formatPromptVersion describes the configured prompt version, e.g. "latest (v2)" or "v1"
+func formatPromptVersion(config *Config) string {
+	if config.PromptVersion > 0 {
+		return fmt.Sprintf("v%d", config.PromptVersion)
+	}
+	return fmt.Sprintf("%s (v%d)", promptLatest, latestPromptVersion())
+}
+
+// builtinPrompt returns a built-in prompt as of a version. A prompt that didn't change in a
+// version is taken from the newest version before it.
+func builtinPrompt(name string, version int) string {
+	for v := version; v > 0; v-- {
+		if data, err := builtinPrompts.ReadFile(path.Join("prompts", fmt.Sprintf("%s-v%d.txt", name, v))); err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// stylePrompt returns a built-in prompt as of a version with the configured commit style's
+// instruction filled in
+func stylePrompt(config *Config, name string, version int) string {
+	prompt := builtinPrompt(name, version)
+	if !strings.Contains(prompt, conventionPlaceholder) {
+		if commitStyle(config) != styleConventional {
+			styleVersionWarned.Do(func() {
+				log.Printf("Warning: prompt version v%d always asks for conventional commits, commit_style %s needs prompt_version 2 or later", version, commitStyle(config))
+			})
+		}
+		return prompt
+	}
+	return strings.Replace(prompt, conventionPlaceholder, styleInstruction(config), 1)
+}
+
+// debugf prints a diagnostic line to stderr when --debug is given
+func debugf(format string, args ...any) {
+	if debugOutput {
+		fmt.Fprintf(os.Stderr, "%s %s\n", cyan("debug:"), fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// promptTemplateFile is a repository's own prompt, which takes precedence over prompt_template
+const promptTemplateFile = ".rmit/prompt.tmpl"
+
+// PromptData is what a prompt template is filled in with
+type PromptData struct {
+	Instructions string // rmit's instructions for the commit style, scope, type and body templates
+	Diff         string // the changes, noisy files summarized and trimmed in subject-only mode
+	ChangedFiles string // the changed files, comma separated
+	ProjectInfo  string // the languages and frameworks of the repository, empty in subject-only mode
+	Branch       string // the current branch, empty when detached or outside git
+	Context      string // the author's intent from --context, .rmit/intent.md or rmit: comments
+	SubjectOnly  bool   // only a subject line is asked for
+}
+
+// parsePromptTemplate parses a prompt template. Unknown fields are errors, so a typo like
+// {{.Dif}} fails instead of sending an empty prompt.
+func parsePromptTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// readPromptTemplateFile reads the file prompt_template points to, which may start with ~/
+func readPromptTemplateFile(path string) (string, error) {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, rest)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt template: %w", err)
+	}
+	return string(data), nil
+}
+
+// loadPromptTemplate returns the prompt template in effect: the repository's .rmit/prompt.tmpl,
+// or the file prompt_template points to. It returns nil when neither exists, for the built-in
+// prompt.
+func loadPromptTemplate(config *Config) (*template.Template, error) {
+	if text, err := readRepoFile(promptTemplateFile); err == nil {
+		return parsePromptTemplate(promptTemplateFile, text)
+	}
+	if config.PromptTemplate == "" {
+		return nil, nil
+	}
+	text, err := readPromptTemplateFile(config.PromptTemplate)
+	if err != nil {
+		return nil, err
+	}
+	return parsePromptTemplate(filepath.Base(config.PromptTemplate), text)
+}
+
+// renderPromptTemplate fills in a prompt template
+func renderPromptTemplate(tmpl *template.Template, data PromptData) (string, error) {
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		return "", fmt.Errorf("failed to fill in prompt template %s: %w", tmpl.Name(), err)
+	}
+	if strings.TrimSpace(prompt.String()) == "" {
+		return "", fmt.Errorf("prompt template %s is empty", tmpl.Name())
+	}
+	return prompt.String(), nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Provenance attestations
+const (
+	provenanceTrailer   = "Rmit-Provenance"
+	provenanceNotesRef  = "rmit-provenance"
+	provenanceKeyFile   = ".rmit_provenance_key"
+	provenanceFormatVer = 1
+)
+
+// ProvenancePayload is what a provenance attestation vouches for
+type ProvenancePayload struct {
+	Format        int       `json:"format"`
+	Rmit          string    `json:"rmit"`
+	Model         string    `json:"model"`
+	PromptSHA256  string    `json:"prompt_sha256"`
+	MessageSHA256 string    `json:"message_sha256"` // the drafted message without the provenance trailer
+	Created       time.Time `json:"created"`
+}
+
+// Provenance is the signed blob stored in git notes and referenced by the commit's trailer
+type Provenance struct {
+	Payload   json.RawMessage `json:"payload"`
+	PublicKey string          `json:"public_key"`
+	Signature string          `json:"signature"`
+}
+
+// provenanceKey returns the signing key, creating it on first use
+func provenanceKey() (ed25519.PrivateKey, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	keyPath := filepath.Join(homeDir, provenanceKeyFile)
+
+	if seed, err := os.ReadFile(keyPath); err == nil && len(seed) == ed25519.SeedSize {
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate provenance key: %w", err)
+	}
+	if err := os.WriteFile(keyPath, key.Seed(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write provenance key: %w", err)
+	}
+	return key, nil
+}
+
+// keyFingerprint identifies a public key for display
+func keyFingerprint(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	return "ed25519:" + hex.EncodeToString(sum[:8])
+}
+
+// sha256Hex returns the hex SHA-256 of a string
+func sha256Hex(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// withoutProvenanceTrailer removes the provenance trailer so the drafted message can be compared
+func withoutProvenanceTrailer(message string) string {
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, provenanceTrailer+":") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// prepareProvenance signs an attestation for the message the model drafted last and stores it as
+// a git blob. It returns the message with a trailer referencing the blob, and the blob id to
+// attach as a note once the commit exists. Messages not drafted by a model get no attestation.
+func prepareProvenance(t *Transcript, message string) (string, string, error) {
+	if t == nil || len(t.Entries) == 0 {
+		return message, "", nil
+	}
+	entry := t.Entries[len(t.Entries)-1]
+
+	payload, err := json.Marshal(ProvenancePayload{
+		Format:        provenanceFormatVer,
+		Rmit:          rmitVersion,
+		Model:         entry.Model,
+		PromptSHA256:  sha256Hex(entry.Prompt),
+		MessageSHA256: sha256Hex(strings.TrimSpace(message)),
+		Created:       time.Now().UTC(),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode provenance: %w", err)
+	}
+	key, err := provenanceKey()
+	if err != nil {
+		return "", "", err
+	}
+	// Compact encoding keeps the payload byte for byte as it was signed
+	blob, err := json.Marshal(Provenance{
+		Payload:   payload,
+		PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	cmd := gitCommand("hash-object", "-w", "--stdin")
+	cmd.Stdin = strings.NewReader(string(blob) + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to store provenance: %w", err)
+	}
+	oid := strings.TrimSpace(string(out))
+	return appendTrailers(message, []Trailer{{Key: provenanceTrailer, Value: oid}}), oid, nil
+}
+
+// attachProvenance adds the provenance blob as a note on HEAD
+func attachProvenance(oid string) error {
+	if oid == "" {
+		return nil
+	}
+	if err := gitCommand("notes", "--ref="+provenanceNotesRef, "add", "-f", "-C", oid, "HEAD").Run(); err != nil {
+		return fmt.Errorf("failed to add provenance note: %w", err)
+	}
+	return nil
+}
+
+// makeAttestedCommit commits a message, with a provenance attestation when enabled. Paths limit
+// the commit as in makeCommit.
+func makeAttestedCommit(config *Config, message string, t *Transcript, paths []string, stagedOnly bool) error {
+	var blob string
+	// Attestations are git notes, which jj and Sapling don't have
+	if config.Provenance && !noPersist && activeVCS == nil {
+		var err error
+		if message, blob, err = prepareProvenance(t, message); err != nil {
+			return err
+		}
+	}
+	if err := makeCommit(message, stagedOnly, paths...); err != nil {
+		return err
+	}
+	return attachProvenance(blob)
+}
+
+// verifyProvenance checks a commit's provenance note against its trailer and signature
+func verifyProvenance(commit string) (*ProvenancePayload, string, error) {
+	message, err := gitOutput("log", "-1", "--format=%B", commit)
+	if err != nil {
+		return nil, "", err
+	}
+	var referenced string
+	for _, line := range strings.Split(message, "\n") {
+		if value, ok := strings.CutPrefix(line, provenanceTrailer+":"); ok {
+			referenced = strings.TrimSpace(value)
+		}
+	}
+	if referenced == "" {
+		return nil, "", errors.New("the commit has no " + provenanceTrailer + " trailer")
+	}
+
+	// The note must be the very blob the trailer names, or it was replaced after committing
+	noted, err := gitOutput("notes", "--ref="+provenanceNotesRef, "list", commit)
+	if err != nil {
+		return nil, "", fmt.Errorf("no provenance note found (fetch refs/notes/%s?)", provenanceNotesRef)
+	}
+	if noted != referenced {
+		return nil, "", fmt.Errorf("the provenance note %s doesn't match the trailer %s", noted, referenced)
+	}
+	blob, err := gitOutput("cat-file", "blob", noted)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var provenance Provenance
+	if err := json.Unmarshal([]byte(blob), &provenance); err != nil {
+		return nil, "", fmt.Errorf("failed to parse provenance: %w", err)
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(provenance.PublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, "", errors.New("the provenance has an invalid public key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(provenance.Signature)
+	if err != nil || !ed25519.Verify(publicKey, provenance.Payload, signature) {
+		return nil, "", errors.New("the provenance signature is invalid")
+	}
+
+	var payload ProvenancePayload
+	if err := json.Unmarshal(provenance.Payload, &payload); err != nil {
+		return nil, "", fmt.Errorf("failed to parse provenance payload: %w", err)
+	}
+	return &payload, keyFingerprint(publicKey), nil
+}
+
+// newProvenanceCmd creates the provenance command that verifies machine-drafted commits
+func newProvenanceCmd() *cobra.Command {
+	provenanceCmd := &cobra.Command{
+		Use:   "provenance",
+		Short: "Verify provenance attestations of machine-drafted commits",
+		Long: "With rmit set provenance true, commits with a generated message get a signed attestation of the model, " +
+			"prompt hash and rmit version in git notes (refs/notes/" + provenanceNotesRef + "), referenced by an " +
+			provenanceTrailer + " trailer.",
+	}
+
+	var expectedKey string
+	verifyCmd := &cobra.Command{
+		Use:   "verify [commit]",
+		Short: "Verify a commit's provenance attestation",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			commit := "HEAD"
+			if len(args) == 1 {
+				commit = args[0]
+			}
+
+			payload, fingerprint, err := verifyProvenance(commit)
+			if err != nil {
+				log.Fatalf("%s %v", red("Provenance verification failed:"), err)
+			}
+			if expectedKey != "" && fingerprint != expectedKey {
+				log.Fatalf("%s signed by %s, expected %s", red("Provenance verification failed:"), fingerprint, expectedKey)
+			}
+
+			message, err := gitOutput("log", "-1", "--format=%B", commit)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error reading commit:"), err)
+			}
+
+			fmt.Printf("%s\n", green("✅ Valid provenance"))
+			fmt.Print(rule())
+			fmt.Printf("%s %s\n", green("signed by:"), cyan(fingerprint))
+			fmt.Printf("%s %s\n", green("model:"), cyan(payload.Model))
+			fmt.Printf("%s %s\n", green("rmit:"), cyan(payload.Rmit))
+			fmt.Printf("%s %s\n", green("prompt sha256:"), cyan(payload.PromptSHA256))
+			fmt.Printf("%s %s\n", green("drafted:"), cyan(payload.Created.Local().Format(time.RFC1123)))
+			if sha256Hex(withoutProvenanceTrailer(message)) == payload.MessageSHA256 {
+				fmt.Printf("%s %s\n", green("message:"), cyan("unchanged since it was drafted"))
+			} else {
+				fmt.Printf("%s %s\n", green("message:"), yellow("edited after it was drafted"))
+			}
+			fmt.Print(rule())
+		},
+	}
+	verifyCmd.Flags().StringVar(&expectedKey, "key", "", "Require the attestation to be signed by this key fingerprint (see rmit provenance key)")
+
+	keyCmd := &cobra.Command{
+		Use:   "key",
+		Short: "Show the fingerprint of your provenance signing key",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			key, err := provenanceKey()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			publicKey := key.Public().(ed25519.PublicKey)
+			fmt.Printf("%s %s\n", green("fingerprint:"), cyan(keyFingerprint(publicKey)))
+			fmt.Printf("%s %s\n", green("public key:"), cyan(base64.StdEncoding.EncodeToString(publicKey)))
+		},
+	}
+
+	provenanceCmd.AddCommand(verifyCmd, keyCmd)
+	return provenanceCmd
+}
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// defaultProvider is the provider used when none is configured
+const defaultProvider = "openrouter"
+
+// anthropicVersion is the Messages API version rmit speaks
+const anthropicVersion = "2023-06-01"
+
+// anthropicMaxTokens caps the length of Anthropic responses, which the Messages API requires
+const anthropicMaxTokens = 1024
+
+// ChatRequest is a request to a model, independent of the provider's wire format
+type ChatRequest struct {
+	Model    string
+	Messages []Message
+	// OnDelta receives the response as it is streamed, for providers that stream
+	OnDelta func(string)
+	// Shared lets concurrent identical requests from several rmit processes share one response
+	Shared bool
+	// Tools the model may call for more context, nil for none
+	Tools *ToolBox
+}
+
+// ChatTurn is one answer of a model that may call tools: the text, or the calls to run before
+// it answers
+type ChatTurn struct {
+	Text      string
+	ToolCalls []ToolCall
+}
+
+// Provider is a backend that answers chat requests, e.g. OpenRouter or a local Ollama
+type Provider interface {
+	// Generate sends the request and returns the model's answer
+	Generate(ctx context.Context, config *Config, request ChatRequest) (string, error)
+	// DefaultURL is the endpoint used when api_url isn't set
+	DefaultURL(config *Config) string
+	// NeedsKey reports whether requests must be authenticated with an API key
+	NeedsKey() bool
+}
+
+// ToolProvider is a provider whose models can call tools. Requests with tools to a provider
+// that isn't one are sent without them.
+type ToolProvider interface {
+	// GenerateTurn sends the request, unstreamed, with its tools and returns the model's answer
+	// or the tools it calls
+	GenerateTurn(ctx context.Context, config *Config, request ChatRequest) (ChatTurn, error)
+}
+
+// providers are the available providers by the name the provider config key takes
+var providers = make(map[string]Provider)
+
+// registerProvider makes a provider available under a name
+func registerProvider(name string, provider Provider) {
+	providers[name] = provider
+}
+
+func init() {
+	registerProvider("openrouter", openAIProvider{url: defaultAPIURL, needsKey: true, referer: true})
+	registerProvider("openai", openAIProvider{url: "https://api.openai.com/v1/chat/completions", needsKey: true})
+	registerProvider("ollama", ollamaProvider{})
+	registerProvider("anthropic", anthropicProvider{})
+	registerProvider("azure", azureProvider{})
+}
+
+// providerNames returns the registered provider names, sorted
+func providerNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateProvider checks that a provider is registered
+func validateProvider(name string) error {
+	if _, ok := providers[name]; !ok {
+		return fmt.Errorf("unknown provider %q, valid providers are: %s", name, strings.Join(providerNames(), ", "))
+	}
+	return nil
+}
+
+// providerName returns the name of the provider the configuration selects
+func providerName(config *Config) string {
+	if _, ok := providers[config.Provider]; ok {
+		return config.Provider
+	}
+	return defaultProvider
+}
+
+// configProvider returns the provider the configuration selects
+func configProvider(config *Config) Provider {
+	return providers[providerName(config)]
+}
+
+// chatURL returns the endpoint requests are sent to. api_url left at the OpenRouter default
+// means the provider's own endpoint, so switching provider doesn't need api_url set too.
+func chatURL(config *Config) string {
+	if config.APIURL == "" || config.APIURL == defaultAPIURL {
+		return configProvider(config).DefaultURL(config)
+	}
+	return config.APIURL
+}
+
+// chat sends a request to the configured provider, with secrets redacted
+func chat(ctx context.Context, config *Config, request ChatRequest) (string, error) {
+	if provider, ok := configProvider(config).(ToolProvider); ok && request.Tools != nil {
+		return chatWithTools(ctx, config, provider, request)
+	}
+	request.Tools = nil
+	request, err := redactRequest(config, request)
+	if err != nil {
+		return "", err
+	}
+	return configProvider(config).Generate(ctx, config, request)
+}
+
+// chatWire is how a provider's requests are authenticated and its streamed responses read
+type chatWire struct {
+	authorize func(req *http.Request, apiKey string)
+	// readStream passes the streamed pieces to onDelta and returns the whole response as the
+	// provider sends it unstreamed; nil for providers rmit doesn't stream from
+	readStream func(r io.Reader, onDelta func(string)) ([]byte, error)
+}
+
+// sendChat posts a provider's request body, sharing the response between concurrent rmit
+// processes when the request asks for it
+func sendChat(ctx context.Context, config *Config, request ChatRequest, jsonBody []byte, wire chatWire) ([]byte, error) {
+	idempotencyKey := nextIdempotencyKey(request.Model, string(jsonBody))
+	send := func() ([]byte, error) {
+		return postChatRequest(ctx, config, jsonBody, idempotencyKey, request.OnDelta, wire)
+	}
+	if request.Shared {
+		return sendIdempotent(idempotencyKey, send)
+	}
+	return send()
+}
+
+// openAIProvider speaks the OpenAI chat completions format, which OpenRouter and OpenAI share
+type openAIProvider struct {
+	url      string
+	needsKey bool
+	referer  bool // OpenRouter attributes requests to the app in HTTP-Referer
+}
+
+// DefaultURL returns the provider's chat completions endpoint
+func (p openAIProvider) DefaultURL(config *Config) string {
+	return p.url
+}
+
+// NeedsKey reports whether the provider requires an API key
+func (p openAIProvider) NeedsKey() bool {
+	return p.needsKey
+}
+
+// Generate sends a chat completion request, streamed when the request has OnDelta
+func (p openAIProvider) Generate(ctx context.Context, config *Config, request ChatRequest) (string, error) {
+	return generateChatCompletion(ctx, config, request, p.authorize)
+}
+
+// GenerateTurn sends a chat completion request with tools
+func (p openAIProvider) GenerateTurn(ctx context.Context, config *Config, request ChatRequest) (ChatTurn, error) {
+	return chatCompletionTurn(ctx, config, request, p.authorize)
+}
+
+// authorize authenticates a request with the API key as a bearer token
+func (p openAIProvider) authorize(req *http.Request, apiKey string) {
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	if p.referer {
+		req.Header.Set("HTTP-Referer", "https://github.com/aixoio/rmit")
+	}
+}
+
+// generateChatCompletion sends a request in the OpenAI chat completions format, authenticated
+// the way the provider expects
+func generateChatCompletion(ctx context.Context, config *Config, request ChatRequest, authorize func(req *http.Request, apiKey string)) (string, error) {
+	turn, err := chatCompletionTurn(ctx, config, request, authorize)
+	return turn.Text, err
+}
+
+// chatCompletionTurn sends a request in the OpenAI chat completions format and returns the
+// answer, or the tools the model calls when the request has tools
+func chatCompletionTurn(ctx context.Context, config *Config, request ChatRequest, authorize func(req *http.Request, apiKey string)) (ChatTurn, error) {
+	jsonBody, err := json.Marshal(OpenRouterRequest{
+		Model:    request.Model,
+		Messages: request.Messages,
+		Stream:   request.OnDelta != nil,
+		Tools:    openAITools(request.Tools),
+	})
+	if err != nil {
+		return ChatTurn{}, fmt.Errorf("failed to create request body: %w", err)
+	}
+	body, err := sendChat(ctx, config, request, jsonBody, chatWire{authorize: authorize, readStream: readChatStream})
+	if err != nil {
+		return ChatTurn{}, err
+	}
+
+	var response OpenRouterResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ChatTurn{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return ChatTurn{}, errEmptyResponse
+	}
+	message := response.Choices[0].Message
+	if len(message.ToolCalls) > 0 {
+		return ChatTurn{Text: message.Content, ToolCalls: message.ToolCalls}, nil
+	}
+	if response.Choices[0].FinishReason == "content_filter" && message.Content == "" {
+		return ChatTurn{}, errContentFiltered
+	}
+	return ChatTurn{Text: message.Content}, nil
+}
+
+// anthropicProvider speaks Anthropic's Messages API
+type anthropicProvider struct{}
+
+// AnthropicRequest is a Messages API request
+type AnthropicRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	Messages  []Message       `json:"messages"`
+	Tools     []AnthropicTool `json:"tools,omitempty"`
+}
+
+// AnthropicResponse is the part of a Messages API response rmit reads
+type AnthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		ID    string          `json:"id,omitempty"`    // of a tool_use block
+		Name  string          `json:"name,omitempty"`  // the tool a tool_use block calls
+		Input json.RawMessage `json:"input,omitempty"` // the arguments of a tool_use block
+	} `json:"content"`
+	StopReason string `json:"stop_reason,omitempty"` // "refusal" when Anthropic's safety filters stopped the answer
+	Usage      *Usage `json:"usage,omitempty"`
+}
+
+// DefaultURL returns the Messages API endpoint
+func (anthropicProvider) DefaultURL(config *Config) string {
+	return "https://api.anthropic.com/v1/messages"
+}
+
+// NeedsKey reports that Anthropic requires an API key
+func (anthropicProvider) NeedsKey() bool {
+	return true
+}
+
+// anthropicContent converts message content to Messages API content blocks. Images become
+// base64 or URL image sources.
+func anthropicContent(content any) any {
+	parts, ok := content.([]ContentPart)
+	if !ok {
+		return content
+	}
+	var blocks []map[string]any
+	for _, part := range parts {
+		if part.ImageURL == nil {
+			blocks = append(blocks, map[string]any{"type": "text", "text": part.Text})
+			continue
+		}
+		source := map[string]any{"type": "url", "url": part.ImageURL.URL}
+		if header, data, ok := strings.Cut(strings.TrimPrefix(part.ImageURL.URL, "data:"), ";base64,"); ok && strings.HasPrefix(part.ImageURL.URL, "data:") {
+			source = map[string]any{"type": "base64", "media_type": header, "data": data}
+		}
+		blocks = append(blocks, map[string]any{"type": "image", "source": source})
+	}
+	return blocks
+}
+
+// anthropicMessages converts messages to the Messages API. Tool calls become tool_use blocks of
+// the assistant's message, and tool results tool_result blocks of a user message, one for each
+// run of results.
+func anthropicMessages(messages []Message) []Message {
+	converted := make([]Message, 0, len(messages))
+	for _, message := range messages {
+		switch {
+		case len(message.ToolCalls) > 0:
+			var blocks []map[string]any
+			if text, _ := message.Content.(string); text != "" {
+				blocks = append(blocks, map[string]any{"type": "text", "text": text})
+			}
+			for _, call := range message.ToolCalls {
+				input := json.RawMessage(call.Function.Arguments)
+				if !json.Valid(input) {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, map[string]any{"type": "tool_use", "id": call.ID, "name": call.Function.Name, "input": input})
+			}
+			converted = append(converted, Message{Role: "assistant", Content: blocks})
+		case message.Role == "tool":
+			result := map[string]any{"type": "tool_result", "tool_use_id": message.ToolCallID, "content": message.Content}
+			if last := len(converted) - 1; last >= 0 && converted[last].Role == "user" {
+				if blocks, ok := converted[last].Content.([]map[string]any); ok && len(blocks) > 0 && blocks[0]["type"] == "tool_result" {
+					converted[last].Content = append(blocks, result)
+					continue
+				}
+			}
+			converted = append(converted, Message{Role: "user", Content: []map[string]any{result}})
+		default:
+			converted = append(converted, Message{Role: message.Role, Content: anthropicContent(message.Content)})
+		}
+	}
+	return converted
+}
+
+// Generate sends a Messages API request. Responses aren't streamed; OnDelta gets the whole
+// answer at once.
+func (p anthropicProvider) Generate(ctx context.Context, config *Config, request ChatRequest) (string, error) {
+	onDelta := request.OnDelta
+	request.OnDelta = nil
+	turn, err := p.GenerateTurn(ctx, config, request)
+	if err != nil {
+		return "", err
+	}
+	if onDelta != nil {
+		onDelta(turn.Text)
+	}
+	return turn.Text, nil
+}
+
+// GenerateTurn sends a Messages API request, with tools when the request has them
+func (p anthropicProvider) GenerateTurn(ctx context.Context, config *Config, request ChatRequest) (ChatTurn, error) {
+	jsonBody, err := json.Marshal(AnthropicRequest{
+		Model:     request.Model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  anthropicMessages(request.Messages),
+		Tools:     anthropicTools(request.Tools),
+	})
+	if err != nil {
+		return ChatTurn{}, fmt.Errorf("failed to create request body: %w", err)
+	}
+	body, err := sendChat(ctx, config, request, jsonBody, chatWire{
+		authorize: func(req *http.Request, apiKey string) {
+			req.Header.Set("x-api-key", apiKey)
+			req.Header.Set("anthropic-version", anthropicVersion)
+		},
+	})
+	if err != nil {
+		return ChatTurn{}, err
+	}
+
+	var response AnthropicResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return ChatTurn{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	var turn ChatTurn
+	var text strings.Builder
+	for _, block := range response.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			call := ToolCall{ID: block.ID, Type: "function"}
+			call.Function.Name, call.Function.Arguments = block.Name, string(block.Input)
+			turn.ToolCalls = append(turn.ToolCalls, call)
+		}
+	}
+	turn.Text = text.String()
+	if len(turn.ToolCalls) > 0 {
+		return turn, nil
+	}
+	if response.StopReason == "refusal" && text.Len() == 0 {
+		return ChatTurn{}, errContentFiltered
+	}
+	if text.Len() == 0 {
+		return ChatTurn{}, errEmptyResponse
+	}
+	return turn, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// questionsDiffLimit caps the diff the model reads to decide what to ask
+const questionsDiffLimit = 12000
+
+// clarifyingQuestions asks the model whether the diff leaves the purpose of the changes unclear,
+// and returns at most max questions for the author. None means the diff speaks for itself.
+func clarifyingQuestions(config *Config, model, diff, context string, max int) ([]string, error) {
+	files := parseDiff(diff)
+	changes := promptDiff(diff, files, summarizeFiles(files))
+	if len(changes) > questionsDiffLimit {
+		changes = changes[:questionsDiffLimit] + "\n[diff truncated]"
+	}
+
+	prompt := "Before a commit message is written for these changes, decide whether their purpose is clear. If it is, " +
+		`respond with {"questions": []}. If the diff mixes unrelated changes, or why something changed can't be told from ` +
+		fmt.Sprintf("the code, ask the author at most %d short questions whose answers would change the commit message, ", max) +
+		`as {"questions": ["<question>"]}. Don't ask about what the diff already shows. Respond only with the JSON object.` + "\n\n"
+	if context != "" {
+		prompt += "The author describes the intent of these changes as:\n" + context + "\n\n"
+	}
+	prompt += "Changes:\n" + changes
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Questions []string `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &parsed); err != nil {
+		return nil, fmt.Errorf("the model didn't return a list of questions")
+	}
+	var questions []string
+	for _, question := range parsed.Questions {
+		if question = strings.TrimSpace(question); question != "" && len(questions) < max {
+			questions = append(questions, question)
+		}
+	}
+	return questions, nil
+}
+
+// askClarifyingQuestions lets the model ask the author about an ambiguous diff before the
+// message is generated, for interactive_questions. The answers are returned as context for the
+// prompt; questions left unanswered are dropped. Generation goes ahead without answers when the
+// questions can't be had.
+func askClarifyingQuestions(config *Config, model, diff, context string) string {
+	ui.Info("\n❓ Checking whether the changes need explaining...")
+	questions, err := clarifyingQuestions(config, model, diff, context, config.InteractiveQuestions)
+	if err != nil {
+		ui.Warn(fmt.Sprintf("⚠️  No questions asked: %v", err))
+		return ""
+	}
+	if len(questions) == 0 {
+		return ""
+	}
+
+	ui.Panel(fmt.Sprintf("❓ %d QUESTION(S) ABOUT THE CHANGES (press Enter to skip one):", len(questions)), "")
+	var answers []string
+	for _, question := range questions {
+		answer, err := ui.Prompt(question + "\n> ")
+		if err != nil {
+			break
+		}
+		if answer = strings.TrimSpace(answer); answer != "" {
+			answers = append(answers, "Q: "+question+"\nA: "+answer)
+		}
+	}
+	if len(answers) == 0 {
+		return ""
+	}
+	return "Answers to questions about the changes:\n" + strings.Join(answers, "\n")
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Rate limit and transient failure handling
+const (
+	defaultMaxRetries = 5
+	maxMaxRetries     = 20
+	maxRateLimitWait  = 2 * time.Minute
+	rateLimitFileName = ".rmit_ratelimit"
+)
+
+// retryAfter works out how long to wait from Retry-After and X-RateLimit-Reset headers,
+// falling back to jittered exponential backoff when the provider doesn't say, so concurrent
+// clients don't all come back at the same moment
+func retryAfter(header http.Header, attempt int, now time.Time) time.Duration {
+	wait := time.Duration(float64(time.Second<<attempt) * (0.5 + rand.Float64()))
+
+	if value := header.Get("Retry-After"); value != "" {
+		if seconds, err := strconv.Atoi(value); err == nil {
+			wait = time.Duration(seconds) * time.Second
+		} else if at, err := http.ParseTime(value); err == nil {
+			wait = at.Sub(now)
+		}
+	} else if value := header.Get("X-RateLimit-Reset"); value != "" {
+		// OpenRouter sends a Unix timestamp in milliseconds, others use seconds
+		if reset, err := strconv.ParseInt(value, 10, 64); err == nil {
+			if reset > 1e12 {
+				wait = time.UnixMilli(reset).Sub(now)
+			} else {
+				wait = time.Unix(reset, 0).Sub(now)
+			}
+		}
+	}
+
+	if wait < time.Second {
+		wait = time.Second
+	}
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+	return wait
+}
+
+// isTransientStatus reports whether a response status is worth retrying: the provider is
+// overloaded or had a problem of its own
+func isTransientStatus(status int) bool {
+	return status >= http.StatusInternalServerError || status == http.StatusRequestTimeout
+}
+
+// isTransientNetworkError reports whether a request failed on the way, e.g. a dropped connection
+// or a timeout. A provider that can't be reached at all isn't retried, rmit offers to commit
+// offline instead.
+func isTransientNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// rateLimitKey identifies an API key in the shared rate limit file without storing the key itself
+func rateLimitKey(apiURL, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiURL + "\x00" + apiKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// rateLimitPath returns the file where rate limits are shared between concurrent rmit processes
+func rateLimitPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, rateLimitFileName), nil
+}
+
+// readRateLimits loads the time until which each key is rate limited
+func readRateLimits() map[string]int64 {
+	limits := make(map[string]int64)
+	statePath, err := rateLimitPath()
+	if err != nil {
+		return limits
+	}
+	if data, err := os.ReadFile(statePath); err == nil {
+		_ = json.Unmarshal(data, &limits)
+	}
+	return limits
+}
+
+// recordRateLimit stores when a key may be used again, so other rmit processes wait too
+func recordRateLimit(key string, until time.Time) {
+	if noPersist {
+		return
+	}
+	limits := readRateLimits()
+	now := time.Now().Unix()
+	for k, t := range limits {
+		if t <= now {
+			delete(limits, k)
+		}
+	}
+	limits[key] = until.Unix()
+
+	statePath, err := rateLimitPath()
+	if err != nil {
+		return
+	}
+	if data, err := json.Marshal(limits); err == nil {
+		_ = os.WriteFile(statePath, data, 0600)
+	}
+}
+
+// waitWithCountdown waits for the given duration, or until ctx is done, counting down the
+// remaining time where the UI can show it
+func waitWithCountdown(ctx context.Context, reason string, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	ui.Info(fmt.Sprintf("⏳ %s, retrying in %s", reason, wait.Round(time.Second)))
+	for remaining := time.Until(deadline); remaining > 0; remaining = time.Until(deadline) {
+		ui.Progress(fmt.Sprintf("⏳ retrying in %ds", int(remaining.Round(time.Second).Seconds())))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(remaining, time.Second)):
+		}
+	}
+	return nil
+}
+
+// protectedRequestFields are the request fields model_params can't override
+var protectedRequestFields = map[string]bool{"model": true, "messages": true, "stream": true}
+
+// mergeModelParams adds the configured model_params to a request body. Fields rmit sets
+// itself, like the model and messages, are kept; anything else in params wins.
+func mergeModelParams(jsonBody []byte, params map[string]any) ([]byte, error) {
+	if len(params) == 0 {
+		return jsonBody, nil
+	}
+	var request map[string]any
+	if err := json.Unmarshal(jsonBody, &request); err != nil {
+		return nil, fmt.Errorf("failed to apply model params: %w", err)
+	}
+	for key, value := range params {
+		if !protectedRequestFields[key] {
+			request[key] = value
+		}
+	}
+	return json.Marshal(request)
+}
+
+// postChatRequest sends a request to the provider's endpoint, queueing behind rate limits instead of failing.
+// Limits hit by one rmit process are shared with others using the same key, and with a key
+// pool a rate limited or rejected key makes way for the next one. When onDelta is set the
+// response is streamed to it and returned as if it had been sent in one piece.
+func postChatRequest(ctx context.Context, config *Config, jsonBody []byte, idempotencyKey string, onDelta func(string), wire chatWire) ([]byte, error) {
+	defer profilePhase(phaseNetwork)()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	jsonBody, err := mergeModelParams(jsonBody, config.ModelParams)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := chatURL(config)
+	poolSize := len(apiKeyPool(config))
+	retries := 0 // transient failures retried so far
+	blocked := make(map[string]time.Time)
+	compress := shouldCompress(config, jsonBody)
+
+	for attempt := 0; ; attempt++ {
+		apiKey := selectAPIKey(config)
+		key := rateLimitKey(endpoint, apiKey)
+		if shared := time.Unix(readRateLimits()[key], 0); shared.After(blocked[key]) {
+			blocked[key] = shared
+		}
+		if time.Now().Before(blocked[key]) {
+			if err := waitWithCountdown(ctx, "Rate limited", time.Until(blocked[key])); err != nil {
+				return nil, err
+			}
+		}
+
+		// Create HTTP request, gzip encoding large bodies when enabled
+		requestBody := jsonBody
+		if compress {
+			compressed, err := gzipBody(jsonBody)
+			if err != nil {
+				return nil, err
+			}
+			requestBody = compressed
+		}
+		attemptCtx, cancel := requestContext(ctx, config)
+		req, err := http.NewRequestWithContext(attemptCtx, "POST", endpoint, bytes.NewBuffer(requestBody))
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if compress {
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+
+		// Set headers, authentication is up to the provider
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+		wire.authorize(req, apiKey)
+
+		// Send request. The transport asks for gzip responses and decompresses them itself.
+		client := &http.Client{}
+		started := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			aborted := requestAborted(ctx, attemptCtx, config)
+			cancel()
+			metrics.observeProvider(0)
+			auditAPICall(config, jsonBody, 0, nil, started)
+			if aborted != nil {
+				return nil, aborted
+			}
+			if ctx.Err() == nil && isTransientNetworkError(err) && retries < config.MaxRetries {
+				if err := waitWithCountdown(ctx, "Connection failed", retryAfter(nil, retries, time.Now())); err != nil {
+					return nil, err
+				}
+				retries++
+				continue
+			}
+			return nil, fmt.Errorf("failed to send request: %w", err)
+		}
+		metrics.observeProvider(resp.StatusCode)
+
+		// Read response, assembling streamed responses as they arrive
+		var body []byte
+		if onDelta != nil && resp.StatusCode == http.StatusOK && wire.readStream != nil && isStreamed(resp.Header) {
+			watch := newStallWatch(resp.Body)
+			body, err = wire.readStream(resp.Body, watch.wrap(onDelta))
+			if stalled := watch.stop(); stalled != nil {
+				err = stalled
+			} else if err != nil && requestAborted(ctx, attemptCtx, config) != nil {
+				// What arrived before the timeout or Ctrl-C may still be worth keeping
+				err = watch.partial(requestAborted(ctx, attemptCtx, config))
+			}
+		} else {
+			body, err = io.ReadAll(resp.Body)
+			if aborted := requestAborted(ctx, attemptCtx, config); err != nil && aborted != nil {
+				err = aborted
+			}
+		}
+		resp.Body.Close()
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		auditAPICall(config, jsonBody, resp.StatusCode, body, started)
+
+		// Providers that don't accept compressed bodies get the request again uncompressed
+		if compress && rejectsCompression(resp.StatusCode) {
+			compress = false
+			continue
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < config.MaxRetries+poolSize {
+			blocked[key] = time.Now().Add(retryAfter(resp.Header, attempt, time.Now()))
+			recordRateLimit(key, blocked[key])
+			continue
+		}
+
+		// A rejected or out of credit key is skipped for a while if the pool has others
+		rejected := resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusPaymentRequired || resp.StatusCode == http.StatusForbidden
+		if rejected || resp.StatusCode == http.StatusOK {
+			recordKeyResult(config, apiKey, !rejected)
+		}
+		if rejected && attempt < poolSize-1 {
+			continue
+		}
+
+		if isTransientStatus(resp.StatusCode) && retries < config.MaxRetries {
+			if err := waitWithCountdown(ctx, fmt.Sprintf("Provider error %d", resp.StatusCode), retryAfter(resp.Header, retries, time.Now())); err != nil {
+				return nil, err
+			}
+			retries++
+			continue
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("API error: %s (status code: %d)", string(body), resp.StatusCode)
+		}
+
+		metrics.addUsage(body)
+		return body, nil
+	}
+}
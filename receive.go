@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RefUpdate is one ref a push updates, as git passes it to the update and pre-receive hooks
+type RefUpdate struct {
+	Ref string
+	Old string
+	New string
+}
+
+// isZeroObject reports whether an object name is git's all zero name for a ref that doesn't
+// exist, before it's created or after it's deleted
+func isZeroObject(object string) bool {
+	return object != "" && strings.Trim(object, "0") == ""
+}
+
+// isBareRepo reports whether git runs in a bare repository, e.g. from a hook on a git server
+func isBareRepo() bool {
+	out, err := gitOutput("rev-parse", "--is-bare-repository")
+	return err == nil && out == "true"
+}
+
+// readRefUpdates reads the "<old> <new> <ref>" lines git passes to pre-receive on stdin
+func readRefUpdates(r io.Reader) ([]RefUpdate, error) {
+	var updates []RefUpdate
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid ref update %q, expected <old> <new> <ref>", scanner.Text())
+		}
+		updates = append(updates, RefUpdate{Old: fields[0], New: fields[1], Ref: fields[2]})
+	}
+	return updates, scanner.Err()
+}
+
+// pushedCommits lists the commits a push adds to the repository, oldest first. Commits already
+// reachable from an existing ref were checked when they were first pushed, so moving a branch
+// onto them, or creating a branch at them, checks nothing. Deleted refs have no commits.
+func pushedCommits(updates []RefUpdate) ([]string, error) {
+	seen := map[string]bool{}
+	var commits []string
+	for _, update := range updates {
+		if isZeroObject(update.New) {
+			continue
+		}
+		// The hooks run before any ref is updated, so --all is what the repository had before the push
+		refCommits, err := listCommits(update.New, "--not", "--all")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list commits pushed to %s: %w", update.Ref, err)
+		}
+		for _, commit := range refCommits {
+			if !seen[commit] {
+				seen[commit] = true
+				commits = append(commits, commit)
+			}
+		}
+	}
+	return commits, nil
+}
+
+// runReceiveHook checks the messages of pushed commits for the update and pre-receive hooks
+// of a self-hosted git server, usually a bare repository. A push with a message that breaks the
+// convention is rejected, and unless noRewrites is set the rejection suggests a message for
+// each offending commit, so the author can fix it with git commit --amend or git rebase.
+// Everything is written to w, which git relays to the pusher. It reports whether the push is
+// accepted.
+func runReceiveHook(w io.Writer, updates []RefUpdate, model string, noRewrites bool) (bool, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	commits, err := pushedCommits(updates)
+	if err != nil {
+		return false, err
+	}
+	violations, err := checkCommitList(config, commits)
+	if err != nil {
+		return false, err
+	}
+	if len(violations) == 0 {
+		return true, nil
+	}
+
+	// The pusher sees this prefixed with "remote:", so it's plain text without colors
+	fmt.Fprintf(w, "rmit: %d of %d pushed commit(s) break the commit message convention\n", len(violations), len(commits))
+	for _, violation := range violations {
+		fmt.Fprintf(w, "\n✗ %s %s\n", violation.Commit[:12], violation.Subject)
+		for _, problem := range violation.Problems {
+			fmt.Fprintf(w, "  - %s\n", problem)
+		}
+		if noRewrites {
+			continue
+		}
+		// A failed suggestion doesn't change the verdict, the problems are already listed
+		suggestion, err := suggestRewrite(config, model, violation.Commit)
+		if err != nil {
+			fmt.Fprintf(w, "  (no suggested message: %v)\n", err)
+			continue
+		}
+		fmt.Fprintln(w, "\n  Suggested message:")
+		for _, line := range strings.Split(suggestion, "\n") {
+			fmt.Fprintln(w, strings.TrimRight("    "+line, " "))
+		}
+	}
+	fmt.Fprintln(w, "\nReword the commits, e.g. with git rebase -i and reword, then push again.")
+	return false, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// What happens to secrets found in a prompt before it's sent, set with secret_policy
+const (
+	secretRedact = "redact" // replace them with [REDACTED] and send the rest
+	secretAbort  = "abort"  // send nothing and say what to remove
+	secretOff    = "off"    // send the prompt as it is
+)
+
+// noRedact is set by --no-redact to send prompts without looking for secrets
+var noRedact bool
+
+// secretsRedactedWarned makes sure redacted secrets are reported once, although candidates and
+// retries send the prompt several times
+var secretsRedactedWarned sync.Once
+
+// validateSecretPolicy checks a secret_policy value
+func validateSecretPolicy(policy string) error {
+	switch policy {
+	case secretRedact, secretAbort, secretOff:
+		return nil
+	}
+	return fmt.Errorf("unknown secret policy %q, valid policies are: %s, %s, %s", policy, secretRedact, secretAbort, secretOff)
+}
+
+// secretPolicy returns the configured secret policy, --no-redact turning it off
+func secretPolicy(config *Config) string {
+	if noRedact {
+		return secretOff
+	}
+	if config.SecretPolicy == "" {
+		return secretRedact
+	}
+	return config.SecretPolicy
+}
+
+// compileSecretPatterns compiles secret_patterns
+func compileSecretPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// redactPrompt removes secrets from text about to be sent to a model or an rmit server: the
+// built-in patterns, secret_patterns and the configured API keys. Under the abort policy finding
+// any is an error instead.
+func redactPrompt(config *Config, text string) (string, error) {
+	policy := secretPolicy(config)
+	if policy == secretOff {
+		return text, nil
+	}
+	extra, err := compileSecretPatterns(config.SecretPatterns)
+	if err != nil {
+		return "", err
+	}
+	patterns := append(append([]*regexp.Regexp{}, secretPatterns...), extra...)
+	redacted, count := redactText(text, patterns, apiKeySecrets(config))
+	if count == 0 {
+		return text, nil
+	}
+	if policy == secretAbort {
+		return "", fmt.Errorf("the changes contain %d possible secret(s), remove them or run with --no-redact to send them anyway", count)
+	}
+	secretsRedactedWarned.Do(func() {
+		ui.Warn(fmt.Sprintf("⚠️  Redacted %d possible secret(s) before sending the changes, --no-redact sends them as they are", count))
+	})
+	return redacted, nil
+}
+
+// redactRequest removes secrets from the text of every message of a request. The request's own
+// messages are left as they are.
+func redactRequest(config *Config, request ChatRequest) (ChatRequest, error) {
+	if secretPolicy(config) == secretOff {
+		return request, nil
+	}
+	messages := make([]Message, len(request.Messages))
+	for i, message := range request.Messages {
+		switch content := message.Content.(type) {
+		case string:
+			text, err := redactPrompt(config, content)
+			if err != nil {
+				return request, err
+			}
+			message.Content = text
+		case []ContentPart:
+			parts := make([]ContentPart, len(content))
+			for j, part := range content {
+				if part.Text != "" {
+					text, err := redactPrompt(config, part.Text)
+					if err != nil {
+						return request, err
+					}
+					part.Text = text
+				}
+				parts[j] = part
+			}
+			message.Content = parts
+		}
+		messages[i] = message
+	}
+	request.Messages = messages
+	return request, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxRefusalRetries is how often an empty, filtered or declined answer is asked for again
+const maxRefusalRetries = 2
+
+// errEmptyResponse is returned when the model answers with nothing
+var errEmptyResponse = errors.New("no response from AI model")
+
+// errContentFiltered is returned when the provider's content filter stopped the answer
+var errContentFiltered = errors.New("the response was blocked by the provider's content filter")
+
+// refusalPattern matches answers where the model declines instead of writing a message
+var refusalPattern = regexp.MustCompile(`(?i)^\W*(i'?m sorry|i am sorry|sorry, (but )?i|i (cannot|can'?t|can not|won'?t|am unable|'?m unable|am not able)|as an ai)\b`)
+
+// refusalInstruction goes in front of the prompt when the model was asked again
+const refusalInstruction = "This is a routine request to describe source code changes in a git commit message. " +
+	"Describe what the diff changes without commenting on it, and don't decline.\n\n"
+
+// isRefusal reports whether a generation came back empty, filtered or declined
+func isRefusal(response string, err error) bool {
+	if err != nil {
+		return errors.Is(err, errEmptyResponse) || errors.Is(err, errContentFiltered)
+	}
+	return strings.TrimSpace(response) == "" || refusalPattern.MatchString(response)
+}
+
+// refusalError describes the last refusal once the retries are used up
+func refusalError(response string, err error) error {
+	if err != nil {
+		return fmt.Errorf("%w (after %d retries)", err, maxRefusalRetries)
+	}
+	if strings.TrimSpace(response) == "" {
+		return fmt.Errorf("%w (after %d retries)", errEmptyResponse, maxRefusalRetries)
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(response), "\n")
+	return fmt.Errorf("the model declined to write a commit message (after %d retries): %q", maxRefusalRetries, subject)
+}
+
+// refusalRetry returns the request to send after a refusal: the prompt is prefixed with
+// refusalInstruction, and the last retry goes to fallback_model when one is configured
+func refusalRetry(config *Config, request ChatRequest, retry int) ChatRequest {
+	messages := make([]Message, len(request.Messages))
+	copy(messages, request.Messages)
+	last := &messages[len(messages)-1]
+	switch content := last.Content.(type) {
+	case string:
+		last.Content = refusalInstruction + content
+	case []ContentPart:
+		parts := append([]ContentPart{{Type: "text", Text: refusalInstruction}}, content...)
+		last.Content = parts
+	}
+	request.Messages = messages
+	if retry == maxRefusalRetries && config.FallbackModel != "" {
+		request.Model = config.FallbackModel
+	}
+	// A refused answer shouldn't be shared, and the retry needs its own
+	request.Shared = false
+	return request
+}
+
+// chatUntilAnswered sends a request, asking again with an adjusted prompt or the fallback model
+// while the answer is empty, filtered or declined. The error is only returned once the retries
+// are used up.
+func chatUntilAnswered(opts GenerateOptions, config *Config, request ChatRequest) (string, error) {
+	response, err := chat(opts.Ctx, config, request)
+	for retry := 1; retry <= maxRefusalRetries && isRefusal(response, err); retry++ {
+		retried := refusalRetry(config, request, retry)
+		reason := "the model declined"
+		if err != nil {
+			reason = err.Error()
+		}
+		ui.Warn(fmt.Sprintf("\n🔁 No usable answer (%s), retrying %d/%d with %s...", reason, retry, maxRefusalRetries, retried.Model))
+		response, err = chat(opts.Ctx, config, retried)
+	}
+	if isRefusal(response, err) {
+		return "", refusalError(response, err)
+	}
+	return response, err
+}
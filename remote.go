@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// remoteCommitMessage asks an rmit server to generate the message, for teams sharing one server
+// in front of their LLM gateway. Only the diff and the author's options are sent.
+func remoteCommitMessage(config *Config, diff string, opts GenerateOptions) (string, error) {
+	// The server is as much a third party as the provider behind it
+	diff, err := redactPrompt(config, diff)
+	if err != nil {
+		return "", err
+	}
+	intent, err := redactPrompt(config, opts.Context)
+	if err != nil {
+		return "", err
+	}
+	req := GenerateRequest{
+		Diff:        diff,
+		Model:       opts.Model,
+		Context:     intent,
+		SubjectOnly: opts.SubjectOnly,
+		Type:        opts.Type,
+		Scope:       opts.Scope,
+	}
+	for _, trailer := range opts.Trailers {
+		req.Trailers = append(req.Trailers, trailer.Key+": "+trailer.Value)
+	}
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request body: %w", err)
+	}
+
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(config.Server, "/")+"/generate", bytes.NewReader(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	if config.ServerToken != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+config.ServerToken)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach rmit server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var state JobState
+	if err := json.Unmarshal(body, &state); err != nil {
+		return "", fmt.Errorf("rmit server error: %s (status code: %d)", strings.TrimSpace(string(body)), resp.StatusCode)
+	}
+	switch {
+	case state.Error != "":
+		return "", fmt.Errorf("rmit server error: %s", state.Error)
+	case state.Status == jobCanceled:
+		return "", errors.New("the request was canceled on the rmit server")
+	case resp.StatusCode != http.StatusOK || state.Message == "":
+		return "", fmt.Errorf("rmit server error: %s (status code: %d)", strings.TrimSpace(string(body)), resp.StatusCode)
+	}
+
+	model := opts.Model
+	if model == "" {
+		model = "server default"
+	}
+	opts.Transcript.record(TranscriptEntry{
+		Model:    model,
+		Prompt:   "(generated by rmit server " + config.Server + ")",
+		Response: state.Message,
+		Message:  state.Message,
+	})
+	return state.Message, nil
+}
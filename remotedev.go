@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Where git can run instead of the current directory
+const (
+	checkoutSSH    = "ssh"
+	checkoutDocker = "docker"
+	checkoutPodman = "podman"
+)
+
+// RemoteCheckout is a checkout git runs in while the message is generated locally: on another
+// machine over SSH, given with --remote host:path, or in a container, given with --exec-in
+// docker:container. It is for remote development setups where the repository lives on a
+// build box or only in a dev container, with its own git version and credentials.
+type RemoteCheckout struct {
+	Kind string // checkoutSSH, checkoutDocker or checkoutPodman
+	Host string // an SSH destination, e.g. dev@devbox or a Host from ~/.ssh/config, or a container
+	Path string // the checkout's directory; for containers "" means the container's working directory
+}
+
+// remoteCheckout is the checkout git runs in, nil for the current directory
+var remoteCheckout *RemoteCheckout
+
+// parseRemoteCheckout parses a --remote value like devbox:~/src/app
+func parseRemoteCheckout(value string) (*RemoteCheckout, error) {
+	host, dir, ok := strings.Cut(value, ":")
+	if !ok || strings.TrimSpace(host) == "" || strings.TrimSpace(dir) == "" {
+		return nil, fmt.Errorf("invalid remote %q, expected host:path, e.g. devbox:~/src/app", value)
+	}
+	return &RemoteCheckout{Kind: checkoutSSH, Host: host, Path: dir}, nil
+}
+
+// parseExecIn parses an --exec-in value like docker:devcontainer or podman:app:/workspace
+func parseExecIn(value string) (*RemoteCheckout, error) {
+	kind, rest, _ := strings.Cut(value, ":")
+	container, dir, _ := strings.Cut(rest, ":")
+	if (kind != checkoutDocker && kind != checkoutPodman) || strings.TrimSpace(container) == "" {
+		return nil, fmt.Errorf("invalid --exec-in %q, expected docker:container or podman:container, optionally followed by :path", value)
+	}
+	return &RemoteCheckout{Kind: kind, Host: container, Path: dir}, nil
+}
+
+// shellQuote quotes a word for the remote shell
+func shellQuote(word string) string {
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
+
+// command returns a command that runs in the checkout, with extra environment variables
+func (r *RemoteCheckout) command(env []string, name string, args ...string) *exec.Cmd {
+	if r.Kind != checkoutSSH {
+		// Containers run the command directly, -i passes stdin through for git apply and hash-object
+		execArgs := []string{"exec", "-i"}
+		if r.Path != "" {
+			execArgs = append(execArgs, "-w", r.Path)
+		}
+		for _, variable := range env {
+			execArgs = append(execArgs, "-e", variable)
+		}
+		execArgs = append(append(execArgs, r.Host, name), args...)
+		return exec.Command(r.Kind, execArgs...)
+	}
+
+	// SSH connections are shared, so the many git calls of a run only log in once
+	dir := shellQuote(r.Path)
+	if rest, ok := strings.CutPrefix(r.Path, "~/"); ok {
+		dir = "~/" + shellQuote(rest)
+	}
+	words := []string{"cd", dir, "&&"}
+	if len(env) > 0 {
+		words = append(words, "env")
+		for _, variable := range env {
+			words = append(words, shellQuote(variable))
+		}
+	}
+	words = append(words, shellQuote(name))
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+
+	controlPath := filepath.Join(os.TempDir(), "rmit-ssh-%C")
+	return exec.Command("ssh", "-o", "ControlMaster=auto", "-o", "ControlPath="+controlPath, "-o", "ControlPersist=60s",
+		r.Host, strings.Join(words, " "))
+}
+
+// gitCommand returns a git command, run in the --remote checkout when there is one
+func gitCommand(args ...string) *exec.Cmd {
+	return gitCommandEnv(nil, args...)
+}
+
+// gitCommandEnv returns a git command with extra environment variables, e.g. GIT_INDEX_FILE
+func gitCommandEnv(env []string, args ...string) *exec.Cmd {
+	if remoteCheckout != nil {
+		return remoteCheckout.command(env, "git", args...)
+	}
+	cmd := exec.Command("git", args...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	return cmd
+}
+
+// readCheckoutFile reads a file of the checkout at root by its repository relative path
+func readCheckoutFile(root, repoPath string) ([]byte, error) {
+	if remoteCheckout != nil {
+		return remoteCheckout.command(nil, "cat", "--", path.Join(root, repoPath)).Output()
+	}
+	return os.ReadFile(filepath.Join(root, filepath.FromSlash(repoPath)))
+}
+
+// checkoutTempFile creates an empty temporary file on the machine git runs on
+func checkoutTempFile(pattern string) (string, error) {
+	if remoteCheckout != nil {
+		out, err := remoteCheckout.command(nil, "mktemp", "-t", strings.ReplaceAll(pattern, "*", "XXXXXX")).Output()
+		return strings.TrimSpace(string(out)), err
+	}
+	tmp, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	return tmp.Name(), tmp.Close()
+}
+
+// removeCheckoutFile removes a file on the machine git runs on
+func removeCheckoutFile(name string) {
+	if remoteCheckout != nil {
+		_ = remoteCheckout.command(nil, "rm", "-f", "--", name).Run()
+		return
+	}
+	os.Remove(name)
+}
+
+// copyCheckoutFile copies a file on the machine git runs on. A missing source is reported as
+// os.ErrNotExist.
+func copyCheckoutFile(from, to string) error {
+	if remoteCheckout != nil {
+		if remoteCheckout.command(nil, "test", "-e", from).Run() != nil {
+			return os.ErrNotExist
+		}
+		return remoteCheckout.command(nil, "cp", "--", from, to).Run()
+	}
+	return copyFile(from, to)
+}
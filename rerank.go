@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxCandidates caps how many messages are generated to choose from
+const maxCandidates = 5
+
+// genericSubjectPattern matches subjects that say nothing about the change
+var genericSubjectPattern = regexp.MustCompile(`(?i)^(?:update[sd]?|changes?|minor (?:changes|fixes|updates)|misc\w*|wip|fix(?:e[sd])? (?:bugs?|issues?|stuff)|update[sd]? (?:code|files?|stuff)|various (?:changes|fixes)|improvements?|cleanup)\.?$`)
+
+// RankedCandidate is a generated message with its predicted quality
+type RankedCandidate struct {
+	Message string
+	Score   int
+	Notes   []string // why points were taken off
+}
+
+// scoreMessage predicts how good a commit message is for a diff, without calling a model. It
+// rewards short subjects following the commit style in the imperative mood that name what
+// changed, and takes points off for generic, overlong or malformed messages.
+func scoreMessage(style, message string, files []*FileDiff) (int, []string) {
+	score := 0
+	var notes []string
+	penalize := func(points int, note string) {
+		score -= points
+		notes = append(notes, note)
+	}
+
+	subject, body, hasBody := strings.Cut(strings.TrimSpace(message), "\n")
+	commitType, description, problem := splitSubject(style, subject)
+	if problem == "" {
+		score += 2
+	} else {
+		penalize(1, problem)
+	}
+
+	switch {
+	case len(subject) <= 50:
+		score += 2
+	case len(subject) <= 72:
+		score++
+	default:
+		penalize(2, "subject over 72 characters")
+	}
+	if genericSubjectPattern.MatchString(strings.TrimSpace(description)) || len(description) < 10 {
+		penalize(3, "generic subject")
+	}
+	if strings.HasSuffix(subject, ".") {
+		penalize(1, "subject ends with a period")
+	}
+	if firstWord, _, _ := strings.Cut(strings.ToLower(description), " "); strings.HasSuffix(firstWord, "ed") || strings.HasSuffix(firstWord, "ing") {
+		penalize(1, "subject not in the imperative mood")
+	}
+	if hasBody && !strings.HasPrefix(body, "\n") {
+		penalize(1, "no blank line after the subject")
+	}
+	if strings.Contains(message, "```") || strings.HasPrefix(message, `"`) {
+		penalize(2, "contains formatting")
+	}
+
+	// Naming something that changed shows the message is about this diff
+	lower := strings.ToLower(message)
+	for _, f := range files {
+		name := strings.ToLower(strings.TrimSuffix(path.Base(f.Path), path.Ext(f.Path)))
+		dir := strings.ToLower(path.Base(path.Dir(f.Path)))
+		if (len(name) >= 3 && strings.Contains(lower, name)) || (len(dir) >= 3 && dir != "." && strings.Contains(lower, dir)) {
+			score++
+			break
+		}
+	}
+
+	// The type should fit changes that are all docs or all tests
+	if len(files) > 0 && commitType != "" {
+		docs, tests := true, true
+		for _, f := range files {
+			ext := path.Ext(f.Path)
+			docs = docs && (ext == ".md" || ext == ".rst" || ext == ".txt")
+			tests = tests && strings.Contains(strings.ToLower(f.Path), "test")
+		}
+		if (docs && commitType != "docs") || (tests && commitType != "test") {
+			penalize(1, "type doesn't match the changed files")
+		}
+	}
+	return score, notes
+}
+
+// messageProblems returns what makes a message unusable as is: what a person would press r for.
+// Unlike scoreMessage it doesn't judge style, only whether the message is broken.
+func messageProblems(style, message string, templates map[string][]string) []string {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return []string{"empty message"}
+	}
+	var problems []string
+	subject, _, _ := strings.Cut(message, "\n")
+	if _, description, problem := splitSubject(style, subject); problem != "" {
+		problems = append(problems, problem)
+	} else if genericSubjectPattern.MatchString(strings.TrimSpace(description)) {
+		problems = append(problems, "generic subject")
+	}
+	if len(subject) > 72 {
+		problems = append(problems, "subject over 72 characters")
+	}
+	if strings.Contains(message, "```") {
+		problems = append(problems, "contains formatting")
+	}
+	if missing := missingSections(message, templates); len(missing) > 0 {
+		problems = append(problems, "missing template sections "+strings.Join(missing, ", "))
+	}
+	return problems
+}
+
+// rankCandidates orders messages by predicted quality, best first. Ties keep the order they
+// were generated in. The configured subject prefix and suffix aren't scored.
+func rankCandidates(style string, messages []string, files []*FileDiff, affixes SubjectAffixes) []RankedCandidate {
+	ranked := make([]RankedCandidate, 0, len(messages))
+	for _, message := range messages {
+		score, notes := scoreMessage(style, affixes.strip(message), files)
+		ranked = append(ranked, RankedCandidate{Message: message, Score: score, Notes: notes})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	return ranked
+}
+
+// generateCandidates generates several messages for the same diff at once. Candidates that fail
+// and duplicates are skipped; it only fails if none could be generated.
+func generateCandidates(config *Config, diff string, opts GenerateOptions, n int) ([]string, error) {
+	messages := make([]string, n)
+	errs := make([]error, n)
+	transcripts := make([]*Transcript, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		candidateOpts := opts
+		candidateOpts.OnDelta = nil // candidates are generated at once and can't share the display
+		if opts.Transcript != nil {
+			transcripts[i] = &Transcript{}
+			candidateOpts.Transcript = transcripts[i]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			messages[i], errs[i] = generateCommitMessage(config, diff, candidateOpts)
+		}()
+	}
+	wg.Wait()
+
+	var generated []string
+	seen := make(map[string]bool)
+	for i := range n {
+		if errs[i] != nil {
+			log.Printf("Warning: candidate %d failed: %v", i+1, errs[i])
+			continue
+		}
+		if !seen[messages[i]] {
+			seen[messages[i]] = true
+			generated = append(generated, messages[i])
+		}
+		if transcripts[i] != nil {
+			opts.Transcript.Entries = append(opts.Transcript.Entries, transcripts[i].Entries...)
+		}
+	}
+	if len(generated) == 0 {
+		return nil, errs[0]
+	}
+	return generated, nil
+}
+
+// printCandidates shows ranked candidates with their scores
+func printCandidates(ranked []RankedCandidate) {
+	var entries []string
+	for i, candidate := range ranked {
+		entry := fmt.Sprintf("%s %s", cyan(fmt.Sprintf("%d.", i+1)), yellow(fmt.Sprintf("(score %d)", candidate.Score)))
+		if len(candidate.Notes) > 0 {
+			entry += " " + strings.Join(candidate.Notes, ", ")
+		}
+		entries = append(entries, entry+"\n"+candidate.Message)
+	}
+	ui.Panel(fmt.Sprintf("🏆 %d CANDIDATES, BEST FIRST:", len(ranked)), strings.Join(entries, "\n\n"))
+}
+
+// chooseCandidate generates n messages, ranks them locally and lets the user pick one. When
+// committing automatically the best ranked message is used.
+func chooseCandidate(config *Config, diff string, opts GenerateOptions, n int, auto bool) (string, error) {
+	messages, err := generateCandidates(config, diff, opts, n)
+	if err != nil {
+		return "", err
+	}
+	ranked := rankCandidates(commitStyle(config), messages, parseDiff(diff), subjectAffixes(config, opts.Trailers))
+	if auto || len(ranked) == 1 {
+		return ranked[0].Message, nil
+	}
+
+	printCandidates(ranked)
+	for {
+		response, err := readUserInput(fmt.Sprintf("Use which candidate? [1-%d, default 1]: ", len(ranked)))
+		if err != nil {
+			return "", err
+		}
+		if response == "y" {
+			return ranked[0].Message, nil
+		}
+		if choice, err := strconv.Atoi(response); err == nil && choice >= 1 && choice <= len(ranked) {
+			return ranked[choice-1].Message, nil
+		}
+		ui.Error(fmt.Sprintf("❌ Enter a number between 1 and %d.", len(ranked)))
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Data retention of a provider endpoint
+const (
+	retentionNone     = "none"     // prompts aren't kept after the response
+	retentionRetained = "retained" // prompts are kept for some time, e.g. for abuse monitoring
+	retentionVaries   = "varies"   // depends on account settings or the upstream provider
+	retentionUnknown  = "unknown"  // not in the registry and not configured
+)
+
+// What to do when a confidential repository would be sent to a provider that may retain it
+const (
+	confidentialWarn  = "warn"
+	confidentialBlock = "block"
+)
+
+// confidentialMarker marks a repository as confidential when committed to it
+const confidentialMarker = ".rmit/confidential"
+
+// ProviderCapability describes how a provider handles the prompts sent to it
+type ProviderCapability struct {
+	Host      string
+	Retention string
+	Note      string
+}
+
+// providerRegistry lists the default data retention of known providers. It is deliberately
+// conservative: hosted APIs are only trusted not to retain prompts when configured so with
+// provider_retention, e.g. after signing a zero data retention agreement.
+var providerRegistry = []ProviderCapability{
+	{Host: "openrouter.ai", Retention: retentionVaries, Note: "depends on your OpenRouter privacy settings and the provider the request is routed to"},
+	{Host: "api.openai.com", Retention: retentionRetained, Note: "kept for abuse monitoring unless your organization has zero data retention"},
+	{Host: "api.anthropic.com", Retention: retentionRetained, Note: "kept for a limited time unless your organization has zero data retention"},
+	{Host: "openai.azure.com", Retention: retentionRetained, Note: "kept for abuse monitoring unless it is turned off for your Azure resource"},
+	{Host: "generativelanguage.googleapis.com", Retention: retentionRetained, Note: "kept for abuse monitoring"},
+}
+
+// confidentialWarned makes sure the warning is shown once per run, not for every regeneration
+var confidentialWarned sync.Once
+
+// validateRetention checks a provider_retention value
+func validateRetention(retention string) error {
+	switch retention {
+	case retentionNone, retentionRetained, retentionVaries:
+		return nil
+	}
+	return fmt.Errorf("unknown retention %q, expected none, retained or varies", retention)
+}
+
+// validateConfidentialPolicy checks a confidential_policy value
+func validateConfidentialPolicy(policy string) error {
+	switch policy {
+	case confidentialWarn, confidentialBlock:
+		return nil
+	}
+	return fmt.Errorf("unknown policy %q, expected warn or block", policy)
+}
+
+// parseProviderRetention parses "host=retention" pairs as used by rmit set provider_retention
+func parseProviderRetention(value string) (map[string]string, error) {
+	retention := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, mode, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(host) == "" {
+			return nil, fmt.Errorf("invalid provider retention entry %q, expected host=retention", entry)
+		}
+		mode = strings.TrimSpace(mode)
+		if err := validateRetention(mode); err != nil {
+			return nil, err
+		}
+		retention[strings.ToLower(strings.TrimSpace(host))] = mode
+	}
+	return retention, nil
+}
+
+// providerCapability looks up the data retention of the configured endpoint. Configured values
+// win over the registry, and local endpoints never retain anything.
+func providerCapability(config *Config) ProviderCapability {
+	parsed, err := url.Parse(chatURL(config))
+	if err != nil {
+		return ProviderCapability{Host: chatURL(config), Retention: retentionUnknown}
+	}
+	host := strings.ToLower(parsed.Hostname())
+
+	if retention, ok := config.ProviderRetention[host]; ok {
+		return ProviderCapability{Host: host, Retention: retention, Note: "set with provider_retention"}
+	}
+	if host == "localhost" || host == "127.0.0.1" || host == "::1" {
+		return ProviderCapability{Host: host, Retention: retentionNone, Note: "local endpoint"}
+	}
+	for _, capability := range providerRegistry {
+		if host == capability.Host || strings.HasSuffix(host, "."+capability.Host) {
+			return capability
+		}
+	}
+	return ProviderCapability{Host: host, Retention: retentionUnknown, Note: "not a known provider; declare it with provider_retention"}
+}
+
+// isConfidentialRepo reports whether the current repository is marked confidential
+func isConfidentialRepo() bool {
+	_, err := readRepoFile(confidentialMarker)
+	return err == nil
+}
+
+// checkConfidential enforces the confidential_policy before a confidential repository's
+// changes are sent to a provider without a no-retention guarantee
+func checkConfidential(config *Config) error {
+	capability := providerCapability(config)
+	if capability.Retention == retentionNone || !isConfidentialRepo() {
+		return nil
+	}
+
+	reason := fmt.Sprintf("this repository is marked confidential (%s) but %s retention is %s", confidentialMarker, capability.Host, capability.Retention)
+	if capability.Note != "" {
+		reason += ": " + capability.Note
+	}
+	if config.ConfidentialPolicy == confidentialBlock {
+		return errors.New(reason + ". Use a provider without retention, or declare one with rmit set provider_retention")
+	}
+	confidentialWarned.Do(func() {
+		fmt.Fprintf(os.Stderr, "%s %s\n", yellow("⚠️  Warning:"), reason)
+	})
+	return nil
+}
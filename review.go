@@ -0,0 +1,352 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// reviewThreadsQuery fetches the review threads of a GitHub pull request
+const reviewThreadsQuery = `query($owner: String!, $name: String!, $number: Int!) {
+  repository(owner: $owner, name: $name) {
+    pullRequest(number: $number) {
+      reviewThreads(first: 100) {
+        nodes {
+          isResolved
+          path
+          line
+          comments(first: 50) { nodes { author { login } body } }
+        }
+      }
+    }
+  }
+}`
+
+// ReviewComment is one comment of a review thread
+type ReviewComment struct {
+	Author string
+	Body   string
+}
+
+// ReviewThread is an unresolved review discussion on a pull request
+type ReviewThread struct {
+	Path     string
+	Line     int
+	Comments []ReviewComment
+}
+
+// location returns where in the code the thread was started
+func (t *ReviewThread) location() string {
+	if t.Path == "" {
+		return "the pull request"
+	}
+	if t.Line > 0 {
+		return fmt.Sprintf("%s:%d", t.Path, t.Line)
+	}
+	return t.Path
+}
+
+// ReviewReply is a drafted reply to a review thread
+type ReviewReply struct {
+	Thread int    `json:"thread"`
+	Reply  string `json:"reply"`
+}
+
+// githubReviewThreads fetches the unresolved review threads of a GitHub pull request. Resolution
+// state is only available through GraphQL, which needs a token.
+func githubReviewThreads(repo *RemoteRepo, pr int) ([]ReviewThread, error) {
+	if githubToken() == "" {
+		return nil, errors.New("set GITHUB_TOKEN or GH_TOKEN to read review comments")
+	}
+	owner, name, ok := strings.Cut(repo.Path, "/")
+	if !ok {
+		return nil, fmt.Errorf("can't tell the owner of %s", repo.Path)
+	}
+
+	endpoint := repo.Base + "/api/graphql"
+	if strings.HasSuffix(repo.Base, "://github.com") {
+		endpoint = "https://api.github.com/graphql"
+	}
+	payload, err := json.Marshal(map[string]any{
+		"query":     reviewThreadsQuery,
+		"variables": map[string]any{"owner": owner, "name": name, "number": pr},
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, err := trackerRequest("POST", endpoint, false, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var response struct {
+		Data struct {
+			Repository struct {
+				PullRequest *struct {
+					ReviewThreads struct {
+						Nodes []struct {
+							IsResolved bool   `json:"isResolved"`
+							Path       string `json:"path"`
+							Line       int    `json:"line"`
+							Comments   struct {
+								Nodes []struct {
+									Author struct {
+										Login string `json:"login"`
+									} `json:"author"`
+									Body string `json:"body"`
+								} `json:"nodes"`
+							} `json:"comments"`
+						} `json:"nodes"`
+					} `json:"reviewThreads"`
+				} `json:"pullRequest"`
+			} `json:"repository"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse review threads: %w", err)
+	}
+	if len(response.Errors) > 0 {
+		return nil, errors.New(response.Errors[0].Message)
+	}
+	if response.Data.Repository.PullRequest == nil {
+		return nil, fmt.Errorf("pull request #%d not found in %s", pr, repo.Path)
+	}
+
+	var threads []ReviewThread
+	for _, node := range response.Data.Repository.PullRequest.ReviewThreads.Nodes {
+		if node.IsResolved {
+			continue
+		}
+		thread := ReviewThread{Path: node.Path, Line: node.Line}
+		for _, comment := range node.Comments.Nodes {
+			thread.Comments = append(thread.Comments, ReviewComment{Author: comment.Author.Login, Body: comment.Body})
+		}
+		if len(thread.Comments) > 0 {
+			threads = append(threads, thread)
+		}
+	}
+	return threads, nil
+}
+
+// gitlabReviewThreads fetches the unresolved discussions of a GitLab merge request
+func gitlabReviewThreads(repo *RemoteRepo, mr int) ([]ReviewThread, error) {
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/discussions?per_page=100", repo.Base, url.PathEscape(repo.Path), mr)
+	body, err := trackerRequest("GET", endpoint, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discussions []struct {
+		Notes []struct {
+			Author struct {
+				Username string `json:"username"`
+			} `json:"author"`
+			Body       string `json:"body"`
+			Resolvable bool   `json:"resolvable"`
+			Resolved   bool   `json:"resolved"`
+			Position   *struct {
+				NewPath string `json:"new_path"`
+				NewLine int    `json:"new_line"`
+			} `json:"position"`
+		} `json:"notes"`
+	}
+	if err := json.Unmarshal(body, &discussions); err != nil {
+		return nil, fmt.Errorf("failed to parse discussions: %w", err)
+	}
+
+	var threads []ReviewThread
+	for _, discussion := range discussions {
+		if len(discussion.Notes) == 0 || !discussion.Notes[0].Resolvable || discussion.Notes[0].Resolved {
+			continue
+		}
+		var thread ReviewThread
+		if position := discussion.Notes[0].Position; position != nil {
+			thread.Path, thread.Line = position.NewPath, position.NewLine
+		}
+		for _, note := range discussion.Notes {
+			thread.Comments = append(thread.Comments, ReviewComment{Author: note.Author.Username, Body: note.Body})
+		}
+		threads = append(threads, thread)
+	}
+	return threads, nil
+}
+
+// formatThreads lists review threads for the model, numbered so replies can refer to them
+func formatThreads(threads []ReviewThread) string {
+	var text strings.Builder
+	for i, thread := range threads {
+		fmt.Fprintf(&text, "Thread %d on %s:\n", i+1, thread.location())
+		for _, comment := range thread.Comments {
+			fmt.Fprintf(&text, "  @%s: %s\n", comment.Author, strings.TrimSpace(comment.Body))
+		}
+	}
+	return text.String()
+}
+
+// reviewContext frames the review comments as the intent behind the changes
+func reviewContext(pr int, threads []ReviewThread) string {
+	return fmt.Sprintf("These changes address unresolved review comments on pull request #%d:\n%s\n"+
+		"Start the subject with \"address review:\" and say whose comment was addressed, "+
+		"e.g. \"address review: fix retry handling per @alice's comment\". "+
+		"Comments the diff doesn't address must not be mentioned.", pr, formatThreads(threads))
+}
+
+// draftReplies asks the model for a reply to each review thread based on the diff
+func draftReplies(config *Config, model string, threads []ReviewThread, diff string) ([]ReviewReply, error) {
+	if len(diff) > issueDiffLimit {
+		diff = diff[:issueDiffLimit] + "\n[diff truncated]"
+	}
+	prompt := "Draft a short reply to each of these pull request review threads, written by the author of the diff below. " +
+		"Say how the diff addresses the comment, or that it is still open if the diff doesn't address it. " +
+		"Respond only with a JSON object of the form " + `{"replies": [{"thread": 1, "reply": "<reply>"}]}` +
+		".\n\n" + formatThreads(threads) + "\nDiff:\n" + diff
+
+	response, err := askModel(config, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var drafts struct {
+		Replies []ReviewReply `json:"replies"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &drafts); err != nil {
+		return nil, fmt.Errorf("the model didn't return reply drafts")
+	}
+	return drafts.Replies, nil
+}
+
+// printReplies shows each review thread with its drafted reply
+func printReplies(threads []ReviewThread, replies []ReviewReply) {
+	byThread := make(map[int]string)
+	for _, reply := range replies {
+		byThread[reply.Thread] = reply.Reply
+	}
+
+	var drafts []string
+	for i, thread := range threads {
+		first := thread.Comments[0]
+		excerpt := strings.Join(strings.Fields(first.Body), " ")
+		if len(excerpt) > 100 {
+			excerpt = excerpt[:97] + "..."
+		}
+		draft := yellow("↳ no draft")
+		if reply, ok := byThread[i+1]; ok {
+			draft = blue("↳") + " " + reply
+		}
+		drafts = append(drafts, fmt.Sprintf("%s %s %s\n   %s\n   %s", cyan(fmt.Sprintf("%d.", i+1)), green("@"+first.Author), yellow("on "+thread.location()), excerpt, draft))
+	}
+	ui.Panel("💬 REPLY DRAFTS:", strings.Join(drafts, "\n\n"))
+}
+
+// newAddressReviewCmd creates the address-review command that commits changes made in response to a review
+func newAddressReviewCmd() *cobra.Command {
+	var (
+		pr         int
+		model      string
+		autoCommit bool
+		stageAll   bool
+		remote     string
+	)
+
+	addressReviewCmd := &cobra.Command{
+		Use:   "address-review --pr <number>",
+		Short: "Generate a commit message and reply drafts for addressed review comments",
+		Long: "Fetch the unresolved review comments of a GitHub pull request or GitLab merge request in the base repository (upstream when working in a fork), " +
+			"generate a commit message saying which comments the staged changes address, and draft a reply per comment. " +
+			"Replies are only printed, never posted. Set GITHUB_TOKEN or GITLAB_TOKEN.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			repo, err := baseRepo(remote)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+			fmt.Printf("%s %s %s\n", green("📂 Repository:"), cyan(repo.Path), yellow("("+repo.Name+")"))
+			var threads []ReviewThread
+			if repo.isGitLab() {
+				threads, err = gitlabReviewThreads(repo, pr)
+			} else {
+				threads, err = githubReviewThreads(repo, pr)
+			}
+			if err != nil {
+				log.Fatalf("%s %v", red("Error fetching review comments:"), err)
+			}
+			if len(threads) == 0 {
+				ui.Warn(fmt.Sprintf("No unresolved review comments on #%d", pr))
+				return
+			}
+			ui.Info(fmt.Sprintf("🔍 Unresolved review threads: %d", len(threads)))
+
+			var commitPaths []string
+			if stageAll {
+				commitPaths = allPathspec
+			}
+			diff, err := describedDiff(commitPaths, nil)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error getting git diff:"), err)
+			}
+			commitTrailers, err := collectTrailers(config, nil)
+			if err != nil {
+				log.Fatalf("%s %v", red("Invalid trailer:"), err)
+			}
+
+			opts := GenerateOptions{
+				Model:    model,
+				Trailers: commitTrailers,
+				Context:  reviewContext(pr, threads),
+			}
+			if config.Provenance {
+				opts.Transcript = &Transcript{}
+			}
+
+			ui.Info("\nGenerating commit message...")
+			message, err := generateCommitMessage(config, diff, opts)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error generating commit message:"), err)
+			}
+
+			ui.Panel("✨ GENERATED COMMIT MESSAGE:", message)
+
+			replies, err := draftReplies(config, model, threads, diff)
+			if err != nil {
+				ui.Warn(fmt.Sprintf("⚠️ Couldn't draft replies: %v", err))
+			} else {
+				printReplies(threads, replies)
+			}
+
+			if !autoCommit {
+				response, err := readUserInput("Create commit with this message? [y/n]: ")
+				if err != nil {
+					log.Fatalf("%s %v", red("Error reading user input:"), err)
+				}
+				if response != "y" && response != "yes" {
+					ui.Warn("⚠️ Commit canceled")
+					return
+				}
+			}
+			if err := makeAttestedCommit(config, message, opts.Transcript, commitPaths, false); err != nil {
+				log.Fatalf("%s %v", red("Error creating commit:"), err)
+			}
+			ui.Success("✅ Commit created successfully")
+		},
+	}
+
+	addressReviewCmd.Flags().IntVar(&pr, "pr", 0, "Number of the pull request or merge request")
+	addressReviewCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation")
+	addressReviewCmd.Flags().StringVar(&remote, "remote", "", "Remote of the repository the pull request is in (default: upstream if present, else the repository origin was forked from, else origin)")
+	addressReviewCmd.Flags().BoolVarP(&autoCommit, "commit", "c", false, "Automatically create commit with generated message")
+	addressReviewCmd.Flags().BoolVarP(&stageAll, "all", "a", false, "Stage and commit every change, untracked files included, instead of only the staged changes")
+	addressReviewCmd.MarkFlagRequired("pr")
+	return addressReviewCmd
+}
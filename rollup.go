@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxRollupBatch caps the characters of changes summarized in one request; longer rollups are
+// summarized batch by batch and the notes combined
+const maxRollupBatch = 24000
+
+// rollupAudiencePrompt tells the model who reads a rollup
+const rollupAudiencePrompt = "The readers are stakeholders who aren't engineers: product managers, support, sales and leadership. " +
+	"Describe what changed for users and the business, not how: no file names, function names, commit hashes or jargon. " +
+	"Group related changes instead of listing them one by one, and leave out changes that only matter to developers, like refactoring, tests or CI. "
+
+// RollupItem is a commit or merged pull request that goes into a rollup
+type RollupItem struct {
+	Ref   string // e.g. a short commit hash or #42
+	Title string
+	Body  string
+}
+
+// text renders an item for the prompt
+func (i RollupItem) text() string {
+	text := fmt.Sprintf("%s %s", i.Ref, i.Title)
+	if body := strings.TrimSpace(i.Body); body != "" {
+		text += "\n" + body
+	}
+	return text
+}
+
+// sinceItems returns the commits after a revision, oldest first, without their trailers
+func sinceItems(since string) ([]RollupItem, error) {
+	out, err := gitOutput("rev-list", "--no-merges", "--reverse", since+"..HEAD", "--")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	var items []RollupItem
+	for _, commit := range strings.Split(out, "\n") {
+		message, err := gitOutput("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return nil, err
+		}
+		subject, body, _ := strings.Cut(message, "\n")
+		if paragraphs := strings.Split(strings.TrimSpace(body), "\n\n"); len(paragraphs) > 0 && isTrailerBlock(paragraphs[len(paragraphs)-1]) {
+			body = strings.Join(paragraphs[:len(paragraphs)-1], "\n\n")
+		}
+		items = append(items, RollupItem{Ref: commit[:12], Title: subject, Body: body})
+	}
+	return items, nil
+}
+
+// labelItems returns the merged pull requests, or GitLab merge requests, with a label
+func labelItems(repo *RemoteRepo, label string) ([]RollupItem, error) {
+	var items []RollupItem
+	if repo.isGitLab() {
+		endpoint := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?state=merged&per_page=100&labels=%s", repo.Base, url.PathEscape(repo.Path), url.QueryEscape(label))
+		body, err := trackerRequest("GET", endpoint, true, nil)
+		if err != nil {
+			return nil, err
+		}
+		var requests []struct {
+			IID         int    `json:"iid"`
+			Title       string `json:"title"`
+			Description string `json:"description"`
+		}
+		if err := json.Unmarshal(body, &requests); err != nil {
+			return nil, fmt.Errorf("failed to parse merge requests: %w", err)
+		}
+		for _, mr := range requests {
+			items = append(items, RollupItem{Ref: fmt.Sprintf("!%d", mr.IID), Title: mr.Title, Body: mr.Description})
+		}
+		return items, nil
+	}
+
+	query := fmt.Sprintf("repo:%s is:pr is:merged label:%q", repo.Path, label)
+	endpoint := fmt.Sprintf("%s/search/issues?per_page=100&sort=created&order=asc&q=%s", githubAPI(repo.Base), url.QueryEscape(query))
+	body, err := trackerRequest("GET", endpoint, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Items []struct {
+			Number int    `json:"number"`
+			Title  string `json:"title"`
+			Body   string `json:"body"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse pull requests: %w", err)
+	}
+	for _, pr := range result.Items {
+		items = append(items, RollupItem{Ref: fmt.Sprintf("#%d", pr.Number), Title: pr.Title, Body: pr.Body})
+	}
+	return items, nil
+}
+
+// rollupBatches splits items into batches that fit in one request
+func rollupBatches(items []RollupItem) [][]RollupItem {
+	var batches [][]RollupItem
+	var batch []RollupItem
+	size := 0
+	for _, item := range items {
+		text := item.text()
+		if len(text) > maxRollupBatch {
+			item.Body = item.Body[:maxRollupBatch/2] + "\n[truncated]"
+			text = item.text()
+		}
+		if size+len(text) > maxRollupBatch && len(batch) > 0 {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, item)
+		size += len(text)
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// writeRollup summarizes the items for stakeholders as a Markdown document. Too many items for
+// one request are first condensed into notes batch by batch.
+func writeRollup(config *Config, model, title string, items []RollupItem) (string, error) {
+	batches := rollupBatches(items)
+	var changes []string
+	if len(batches) == 1 {
+		for _, item := range items {
+			changes = append(changes, item.text())
+		}
+	} else {
+		for i, batch := range batches {
+			ui.Info(fmt.Sprintf("📝 Condensing changes %d/%d...", i+1, len(batches)))
+			var texts []string
+			for _, item := range batch {
+				texts = append(texts, item.text())
+			}
+			notes, err := askModel(config, model, "Condense these changes of a software project into short notes, one line per user-visible change, "+
+				"and drop changes that only matter to developers. Respond only with the notes.\n\n"+strings.Join(texts, "\n\n"))
+			if err != nil {
+				return "", err
+			}
+			changes = append(changes, notes)
+		}
+	}
+
+	prompt := "Write a summary document titled \"" + title + "\" of the changes below. " + rollupAudiencePrompt +
+		"Use Markdown: the title as a level 1 heading, a short overview paragraph, then sections such as \"What's new\", " +
+		"\"Improvements\" and \"Fixes\", only for what there is. Respond only with the document.\n\n" + strings.Join(changes, "\n\n")
+	return askModel(config, model, prompt)
+}
+
+// newRollupCmd creates the rollup command that summarizes a milestone or epic for stakeholders
+func newRollupCmd() *cobra.Command {
+	var (
+		label  string
+		since  string
+		remote string
+		model  string
+		file   string
+	)
+
+	rollupCmd := &cobra.Command{
+		Use:   "rollup",
+		Short: "Summarize an epic or milestone for stakeholders",
+		Long: "Gather the merged pull requests with a label (--label), or the commits since a tag or commit (--since), " +
+			"and write a Markdown summary of them for readers who aren't engineers. " +
+			"Pull requests come from the base repository (upstream when working in a fork); set GITHUB_TOKEN or GITLAB_TOKEN for private repositories.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if label == "" && since == "" {
+				log.Fatalf("%s pass --label, --since or both", red("Error:"))
+			}
+			config, err := loadConfig()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading configuration:"), err)
+			}
+
+			var items []RollupItem
+			var titles []string
+			if label != "" {
+				repo, err := baseRepo(remote)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+				labeled, err := labelItems(repo, label)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error fetching pull requests:"), err)
+				}
+				ui.Info(fmt.Sprintf("🔍 Merged pull requests labeled %s: %d", label, len(labeled)))
+				items = append(items, labeled...)
+				titles = append(titles, label)
+			}
+			if since != "" {
+				commits, err := sinceItems(since)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error reading commits:"), err)
+				}
+				ui.Info(fmt.Sprintf("🔍 Commits since %s: %d", since, len(commits)))
+				items = append(items, commits...)
+				titles = append(titles, "changes since "+since)
+			}
+			if len(items) == 0 {
+				ui.Warn("Nothing to summarize")
+				return
+			}
+
+			ui.Info("Writing the rollup...")
+			rollup, err := writeRollup(config, model, "Rollup: "+strings.Join(titles, ", "), items)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error writing rollup:"), err)
+			}
+			rollup = stripCodeFence(strings.TrimPrefix(strings.TrimSpace(rollup), "```markdown"))
+
+			if file == "" {
+				fmt.Println(rollup)
+				return
+			}
+			if err := os.WriteFile(file, []byte(rollup+"\n"), 0644); err != nil {
+				log.Fatalf("%s %v", red("Error writing rollup:"), err)
+			}
+			ui.Success("✅ Wrote " + file)
+		},
+	}
+
+	rollupCmd.Flags().StringVar(&label, "label", "", "Summarize the merged pull requests with this label, e.g. epic-checkout")
+	rollupCmd.Flags().StringVar(&since, "since", "", "Summarize the commits after this tag or commit, e.g. v1.4.0")
+	rollupCmd.Flags().StringVar(&remote, "remote", "", "Remote of the repository the pull requests are in (defaults to upstream, then origin)")
+	rollupCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use (overrides default_model from config)")
+	rollupCmd.Flags().StringVarP(&file, "file", "f", "", "Write the summary to this file instead of stdout")
+	return rollupCmd
+}
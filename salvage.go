@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// streamStallTimeout is how long a streamed response may go without new text, once text has
+// started arriving, before it is given up on
+const streamStallTimeout = 30 * time.Second
+
+// partialMessageTitle marks a message salvaged from a stalled response
+const partialMessageTitle = "⚠️  PARTIAL COMMIT MESSAGE, THE BODY MAY BE INCOMPLETE:"
+
+// errIncompleteMessage is returned along with a message salvaged from a stalled response
+var errIncompleteMessage = errors.New("the response stalled, the message body may be incomplete")
+
+// StalledResponseError is returned when a streamed response stops producing text, or is cut off
+// by the timeout or Ctrl-C. Partial is the text received until then.
+type StalledResponseError struct {
+	Partial string
+	Cause   error // why the response was cut off, nil when it stalled
+}
+
+func (e *StalledResponseError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("the response was cut off after %d characters: %v", len(e.Partial), e.Cause)
+	}
+	return fmt.Sprintf("the response stalled for %s after %d characters", streamStallTimeout, len(e.Partial))
+}
+
+func (e *StalledResponseError) Unwrap() error {
+	return e.Cause
+}
+
+// stallWatch closes a streamed response body when no text arrives for streamStallTimeout, which
+// makes the stream reader return. The wait for the first text isn't limited, models may think
+// for a long time before answering.
+type stallWatch struct {
+	body    io.Closer
+	timer   *time.Timer
+	text    strings.Builder
+	stalled atomic.Bool
+}
+
+// newStallWatch watches a response body
+func newStallWatch(body io.Closer) *stallWatch {
+	return &stallWatch{body: body}
+}
+
+// wrap returns an onDelta that records the text and restarts the timer before passing it on
+func (w *stallWatch) wrap(onDelta func(string)) func(string) {
+	return func(text string) {
+		w.text.WriteString(text)
+		if w.timer == nil {
+			w.timer = time.AfterFunc(streamStallTimeout, func() {
+				w.stalled.Store(true)
+				w.body.Close()
+			})
+		} else {
+			w.timer.Reset(streamStallTimeout)
+		}
+		onDelta(text)
+	}
+}
+
+// stop ends the watch, returning a StalledResponseError if the stream stalled
+func (w *stallWatch) stop() error {
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	if w.stalled.Load() {
+		return &StalledResponseError{Partial: w.text.String()}
+	}
+	return nil
+}
+
+// partial returns the text received so far when a response was cut off, or cause itself when
+// there was none yet
+func (w *stallWatch) partial(cause error) error {
+	if w.text.Len() == 0 {
+		return cause
+	}
+	return &StalledResponseError{Partial: w.text.String(), Cause: cause}
+}
+
+// salvageResponse returns the text of a stalled response if its subject line is complete, i.e.
+// the model had moved on past it when the stream stalled
+func salvageResponse(err error) (string, bool) {
+	var stalled *StalledResponseError
+	if !errors.As(err, &stalled) {
+		return "", false
+	}
+	subject, _, complete := strings.Cut(strings.TrimLeft(stalled.Partial, "\n"), "\n")
+	if !complete || strings.TrimSpace(subject) == "" {
+		return "", false
+	}
+	return stalled.Partial, true
+}
+
+// usePartialMessage reports whether a generation error carries a salvaged message that can be
+// offered, warning that its body may be incomplete. Unattended runs never commit one.
+func usePartialMessage(err error, unattended bool) bool {
+	if !errors.Is(err, errIncompleteMessage) || unattended {
+		return false
+	}
+	ui.Warn("\n⏸️  The response stopped after the subject line, the body may be incomplete")
+	return true
+}
+
+// messageTitle returns the panel title for a generated message, marking salvaged ones as partial
+func messageTitle(title string, err error) string {
+	if errors.Is(err, errIncompleteMessage) {
+		return partialMessageTitle
+	}
+	return title
+}
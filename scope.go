@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// conventionalSubjectPattern matches "type(scope)!: subject" subject lines
+var conventionalSubjectPattern = regexp.MustCompile(`^(\w+)(\([^)]*\))?(!?):\s*(.*)$`)
+
+// Directory names too generic to be useful as a scope
+var genericDirs = map[string]bool{
+	"src":      true,
+	"lib":      true,
+	"pkg":      true,
+	"internal": true,
+	"cmd":      true,
+	"app":      true,
+	"apps":     true,
+	"packages": true,
+	"source":   true,
+}
+
+// commonDir returns the deepest directory containing all paths, or "" for the repository root
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	common := strings.Split(path.Dir(paths[0]), "/")
+	for _, p := range paths[1:] {
+		parts := strings.Split(path.Dir(p), "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+
+	dir := strings.Join(common, "/")
+	if dir == "." {
+		return ""
+	}
+	return dir
+}
+
+// lookupScope finds the scope for a directory in the scope map, preferring the longest matching prefix
+func lookupScope(dir string, scopeMap map[string]string) (string, bool) {
+	best := ""
+	scope := ""
+	for prefix, s := range scopeMap {
+		prefix = strings.Trim(prefix, "/")
+		if (dir == prefix || strings.HasPrefix(dir, prefix+"/")) && len(prefix) >= len(best) {
+			best = prefix
+			scope = s
+		}
+	}
+	return scope, scope != ""
+}
+
+// detectScope derives a conventional commit scope when all changes live under one directory.
+// The scope map takes precedence; otherwise the most specific non-generic directory name is used.
+func detectScope(files []*FileDiff, scopeMap map[string]string) string {
+	dir := commonDir(diffPaths(files))
+	if dir == "" {
+		return ""
+	}
+
+	if scope, ok := lookupScope(dir, scopeMap); ok {
+		return scope
+	}
+
+	parts := strings.Split(dir, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		if !genericDirs[strings.ToLower(parts[i])] && !strings.HasPrefix(parts[i], ".") {
+			return strings.ToLower(parts[i])
+		}
+	}
+	return ""
+}
+
+// scopeInstruction tells the model not to pick a scope itself
+func scopeInstruction(scope string) string {
+	return fmt.Sprintf("Do not include a scope in the subject (write \"type: subject\"); the scope %q is added automatically. ", scope)
+}
+
+// applyScope sets the scope of a conventional commit subject, leaving other messages untouched
+func applyScope(message, scope string) string {
+	lines := strings.SplitN(message, "\n", 2)
+	match := conventionalSubjectPattern.FindStringSubmatch(lines[0])
+	if match == nil {
+		return message
+	}
+
+	lines[0] = fmt.Sprintf("%s(%s)%s: %s", match[1], scope, match[3], match[4])
+	return strings.Join(lines, "\n")
+}
+
+// commitTypePattern matches commit types and scopes that can be pinned with --type and --scope
+var commitTypePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// validatePinned checks a type or scope given on the command line
+func validatePinned(kind, value string) error {
+	if value != "" && !commitTypePattern.MatchString(value) {
+		return fmt.Errorf("invalid %s %q: use lowercase letters, digits and dashes", kind, value)
+	}
+	return nil
+}
+
+// typeInstruction tells the model which commit type to use
+func typeInstruction(commitType string) string {
+	return fmt.Sprintf("The commit type is %q, use it and don't pick another one. ", commitType)
+}
+
+// applyType sets the type of a conventional commit subject. Other subjects get the type added, so
+// the message is a conventional commit of that type either way.
+func applyType(message, commitType string) string {
+	lines := strings.SplitN(message, "\n", 2)
+	if match := conventionalSubjectPattern.FindStringSubmatch(lines[0]); match != nil {
+		lines[0] = fmt.Sprintf("%s%s%s: %s", commitType, match[2], match[3], match[4])
+	} else {
+		lines[0] = commitType + ": " + lines[0]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// classifyMessage applies the type and scope pinned with --type and --scope, if any
+func classifyMessage(message string, opts GenerateOptions) string {
+	if opts.Type != "" {
+		message = applyType(message, opts.Type)
+	}
+	if opts.Scope != "" {
+		message = applyScope(message, opts.Scope)
+	}
+	return message
+}
+
+// parseScopeMap parses "path=scope,path=scope" as used by rmit set scope_map
+func parseScopeMap(value string) (map[string]string, error) {
+	scopeMap := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, scope, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(prefix) == "" || strings.TrimSpace(scope) == "" {
+			return nil, fmt.Errorf("invalid scope map entry %q, expected path=scope", entry)
+		}
+		scopeMap[strings.Trim(strings.TrimSpace(prefix), "/")] = strings.TrimSpace(scope)
+	}
+	return scopeMap, nil
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// selftestModel is the model name fixture requests are sent with
+const selftestModel = "selftest-model"
+
+// providerFixtures holds recorded, sanitized provider responses, one per file in fixtures/
+//
+//go:embed fixtures/*.yml
+var providerFixtures embed.FS
+
+// ProviderFixture is a recorded response and what rmit should make of it
+type ProviderFixture struct {
+	Name        string            `yaml:"-"`
+	Provider    string            `yaml:"provider"`
+	Description string            `yaml:"description"`
+	Status      int               `yaml:"status"`
+	ContentType string            `yaml:"content_type"`
+	Stream      bool              `yaml:"stream"`
+	Body        string            `yaml:"body"`
+	Headers     map[string]string `yaml:"headers"` // expected request headers
+	Fields      map[string]any    `yaml:"fields"`  // expected top-level request body fields
+	Expect      string            `yaml:"expect"`
+	ExpectError string            `yaml:"expect_error"` // text the error must contain
+}
+
+// loadProviderFixtures reads the fixtures, sorted by name
+func loadProviderFixtures() ([]ProviderFixture, error) {
+	entries, err := providerFixtures.ReadDir("fixtures")
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []ProviderFixture
+	for _, entry := range entries {
+		data, err := providerFixtures.ReadFile(path.Join("fixtures", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var fixture ProviderFixture
+		if err := yaml.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", entry.Name(), err)
+		}
+		fixture.Name = strings.TrimSuffix(entry.Name(), ".yml")
+		if err := validateProvider(fixture.Provider); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", entry.Name(), err)
+		}
+		if fixture.Status == 0 {
+			fixture.Status = http.StatusOK
+		}
+		if fixture.ContentType == "" {
+			fixture.ContentType = "application/json"
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+// checkFixtureRequest compares the request rmit sent with what the fixture expects
+func checkFixtureRequest(fixture ProviderFixture, req *http.Request, body []byte) []string {
+	var problems []string
+	for name, want := range fixture.Headers {
+		if got := req.Header.Get(name); got != want {
+			problems = append(problems, fmt.Sprintf("header %s is %q, expected %q", name, got, want))
+		}
+	}
+
+	var request map[string]any
+	if err := json.Unmarshal(body, &request); err != nil {
+		return append(problems, fmt.Sprintf("request body isn't JSON: %v", err))
+	}
+	if request["model"] != selftestModel {
+		problems = append(problems, fmt.Sprintf("model is %v, expected %s", request["model"], selftestModel))
+	}
+	for field, want := range fixture.Fields {
+		if got := request[field]; fmt.Sprint(got) != fmt.Sprint(want) {
+			problems = append(problems, fmt.Sprintf("field %s is %v, expected %v", field, got, want))
+		}
+	}
+	return problems
+}
+
+// runProviderFixture serves a fixture's recorded response to the provider and checks what it
+// sent and what it made of the answer. It returns the problems found, none if it passed.
+func runProviderFixture(fixture ProviderFixture) []string {
+	var mu sync.Mutex
+	var requestProblems []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		requestProblems = checkFixtureRequest(fixture, req, body)
+		mu.Unlock()
+		w.Header().Set("Content-Type", fixture.ContentType)
+		w.WriteHeader(fixture.Status)
+		io.WriteString(w, fixture.Body)
+	}))
+	defer server.Close()
+
+	config := &Config{
+		Provider: fixture.Provider,
+		APIURL:   server.URL,
+		APIKey:   "selftest",
+		Timeout:  10 * time.Second,
+	}
+	request := ChatRequest{
+		Model:    selftestModel,
+		Messages: []Message{{Role: "user", Content: "Write a commit message for this diff"}},
+	}
+	var streamed strings.Builder
+	if fixture.Stream {
+		request.OnDelta = func(delta string) { streamed.WriteString(delta) }
+	}
+	response, err := providers[fixture.Provider].Generate(context.Background(), config, request)
+
+	mu.Lock()
+	problems := requestProblems
+	mu.Unlock()
+	switch {
+	case fixture.ExpectError != "" && err == nil:
+		problems = append(problems, fmt.Sprintf("expected an error containing %q, got %q", fixture.ExpectError, response))
+	case fixture.ExpectError != "" && !strings.Contains(err.Error(), fixture.ExpectError):
+		problems = append(problems, fmt.Sprintf("expected an error containing %q, got: %v", fixture.ExpectError, err))
+	case fixture.ExpectError != "":
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("unexpected error: %v", err))
+	case response != fixture.Expect:
+		problems = append(problems, fmt.Sprintf("response is %q, expected %q", response, fixture.Expect))
+	case fixture.Stream && streamed.String() != fixture.Expect:
+		problems = append(problems, fmt.Sprintf("streamed %q, expected %q", streamed.String(), fixture.Expect))
+	}
+	return problems
+}
+
+// newSelftestCmd creates the selftest command that checks rmit still understands each provider
+func newSelftestCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "selftest [provider]",
+		Short: "Check that rmit parses each provider's responses",
+		Long: "Runs the request and parsing code of every provider against recorded, sanitized responses, served from a local " +
+			"server so nothing is sent to the provider and no API key is used. A failure means this build of rmit no longer " +
+			"matches the format it was tested against. Exits with status 1 when a fixture fails.",
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			fixtures, err := loadProviderFixtures()
+			if err != nil {
+				log.Fatalf("%s %v", red("Error loading fixtures:"), err)
+			}
+			if len(args) == 1 {
+				if err := validateProvider(args[0]); err != nil {
+					log.Fatalf("%s %v", red("Error:"), err)
+				}
+			}
+
+			passed, failed := 0, 0
+			for _, fixture := range fixtures {
+				if len(args) == 1 && fixture.Provider != args[0] {
+					continue
+				}
+				problems := runProviderFixture(fixture)
+				if len(problems) == 0 {
+					passed++
+					ui.Success(fmt.Sprintf("✅ %s: %s", fixture.Name, fixture.Description))
+					continue
+				}
+				failed++
+				lines := []string{fmt.Sprintf("❌ %s: %s", fixture.Name, fixture.Description)}
+				for _, problem := range problems {
+					lines = append(lines, "  - "+problem)
+				}
+				ui.Error(strings.Join(lines, "\n"))
+			}
+
+			if failed > 0 {
+				ui.Error(fmt.Sprintf("\n❌ %d of %d fixture(s) failed", failed, passed+failed))
+				os.Exit(1)
+			}
+			ui.Success(fmt.Sprintf("\n✅ All %d fixture(s) passed", passed))
+		},
+	}
+}
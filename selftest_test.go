@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+// TestProviderFixtures runs the recorded provider responses through the parsing pipeline like
+// rmit selftest, so a change that breaks a provider's format fails the build
+func TestProviderFixtures(t *testing.T) {
+	fixtures, err := loadProviderFixtures()
+	if err != nil {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("no fixtures in fixtures/")
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			for _, problem := range runProviderFixture(fixture) {
+				t.Error(problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Serve mode
+const (
+	defaultServeAddr = "127.0.0.1:7878"
+	jobRetention     = 10 * time.Minute
+	maxPollWait      = 60 * time.Second
+	maxRequestBytes  = 8 << 20 // largest POST /generate body, diff included
+)
+
+// Generation job states
+const (
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobFailed   = "failed"
+	jobCanceled = "canceled"
+)
+
+// GenerateRequest is the body of POST /generate
+type GenerateRequest struct {
+	ID          string   `json:"id,omitempty"`   // optional, lets the client cancel before the first response arrives
+	Diff        string   `json:"diff,omitempty"` // defaults to the staged changes of the served repository
+	Model       string   `json:"model,omitempty"`
+	Context     string   `json:"context,omitempty"`
+	SubjectOnly bool     `json:"subject_only,omitempty"`
+	Trailers    []string `json:"trailers,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+}
+
+// JobState is what clients see of a generation job
+type JobState struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Partial string `json:"partial,omitempty"` // the response streamed so far, before post-processing
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ServeUser is a developer allowed to use a shared rmit server, from the --users file
+type ServeUser struct {
+	Token   string   `json:"token"`
+	APIKey  string   `json:"api_key,omitempty"`  // used instead of the server's own key
+	APIKeys []string `json:"api_keys,omitempty"` // used instead of the server's own pool
+}
+
+// ServeAuditEntry is one line of the --audit-log file. Diffs and messages are never logged.
+type ServeAuditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"`
+	ID        string    `json:"id"`
+	Remote    string    `json:"remote"`
+	Model     string    `json:"model"`
+	DiffBytes int       `json:"diff_bytes"`
+	Status    string    `json:"status"`
+	Duration  int64     `json:"duration_ms"`
+}
+
+// generateJob is a generation running for a client of rmit serve
+type generateJob struct {
+	mu      sync.Mutex
+	state   JobState
+	changed chan struct{} // closed and replaced whenever the state changes
+	cancel  context.CancelFunc
+}
+
+// update changes the job's state and wakes everyone waiting for it
+func (j *generateJob) update(change func(state *JobState)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	change(&j.state)
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// snapshot returns the current state and a channel that is closed on the next change
+func (j *generateJob) snapshot() (JobState, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.state, j.changed
+}
+
+// jobKey identifies a job. Request ids are chosen by clients, so each user has their own.
+type jobKey struct {
+	owner string
+	id    string
+}
+
+// jobServer keeps track of generation jobs by user and request id
+type jobServer struct {
+	mu       sync.Mutex
+	jobs     map[jobKey]*generateJob
+	users    map[string]ServeUser // empty when the server doesn't require a token
+	auditLog string
+}
+
+// loadServeUsers reads the --users file, a JSON object of user names to tokens and API keys
+func loadServeUsers(path string) (map[string]ServeUser, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users file: %w", err)
+	}
+	var users map[string]ServeUser
+	if err := json.Unmarshal(data, &users); err != nil {
+		return nil, fmt.Errorf("failed to parse users file: %w", err)
+	}
+	for name, user := range users {
+		if user.Token == "" {
+			return nil, fmt.Errorf("user %q has no token", name)
+		}
+		if _, err := parseAPIKeys(strings.Join(user.APIKeys, ",")); err != nil {
+			return nil, fmt.Errorf("user %q: %w", name, err)
+		}
+	}
+	return users, nil
+}
+
+// authenticate returns the user a request's bearer token belongs to. Without a users file
+// everyone is let in anonymously.
+func (s *jobServer) authenticate(r *http.Request) (string, bool) {
+	if len(s.users) == 0 {
+		return "", true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return "", false
+	}
+	for name, user := range s.users {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(user.Token)) == 1 {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// audit appends an entry to the --audit-log file, if there is one
+func (s *jobServer) audit(entry ServeAuditEntry) {
+	if s.auditLog == "" {
+		return
+	}
+	if err := appendJSONLine(s.auditLog, entry); err != nil {
+		log.Printf("Warning: couldn't write audit log: %v", err)
+	}
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError writes an error response
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeEvent writes one server-sent event and flushes it to the client
+func writeEvent(w http.ResponseWriter, event string, value any) {
+	data, _ := json.Marshal(value)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// job returns a job by id, if it belongs to the user
+func (s *jobServer) job(id, user string) (*generateJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[jobKey{user, id}]
+	return job, ok
+}
+
+// start validates a generate request and runs it in the background
+func (s *jobServer) start(req GenerateRequest, user, remote string) (*generateJob, int, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	config.Server = ""
+
+	// Users with their own keys are billed to them, everyone else shares the server's keys
+	if serveUser := s.users[user]; serveUser.APIKey != "" || len(serveUser.APIKeys) > 0 {
+		config.APIKey, config.APIKeys = serveUser.APIKey, serveUser.APIKeys
+	}
+
+	// Tools read the server's checkout, which a client's own diff says nothing about
+	diff := req.Diff
+	if diff != "" {
+		config.MaxToolCalls = 0
+	} else {
+		if diff, err = getStagedDiff(); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+
+	// There's nobody to ask, so required trailers must come from config, branch or the request
+	trailers, err := collectTrailers(config, req.Trailers)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if missing := missingTrailers(trailers, config.RequiredTrailers); len(missing) > 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("missing required trailers: %v", missing)
+	}
+
+	for kind, value := range map[string]string{"type": req.Type, "scope": req.Scope} {
+		if err := validatePinned(kind, value); err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+	}
+	if err := checkCommitStyle(config, GenerateOptions{Type: req.Type, Scope: req.Scope}); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	opts := GenerateOptions{
+		Model:       req.Model,
+		SubjectOnly: req.SubjectOnly || config.SubjectOnly,
+		Trailers:    trailers,
+		Context:     req.Context,
+		Type:        req.Type,
+		Scope:       req.Scope,
+	}
+	// Clients sending their own diff have already added their intent to the context
+	if config.ReadIntent && req.Diff == "" {
+		intent, _ := readIntent(parseDiff(diff))
+		opts.Context = joinContext(opts.Context, intent)
+	}
+
+	id := req.ID
+	if id == "" {
+		id = newRequestID()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &generateJob{state: JobState{ID: id, Status: jobRunning}, changed: make(chan struct{}), cancel: cancel}
+
+	s.mu.Lock()
+	key := jobKey{user, id}
+	if _, exists := s.jobs[key]; exists {
+		s.mu.Unlock()
+		cancel()
+		return nil, http.StatusConflict, fmt.Errorf("request id %q is already in use", id)
+	}
+	s.jobs[key] = job
+	s.mu.Unlock()
+
+	opts.Ctx = ctx
+	opts.OnDelta = func(text string) {
+		job.update(func(state *JobState) { state.Partial += text })
+	}
+
+	model := req.Model
+	if model == "" {
+		model = config.DefaultModel
+	}
+
+	go func() {
+		started := time.Now()
+		message, err := suggestCommitMessage(config, diff, opts)
+		job.update(func(state *JobState) {
+			switch {
+			case ctx.Err() != nil:
+				state.Status = jobCanceled
+			case err != nil:
+				state.Status, state.Error = jobFailed, err.Error()
+			default:
+				state.Status, state.Message = jobDone, message
+			}
+		})
+		cancel()
+
+		state, _ := job.snapshot()
+		metrics.observeRequest(state.Status, time.Since(started))
+		s.audit(ServeAuditEntry{
+			Time:      started,
+			User:      user,
+			ID:        id,
+			Remote:    remote,
+			Model:     model,
+			DiffBytes: len(diff),
+			Status:    state.Status,
+			Duration:  time.Since(started).Milliseconds(),
+		})
+
+		// Finished jobs stay around for a while so clients can still fetch the result
+		time.AfterFunc(jobRetention, func() {
+			s.mu.Lock()
+			delete(s.jobs, key)
+			s.mu.Unlock()
+		})
+	}()
+
+	return job, http.StatusOK, nil
+}
+
+// handleGenerate starts a generation and streams it as server-sent events when the client
+// accepts them, or waits for it to finish and returns the result as JSON
+func (s *jobServer) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(r)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+
+	var req GenerateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body is larger than %d bytes", tooLarge.Limit))
+			return
+		}
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	job, status, err := s.start(req, user, r.RemoteAddr)
+	if err != nil {
+		writeJSONError(w, status, err)
+		return
+	}
+
+	// A client that goes away doesn't need its message anymore
+	state, changed := job.snapshot()
+	stop := context.AfterFunc(r.Context(), job.cancel)
+	defer stop()
+
+	if r.Header.Get("Accept") != "text/event-stream" {
+		for state.Status == jobRunning {
+			<-changed
+			state, changed = job.snapshot()
+		}
+		writeJSON(w, jobStatusCode(state), state)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Rmit-Request-Id", state.ID)
+	writeEvent(w, "start", map[string]string{"id": state.ID})
+
+	sent := 0
+	for {
+		if len(state.Partial) > sent {
+			writeEvent(w, "delta", map[string]string{"text": state.Partial[sent:]})
+			sent = len(state.Partial)
+		}
+		if state.Status != jobRunning {
+			writeEvent(w, state.Status, state)
+			return
+		}
+		<-changed
+		state, changed = job.snapshot()
+	}
+}
+
+// handleJob returns a job's state. With ?wait=<seconds> it long-polls until the job has more
+// output than the ?since=<bytes> the client already has, or finishes.
+func (s *jobServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(r)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+	job, ok := s.job(r.PathValue("id"), user)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errors.New("unknown request id"))
+		return
+	}
+
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	wait, _ := strconv.Atoi(r.URL.Query().Get("wait"))
+	timeout := time.NewTimer(min(time.Duration(wait)*time.Second, maxPollWait))
+	defer timeout.Stop()
+
+	state, changed := job.snapshot()
+	for state.Status == jobRunning && len(state.Partial) <= since && wait > 0 {
+		select {
+		case <-changed:
+			state, changed = job.snapshot()
+		case <-timeout.C:
+			wait = 0
+		case <-r.Context().Done():
+			return
+		}
+	}
+	writeJSON(w, jobStatusCode(state), state)
+}
+
+// handleCancel cancels a running job
+func (s *jobServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.authenticate(r)
+	if !ok {
+		writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+	job, ok := s.job(r.PathValue("id"), user)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errors.New("unknown request id"))
+		return
+	}
+	job.cancel()
+
+	state, changed := job.snapshot()
+	for state.Status == jobRunning {
+		<-changed
+		state, changed = job.snapshot()
+	}
+	writeJSON(w, http.StatusOK, state)
+}
+
+// jobStatusCode maps a job state to an HTTP status code
+func jobStatusCode(state JobState) int {
+	if state.Status == jobFailed {
+		return http.StatusBadGateway
+	}
+	return http.StatusOK
+}
+
+// handleMetrics serves the Prometheus metrics, to the same users as /generate since request
+// counts, provider responses and token usage say how the server is used
+func (s *jobServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authenticate(r); !ok {
+		writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+		return
+	}
+	metrics.handleMetrics(w, r)
+}
+
+// newServeCmd creates the serve command that exposes generation over HTTP for GUI clients
+func newServeCmd() *cobra.Command {
+	var (
+		addr      string
+		usersFile string
+		auditLog  string
+	)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve commit message generation over HTTP",
+		Long: "Run an HTTP server for GUI clients and editors. POST /generate streams the response as server-sent events " +
+			"(Accept: text/event-stream) or returns it when done; GET /generate/{id} long-polls a running request and " +
+			"POST /generate/{id}/cancel cancels it. GET /metrics serves Prometheus metrics. Git commands run in the directory rmit serve was started in. " +
+			"With --users, requests need a bearer token and can use per-user API keys; developers point rmit --server at it.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			server := &jobServer{jobs: make(map[jobKey]*generateJob), auditLog: auditLog}
+			if usersFile != "" {
+				users, err := loadServeUsers(usersFile)
+				if err != nil {
+					log.Fatalf("%s %v", red("Error loading users:"), err)
+				}
+				server.users = users
+			}
+			mux := http.NewServeMux()
+			mux.HandleFunc("POST /generate", server.handleGenerate)
+			mux.HandleFunc("GET /generate/{id}", server.handleJob)
+			mux.HandleFunc("POST /generate/{id}/cancel", server.handleCancel)
+			metrics = newServeMetrics()
+			mux.HandleFunc("GET /metrics", server.handleMetrics)
+
+			fmt.Printf("%s %s\n", green("🌐 Serving on"), blue("http://"+addr))
+			if len(server.users) > 0 {
+				fmt.Printf("%s %s\n", green("🔒 Token auth enabled for"), cyan(fmt.Sprintf("%d user(s)", len(server.users))))
+			}
+			if err := http.ListenAndServe(addr, mux); err != nil {
+				log.Fatalf("%s %v", red("Error running server:"), err)
+			}
+		},
+	}
+
+	serveCmd.Flags().StringVar(&addr, "addr", defaultServeAddr, "Address to listen on")
+	serveCmd.Flags().StringVar(&usersFile, "users", "", "JSON file of users, e.g. {\"jane\": {\"token\": \"...\", \"api_key\": \"...\"}}; requires a bearer token on every request")
+	serveCmd.Flags().StringVar(&auditLog, "audit-log", "", "Append one JSON line per request (user, model, status, duration; never diffs) to this file")
+	return serveCmd
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGenerateRefusesLargeBodies(t *testing.T) {
+	server := &jobServer{jobs: make(map[jobKey]*generateJob)}
+	body := `{"diff": "` + strings.Repeat("x", maxRequestBytes) + `"}`
+	recorder := httptest.NewRecorder()
+	server.handleGenerate(recorder, httptest.NewRequest("POST", "/generate", strings.NewReader(body)))
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusRequestEntityTooLarge, recorder.Body)
+	}
+}
+
+func TestJobIdsArePerUser(t *testing.T) {
+	server := &jobServer{jobs: make(map[jobKey]*generateJob)}
+	janes := &generateJob{state: JobState{ID: "build-1"}}
+	server.jobs[jobKey{"jane", "build-1"}] = janes
+
+	if job, ok := server.job("build-1", "jane"); !ok || job != janes {
+		t.Error("jane's job isn't found for jane")
+	}
+	if _, ok := server.job("build-1", "bob"); ok {
+		t.Error("bob found jane's job")
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errNothingStaged is returned when only staged changes are committed and none are staged
+var errNothingStaged = errors.New("nothing is staged, stage changes with git add or pass --all to commit every change")
+
+// allPathspec selects every change in the repository, untracked files included, for --all
+var allPathspec = []string{":/"}
+
+// stagedDiff returns the staged changes, limited to paths when given. When nothing is staged it
+// tells apart a clean working tree from changes that only --all would commit.
+func stagedDiff(paths ...string) (string, error) {
+	defer profilePhase(phaseGit)()
+	pathspec := append([]string{"--"}, paths...)
+	output, err := gitCommand(append([]string{"diff", "--staged"}, pathspec...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged changes: %w", err)
+	}
+	if len(output) > 0 {
+		return string(output), nil
+	}
+
+	status, err := gitCommand(append([]string{"status", "--porcelain"}, pathspec...)...).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get repository status: %w", err)
+	}
+	if len(bytes.TrimSpace(status)) > 0 {
+		return "", errNothingStaged
+	}
+	return "", errNoChanges
+}
+
+// withTempIndex runs fn with a scratch copy of the index, so changes can be staged without
+// touching the real one. fn gets the environment variables that point git at the copy. The copy
+// starts as the index, or as HEAD when fromHead is set.
+func withTempIndex(fromHead bool, fn func(env []string) error) error {
+	tmp, err := checkoutTempFile("rmit-index-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary index: %w", err)
+	}
+	defer removeCheckoutFile(tmp)
+	env := []string{"GIT_INDEX_FILE=" + tmp}
+
+	if fromHead {
+		// An empty file isn't a valid index, git creates it from scratch
+		removeCheckoutFile(tmp)
+		readTree := gitCommandEnv(env, "read-tree", "HEAD")
+		if _, err := gitOutput("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+			readTree = gitCommandEnv(env, "read-tree", "--empty")
+		}
+		if err := readTree.Run(); err != nil {
+			return fmt.Errorf("failed to read HEAD into temporary index: %w", err)
+		}
+		return fn(env)
+	}
+
+	indexPath, err := gitOutput("rev-parse", "--git-path", "index")
+	if err != nil {
+		return err
+	}
+	if err := copyCheckoutFile(indexPath, tmp); os.IsNotExist(err) {
+		// A repository where nothing was ever staged has no index yet
+		removeCheckoutFile(tmp)
+	} else if err != nil {
+		return fmt.Errorf("failed to copy the index: %w", err)
+	}
+	return fn(env)
+}
+
+// copyFile copies a file's contents
+func copyFile(from, to string) error {
+	in, err := os.Open(filepath.Clean(from))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	_, err = io.Copy(out, in)
+	return err
+}
+
+// worktreeDiff returns the changes of paths as committing them with makeCommit records them: as
+// they are in the working tree, staged or not, untracked files included. The index isn't touched.
+func worktreeDiff(paths []string) (string, error) {
+	defer profilePhase(phaseGit)()
+	pathspec := append([]string{"--"}, paths...)
+	var output []byte
+	err := withTempIndex(false, func(env []string) error {
+		add := gitCommandEnv(env, append([]string{"add", "-A"}, pathspec...)...)
+		if out, err := add.CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to stage changes: %s", strings.TrimSpace(string(out)))
+		}
+		diff := gitCommandEnv(env, append([]string{"diff", "--cached"}, pathspec...)...)
+		var err error
+		if output, err = diff.Output(); err != nil {
+			return fmt.Errorf("failed to get changes: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(output) == 0 {
+		return "", errNoChanges
+	}
+	return string(output), nil
+}
+
+// commitStagedPaths commits the staged changes of some paths and leaves everything else staged.
+// The commit is made from a scratch index holding HEAD plus those changes; afterwards the real
+// index matches the new HEAD for these paths, so they no longer show as staged.
+func commitStagedPaths(message string, paths []string) error {
+	patch, err := gitCommand(append([]string{"diff", "--cached", "--binary", "--"}, paths...)...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to get staged changes: %w", err)
+	}
+	if len(patch) == 0 {
+		return errNoChanges
+	}
+
+	return withTempIndex(true, func(env []string) error {
+		apply := gitCommandEnv(env, "apply", "--cached", "--binary")
+		apply.Stdin = bytes.NewReader(patch)
+		apply.Stderr = os.Stderr
+		if err := apply.Run(); err != nil {
+			return fmt.Errorf("failed to stage changes: %w", err)
+		}
+
+		commitCmd := gitCommandEnv(env, "commit", "-m", message)
+		commitCmd.Stdout = commandOutput()
+		commitCmd.Stderr = os.Stderr
+		return commitCmd.Run()
+	})
+}
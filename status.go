@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Commit statuses posted by rmit bot --status
+const (
+	statusContext        = "rmit/commit-message"
+	maxStatusDescription = 140 // GitHub truncates longer descriptions
+	zeroCommit           = "0000000000000000000000000000000000000000"
+)
+
+// GitHubEvent is the part of the event that triggered a GitHub Actions job that says which commits were pushed
+type GitHubEvent struct {
+	Before      string `json:"before"`
+	After       string `json:"after"`
+	PullRequest *struct {
+		Base struct {
+			SHA string `json:"sha"`
+		} `json:"base"`
+		Head struct {
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+}
+
+// eventRange returns the commits the GitHub Actions event is about: the commits of a pull
+// request, or the commits a push added. A push that created a branch only covers its last commit.
+func eventRange() (string, error) {
+	eventPath := os.Getenv("GITHUB_EVENT_PATH")
+	if eventPath == "" {
+		return "", fmt.Errorf("not running in GitHub Actions, pass the commits with --status-range")
+	}
+	content, err := os.ReadFile(eventPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read GITHUB_EVENT_PATH: %w", err)
+	}
+	var event GitHubEvent
+	if err := json.Unmarshal(content, &event); err != nil {
+		return "", fmt.Errorf("failed to parse GITHUB_EVENT_PATH: %w", err)
+	}
+
+	switch {
+	case event.PullRequest != nil:
+		return event.PullRequest.Base.SHA + ".." + event.PullRequest.Head.SHA, nil
+	case event.After == "" || event.After == zeroCommit:
+		return "", fmt.Errorf("the %s event has no pushed commits, pass them with --status-range", os.Getenv("GITHUB_EVENT_NAME"))
+	case event.Before == "" || event.Before == zeroCommit:
+		return event.After, nil
+	default:
+		return event.Before + ".." + event.After, nil
+	}
+}
+
+// statusRepo returns the GitHub repository statuses are posted to: the one the Actions job
+// runs for, or the origin remote's
+func statusRepo() (*RemoteRepo, error) {
+	if repo := os.Getenv("GITHUB_REPOSITORY"); repo != "" {
+		base := os.Getenv("GITHUB_SERVER_URL")
+		if base == "" {
+			base = "https://github.com"
+		}
+		return &RemoteRepo{Name: "origin", Base: strings.TrimSuffix(base, "/"), Path: repo}, nil
+	}
+	repo, err := remoteRepo("origin")
+	if err != nil {
+		return nil, err
+	}
+	if repo.isGitLab() {
+		return nil, fmt.Errorf("commit statuses are only supported on GitHub")
+	}
+	return repo, nil
+}
+
+// statusDescription summarizes a commit's problems within GitHub's description limit
+func statusDescription(problems []string) string {
+	if len(problems) == 0 {
+		return "The message follows the convention"
+	}
+	description := strings.Join(problems, ", ")
+	if runes := []rune(description); len(runes) > maxStatusDescription {
+		description = string(runes[:maxStatusDescription-1]) + "…"
+	}
+	return description
+}
+
+// postCommitStatus sets rmit's status on a commit, which GitHub shows on pull requests
+func postCommitStatus(repo *RemoteRepo, commit string, problems []string) error {
+	state := "success"
+	if len(problems) > 0 {
+		state = "failure"
+	}
+	payload, err := json.Marshal(map[string]string{
+		"state":       state,
+		"context":     statusContext,
+		"description": statusDescription(problems),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode status: %w", err)
+	}
+	_, err = trackerRequest("POST", fmt.Sprintf("%s/repos/%s/statuses/%s", githubAPI(repo.Base), repo.Path, commit), false, payload)
+	return err
+}
+
+// postMessageStatuses checks the message of every commit in a range and posts the verdict as a
+// commit status. It returns how many messages break the convention.
+func postMessageStatuses(config *Config, revisions string) (int, error) {
+	if githubToken() == "" {
+		return 0, fmt.Errorf("set GITHUB_TOKEN or GH_TOKEN to post commit statuses")
+	}
+	repo, err := statusRepo()
+	if err != nil {
+		return 0, err
+	}
+	commits, err := rangeCommits(revisions)
+	if err != nil {
+		return 0, err
+	}
+	repoConfig, err := loadRepoConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, commit := range commits {
+		message, err := gitOutput("log", "-1", "--format=%B", commit)
+		if err != nil {
+			return failed, err
+		}
+		problems := checkMessage(config, repoConfig.Templates, message)
+		if len(problems) > 0 {
+			failed++
+			subject, _, _ := strings.Cut(message, "\n")
+			botWarning(fmt.Sprintf("%s %s: %s", commit[:12], subject, strings.Join(problems, ", ")))
+		}
+		if err := postCommitStatus(repo, commit, problems); err != nil {
+			return failed, fmt.Errorf("failed to post status for %s: %w", commit[:12], err)
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamChunk is one server-sent event of a streamed chat completion
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+	Usage *Usage `json:"usage"`
+}
+
+// isStreamed reports whether a response is streamed, as server-sent events or as
+// newline-delimited JSON like Ollama's
+func isStreamed(header http.Header) bool {
+	contentType := header.Get("Content-Type")
+	return strings.HasPrefix(contentType, "text/event-stream") || strings.HasPrefix(contentType, "application/x-ndjson")
+}
+
+// readChatStream passes each streamed piece of the response to onDelta and returns the whole
+// response in the non-streamed format, so callers and the response cache don't need to care
+func readChatStream(r io.Reader, onDelta func(string)) ([]byte, error) {
+	var content strings.Builder
+	var usage *Usage
+	var finishReason string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		// Comment lines (": OPENROUTER PROCESSING") keep the connection alive and carry no data
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse streamed response: %w", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				finishReason = choice.FinishReason
+			}
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read streamed response: %w", err)
+	}
+	if content.Len() == 0 && finishReason == "content_filter" {
+		return nil, errContentFiltered
+	}
+	if content.Len() == 0 {
+		return nil, errEmptyResponse
+	}
+
+	return json.Marshal(map[string]any{
+		"choices": []map[string]any{{"message": map[string]string{"content": content.String()}, "finish_reason": finishReason}},
+		"usage":   usage,
+	})
+}
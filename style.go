@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Supported commit conventions, set with commit_style or --style
+const (
+	styleConventional = "conventional"
+	styleAngular      = "angular"
+	styleGitmoji      = "gitmoji"
+	stylePlain        = "plain"
+	styleCustom       = "custom"
+)
+
+// commitStyles lists the supported commit conventions, the default first
+var commitStyles = []string{styleConventional, styleAngular, styleGitmoji, stylePlain, styleCustom}
+
+// Writing style presets, set with writing_style or --style, for how the message reads whatever
+// the convention
+const (
+	writingDefault   = "default"
+	writingTerse     = "terse"
+	writingNarrative = "narrative"
+	writingChangelog = "changelog"
+	writingBeginner  = "beginner"
+)
+
+// writingStyles maps each writing style preset to what it adds to the prompt
+var writingStyles = map[string]string{
+	writingDefault: "",
+	writingTerse:   "Be terse: a subject, and a body of at most two short lines only if the subject can't say it all. No filler. ",
+	writingNarrative: "Write the body as a short narrative in full sentences: what was wrong or missing before, what the change does " +
+		"about it, and why this approach was taken. ",
+	writingChangelog: "Write the subject as a user-facing changelog entry that makes sense without reading the code, and keep " +
+		"implementation details in the body. ",
+	writingBeginner: "Write for someone new to the codebase: explain in the body what the changed parts do and why the change " +
+		"was needed, and define project-specific terms. ",
+}
+
+// writingStyleNames lists the writing style presets, the default first
+var writingStyleNames = []string{writingDefault, writingTerse, writingNarrative, writingChangelog, writingBeginner}
+
+// angularTypes are the types the Angular commit convention allows
+var angularTypes = []string{"build", "ci", "docs", "feat", "fix", "perf", "refactor", "test"}
+
+// gitmojiSubjectPattern matches subjects starting with an emoji, or a :shortcode: like :bug:
+var gitmojiSubjectPattern = regexp.MustCompile(`^(?:[\p{So}\p{Sk}][\x{FE0F}\x{200D}\p{So}]*|:[a-z0-9_+-]+:)\s+(.*)$`)
+
+// validateCommitStyle checks if the commit convention is supported
+func validateCommitStyle(style string) error {
+	for _, known := range commitStyles {
+		if style == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown commit style %q, valid styles are: %s", style, strings.Join(commitStyles, ", "))
+}
+
+// validateWritingStyle checks if the writing style preset exists
+func validateWritingStyle(style string) error {
+	if _, ok := writingStyles[style]; !ok {
+		return fmt.Errorf("unknown writing style %q, valid styles are: %s", style, strings.Join(writingStyleNames, ", "))
+	}
+	return nil
+}
+
+// applyStyleFlag applies a --style value: a commit convention, a writing style preset, or one of
+// each separated by a comma, e.g. gitmoji,terse
+func applyStyleFlag(config *Config, value string) error {
+	for _, style := range strings.Split(value, ",") {
+		style = strings.ToLower(strings.TrimSpace(style))
+		switch {
+		case validateCommitStyle(style) == nil:
+			config.CommitStyle = style
+		case validateWritingStyle(style) == nil:
+			config.WritingStyle = style
+		default:
+			return fmt.Errorf("unknown style %q, valid styles are commit conventions (%s) and writing styles (%s)",
+				style, strings.Join(commitStyles, ", "), strings.Join(writingStyleNames, ", "))
+		}
+	}
+	return nil
+}
+
+// writingStyle returns the configured writing style preset
+func writingStyle(config *Config) string {
+	if config.WritingStyle == "" {
+		return writingDefault
+	}
+	return config.WritingStyle
+}
+
+// writingInstruction returns what the configured writing style preset adds to the prompt
+func writingInstruction(config *Config) string {
+	return writingStyles[writingStyle(config)]
+}
+
+// commitStyle returns the configured commit convention, conventional commits when none is set
+func commitStyle(config *Config) string {
+	if config.CommitStyle == "" {
+		return styleConventional
+	}
+	return config.CommitStyle
+}
+
+// styleUsesTypes reports whether a convention starts subjects with a type and scope, which is
+// what scope detection, --type and --scope fill in
+func styleUsesTypes(style string) bool {
+	return style == styleConventional || style == styleAngular
+}
+
+// styleInstruction tells the model how subjects are formatted under the configured convention.
+// The custom style uses commit_style_prompt, written by the team.
+func styleInstruction(config *Config) string {
+	switch commitStyle(config) {
+	case styleAngular:
+		return "Follow the Angular commit convention: type(scope): subject, where type is one of " +
+			strings.Join(angularTypes, ", ") + ", and the subject is in the imperative mood, lowercase, without a period at the end."
+	case styleGitmoji:
+		return "Start the subject with the gitmoji that fits the change (e.g., ✨ for a feature, 🐛 for a bug fix, " +
+			"📝 for docs, ♻️ for a refactor, ✅ for tests, 🔧 for configuration), followed by a space and the subject. Don't add a type like feat:."
+	case stylePlain:
+		return "Write a plain subject in the imperative mood starting with a capital letter, without a type prefix like feat: or an emoji."
+	case styleCustom:
+		return strings.TrimSpace(config.CommitStylePrompt)
+	}
+	return "Follow the conventional commit format (e.g., feat:, fix:, docs:, style:, refactor:, test:, chore:)."
+}
+
+// splitSubject splits a subject by the convention into its type, if the convention has types,
+// and the description after the type or emoji. problem says how the subject breaks the
+// convention, "" if it doesn't.
+func splitSubject(style, subject string) (commitType, description, problem string) {
+	switch style {
+	case styleConventional, styleAngular:
+		match := conventionalSubjectPattern.FindStringSubmatch(subject)
+		if match == nil {
+			if style == styleAngular {
+				return "", subject, "not an Angular commit"
+			}
+			return "", subject, "not a conventional commit"
+		}
+		commitType = strings.ToLower(match[1])
+		if style == styleAngular && !containsString(angularTypes, commitType) {
+			return commitType, match[4], fmt.Sprintf("%s isn't an Angular type", commitType)
+		}
+		return commitType, match[4], ""
+	case styleGitmoji:
+		if match := gitmojiSubjectPattern.FindStringSubmatch(subject); match != nil {
+			return "", match[1], ""
+		}
+		return "", subject, "subject doesn't start with a gitmoji"
+	case stylePlain:
+		if conventionalSubjectPattern.MatchString(subject) || gitmojiSubjectPattern.MatchString(subject) {
+			return "", subject, "subject has a type or emoji prefix"
+		}
+	}
+	return "", subject, ""
+}
+
+// checkCommitStyle reports settings the commit style can't honor: a custom style without
+// instructions, and a type or scope pinned for a style whose subjects have none
+func checkCommitStyle(config *Config, opts GenerateOptions) error {
+	style := commitStyle(config)
+	if style == styleCustom && strings.TrimSpace(config.CommitStylePrompt) == "" {
+		return fmt.Errorf("commit_style custom needs instructions, set them with rmit set commit_style_prompt")
+	}
+	if !styleUsesTypes(style) && (opts.Type != "" || opts.Scope != "") {
+		return fmt.Errorf("--type and --scope need a commit style with types (%s or %s), not %s", styleConventional, styleAngular, style)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxSubjectLength is the conventional limit for a commit subject line
+	maxSubjectLength = 50
+	// maxSubjectLineLength is the hard limit for a subject line; longer ones wrap in git tools
+	maxSubjectLineLength = 72
+	// minSubjectBudget is how much of the subject is kept for the model's words, however long the prefix and suffix are
+	minSubjectBudget = 20
+	// subjectOnlyDiffLimit caps how much of the diff is sent in subject-only mode
+	subjectOnlyDiffLimit = 6000
+)
+
+// subjectOnlyPrompt builds a minimal prompt asking for a single subject line in the commit
+// style with the given prompt version, with any extra instructions appended to the format rules
+func subjectOnlyPrompt(config *Config, fileList, diff, extra string, version int) string {
+	if len(diff) > subjectOnlyDiffLimit {
+		diff = diff[:subjectOnlyDiffLimit] + "\n[diff truncated]"
+	}
+
+	instructions := strings.Replace(stylePrompt(config, promptSubject, version), "{hints}", extra, 1)
+	return instructions + "\n\n" + fileList + "Changes:\n" + diff
+}
+
+// subjectLine reduces a generated message to its first line, without the quotes models like to
+// put around it
+func subjectLine(message string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(message), "\n", 2)[0])
+	return strings.Trim(line, "\"'`")
+}
+
+// fitSubject fits a message's subject within maxLen, cutting at a word boundary when the model
+// ignored the limit or a pinned type or scope made it longer
+func fitSubject(message string, maxLen int) string {
+	lines := strings.SplitN(message, "\n", 2)
+	lines[0] = truncateSubject(lines[0], maxLen)
+	return strings.Join(lines, "\n")
+}
+
+// truncateSubject fits a subject within maxLen characters, cutting at a word boundary
+func truncateSubject(subject string, maxLen int) string {
+	runes := []rune(subject)
+	if len(runes) <= maxLen {
+		return subject
+	}
+
+	cut := string(runes[:maxLen])
+	if idx := strings.LastIndex(cut, " "); idx > 0 {
+		cut = cut[:idx]
+	}
+	return strings.TrimRight(cut, " ,.;:-")
+}
+
+// affixPlaceholderPattern matches placeholders in subject_prefix and subject_suffix, e.g. {ticket}
+var affixPlaceholderPattern = regexp.MustCompile(`\{(\w+)\}`)
+
+// affixPlaceholders are the placeholders subject_prefix and subject_suffix may use
+var affixPlaceholders = []string{"ticket", "branch"}
+
+// validateSubjectAffix checks a subject_prefix or subject_suffix
+func validateSubjectAffix(value string) error {
+	if strings.Contains(value, "\n") {
+		return fmt.Errorf("must be a single line")
+	}
+	for _, match := range affixPlaceholderPattern.FindAllStringSubmatch(value, -1) {
+		known := false
+		for _, name := range affixPlaceholders {
+			known = known || match[1] == name
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s, valid placeholders are: {%s}", match[0], strings.Join(affixPlaceholders, "}, {"))
+		}
+	}
+	if len([]rune(value)) > maxSubjectLineLength-minSubjectBudget {
+		return fmt.Errorf("longer than %d characters, which leaves no room for the subject", maxSubjectLineLength-minSubjectBudget)
+	}
+	return nil
+}
+
+// SubjectAffixes are the configured prefix and suffix around every subject, with placeholders filled in
+type SubjectAffixes struct {
+	Prefix string
+	Suffix string
+}
+
+// subjectAffixes fills in the configured prefix and suffix for a commit. {ticket} is the Ticket
+// trailer, or the issue number of the Refs trailer, and {branch} the current branch. A prefix
+// or suffix with a placeholder that has no value, e.g. {ticket} on a branch without one, is left out.
+func subjectAffixes(config *Config, trailers []Trailer) SubjectAffixes {
+	if config.SubjectPrefix == "" && config.SubjectSuffix == "" {
+		return SubjectAffixes{}
+	}
+
+	values := map[string]string{}
+	for _, t := range trailers {
+		if t.Key == "Ticket" && values["ticket"] == "" {
+			values["ticket"] = t.Value
+		}
+	}
+	for _, t := range trailers {
+		if t.Key == "Refs" && values["ticket"] == "" {
+			values["ticket"] = strings.TrimPrefix(t.Value, "#")
+		}
+	}
+	if affixPlaceholderPattern.MatchString(config.SubjectPrefix + config.SubjectSuffix) {
+		values["branch"] = getCurrentBranch()
+	}
+
+	fill := func(affix string) string {
+		missing := false
+		filled := affixPlaceholderPattern.ReplaceAllStringFunc(affix, func(placeholder string) string {
+			value := values[strings.Trim(placeholder, "{}")]
+			missing = missing || value == ""
+			return value
+		})
+		if missing {
+			return ""
+		}
+		return filled
+	}
+	return SubjectAffixes{Prefix: fill(config.SubjectPrefix), Suffix: fill(config.SubjectSuffix)}
+}
+
+// budget returns how many characters of a subject limited to limit are left for the model's words
+func (a SubjectAffixes) budget(limit int) int {
+	return max(limit-len([]rune(a.Prefix+a.Suffix)), minSubjectBudget)
+}
+
+// instruction tells the model that rmit adds the prefix and suffix, and how long the rest may be
+func (a SubjectAffixes) instruction() string {
+	if a.Prefix == "" && a.Suffix == "" {
+		return ""
+	}
+	return fmt.Sprintf("rmit adds %q around the subject line itself, so don't write it, and keep the rest of the subject under %d characters. ",
+		a.Prefix+"…"+a.Suffix, a.budget(maxSubjectLength))
+}
+
+// strip removes the prefix and suffix from a message's subject
+func (a SubjectAffixes) strip(message string) string {
+	lines := strings.SplitN(message, "\n", 2)
+	subject := strings.TrimSpace(lines[0])
+	if a.Prefix != "" {
+		subject = strings.TrimPrefix(subject, strings.TrimSpace(a.Prefix))
+	}
+	if a.Suffix != "" {
+		subject = strings.TrimSuffix(subject, strings.TrimSpace(a.Suffix))
+	}
+	lines[0] = strings.TrimSpace(subject)
+	return strings.Join(lines, "\n")
+}
+
+// apply puts the prefix and suffix around a message's subject, shortening the subject so the
+// whole line stays within limit. A prefix or suffix the model wrote anyway isn't doubled.
+func (a SubjectAffixes) apply(message string, limit int) string {
+	if a.Prefix == "" && a.Suffix == "" {
+		return message
+	}
+	lines := strings.SplitN(a.strip(message), "\n", 2)
+	lines[0] = a.Prefix + truncateSubject(lines[0], a.budget(limit)) + a.Suffix
+	return strings.Join(lines, "\n")
+}
+
+// subjectLimit is the longest subject a message may have
+func subjectLimit(opts GenerateOptions) int {
+	if opts.SubjectOnly {
+		return maxSubjectLength
+	}
+	return maxSubjectLineLength
+}
+
+// finishLocalMessage applies what generated messages get to a message built without the model
+func finishLocalMessage(config *Config, message string, opts GenerateOptions) string {
+	message = classifyMessage(message, opts)
+	message = subjectAffixes(config, opts.Trailers).apply(message, subjectLimit(opts))
+	return appendTrailers(message, opts.Trailers)
+}
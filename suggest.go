@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// suggestionHeader starts the commented-out suggestion in a commit message file
+const suggestionHeader = "rmit suggestion (uncomment the lines you want to keep):"
+
+// commentChar returns git's comment character for commit messages
+func commentChar() string {
+	out, err := gitCommand("config", "--get", "core.commentChar").Output()
+	char := strings.TrimSpace(string(out))
+	if err != nil || char == "" || char == "auto" {
+		return "#"
+	}
+	return char
+}
+
+// commentSuggestion renders a message as comment lines, the way git writes its status comments
+func commentSuggestion(message, char string) string {
+	var lines []string
+	lines = append(lines, char+" "+suggestionHeader, char)
+	for _, line := range strings.Split(message, "\n") {
+		if line == "" {
+			lines = append(lines, char)
+		} else {
+			lines = append(lines, char+" "+line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// insertSuggestion places the suggestion after the user's message and before git's own comments
+func insertSuggestion(content, suggestion, char string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+
+	// Git's comment block is the trailing run of comment and blank lines
+	start := len(lines)
+	for start > 0 && (strings.TrimSpace(lines[start-1]) == "" || strings.HasPrefix(lines[start-1], char)) {
+		start--
+	}
+	for start < len(lines) && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+
+	head := strings.Join(lines[:start], "\n")
+	tail := strings.Join(lines[start:], "\n")
+	if strings.TrimSpace(head) != "" {
+		head = strings.TrimRight(head, "\n") + "\n\n"
+	}
+	if tail != "" {
+		tail = "\n" + tail + "\n"
+	}
+	return head + suggestion + tail
+}
+
+// newSuggestCmd creates the suggest command that adds a commented-out suggestion to a commit message file
+func newSuggestCmd() *cobra.Command {
+	var (
+		msgFile string
+		model   string
+	)
+
+	suggestCmd := &cobra.Command{
+		Use:   "suggest",
+		Short: "Add a commented-out suggestion to a commit message file",
+		Long: "Append the generated message as comment lines below your own message in the commit editor, " +
+			"so you can uncomment what you like instead of having your message replaced. Works with any editor, " +
+			"e.g. from a prepare-commit-msg hook: rmit suggest --commit-msg-file \"$1\"",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Like the hook, a failure must never get in the way of committing
+			if err := runSuggest(msgFile, model); err != nil {
+				fmt.Fprintf(os.Stderr, "%s %v\n", yellow("rmit: no suggestion added:"), err)
+			}
+		},
+	}
+
+	suggestCmd.Flags().StringVar(&msgFile, "commit-msg-file", "", "Commit message file to add the suggestion to, e.g. .git/COMMIT_EDITMSG")
+	suggestCmd.Flags().StringVarP(&model, "model", "m", "", "OpenRouter model to use for generation (overrides default_model from config)")
+	suggestCmd.MarkFlagRequired("commit-msg-file")
+
+	return suggestCmd
+}
+
+// runSuggest generates a message for the staged changes and adds it to the file as comments
+func runSuggest(msgFile, model string) error {
+	existing, err := os.ReadFile(msgFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read commit message file: %w", err)
+	}
+
+	char := commentChar()
+	if strings.Contains(string(existing), char+" "+suggestionHeader) {
+		return nil
+	}
+
+	config, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	diff, err := getStagedDiff()
+	if err != nil {
+		return err
+	}
+
+	trailers, err := collectTrailers(config, nil)
+	if err != nil {
+		return err
+	}
+	message, err := suggestCommitMessage(config, diff, GenerateOptions{Model: model, Trailers: trailers})
+	if err != nil {
+		return err
+	}
+
+	content := insertSuggestion(string(existing), commentSuggestion(message, char), char)
+	if err := os.WriteFile(msgFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write commit message file: %w", err)
+	}
+	return nil
+}
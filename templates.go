@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// repoConfigFile holds settings shared by everyone working on a repository
+const repoConfigFile = ".rmit/config.yml"
+
+// RepoConfig is the repository's .rmit/config.yml
+type RepoConfig struct {
+	// Templates lists the sections the body must have per commit type, e.g. fix: ["Root cause:", "Fix:"]
+	Templates map[string][]string `yaml:"templates"`
+	// Experiments split generations between prompt templates or models, see rmit experiments
+	Experiments []Experiment `yaml:"experiments"`
+}
+
+// loadRepoConfig reads .rmit/config.yml, returning an empty config if there is none
+func loadRepoConfig() (*RepoConfig, error) {
+	content, err := readRepoFile(repoConfigFile)
+	if err != nil {
+		return &RepoConfig{}, nil
+	}
+
+	var config RepoConfig
+	if err := yaml.Unmarshal([]byte(content), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", repoConfigFile, err)
+	}
+	return &config, nil
+}
+
+// commitType returns the conventional commit type of a message, or "" if it has none
+func commitType(message string) string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if match := conventionalSubjectPattern.FindStringSubmatch(subject); match != nil {
+		return strings.ToLower(match[1])
+	}
+	return ""
+}
+
+// templateInstructions tells the model which body sections each commit type needs
+func templateInstructions(templates map[string][]string) string {
+	if len(templates) == 0 {
+		return ""
+	}
+	types := make([]string, 0, len(templates))
+	for commitType := range templates {
+		types = append(types, commitType)
+	}
+	sort.Strings(types)
+
+	var instructions strings.Builder
+	instructions.WriteString("The commit body must follow the template for the chosen commit type, with each section on its own line followed by its text:\n")
+	for _, commitType := range types {
+		fmt.Fprintf(&instructions, "- %s: %s\n", commitType, strings.Join(templates[commitType], ", "))
+	}
+	instructions.WriteString("\n")
+	return instructions.String()
+}
+
+// missingSections returns the template sections the message's body doesn't start a line with
+func missingSections(message string, templates map[string][]string) []string {
+	sections := templates[commitType(message)]
+	if len(sections) == 0 {
+		return nil
+	}
+
+	_, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	var missing []string
+	for _, section := range sections {
+		found := false
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), section) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, section)
+		}
+	}
+	return missing
+}
+
+// enforceTemplate asks the model once more when its message lacks sections the template
+// requires. It returns the corrected response, or the original one if the retry fails.
+func enforceTemplate(opts GenerateOptions, config *Config, request ChatRequest, response string, templates map[string][]string) string {
+	missing := missingSections(response, templates)
+	if len(missing) == 0 {
+		return response
+	}
+
+	request.Messages = append(request.Messages,
+		Message{Role: "assistant", Content: response},
+		Message{Role: "user", Content: fmt.Sprintf("The body is missing these required sections for a %s commit: %s. "+
+			"Rewrite the commit message with every section on its own line. Only respond with the commit message, nothing else.",
+			commitType(response), strings.Join(missing, ", "))},
+	)
+	request.OnDelta, request.Shared = nil, false
+	corrected, err := chat(opts.Ctx, config, request)
+	if err != nil {
+		return response
+	}
+	return strings.TrimSpace(corrected)
+}
+
+// printTemplateWarning lists template sections the final message still lacks
+func printTemplateWarning(message string, templates map[string][]string) {
+	missing := missingSections(message, templates)
+	if len(missing) == 0 {
+		return
+	}
+	fmt.Printf("\n%s %s\n", yellow(fmt.Sprintf("⚠️  The %s template in %s requires these sections:", commitType(message), repoConfigFile)), strings.Join(missing, ", "))
+}
@@ -0,0 +1,9 @@
+package main
+
+import "golang.org/x/sys/unix"
+
+// Requests for reading and writing terminal settings
+const (
+	ioctlReadTermios  = unix.TCGETS
+	ioctlWriteTermios = unix.TCSETS
+)
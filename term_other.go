@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// isTerminal reports whether f is a terminal. Keypresses aren't supported here, so menus always
+// read whole lines.
+func isTerminal(f *os.File) bool {
+	return false
+}
+
+// terminalWidth isn't known here, streamed text is then assumed not to wrap
+func terminalWidth(f *os.File) int {
+	return 0
+}
+
+// keypressMode isn't supported on this platform, e.g. on Windows
+func keypressMode(f *os.File) (func(), error) {
+	return nil, errors.New("reading single keypresses isn't supported on this platform")
+}
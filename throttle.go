@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// autoCommitLogGitPath is where the times of recent automatic commits are kept, inside the git directory
+const autoCommitLogGitPath = "rmit-autocommits"
+
+// parseAutoCommitInterval parses auto_commit_min_interval: a duration like "30s" or "5m", or a
+// number of seconds. 0 turns the limit off.
+func parseAutoCommitInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		return 0, fmt.Errorf("invalid auto commit interval %q: expected a duration like 30s or 5m, or 0 for no limit", value)
+	}
+	return interval, nil
+}
+
+// autoCommitLogPath returns the file recent automatic commits are recorded in
+func autoCommitLogPath() (string, error) {
+	out, err := gitCommand("rev-parse", "--git-path", autoCommitLogGitPath).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find git directory: %w", err)
+	}
+	return filepath.Abs(strings.TrimSpace(string(out)))
+}
+
+// readAutoCommits returns the Unix times of this repository's automatic commits in the last hour
+func readAutoCommits(now time.Time) []int64 {
+	var commits []int64
+	logPath, err := autoCommitLogPath()
+	if err != nil {
+		return nil
+	}
+	if data, err := os.ReadFile(logPath); err == nil {
+		_ = json.Unmarshal(data, &commits)
+	}
+	recent := commits[:0]
+	for _, t := range commits {
+		if now.Sub(time.Unix(t, 0)) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	return recent
+}
+
+// recordAutoCommit adds an automatic commit to the repository's log, so the next run counts it
+func recordAutoCommit(now time.Time) {
+	if noPersist {
+		return
+	}
+	commits := append(readAutoCommits(now), now.Unix())
+	logPath, err := autoCommitLogPath()
+	if err != nil {
+		return
+	}
+	if data, err := json.Marshal(commits); err == nil {
+		_ = os.WriteFile(logPath, data, 0600)
+	}
+}
+
+// autoCommitThrottled reports why an automatic commit isn't allowed yet, or "" if it is. A file
+// watcher or script calling rmit --commit in a loop would otherwise fill the history and spend
+// API credits as fast as files change.
+func autoCommitThrottled(config *Config, now time.Time) string {
+	if config.AutoCommitMinInterval == 0 && config.AutoCommitMaxPerHour == 0 {
+		return ""
+	}
+	commits := readAutoCommits(now)
+	if len(commits) == 0 {
+		return ""
+	}
+
+	if last := time.Unix(commits[len(commits)-1], 0); now.Sub(last) < config.AutoCommitMinInterval {
+		return fmt.Sprintf("the last automatic commit was %s ago, auto_commit_min_interval is %s (next allowed in %s)",
+			now.Sub(last).Round(time.Second), config.AutoCommitMinInterval, last.Add(config.AutoCommitMinInterval).Sub(now).Round(time.Second))
+	}
+	if config.AutoCommitMaxPerHour > 0 && len(commits) >= config.AutoCommitMaxPerHour {
+		// The oldest commit in the window has to age out before another is allowed
+		oldest := time.Unix(commits[len(commits)-config.AutoCommitMaxPerHour], 0)
+		return fmt.Sprintf("%d automatic commits in the last hour, auto_commit_max_per_hour is %d (next allowed in %s)",
+			len(commits), config.AutoCommitMaxPerHour, oldest.Add(time.Hour).Sub(now).Round(time.Second))
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is how long a request may take when timeout isn't set
+const defaultTimeout = 2 * time.Minute
+
+// timeoutOverride is set by --timeout for one run, overriding the timeout config key
+var timeoutOverride *time.Duration
+
+// parseTimeout parses a timeout: a duration like "90s" or "2m", or a number of seconds. 0 turns
+// the limit off.
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(value); err == nil {
+		value += "s"
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid timeout %q: can't be negative", value)
+		}
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid timeout %q: expected a duration like 90s or 2m, or 0 for no limit", value)
+	}
+	return timeout, nil
+}
+
+// requestTimeout returns how long a request may take, 0 for no limit
+func requestTimeout(config *Config) time.Duration {
+	if timeoutOverride != nil {
+		return *timeoutOverride
+	}
+	return config.Timeout
+}
+
+// requestContext returns the context of one request attempt: limited to the timeout, and
+// canceled by Ctrl-C so an in-flight request is abandoned cleanly rather than killed with the
+// process. Ctrl-C behaves as usual again once the returned function is called.
+func requestContext(ctx context.Context, config *Config) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	timeout := requestTimeout(config)
+	if timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
+
+// requestAborted tells why an attempt ended early: its timeout, or Ctrl-C, reported as
+// errInterrupted. Cancellation by the caller, e.g. a client of rmit serve going away, is left to
+// the caller, so nil is returned for it.
+func requestAborted(ctx, attempt context.Context, config *Config) error {
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case errors.Is(attempt.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("no response within %s, allow more time with --timeout or rmit set timeout: %w", requestTimeout(config), context.DeadlineExceeded)
+	case attempt.Err() != nil:
+		return errInterrupted
+	}
+	return nil
+}
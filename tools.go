@@ -0,0 +1,571 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Limits on what tools can add to a prompt
+const (
+	toolResultLimit   = 6000 // bytes of a single tool result
+	defaultToolTokens = 6000 // estimated tokens of all tool results of a generation, unless max_tool_tokens says otherwise
+	toolFileLines     = 40   // lines shown on each side of the line read_file asks for
+	toolListLimit     = 200  // files list_files shows
+	toolGrepLimit     = 50   // matching lines search_code shows
+	toolCommitLimit   = 10   // commits recent_commits shows
+	maxToolRounds     = 8    // rounds of tool calls before the model has to answer
+)
+
+// ToolCall is a tool the model calls, in the OpenAI chat completions format other providers'
+// calls are converted to
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"` // always "function"
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // a JSON object
+	} `json:"function"`
+}
+
+// OpenAITool declares a tool in the OpenAI chat completions format
+type OpenAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string         `json:"name"`
+		Description string         `json:"description"`
+		Parameters  map[string]any `json:"parameters"`
+	} `json:"function"`
+}
+
+// AnthropicTool declares a tool in the Messages API format
+type AnthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// Tool is a read-only function the model can call for context the diff doesn't show. Tools
+// never change the repository, the working tree or anything outside it.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any // JSON schema of the arguments object
+	GitOnly     bool           // needs git, e.g. for history
+	LocalOnly   bool           // reads the working tree outside git, which is only checked locally
+	Run         func(root string, args map[string]any, excluded func(file string) bool) (string, error)
+}
+
+// errExcludedFile is returned for files that were left out of the prompt, which tools don't show
+// the model either
+var errExcludedFile = errors.New("left out of the prompt")
+
+// ToolBox holds the tools of one generation and what's left of its budget. The budget is
+// shared by everything sent with the box, retries and corrections included.
+type ToolBox struct {
+	Config    *Config
+	Tools     []Tool
+	MaxCalls  int
+	MaxTokens int
+	Calls     int // tool calls left
+	Tokens    int // estimated tokens of tool results left
+	Retrieved []ToolRecord
+	Ignore    []ignorePattern // ignore_patterns and .rmitignore
+	Withheld  map[string]bool // changed files left out of the prompt, e.g. by --preview or --describe-only
+}
+
+// ToolRecord is a tool call the model made and what it got back, for the report and the
+// transcript
+type ToolRecord struct {
+	Tool      string
+	Arguments string
+	Result    string
+	Tokens    int
+	Status    string   // empty when the call succeeded, else e.g. "truncated", "failed" or "refused"
+	Refused   []string // files left out of the prompt the call asked for or would have shown
+}
+
+// contextTools are the tools the model can call to look around the changes
+var contextTools = []Tool{
+	{
+		Name:        "read_file",
+		Description: fmt.Sprintf("Show the lines of a file in the repository around a line, %d on each side, as it will be committed.", toolFileLines),
+		Parameters: toolSchema(map[string]any{
+			"path": map[string]any{"type": "string", "description": "Path relative to the repository root"},
+			"line": map[string]any{"type": "integer", "description": "Line number to show the surroundings of, 1 if omitted"},
+		}, "path"),
+		LocalOnly: true,
+		Run:       readFileTool,
+	},
+	{
+		Name:        "list_files",
+		Description: "List the files in a directory of the repository, recursively.",
+		Parameters: toolSchema(map[string]any{
+			"dir": map[string]any{"type": "string", "description": "Directory relative to the repository root, empty for the root"},
+		}),
+		Run: listFilesTool,
+	},
+	{
+		Name:        "recent_commits",
+		Description: fmt.Sprintf("Show the subjects of the last commits touching a file or directory, at most %d.", toolCommitLimit),
+		Parameters: toolSchema(map[string]any{
+			"path":  map[string]any{"type": "string", "description": "Path relative to the repository root, empty for the whole repository"},
+			"count": map[string]any{"type": "integer", "description": "Number of commits, 5 if omitted"},
+		}),
+		GitOnly: true,
+		Run:     recentCommitsTool,
+	},
+	{
+		Name:        "search_code",
+		Description: fmt.Sprintf("Search the repository for a fixed string, e.g. a function name, and show up to %d matching lines.", toolGrepLimit),
+		Parameters: toolSchema(map[string]any{
+			"text": map[string]any{"type": "string", "description": "The text to search for"},
+		}, "text"),
+		GitOnly: true,
+		Run:     searchCodeTool,
+	},
+}
+
+// toolSchema builds the JSON schema of a tool's arguments
+func toolSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// parseToolNames parses the comma separated tool names rmit set allowed_tools takes
+func parseToolNames(value string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		if !slices.ContainsFunc(contextTools, func(tool Tool) bool { return tool.Name == name }) {
+			var known []string
+			for _, tool := range contextTools {
+				known = append(known, tool.Name)
+			}
+			return nil, fmt.Errorf("unknown tool %q, the tools are: %s", name, strings.Join(known, ", "))
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// newToolBox returns the tools for a generation under max_tool_calls, max_tool_tokens and
+// allowed_tools, nil when tools are off. Tools refuse the withheld files and those ignore
+// patterns leave out, so they can't send what the prompt doesn't.
+func newToolBox(config *Config, withheld []string) *ToolBox {
+	if config.MaxToolCalls <= 0 {
+		return nil
+	}
+	tokens := config.MaxToolTokens
+	if tokens <= 0 {
+		tokens = defaultToolTokens
+	}
+	box := &ToolBox{Config: config, MaxCalls: config.MaxToolCalls, MaxTokens: tokens, Calls: config.MaxToolCalls, Tokens: tokens}
+	box.Ignore = ignorePatterns(config)
+	box.Withheld = make(map[string]bool)
+	for _, file := range withheld {
+		box.Withheld[file] = true
+	}
+	for _, tool := range contextTools {
+		if tool.GitOnly && activeVCS != nil {
+			continue
+		}
+		// Symlinks in a remote checkout can't be resolved from here, so its files stay unread
+		if tool.LocalOnly && activeVCS != nil && remoteCheckout != nil {
+			continue
+		}
+		if len(config.AllowedTools) == 0 || slices.Contains(config.AllowedTools, tool.Name) {
+			box.Tools = append(box.Tools, tool)
+		}
+	}
+	if len(box.Tools) == 0 {
+		return nil
+	}
+	return box
+}
+
+// openAITools declares a box's tools in the OpenAI chat completions format
+func openAITools(box *ToolBox) []OpenAITool {
+	if box == nil {
+		return nil
+	}
+	var tools []OpenAITool
+	for _, tool := range box.Tools {
+		declared := OpenAITool{Type: "function"}
+		declared.Function.Name, declared.Function.Description, declared.Function.Parameters = tool.Name, tool.Description, tool.Parameters
+		tools = append(tools, declared)
+	}
+	return tools
+}
+
+// anthropicTools declares a box's tools in the Messages API format
+func anthropicTools(box *ToolBox) []AnthropicTool {
+	if box == nil {
+		return nil
+	}
+	var tools []AnthropicTool
+	for _, tool := range box.Tools {
+		tools = append(tools, AnthropicTool{Name: tool.Name, Description: tool.Description, InputSchema: tool.Parameters})
+	}
+	return tools
+}
+
+// run runs a tool call and returns what the model is told. Failures are reported to the model,
+// which can try something else, rather than failing the generation.
+func (box *ToolBox) run(call ToolCall) string {
+	record := ToolRecord{Tool: call.Function.Name, Arguments: call.Function.Arguments}
+	defer func() {
+		box.Retrieved = append(box.Retrieved, record)
+		debugf("tool %s %s: ~%d tokens %s, %d call(s) and ~%d tokens left", record.Tool, record.Arguments, record.Tokens, record.Status, box.Calls, box.Tokens)
+	}()
+
+	if box.Calls <= 0 || box.Tokens <= 0 {
+		record.Status = "refused"
+		record.Result = "The tool budget is used up. Write the commit message with what you have."
+		return record.Result
+	}
+	var tool *Tool
+	for i := range box.Tools {
+		if box.Tools[i].Name == call.Function.Name {
+			tool = &box.Tools[i]
+		}
+	}
+	if tool == nil {
+		record.Status = "refused"
+		record.Result = fmt.Sprintf("error: there is no tool %q", call.Function.Name)
+		return record.Result
+	}
+	box.Calls--
+
+	result, refused, err := box.runTool(tool, call.Function.Arguments)
+	record.Refused = refused
+	if err != nil {
+		record.Status = "failed"
+		if errors.Is(err, errExcludedFile) {
+			record.Status = "refused"
+		}
+		result = "error: " + err.Error()
+	}
+	const truncated = "\n[truncated]"
+	if limit := max(min(toolResultLimit, box.Tokens*4)-len(truncated), 0); len(result) > limit {
+		record.Status = "truncated"
+		result = result[:limit] + truncated
+	}
+
+	// Tool results are part of the prompt, but asking for them isn't the author's doing, so
+	// secrets in them are always redacted rather than stopping the generation
+	if secretPolicy(box.Config) != secretOff {
+		extra, _ := compileSecretPatterns(box.Config.SecretPatterns) // validated by the first redaction
+		result, _ = redactText(result, append(slices.Clone(secretPatterns), extra...), apiKeySecrets(box.Config))
+	}
+	record.Result, record.Tokens = result, estimateTokens(result)
+	box.Tokens -= record.Tokens
+	return result
+}
+
+// runTool parses a call's arguments and runs the tool in the repository. It also returns the
+// excluded files the tool refused or left out of its result.
+func (box *ToolBox) runTool(tool *Tool, arguments string) (string, []string, error) {
+	args := map[string]any{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return "", nil, errors.New("the arguments aren't a JSON object")
+		}
+	}
+	root, err := getRepoRoot()
+	if err != nil {
+		return "", nil, err
+	}
+	var refused []string
+	result, err := tool.Run(root, args, func(file string) bool {
+		if !box.excluded(file) {
+			return false
+		}
+		if !slices.Contains(refused, file) {
+			refused = append(refused, file)
+		}
+		return true
+	})
+	return result, refused, err
+}
+
+// excluded reports whether a file was left out of the prompt, so tools mustn't show it
+func (box *ToolBox) excluded(file string) bool {
+	return box.Withheld[file] || isIgnored(box.Ignore, file)
+}
+
+// withheldFiles returns the changed files missing from the described diff, e.g. those outside
+// --commit-only or --describe-only paths
+func withheldFiles(diff string) []string {
+	all, err := describedDiff(allPathspec, nil)
+	if err != nil {
+		return nil
+	}
+	described := make(map[string]bool)
+	for _, f := range parseDiff(diff) {
+		described[f.Path] = true
+	}
+	var withheld []string
+	for _, f := range parseDiff(all) {
+		if !described[f.Path] {
+			withheld = append(withheld, f.Path)
+		}
+	}
+	return withheld
+}
+
+// retrieved returns the tool calls made so far; it's nil on a nil box
+func (box *ToolBox) retrieved() []ToolRecord {
+	if box == nil {
+		return nil
+	}
+	return slices.Clone(box.Retrieved)
+}
+
+// printToolReport shows what the model retrieved with tools and how much of the budget it used
+func printToolReport(w io.Writer, box *ToolBox) {
+	if box == nil || len(box.Retrieved) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", blue("🔧 CONTEXT RETRIEVED BY THE MODEL:"))
+	fmt.Fprint(w, rule())
+	for _, record := range box.Retrieved {
+		status := cyan(fmt.Sprintf("~%d tokens", record.Tokens))
+		if record.Status != "" {
+			status += " " + yellow(record.Status)
+		}
+		fmt.Fprintf(w, "  %-16s %s %s\n", record.Tool, record.Arguments, status)
+		if len(record.Refused) > 0 {
+			fmt.Fprintf(w, "  %-16s %s %s\n", "", yellow("refused:"), refusedList(record.Refused))
+		}
+	}
+	fmt.Fprint(w, rule())
+	fmt.Fprintf(w, "%s\n", green(fmt.Sprintf("%d of %d call(s), ~%d of %d tokens", box.MaxCalls-box.Calls, box.MaxCalls, box.MaxTokens-box.Tokens, box.MaxTokens)))
+}
+
+// refusedList names the files a tool call refused, the first few of them when there are many
+func refusedList(files []string) string {
+	const shown = 10
+	if len(files) > shown {
+		return fmt.Sprintf("%s and %d more", strings.Join(files[:shown], ", "), len(files)-shown)
+	}
+	return strings.Join(files, ", ")
+}
+
+// chatWithTools answers a request the model may call tools for: each round of calls is run and
+// the results sent back until the model answers. The answer isn't streamed, OnDelta gets it
+// whole.
+func chatWithTools(ctx context.Context, config *Config, provider ToolProvider, request ChatRequest) (string, error) {
+	request, err := redactRequest(config, request)
+	if err != nil {
+		return "", err
+	}
+	onDelta := request.OnDelta
+	request.OnDelta = nil
+	request.Messages = slices.Clone(request.Messages)
+
+	for round := 0; ; round++ {
+		turn, err := provider.GenerateTurn(ctx, config, request)
+		if err != nil {
+			return "", err
+		}
+		if len(turn.ToolCalls) == 0 {
+			if onDelta != nil {
+				onDelta(turn.Text)
+			}
+			return turn.Text, nil
+		}
+		if round == maxToolRounds {
+			return "", fmt.Errorf("the model kept calling tools instead of answering")
+		}
+		request.Messages = append(request.Messages, Message{Role: "assistant", Content: turn.Text, ToolCalls: turn.ToolCalls})
+		for _, call := range turn.ToolCalls {
+			request.Messages = append(request.Messages, Message{Role: "tool", ToolCallID: call.ID, Content: request.Tools.run(call)})
+		}
+	}
+}
+
+// toolPath validates a repository relative path a tool was given. Paths can't leave the
+// repository or look into .git.
+func toolPath(args map[string]any, key string, required bool) (string, error) {
+	value, _ := args[key].(string)
+	value = strings.TrimPrefix(strings.TrimSpace(filepath.ToSlash(value)), "./")
+	if value == "" || value == "." {
+		if required {
+			return "", fmt.Errorf("%s is required", key)
+		}
+		return "", nil
+	}
+	cleaned := path.Clean(value)
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || cleaned == ".git" || strings.HasPrefix(cleaned, ".git/") {
+		return "", fmt.Errorf("%s must be inside the repository", key)
+	}
+	return cleaned, nil
+}
+
+// toolInt reads an integer argument, def when it's missing, capped at limit
+func toolInt(args map[string]any, key string, def, limit int) int {
+	value := def
+	switch v := args[key].(type) {
+	case float64:
+		value = int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			value = n
+		}
+	}
+	return min(max(value, 1), limit)
+}
+
+// readFileTool shows the lines of a file around a line. In git the staged version is read, which
+// is what gets committed, and symlinks show where they point instead of being followed.
+func readFileTool(root string, args map[string]any, excluded func(file string) bool) (string, error) {
+	file, err := toolPath(args, "path", true)
+	if err != nil {
+		return "", err
+	}
+	if excluded(file) {
+		return "", fmt.Errorf("%s is %w", file, errExcludedFile)
+	}
+	var content []byte
+	if activeVCS == nil {
+		out, err := gitCommand("-C", root, "show", ":"+file).Output()
+		if err != nil {
+			return "", fmt.Errorf("%s isn't in the repository", file)
+		}
+		content = out
+	} else {
+		if !checkoutFile(root, file) {
+			return "", fmt.Errorf("%s isn't a file in the repository", file)
+		}
+		if content, err = readCheckoutFile(root, file); err != nil {
+			return "", fmt.Errorf("%s isn't in the repository", file)
+		}
+	}
+	if bytes.IndexByte(content, 0) != -1 {
+		return "", fmt.Errorf("%s is a binary file", file)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	line := toolInt(args, "line", 1, len(lines))
+	first, last := max(line-toolFileLines, 1), min(line+toolFileLines, len(lines))
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s, lines %d-%d of %d:\n", file, first, last, len(lines))
+	for i := first; i <= last; i++ {
+		fmt.Fprintf(&out, "%d: %s\n", i, lines[i-1])
+	}
+	return out.String(), nil
+}
+
+// checkoutFile reports whether a path in a working tree is a regular file that, with every
+// symlink on the way resolved, is still inside the working tree
+func checkoutFile(root, file string) bool {
+	full := filepath.Join(root, filepath.FromSlash(file))
+	if info, err := os.Lstat(full); err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false
+	}
+	resolved, err := filepath.EvalSymlinks(full)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realRoot, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// listFilesTool lists the files under a directory, without those left out of the prompt
+func listFilesTool(root string, args map[string]any, excluded func(file string) bool) (string, error) {
+	dir, err := toolPath(args, "dir", false)
+	if err != nil {
+		return "", err
+	}
+	files, err := repoFiles(root)
+	if err != nil {
+		return "", err
+	}
+	var listed []string
+	for _, file := range files {
+		if (dir == "" || strings.HasPrefix(file, dir+"/")) && !excluded(file) {
+			listed = append(listed, file)
+		}
+	}
+	if len(listed) == 0 {
+		return "", fmt.Errorf("no files in %s", dir)
+	}
+	more := ""
+	if len(listed) > toolListLimit {
+		more = fmt.Sprintf("\n[%d more]", len(listed)-toolListLimit)
+		listed = listed[:toolListLimit]
+	}
+	return strings.Join(listed, "\n") + more, nil
+}
+
+// recentCommitsTool shows the last commits touching a path
+func recentCommitsTool(root string, args map[string]any, excluded func(file string) bool) (string, error) {
+	file, err := toolPath(args, "path", false)
+	if err != nil {
+		return "", err
+	}
+	if file != "" && excluded(file) {
+		return "", fmt.Errorf("%s is %w", file, errExcludedFile)
+	}
+	count := toolInt(args, "count", 5, toolCommitLimit)
+	gitArgs := []string{"-C", root, "log", "-n", strconv.Itoa(count), "--format=%h %as %s"}
+	if file != "" {
+		gitArgs = append(gitArgs, "--", file)
+	}
+	out, err := gitCommand(gitArgs...).Output()
+	if err != nil {
+		return "", errors.New("no history, the repository may have no commits yet")
+	}
+	if strings.TrimSpace(string(out)) == "" {
+		return "", errors.New("no commits touch it")
+	}
+	return string(out), nil
+}
+
+// searchCodeTool searches the staged files for a fixed string, skipping files left out of the
+// prompt
+func searchCodeTool(root string, args map[string]any, excluded func(file string) bool) (string, error) {
+	text, _ := args["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return "", errors.New("text is required")
+	}
+	// -z ends file names and line numbers with a NUL, so names with colons can't be misread
+	out, err := gitCommand("-C", root, "grep", "--cached", "-z", "-n", "-I", "-F", "-e", text).Output()
+	if err != nil {
+		// git grep exits 1 when nothing matches
+		return "", fmt.Errorf("no matches for %q", text)
+	}
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSuffix(string(out), "\n"), "\n") {
+		file, match, _ := strings.Cut(line, "\x00")
+		if !excluded(file) {
+			lines = append(lines, file+":"+strings.Replace(match, "\x00", ":", 1))
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no matches for %q", text)
+	}
+	if len(lines) > toolGrepLimit {
+		lines = append(lines[:toolGrepLimit], fmt.Sprintf("[%d more]", len(lines)-toolGrepLimit))
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToolPath(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+		err   bool
+	}{
+		{value: "main.go", want: "main.go"},
+		{value: "./src/main.go", want: "src/main.go"},
+		{value: "src/../main.go", want: "main.go"},
+		{value: "..", err: true},
+		{value: "../secrets.txt", err: true},
+		{value: "src/../../secrets.txt", err: true},
+		{value: ".git", err: true},
+		{value: ".git/config", err: true},
+		{value: "src/../.git/config", err: true},
+		{value: "/etc/passwd", err: true},
+		{value: "", err: true},
+		{value: ".", err: true},
+	}
+	for _, tt := range tests {
+		got, err := toolPath(map[string]any{"path": tt.value}, "path", true)
+		if tt.err {
+			if err == nil {
+				t.Errorf("toolPath(%q) = %q, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("toolPath(%q) = %q, %v; want %q", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckoutFileStaysInside(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{filepath.Join(root, "src", "main.go"), filepath.Join(outside, "secret.txt")} {
+		if err := os.WriteFile(file, []byte("data\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"src/link.go": filepath.Join(root, "src", "main.go"), // a symlink itself isn't read
+		"escape":      outside,                               // a directory leading out of the checkout
+		"src/inner":   filepath.Join(root, "src"),            // a directory staying inside it
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(link))); err != nil {
+			t.Skipf("symlinks aren't supported here: %v", err)
+		}
+	}
+
+	tests := map[string]bool{
+		"src/main.go":       true,
+		"src/inner/main.go": true,
+		"src/link.go":       false,
+		"escape/secret.txt": false,
+		"src/missing.go":    false,
+		"src":               false,
+	}
+	for file, want := range tests {
+		if got := checkoutFile(root, file); got != want {
+			t.Errorf("checkoutFile(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Trailer is a "Key: value" line at the end of a commit message
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// trailerOrder is the order well-known trailers are written in; others follow alphabetically
+var trailerOrder = []string{"Reviewed-by", "Refs", "Ticket", "Risk", "Changelog"}
+
+var (
+	// trailerLinePattern matches a git trailer line such as "Signed-off-by: Jane <jane@example.com>"
+	trailerLinePattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*):\s+\S`)
+	// ticketBranchPattern matches issue tracker keys such as ABC-123 in branch names
+	ticketBranchPattern = regexp.MustCompile(`(?:^|[/_-])([A-Z][A-Z0-9]+-[0-9]+)(?:$|[/_-])`)
+	// issueBranchPattern matches issue numbers in branch names such as fix/123-crash or issue-123
+	issueBranchPattern = regexp.MustCompile(`(?:^|/)(?:(?:issue|issues|gh)[-_]?)?([0-9]+)(?:$|[/_-])`)
+)
+
+// canonicalTrailerKey normalizes the case of a trailer key, e.g. "reviewed-by" to "Reviewed-by"
+func canonicalTrailerKey(key string) string {
+	key = strings.TrimSpace(key)
+	for _, known := range trailerOrder {
+		if strings.EqualFold(key, known) {
+			return known
+		}
+	}
+	if key == "" {
+		return key
+	}
+	return strings.ToUpper(key[:1]) + key[1:]
+}
+
+// parseTrailer parses "Key: value" or "Key=value"
+func parseTrailer(value string) (Trailer, error) {
+	sep := strings.IndexAny(value, ":=")
+	if sep <= 0 {
+		return Trailer{}, fmt.Errorf("invalid trailer %q, expected \"Key: value\"", value)
+	}
+	trailer := Trailer{Key: canonicalTrailerKey(value[:sep]), Value: strings.TrimSpace(value[sep+1:])}
+	if strings.ContainsAny(trailer.Key, " \t") || trailer.Value == "" {
+		return Trailer{}, fmt.Errorf("invalid trailer %q, expected \"Key: value\"", value)
+	}
+	return trailer, nil
+}
+
+// getCurrentBranch returns the checked out branch name, or an empty string on a detached HEAD
+func getCurrentBranch() string {
+	out, err := gitCommand("symbolic-ref", "--short", "-q", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// branchTrailers derives Ticket or Refs trailers from branch names like feature/ABC-123-login or fix/42-crash
+func branchTrailers(branch string) []Trailer {
+	if match := ticketBranchPattern.FindStringSubmatch(branch); match != nil {
+		return []Trailer{{Key: "Ticket", Value: match[1]}}
+	}
+	if match := issueBranchPattern.FindStringSubmatch(branch); match != nil {
+		return []Trailer{{Key: "Refs", Value: "#" + match[1]}}
+	}
+	return nil
+}
+
+// collectTrailers merges trailers from config, the branch name and --trailer flags.
+// Later sources replace earlier ones with the same key; repeated flags for one key are all kept.
+func collectTrailers(config *Config, flagValues []string) ([]Trailer, error) {
+	byKey := make(map[string][]Trailer)
+	set := func(trailers []Trailer) {
+		replaced := make(map[string]bool)
+		for _, t := range trailers {
+			if !replaced[t.Key] {
+				byKey[t.Key] = nil
+				replaced[t.Key] = true
+			}
+			byKey[t.Key] = append(byKey[t.Key], t)
+		}
+	}
+
+	var configured []Trailer
+	for key, value := range config.Trailers {
+		configured = append(configured, Trailer{Key: canonicalTrailerKey(key), Value: value})
+	}
+	set(configured)
+	set(branchTrailers(getCurrentBranch()))
+
+	var flagged []Trailer
+	for _, value := range flagValues {
+		trailer, err := parseTrailer(value)
+		if err != nil {
+			return nil, err
+		}
+		flagged = append(flagged, trailer)
+	}
+	set(flagged)
+
+	var trailers []Trailer
+	for _, list := range byKey {
+		trailers = append(trailers, list...)
+	}
+	sortTrailers(trailers)
+	return trailers, nil
+}
+
+// sortTrailers orders trailers deterministically: well-known keys first, then alphabetically
+func sortTrailers(trailers []Trailer) {
+	rank := func(key string) int {
+		for i, known := range trailerOrder {
+			if key == known {
+				return i
+			}
+		}
+		return len(trailerOrder)
+	}
+	sort.SliceStable(trailers, func(i, j int) bool {
+		ri, rj := rank(trailers[i].Key), rank(trailers[j].Key)
+		if ri != rj {
+			return ri < rj
+		}
+		return trailers[i].Key < trailers[j].Key
+	})
+}
+
+// missingTrailers returns the required trailer keys that have no value
+func missingTrailers(trailers []Trailer, required []string) []string {
+	present := make(map[string]bool)
+	for _, t := range trailers {
+		present[t.Key] = true
+	}
+	var missing []string
+	for _, key := range required {
+		if key = canonicalTrailerKey(key); !present[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// promptTrailers asks the user for the value of each missing trailer
+func promptTrailers(trailers []Trailer, missing []string) ([]Trailer, error) {
+	for _, key := range missing {
+		for {
+			value, err := ui.Prompt(fmt.Sprintf("%s (required trailer): ", key))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read trailer: %w", err)
+			}
+			if value != "" {
+				trailers = append(trailers, Trailer{Key: key, Value: value})
+				break
+			}
+		}
+	}
+	sortTrailers(trailers)
+	return trailers, nil
+}
+
+// appendTrailers adds trailers after the message body, skipping any the message already contains.
+// Existing trailer lines written by the model are kept in the same final block.
+func appendTrailers(message string, trailers []Trailer) string {
+	if len(trailers) == 0 {
+		return message
+	}
+
+	message = strings.TrimRight(message, "\n ")
+	var lines []string
+	for _, t := range trailers {
+		line := t.Key + ": " + t.Value
+		if !strings.Contains(message, "\n"+line) {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return message
+	}
+
+	// Join an existing trailer block instead of starting a second one
+	paragraphs := strings.Split(message, "\n\n")
+	last := paragraphs[len(paragraphs)-1]
+	if len(paragraphs) > 1 && isTrailerBlock(last) {
+		return message + "\n" + strings.Join(lines, "\n")
+	}
+	return message + "\n\n" + strings.Join(lines, "\n")
+}
+
+// isTrailerBlock reports whether every line of a paragraph is a trailer
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		if !trailerLinePattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// trailerPlaceholders renders missing required trailers as comment lines for the commit message editor
+func trailerPlaceholders(missing []string) string {
+	var placeholders strings.Builder
+	for _, key := range missing {
+		fmt.Fprintf(&placeholders, "# %s: (required trailer, fill in and uncomment)\n", key)
+	}
+	return placeholders.String()
+}
+
+// parseTrailerMap parses "Key=value,Key=value" as used by rmit set trailers
+func parseTrailerMap(value string) (map[string]string, error) {
+	trailers := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		trailer, err := parseTrailer(entry)
+		if err != nil {
+			return nil, err
+		}
+		trailers[trailer.Key] = trailer.Value
+	}
+	return trailers, nil
+}
+
+// parseTrailerKeys parses a comma separated list of trailer keys
+func parseTrailerKeys(value string) []string {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = canonicalTrailerKey(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Where transcripts of the prompt and response are saved after committing
+const (
+	transcriptOff   = "off"
+	transcriptFile  = "file"
+	transcriptNotes = "notes"
+
+	transcriptDir      = ".rmit/transcripts"
+	transcriptNotesRef = "rmit"
+)
+
+// secretPatterns match credentials that must never be sent to a model or end up in a saved
+// transcript
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`sk-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{20,}`),
+	regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+	regexp.MustCompile(`[sr]k_live_[0-9A-Za-z]{16,}`),
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api_?key)["']?\s*[:=]\s*["']?)[^\s"']{4,}`),
+	// .env style assignments, e.g. AWS_SECRET_ACCESS_KEY=...
+	regexp.MustCompile(`(\b[A-Z0-9_]*(?:KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIALS?)\s*=\s*["']?)[^\s"']{4,}`),
+}
+
+// TranscriptEntry is one prompt sent to the model and what came back
+type TranscriptEntry struct {
+	Model         string
+	PromptVersion int
+	Prompt        string
+	Images        int
+	Retrieved     []ToolRecord // what the model asked tools for
+	Response      string
+	Message       string
+}
+
+// Transcript records the conversation with the model while generating a commit message
+type Transcript struct {
+	Entries []TranscriptEntry
+}
+
+// record adds an exchange to the transcript; it's a no-op on a nil transcript
+func (t *Transcript) record(entry TranscriptEntry) {
+	if t != nil {
+		t.Entries = append(t.Entries, entry)
+	}
+}
+
+// validateTranscriptMode checks if the transcript mode is supported
+func validateTranscriptMode(mode string) error {
+	switch mode {
+	case transcriptOff, transcriptFile, transcriptNotes:
+		return nil
+	}
+	return fmt.Errorf("unknown transcript mode %q, valid modes are: %s, %s, %s", mode, transcriptOff, transcriptFile, transcriptNotes)
+}
+
+// redactSecrets replaces credentials, and any extra known secrets, with a placeholder
+func redactSecrets(text string, known ...string) string {
+	text, _ = redactText(text, secretPatterns, known)
+	return text
+}
+
+// redactText replaces the matches of the patterns, and the known secrets, with a placeholder and
+// counts them
+func redactText(text string, patterns []*regexp.Regexp, known []string) (string, int) {
+	count := 0
+	for _, secret := range known {
+		// Very short values would redact ordinary words
+		if len(secret) >= 8 {
+			count += strings.Count(text, secret)
+			text = strings.ReplaceAll(text, secret, "[REDACTED]")
+		}
+	}
+	for _, pattern := range patterns {
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			// Text redacted before, e.g. by the client of an rmit server, isn't counted again
+			if strings.HasSuffix(match, "[REDACTED]") {
+				return match
+			}
+			count++
+			// Keep the "password=" part of key/value matches so the text stays readable
+			if sub := pattern.FindStringSubmatch(match); len(sub) > 1 {
+				return sub[1] + "[REDACTED]"
+			}
+			return "[REDACTED]"
+		})
+	}
+	return text, count
+}
+
+// render formats the transcript as markdown with secrets redacted
+func (t *Transcript) render(commit string, secrets []string) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "# rmit transcript for %s\n\n", commit)
+	fmt.Fprintf(&out, "Generated %s\n", time.Now().UTC().Format(time.RFC3339))
+
+	for i, entry := range t.Entries {
+		fmt.Fprintf(&out, "\n## Generation %d (%s, prompt v%d)\n\n", i+1, entry.Model, entry.PromptVersion)
+		fmt.Fprintf(&out, "### Prompt\n\n```\n%s\n```\n\n", redactSecrets(entry.Prompt, secrets...))
+		if entry.Images > 0 {
+			fmt.Fprintf(&out, "%d image(s) were attached.\n\n", entry.Images)
+		}
+		for j, record := range entry.Retrieved {
+			status := ""
+			if record.Status != "" {
+				status = ", " + record.Status
+			}
+			fmt.Fprintf(&out, "### Tool call %d: %s %s (~%d tokens%s)\n\n", j+1, record.Tool, redactSecrets(record.Arguments, secrets...), record.Tokens, status)
+			if len(record.Refused) > 0 {
+				fmt.Fprintf(&out, "Refused, left out of the prompt: %s\n\n", redactSecrets(refusedList(record.Refused), secrets...))
+			}
+			fmt.Fprintf(&out, "```\n%s\n```\n\n", redactSecrets(record.Result, secrets...))
+		}
+		fmt.Fprintf(&out, "### Response\n\n```\n%s\n```\n\n", redactSecrets(entry.Response, secrets...))
+		fmt.Fprintf(&out, "### Commit message\n\n```\n%s\n```\n", redactSecrets(entry.Message, secrets...))
+	}
+
+	return out.String()
+}
+
+// saveTranscript stores the transcript for the HEAD commit in a file or in git notes and
+// returns where it was saved
+func saveTranscript(t *Transcript, mode string, secrets []string) (string, error) {
+	if t == nil || len(t.Entries) == 0 || mode == transcriptOff || noPersist {
+		return "", nil
+	}
+
+	out, err := gitCommand("rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit := strings.TrimSpace(string(out))
+	content := t.render(commit, secrets)
+
+	if mode == transcriptNotes {
+		if err := gitCommand("notes", "--ref="+transcriptNotesRef, "add", "-f", "-m", content, commit).Run(); err != nil {
+			return "", fmt.Errorf("failed to add git note: %w", err)
+		}
+		return "git notes --ref=" + transcriptNotesRef, nil
+	}
+
+	root, err := getRepoRoot()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(root, filepath.FromSlash(transcriptDir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create transcript directory: %w", err)
+	}
+	transcriptPath := filepath.Join(dir, commit[:12]+".md")
+	if err := os.WriteFile(transcriptPath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return filepath.ToSlash(filepath.Join(transcriptDir, commit[:12]+".md")), nil
+}
+
+// printTranscriptSaved saves the transcript after a commit and reports where it went
+func printTranscriptSaved(t *Transcript, mode string, secrets []string) {
+	location, err := saveTranscript(t, mode, secrets)
+	if err != nil {
+		fmt.Printf("%s %v\n", yellow("⚠️ Couldn't save transcript:"), err)
+		return
+	}
+	if location != "" {
+		fmt.Printf("%s %s\n", green("📜 Transcript saved to"), cyan(location))
+	}
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// Terminal colors
+var (
+	red     = color.New(color.FgRed).SprintFunc()
+	green   = color.New(color.FgGreen).SprintFunc()
+	blue    = color.New(color.FgBlue).SprintFunc()
+	yellow  = color.New(color.FgYellow).SprintFunc()
+	cyan    = color.New(color.FgCyan).SprintFunc()
+	magenta = color.New(color.FgMagenta).SprintFunc()
+)
+
+// ruleLine separates sections of output
+const ruleLine = "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━"
+
+// rule returns a colored ruleLine for reports printed directly, or nothing in a11y mode, where
+// screen readers would read every box-drawing character out
+func rule() string {
+	if _, ok := ui.(*accessibleUI); ok {
+		return ""
+	}
+	return magenta(ruleLine) + "\n"
+}
+
+// bannerArt is the rmit logo
+const bannerArt = `██████╗ ███╗   ███╗██╗████████╗
+██╔══██╗████╗ ████║██║╚══██╔══╝
+██████╔╝██╔████╔██║██║   ██║   
+██╔══██╗██║╚██╔╝██║██║   ██║   
+██║  ██║██║ ╚═╝ ██║██║   ██║   
+╚═╝  ╚═╝╚═╝     ╚═╝╚═╝   ╚═╝   `
+
+// renderBanner returns the rmit header with version info
+func renderBanner() string {
+	var banner strings.Builder
+	for _, line := range strings.Split(bannerArt, "\n") {
+		banner.WriteString(blue(line) + "\n")
+	}
+	fmt.Fprintf(&banner, "\n%s %s\n", cyan("RMIT"), green(rmitVersion))
+	fmt.Fprintf(&banner, "%s\n", yellow("AI-powered commit message generator"))
+	fmt.Fprintf(&banner, "%s\n\n", magenta(ruleLine))
+	return banner.String()
+}
+
+// printBanner prints the rmit header. Only the interactive root command shows it, and only in
+// the terminal output modes, so subcommands, help and tools reading rmit's output start without it.
+func printBanner() {
+	if _, ok := ui.(*terminalUI); ok {
+		fmt.Print(renderBanner())
+	}
+}
+
+// Output modes selected with --output
+const (
+	outputColor = "color"
+	outputPlain = "plain"
+	outputJSON  = "json"
+	outputQuiet = "quiet"
+	outputA11y  = "a11y"
+)
+
+// UI is where rmit's messages to the user go. Features report through it instead of printing,
+// so every output mode works everywhere and output can be captured. A leading newline in a
+// message leaves a blank line before it in the terminal and is dropped by the other modes.
+type UI interface {
+	// Info reports progress, e.g. "Generating commit message..."
+	Info(message string)
+	// Success reports something that worked
+	Success(message string)
+	// Warn reports something the user should look at
+	Warn(message string)
+	// Error reports a failure
+	Error(message string)
+	// Panel shows a titled block, e.g. the generated message or a list of options
+	Panel(title, body string)
+	// Prompt asks a question and returns the answer without surrounding whitespace
+	Prompt(question string) (string, error)
+	// Choose asks the user to pick one of the options and returns its key
+	Choose(question string, options []MenuOption) (string, error)
+	// Stream shows a response as it arrives, until the next output takes its place
+	Stream(text string)
+	// Progress shows a status that changes in place, e.g. a countdown, until the next output
+	// takes its place
+	Progress(status string)
+}
+
+// ui is the UI in use, chosen with --output
+var ui UI = newTerminalUI(os.Stdout, os.Stdin, true)
+
+// setOutputMode switches the UI to an output mode. Outside color mode, colors are turned off for
+// everything printed, including data commands like rmit get.
+func setOutputMode(mode string) error {
+	switch mode {
+	case outputColor:
+		ui = newTerminalUI(os.Stdout, os.Stdin, true)
+	case outputPlain:
+		ui = newTerminalUI(os.Stdout, os.Stdin, false)
+	case outputJSON:
+		ui = newJSONUI(os.Stdout, os.Stdin)
+	case outputQuiet:
+		ui = newQuietUI(os.Stderr, os.Stdin)
+	case outputA11y:
+		ui = newAccessibleUI(os.Stdout, os.Stdin)
+	default:
+		return fmt.Errorf("unknown output mode %q, valid modes are: %s, %s, %s, %s, %s", mode, outputColor, outputPlain, outputJSON, outputQuiet, outputA11y)
+	}
+	color.NoColor = color.NoColor || mode != outputColor
+	return nil
+}
+
+// commandOutput is where output of commands rmit runs, like git commit's summary, goes. Only the
+// terminal and a11y modes show it, so it can't break up JSON events or quiet output; errors still
+// reach stderr.
+func commandOutput() io.Writer {
+	switch ui.(type) {
+	case *terminalUI, *accessibleUI:
+		return os.Stdout
+	}
+	return io.Discard
+}
+
+// readAnswer reads one line of input, counting the wait as input time in --profile
+func readAnswer(in *bufio.Reader) (string, error) {
+	defer profilePhase(phaseInput)()
+	line, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// terminalUI writes human-readable output, in color or plain
+type terminalUI struct {
+	out   io.Writer
+	in    *bufio.Reader
+	color bool
+	tty   *os.File // stdin when both ends are a terminal, so menus can read single keypresses
+
+	streamed strings.Builder // the response streamed so far, cleared before the next output
+}
+
+// newTerminalUI creates a UI for people reading a terminal
+func newTerminalUI(out io.Writer, in io.Reader, colored bool) *terminalUI {
+	t := &terminalUI{out: out, in: bufio.NewReader(in), color: colored}
+	inFile, inOK := in.(*os.File)
+	outFile, outOK := out.(*os.File)
+	if inOK && outOK && isTerminal(inFile) && isTerminal(outFile) && os.Getenv("TERM") != "dumb" {
+		t.tty = inFile
+	}
+	return t
+}
+
+// paint colors text in color mode
+func (t *terminalUI) paint(colorize func(a ...any) string, text string) string {
+	if !t.color {
+		return text
+	}
+	return colorize(text)
+}
+
+// message prints a message, keeping leading blank lines outside the color
+func (t *terminalUI) message(colorize func(a ...any) string, message string) {
+	t.endStream()
+	text := strings.TrimLeft(message, "\n")
+	fmt.Fprintf(t.out, "%s%s\n", message[:len(message)-len(text)], t.paint(colorize, text))
+}
+
+func (t *terminalUI) Info(message string)    { t.message(blue, message) }
+func (t *terminalUI) Success(message string) { t.message(green, message) }
+func (t *terminalUI) Warn(message string)    { t.message(yellow, message) }
+func (t *terminalUI) Error(message string)   { t.message(red, message) }
+
+func (t *terminalUI) Panel(title, body string) {
+	t.endStream()
+	fmt.Fprintf(t.out, "\n%s\n%s\n", t.paint(blue, title), t.paint(magenta, ruleLine))
+	if body != "" {
+		fmt.Fprintf(t.out, "%s\n%s\n", t.paint(cyan, body), t.paint(magenta, ruleLine))
+	}
+}
+
+func (t *terminalUI) Prompt(question string) (string, error) {
+	t.endStream()
+	fmt.Fprint(t.out, t.paint(yellow, question))
+	return readAnswer(t.in)
+}
+
+// Stream prints the response as it arrives in an interactive terminal. Elsewhere, e.g. in logs,
+// only the finished message is shown.
+func (t *terminalUI) Stream(text string) {
+	if t.tty == nil {
+		return
+	}
+	t.streamed.WriteString(text)
+	fmt.Fprint(t.out, t.paint(cyan, text))
+}
+
+// Progress replaces the last status in an interactive terminal. Elsewhere every status would be
+// a line of its own, so none are shown.
+func (t *terminalUI) Progress(status string) {
+	if t.tty == nil {
+		return
+	}
+	t.endStream()
+	t.streamed.WriteString(status)
+	fmt.Fprint(t.out, t.paint(cyan, status))
+}
+
+// endStream erases the streamed response, so the finished message is shown in its place
+func (t *terminalUI) endStream() {
+	if t.streamed.Len() == 0 {
+		return
+	}
+	rows := streamRows(t.streamed.String(), terminalWidth(t.tty))
+	t.streamed.Reset()
+	if rows > 1 {
+		fmt.Fprintf(t.out, "\x1b[%dA", rows-1)
+	}
+	fmt.Fprint(t.out, "\r\x1b[J")
+}
+
+// streamRows returns how many terminal rows text takes up when lines wrap at width columns
+func streamRows(text string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		columns := utf8.RuneCountInString(line)
+		if width <= 0 || columns <= width {
+			rows++
+			continue
+		}
+		rows += (columns + width - 1) / width
+	}
+	return rows
+}
+
+// jsonUI writes one JSON object per line, for tools that drive rmit
+type jsonUI struct {
+	out *json.Encoder
+	in  *bufio.Reader
+}
+
+// UIEvent is one line of --output json
+type UIEvent struct {
+	Type     string       `json:"type"` // info, success, warn, error, panel, prompt or delta
+	Message  string       `json:"message,omitempty"`
+	Title    string       `json:"title,omitempty"`
+	Body     string       `json:"body,omitempty"`
+	Question string       `json:"question,omitempty"`
+	Options  []MenuOption `json:"options,omitempty"`
+}
+
+// newJSONUI creates a UI for programs reading rmit's output
+func newJSONUI(out io.Writer, in io.Reader) *jsonUI {
+	return &jsonUI{out: json.NewEncoder(out), in: bufio.NewReader(in)}
+}
+
+// event writes a message event
+func (j *jsonUI) event(kind, message string) {
+	j.out.Encode(UIEvent{Type: kind, Message: strings.TrimSpace(message)})
+}
+
+func (j *jsonUI) Info(message string)    { j.event("info", message) }
+func (j *jsonUI) Success(message string) { j.event("success", message) }
+func (j *jsonUI) Warn(message string)    { j.event("warn", message) }
+func (j *jsonUI) Error(message string)   { j.event("error", message) }
+
+func (j *jsonUI) Panel(title, body string) {
+	j.out.Encode(UIEvent{Type: "panel", Title: strings.TrimSuffix(strings.TrimSpace(title), ":"), Body: strings.Trim(body, "\n")})
+}
+
+// Stream writes a delta event per piece of the response, whitespace included
+func (j *jsonUI) Stream(text string) {
+	j.out.Encode(UIEvent{Type: "delta", Message: text})
+}
+
+// Progress writes nothing, the info event before it says how long the wait is
+func (j *jsonUI) Progress(status string) {}
+
+func (j *jsonUI) Prompt(question string) (string, error) {
+	j.out.Encode(UIEvent{Type: "prompt", Question: strings.TrimSpace(question)})
+	return readAnswer(j.in)
+}
+
+// quietUI only reports warnings and errors, on stderr
+type quietUI struct {
+	err io.Writer
+	in  *bufio.Reader
+}
+
+// newQuietUI creates a UI for scripts that only want to hear about problems
+func newQuietUI(err io.Writer, in io.Reader) *quietUI {
+	return &quietUI{err: err, in: bufio.NewReader(in)}
+}
+
+func (q *quietUI) Info(message string)      {}
+func (q *quietUI) Success(message string)   {}
+func (q *quietUI) Warn(message string)      { fmt.Fprintln(q.err, strings.TrimLeft(message, "\n")) }
+func (q *quietUI) Error(message string)     { fmt.Fprintln(q.err, strings.TrimLeft(message, "\n")) }
+func (q *quietUI) Panel(title, body string) {}
+func (q *quietUI) Stream(text string)       {}
+func (q *quietUI) Progress(status string)   {}
+
+func (q *quietUI) Prompt(question string) (string, error) {
+	fmt.Fprint(q.err, question)
+	return readAnswer(q.in)
+}
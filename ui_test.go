@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useUI swaps the UI in use for the length of a test
+func useUI(t *testing.T, replacement UI) {
+	t.Helper()
+	previous := ui
+	ui = replacement
+	t.Cleanup(func() { ui = previous })
+}
+
+// decodeEvents parses the lines of --output json
+func decodeEvents(t *testing.T, out string) []UIEvent {
+	t.Helper()
+	var events []UIEvent
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var event UIEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %q isn't a JSON event: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestJSONUIEvents(t *testing.T) {
+	var out bytes.Buffer
+	useUI(t, newJSONUI(&out, strings.NewReader("  yes \n")))
+
+	ui.Info("\nGenerating commit message...")
+	ui.Warn("⚠️ Commit canceled")
+	ui.Progress("⏳ retrying in 3s")
+	ui.Panel("📝 COMMIT MESSAGE:", "feat: add login\n")
+	answer, err := ui.Prompt("Commit? [y/n]: ")
+	if err != nil {
+		t.Fatalf("Prompt: %v", err)
+	}
+	if answer != "yes" {
+		t.Errorf("Prompt returned %q, want %q", answer, "yes")
+	}
+
+	want := []UIEvent{
+		{Type: "info", Message: "Generating commit message..."},
+		{Type: "warn", Message: "⚠️ Commit canceled"},
+		{Type: "panel", Title: "📝 COMMIT MESSAGE", Body: "feat: add login"},
+		{Type: "prompt", Question: "Commit? [y/n]:"},
+	}
+	events := decodeEvents(t, out.String())
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d:\n%s", len(events), len(want), out.String())
+	}
+	for i := range want {
+		if events[i].Type != want[i].Type || events[i].Message != want[i].Message || events[i].Title != want[i].Title ||
+			events[i].Body != want[i].Body || events[i].Question != want[i].Question {
+			t.Errorf("event %d = %+v, want %+v", i, events[i], want[i])
+		}
+	}
+}
+
+func TestQuietUIOnlyReportsProblems(t *testing.T) {
+	var out bytes.Buffer
+	useUI(t, newQuietUI(&out, strings.NewReader("n\n")))
+
+	ui.Info("\nGenerating commit message...")
+	ui.Progress("⏳ retrying in 3s")
+	ui.Panel("📝 COMMIT MESSAGE:", "feat: add login")
+	ui.Warn("\n⚠️ Commit canceled")
+	answer, err := ui.Prompt("Commit? [y/n]: ")
+	if err != nil {
+		t.Fatalf("Prompt: %v", err)
+	}
+	if answer != "n" {
+		t.Errorf("Prompt returned %q, want %q", answer, "n")
+	}
+
+	if want := "⚠️ Commit canceled\nCommit? [y/n]: "; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestPlainUILines(t *testing.T) {
+	var out bytes.Buffer
+	useUI(t, newTerminalUI(&out, strings.NewReader("y\n"), false))
+
+	ui.Info("\nGenerating commit message...")
+	ui.Progress("⏳ retrying in 3s")
+	ui.Warn("⚠️ Commit canceled")
+	ui.Panel("📝 COMMIT MESSAGE:", "feat: add login")
+	if _, err := ui.Prompt("Commit? [y/n]: "); err != nil {
+		t.Fatalf("Prompt: %v", err)
+	}
+
+	want := "\nGenerating commit message...\n" +
+		"⚠️ Commit canceled\n" +
+		"\n📝 COMMIT MESSAGE:\n" + ruleLine + "\nfeat: add login\n" + ruleLine + "\n" +
+		"Commit? [y/n]: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestWaitWithCountdownStopsWhenCanceled(t *testing.T) {
+	var out bytes.Buffer
+	useUI(t, newJSONUI(&out, strings.NewReader("")))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	started := time.Now()
+	if err := waitWithCountdown(ctx, "Rate limited", time.Minute); err != context.Canceled {
+		t.Errorf("waitWithCountdown returned %v, want context.Canceled", err)
+	}
+	if waited := time.Since(started); waited > time.Second {
+		t.Errorf("waitWithCountdown waited %s after being canceled", waited)
+	}
+	events := decodeEvents(t, out.String())
+	if len(events) != 1 || events[0].Type != "info" || events[0].Message != "⏳ Rate limited, retrying in 1m0s" {
+		t.Errorf("events = %+v, want a single info event", events)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// undoRef points at a snapshot of the state before rmit's last commit. The snapshot is a commit
+// whose tree is the index as it was and whose parent, if any, is the previous HEAD.
+const undoRef = "refs/rmit/undo"
+
+// saveUndoSnapshot records HEAD and the index before committing, so rmit undo can restore both
+// even when staging everything pulled in files the user didn't expect
+func saveUndoSnapshot() error {
+	tree, err := gitOutput("write-tree")
+	if err != nil {
+		return fmt.Errorf("failed to snapshot the index: %w", err)
+	}
+
+	args := []string{"commit-tree", tree, "-m", "rmit undo snapshot"}
+	if head, err := gitOutput("rev-parse", "--verify", "-q", "HEAD"); err == nil {
+		args = append(args, "-p", head)
+	}
+	snapshot, err := gitOutput(args...)
+	if err != nil {
+		return fmt.Errorf("failed to create the undo snapshot: %w", err)
+	}
+	if _, err := gitOutput("update-ref", "-m", "rmit: before commit", undoRef, snapshot); err != nil {
+		return fmt.Errorf("failed to save the undo snapshot: %w", err)
+	}
+	return nil
+}
+
+// undoLastCommit removes rmit's last commit and restores the index from the snapshot. The
+// working tree is left alone, so nothing that was changed is lost.
+func undoLastCommit(force bool) (string, error) {
+	snapshot, err := gitOutput("rev-parse", "--verify", "-q", undoRef)
+	if err != nil {
+		return "", fmt.Errorf("there is no rmit commit to undo")
+	}
+	previous, _ := gitOutput("rev-parse", "--verify", "-q", snapshot+"^")
+	head, err := gitOutput("rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("there is no commit to undo")
+	}
+
+	// Only the commit made right after the snapshot is undone, never anything committed since
+	parent, _ := gitOutput("rev-parse", "--verify", "-q", head+"^")
+	if parent != previous && !force {
+		return "", fmt.Errorf("HEAD has moved since rmit's last commit; use --force to reset to the snapshot anyway")
+	}
+
+	if previous == "" {
+		// Undoing the first commit leaves the branch unborn again
+		if _, err := gitOutput("update-ref", "-d", "HEAD"); err != nil {
+			return "", err
+		}
+	} else if _, err := gitOutput("reset", "-q", "--soft", previous); err != nil {
+		return "", err
+	}
+	if _, err := gitOutput("read-tree", snapshot+"^{tree}"); err != nil {
+		return "", fmt.Errorf("failed to restore the index: %w", err)
+	}
+	if _, err := gitOutput("update-ref", "-d", undoRef); err != nil {
+		return "", err
+	}
+	return head, nil
+}
+
+// newUndoCmd creates the undo command that reverts rmit's last commit
+func newUndoCmd() *cobra.Command {
+	var force bool
+
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Undo the last commit made by rmit",
+		Long: "Before committing, rmit saves HEAD and the index to " + undoRef + ". rmit undo removes the commit and restores " +
+			"the index exactly as it was, unstaging files that were only staged by the commit. The working tree isn't touched.",
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			undone, err := undoLastCommit(force)
+			if err != nil {
+				log.Fatalf("%s %v", red("Error:"), err)
+			}
+
+			subject, _ := gitOutput("log", "-1", "--format=%s", undone)
+			ui.Success(fmt.Sprintf("↩️  Undid commit %s %s", undone[:7], subject))
+			ui.Success("✅ The index is back to how it was before committing, your changes are still in the working tree")
+		},
+	}
+	undoCmd.Flags().BoolVar(&force, "force", false, "Reset to the snapshot even if other commits were made since")
+	return undoCmd
+}
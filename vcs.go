@@ -0,0 +1,268 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Version control systems rmit can describe and commit changes in, chosen with --vcs
+const (
+	vcsAuto    = "auto"
+	vcsGit     = "git"
+	vcsJJ      = "jj"
+	vcsSapling = "sapling"
+	vcsSVN     = "svn"
+)
+
+// VCS is a version control system other than git whose working copy rmit describes and
+// commits. None of them has a staging area: what gets committed is every change in the working
+// copy, or in the given paths. All print diffs in git's format, so everything that reads a diff
+// works unchanged.
+type VCS interface {
+	// Root returns the top level directory of the working copy
+	Root() (string, error)
+	// Diff returns the working copy's changes in git's diff format, limited to paths when given
+	Diff(paths []string) (string, error)
+	// Files lists the tracked files relative to root
+	Files(root string) ([]string, error)
+	// Commit records the changes, limited to paths when given. addRemove also commits untracked
+	// files and deletions, for --all.
+	Commit(message string, paths []string, addRemove bool) error
+}
+
+// vcsSystems are the supported version control systems besides git
+var vcsSystems = map[string]VCS{
+	vcsJJ:      jjVCS{},
+	vcsSapling: saplingVCS{},
+	vcsSVN:     svnVCS{},
+}
+
+// activeVCS is the version control system of the working copy, nil for git
+var activeVCS VCS
+
+// detectVCS finds the version control system of the working directory from the metadata
+// directory closest to it. A jj repository colocated with git has both .jj and .git, and jj
+// owns its working copy.
+func detectVCS() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return vcsGit
+	}
+	for {
+		for _, marker := range []struct{ dir, vcs string }{{".jj", vcsJJ}, {".sl", vcsSapling}, {".git", vcsGit}, {".svn", vcsSVN}} {
+			if _, err := os.Stat(filepath.Join(dir, marker.dir)); err == nil {
+				return marker.vcs
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return vcsGit
+		}
+		dir = parent
+	}
+}
+
+// setupVCS selects the version control system given with --vcs. auto detects it, except in
+// --remote and --exec-in checkouts, which are git unless told otherwise.
+func setupVCS(name string) error {
+	if name == vcsAuto {
+		name = vcsGit
+		if remoteCheckout == nil {
+			name = detectVCS()
+		}
+	}
+	if name == vcsGit {
+		activeVCS = nil
+		return nil
+	}
+	vcs, ok := vcsSystems[name]
+	if !ok {
+		return fmt.Errorf("unknown version control system %q, valid values are: %s, %s, %s, %s, %s", name, vcsAuto, vcsGit, vcsJJ, vcsSapling, vcsSVN)
+	}
+	activeVCS = vcs
+	return nil
+}
+
+// vcsPaths converts the paths makeCommit and describedDiff take to paths for another VCS: --all
+// selects every change, which is what no paths means there
+func vcsPaths(paths []string) []string {
+	if slices.Equal(paths, allPathspec) {
+		return nil
+	}
+	return paths
+}
+
+// vcsCommand returns a command run in the checkout, in dir when given
+func vcsCommand(dir, name string, args ...string) *exec.Cmd {
+	if remoteCheckout != nil {
+		return remoteCheckout.command(nil, name, args...)
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// vcsOutput runs a command in the checkout and returns its output, with the command's own
+// error message when it fails
+func vcsOutput(dir, name string, args ...string) (string, error) {
+	defer profilePhase(phaseGit)()
+	cmd := vcsCommand(dir, name, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%s %s failed: %s", name, args[0], message)
+		}
+		return "", fmt.Errorf("%s %s failed: %w", name, args[0], err)
+	}
+	return string(out), nil
+}
+
+// vcsRun runs a command in the checkout, showing its output like git commit's
+func vcsRun(name string, args ...string) error {
+	defer profilePhase(phaseGit)()
+	cmd := vcsCommand("", name, args...)
+	cmd.Stdout = commandOutput()
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// nonEmptyLines splits command output into its lines, without empty ones
+func nonEmptyLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// jjVCS is Jujutsu. The working copy is a change of its own, @, which rmit describes and
+// commits with jj commit: @ gets the message and a new empty change starts on top of it.
+// Untracked files are tracked automatically, so --all commits nothing more.
+type jjVCS struct{}
+
+func (jjVCS) Root() (string, error) {
+	out, err := vcsOutput("", "jj", "root")
+	return strings.TrimSpace(out), err
+}
+
+func (jjVCS) Diff(paths []string) (string, error) {
+	out, err := vcsOutput("", "jj", append([]string{"diff", "--git", "--color=never", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", errNoChanges
+	}
+	return out, nil
+}
+
+func (jjVCS) Files(root string) ([]string, error) {
+	out, err := vcsOutput(root, "jj", "file", "list", "--color=never")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(out), nil
+}
+
+func (jjVCS) Commit(message string, paths []string, addRemove bool) error {
+	return vcsRun("jj", append([]string{"commit", "-m", message, "--"}, paths...)...)
+}
+
+// saplingVCS is Sapling. sl commit records every change to tracked files; untracked files are
+// only committed with --all, through --addremove, and aren't in sl diff, so they're not
+// described unless added with sl add first.
+type saplingVCS struct{}
+
+func (saplingVCS) Root() (string, error) {
+	out, err := vcsOutput("", "sl", "root")
+	return strings.TrimSpace(out), err
+}
+
+func (saplingVCS) Diff(paths []string) (string, error) {
+	out, err := vcsOutput("", "sl", append([]string{"diff", "--git", "--color=never", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", errNoChanges
+	}
+	return out, nil
+}
+
+func (saplingVCS) Files(root string) ([]string, error) {
+	out, err := vcsOutput(root, "sl", "files", "--color=never")
+	if err != nil {
+		return nil, err
+	}
+	return nonEmptyLines(out), nil
+}
+
+func (saplingVCS) Commit(message string, paths []string, addRemove bool) error {
+	args := []string{"commit", "-m", message}
+	if addRemove {
+		args = append(args, "--addremove")
+	}
+	return vcsRun("sl", append(append(args, "--"), paths...)...)
+}
+
+// svnVCS is a Subversion working copy. svn commit sends the commit to the server right away.
+// Unversioned files aren't in svn diff; --all adds them with svn add before committing, but
+// only files added beforehand are described.
+type svnVCS struct{}
+
+// svnVersionedPattern matches the lines of svn status -v for versioned files: the status
+// columns, working and last changed revisions, author and path
+var svnVersionedPattern = regexp.MustCompile(`^[ ACDIMRX!~L+SKOTB*]{9}\s*\d+\s+\d+\s+\S+\s+(.+)$`)
+
+func (svnVCS) Root() (string, error) {
+	out, err := vcsOutput("", "svn", "info", "--show-item", "wc-root")
+	return strings.TrimSpace(out), err
+}
+
+func (svnVCS) Diff(paths []string) (string, error) {
+	out, err := vcsOutput("", "svn", append([]string{"diff", "--git", "--"}, paths...)...)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(out) == "" {
+		return "", errNoChanges
+	}
+	return out, nil
+}
+
+func (svnVCS) Files(root string) ([]string, error) {
+	// svn list asks the server, svn status -v only reads the working copy
+	out, err := vcsOutput(root, "svn", "status", "-v")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if match := svnVersionedPattern.FindStringSubmatch(strings.TrimRight(line, "\r")); match != nil && match[1] != "." {
+			files = append(files, filepath.ToSlash(match[1]))
+		}
+	}
+	return files, nil
+}
+
+func (svnVCS) Commit(message string, paths []string, addRemove bool) error {
+	if addRemove {
+		targets := paths
+		if len(targets) == 0 {
+			targets = []string{"."}
+		}
+		if err := vcsRun("svn", append([]string{"add", "--force", "-q", "--"}, targets...)...); err != nil {
+			return fmt.Errorf("failed to add unversioned files: %w", err)
+		}
+	}
+	return vcsRun("svn", append([]string{"commit", "-m", message, "--"}, paths...)...)
+}